@@ -4,6 +4,7 @@ import (
 	"container/heap"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -60,6 +61,7 @@ type Beam struct {
 }
 
 var _ Interface = &Beam{}
+var _ KBest = &Beam{}
 var _ perceptron.EarlyUpdateInstanceDecoder = &Beam{}
 
 func (b *Beam) Name() string {
@@ -78,6 +80,16 @@ func (b *Beam) Concurrent() bool {
 	return b.ConcurrentExec
 }
 
+// Copy returns a beam sharing the read-only model, transition system and
+// feature extractor, with its own parsing state, to be used concurrently
+func (b *Beam) Copy() *Beam {
+	newBeam := *b
+	newBeam.currentBeamSize = 0
+	newBeam.candidateScorePool = nil
+	newBeam.DurTotal = 0
+	return &newBeam
+}
+
 func (b *Beam) StartItem(p Problem) []Candidate {
 	if b.Base == nil {
 		panic("Set Base to a transition.Configuration to parse")
@@ -341,6 +353,46 @@ func (b *Beam) Best(a Agenda) Candidate {
 	return agenda.Confs[0]
 }
 
+// BestK returns up to k terminal candidates of the agenda by descending
+// score, skipping candidates with the result of a better one
+func (b *Beam) BestK(a Agenda, k int) []Candidate {
+	agenda := a.(*BaseAgenda)
+	ranked := make([]*ScoredConfiguration, len(agenda.Confs))
+	copy(ranked, agenda.Confs)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score() > ranked[j].Score()
+	})
+	var (
+		results = make([]Candidate, 0, k)
+		seen    = make([]util.Equaler, 0, k)
+	)
+	for _, candidate := range ranked {
+		if len(results) == k {
+			break
+		}
+		candidate.Expand(b.TransFunc)
+		if !candidate.Terminal() {
+			continue
+		}
+		if distinct, ok := candidate.C.(Distinct); ok {
+			result := distinct.Result()
+			var duplicate bool
+			for _, other := range seen {
+				if other.Equal(result) {
+					duplicate = true
+					break
+				}
+			}
+			if duplicate {
+				continue
+			}
+			seen = append(seen, result)
+		}
+		results = append(results, candidate.Copy())
+	}
+	return results
+}
+
 func (b *Beam) Top(a Agenda) Candidate {
 	// start := time.Now()
 	agenda := a.(*BaseAgenda)
@@ -450,6 +502,24 @@ func (b *Beam) Parse(problem Problem) (transition.Configuration, interface{}) {
 	return beamScored.C, resultParams
 }
 
+// ParseKBest returns up to k distinct configurations of the final agenda,
+// ranked by score
+func (b *Beam) ParseKBest(problem Problem, k int) *KBestResult {
+	start := time.Now()
+	candidates := SearchKBest(b, problem, b.Size, k)
+	result := &KBestResult{
+		Configurations: make([]transition.Configuration, len(candidates)),
+		Scores:         make([]float64, len(candidates)),
+	}
+	for i, candidate := range candidates {
+		scored := candidate.(*ScoredConfiguration)
+		result.Configurations[i] = scored.C
+		result.Scores[i] = scored.Score()
+	}
+	b.DurTotal += time.Since(start)
+	return result
+}
+
 func (b *Beam) DecodeEarlyUpdate(goldInstance perceptron.DecodedInstance, m perceptron.Model) (perceptron.DecodedInstance, interface{}, interface{}, int, int, float64) {
 	b.EarlyUpdateAt = -1
 	start := time.Now()
@@ -1001,6 +1071,13 @@ type ParseResultParameters struct {
 	Sequence   transition.ConfigurationSequence
 }
 
+// KBestResult holds the k best configurations of a parse with their scores,
+// best first
+type KBestResult struct {
+	Configurations []transition.Configuration
+	Scores         []float64
+}
+
 func (a *BaseAgenda) Copy(i, j int) {
 	a.Confs[j] = a.Confs[i]
 }
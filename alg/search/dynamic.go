@@ -0,0 +1,142 @@
+package search
+
+import (
+	"yap/alg/featurevector"
+	"yap/alg/perceptron"
+	"yap/alg/transition"
+	TransitionModel "yap/alg/transition/model"
+	"yap/util"
+
+	"log"
+	"math/rand"
+)
+
+// Dynamic trains a deterministic parser with a dynamic oracle and
+// exploration (Goldberg & Nivre 2012). At each step the model's transition
+// is compared to the best scoring transition of minimal cost, updating the
+// model when the model's transition costs more. The parser follows the
+// best transition of minimal cost, and after ExploreAfter iterations it
+// follows the model's wrong transitions with probability ExploreProb.
+// The transition system's oracle must be a transition.DynamicOracle
+type Dynamic struct {
+	Deterministic
+	ExploreAfter int
+	ExploreProb  float64
+	// Instances is the number of training instances per iteration
+	Instances int
+	Rand      *rand.Rand
+
+	decoded int
+}
+
+var _ perceptron.InstanceDecoder = &Dynamic{}
+var _ perceptron.EarlyUpdateInstanceDecoder = &Dynamic{}
+
+// dynamicDecoded is a training instance decoded with a dynamic oracle, it
+// equals its gold instance when no update is needed
+type dynamicDecoded struct {
+	instance perceptron.Instance
+	decoded  interface{}
+	update   bool
+}
+
+func (d *dynamicDecoded) Instance() perceptron.Instance {
+	return d.instance
+}
+
+func (d *dynamicDecoded) Decoded() interface{} {
+	return d.decoded
+}
+
+func (d *dynamicDecoded) Equal(otherEq util.Equaler) bool {
+	other, ok := otherEq.(*dynamicDecoded)
+	return ok && !d.update && !other.update
+}
+
+// DecodeGold returns the gold instance as is, the dynamic oracle needs the
+// gold graph rather than a gold sequence
+func (d *Dynamic) DecodeGold(goldInstance perceptron.DecodedInstance, m perceptron.Model) (perceptron.DecodedInstance, interface{}) {
+	return &dynamicDecoded{instance: goldInstance.Instance(), decoded: goldInstance.Decoded()}, nil
+}
+
+func (d *Dynamic) DecodeEarlyUpdate(goldInstance perceptron.DecodedInstance, m perceptron.Model) (decoded perceptron.DecodedInstance, predFeatures interface{}, goldFeatures interface{}, firstError int, steps int, score float64) {
+	if !d.NoRecover {
+		defer func() {
+			if r := recover(); r != nil {
+				decoded = nil
+				log.Println("Recovering parse error: ", r)
+			}
+		}()
+	}
+	var iteration int
+	if d.Instances > 0 {
+		iteration = d.decoded / d.Instances
+	}
+	d.decoded++
+	if d.Rand == nil {
+		d.Rand = rand.New(rand.NewSource(1))
+	}
+	oracle, ok := d.TransFunc.Oracle().(transition.DynamicOracle)
+	if !ok {
+		panic("Dynamic oracle training requires a transition system with a dynamic oracle")
+	}
+	oracle.SetGold(goldInstance.Decoded())
+	model := m.(TransitionModel.Interface)
+
+	c := d.Base.Copy()
+	c.Clear()
+	c.Init(goldInstance.Instance())
+
+	var (
+		updateFeatures         [][]featurevector.Feature
+		predTrans, oracleTrans []transition.Transition
+	)
+	firstError = -1
+	for ; !c.Terminal(); steps++ {
+		tType, possible := d.TransFunc.GetTransitions(c)
+		if len(possible) == 0 {
+			panic("No transitions possible for non-terminal configuration")
+		}
+		feats := d.FeatExtractor.Features(c, false, tType, nil)
+		var (
+			pred, best           transition.Transition
+			predScore, bestScore int64
+			predCost, minCost    int
+		)
+		costs := make([]int, len(possible))
+		for i, val := range possible {
+			costs[i] = oracle.Cost(c, &transition.TypedTransition{tType, val})
+			if i == 0 || costs[i] < minCost {
+				minCost = costs[i]
+			}
+		}
+		for i, val := range possible {
+			curScore := model.TransitionScore(transition.ConstTransition(val), feats)
+			if pred == nil || curScore > predScore {
+				pred, predScore, predCost = &transition.TypedTransition{tType, val}, curScore, costs[i]
+			}
+			if costs[i] == minCost && (best == nil || curScore > bestScore) {
+				best, bestScore = &transition.TypedTransition{tType, val}, curScore
+			}
+		}
+		next := pred
+		if predCost > minCost {
+			if firstError < 0 {
+				firstError = steps
+			}
+			updateFeatures = append(updateFeatures, feats)
+			predTrans = append(predTrans, pred)
+			oracleTrans = append(oracleTrans, best)
+			if iteration < d.ExploreAfter || d.Rand.Float64() >= d.ExploreProb {
+				next = best
+			}
+		}
+		c = d.TransFunc.Transition(c, next)
+	}
+	decoded = &dynamicDecoded{goldInstance.Instance(), c, len(updateFeatures) > 0}
+	if len(updateFeatures) > 0 {
+		predFeatures = transition.NewFeaturesList(updateFeatures, predTrans)
+		goldFeatures = transition.NewFeaturesList(updateFeatures, oracleTrans)
+	}
+	return
+}
@@ -35,6 +35,13 @@ type Aligned interface {
 	Alignment() int
 }
 
+// Distinct configurations are compared by their result (e.g. the chosen
+// analyses) rather than by the transitions leading to it when choosing the
+// k best candidates
+type Distinct interface {
+	Result() util.Equaler
+}
+
 type Candidates interface {
 	Get(int) Candidate
 	Len() int
@@ -62,20 +69,36 @@ type Idle interface {
 	Idle(c Candidate, candidateNum int) Candidate
 }
 
+type KBest interface {
+	BestK(a Agenda, k int) []Candidate
+}
+
 func Search(b Interface, problem Problem, B int) Candidate {
-	candidate, _ := search(b, problem, B, 1, false, nil)
+	candidate, _, _ := search(b, problem, B, 1, false, nil)
 	return candidate
 }
 
+// SearchKBest returns up to K distinct candidates of the final agenda,
+// ranked by score
+func SearchKBest(b Interface, problem Problem, B, K int) []Candidate {
+	candidate, _, kBest := search(b, problem, B, K, false, nil)
+	if kBest == nil {
+		return []Candidate{candidate}
+	}
+	return kBest
+}
+
 func SearchEarlyUpdate(b Interface, problem Problem, B int, goldSequence Candidates) (Candidate, Candidate) {
-	return search(b, problem, B, 1, true, goldSequence)
+	candidate, gold, _ := search(b, problem, B, 1, true, goldSequence)
+	return candidate, gold
 }
 
-func search(b Interface, problem Problem, B, topK int, earlyUpdate bool, goldSequence Candidates) (Candidate, Candidate) {
+func search(b Interface, problem Problem, B, topK int, earlyUpdate bool, goldSequence Candidates) (Candidate, Candidate, []Candidate) {
 	var (
 		goldValue Candidate
 		best      Candidate
 		agenda    Agenda
+		kBest     []Candidate
 
 		// for early update
 		i                 int
@@ -322,8 +345,15 @@ func search(b Interface, problem Problem, B, topK int, earlyUpdate bool, goldSeq
 	}
 	if !earlyUpdate {
 		best = b.Best(agenda)
+		if topK > 1 {
+			kBestInterface, ok := b.(KBest)
+			if !ok {
+				panic("Can't search for k best when beam does not have a k best function")
+			}
+			kBest = kBestInterface.BestK(agenda, topK)
+		}
 	}
 	best = best.Copy()
 	agenda = b.Clear(agenda)
-	return best, goldValue
+	return best, goldValue, kBest
 }
@@ -18,6 +18,20 @@ type FeaturesList struct {
 	Previous   *FeaturesList
 }
 
+// NewFeaturesList links the features and transitions of successive updates,
+// each transition is applied with the features of the previous element
+func NewFeaturesList(features [][]Feature, transitions []Transition) *FeaturesList {
+	var (
+		list *FeaturesList
+		last Transition = ConstTransition(0)
+	)
+	for i, feats := range features {
+		list = &FeaturesList{feats, last, list}
+		last = transitions[i]
+	}
+	return &FeaturesList{nil, last, list}
+}
+
 func (l *FeaturesList) String() string {
 	var (
 		retval []string      = make([]string, 0, 100)
@@ -126,6 +140,14 @@ type Oracle interface {
 	Name() string
 }
 
+// DynamicOracle is an oracle that can also tell the cost of any transition
+// from any configuration, the number of gold arcs it makes unreachable
+// (Goldberg & Nivre 2012)
+type DynamicOracle interface {
+	Oracle
+	Cost(c Configuration, transition Transition) int
+}
+
 func (seq ConfigurationSequence) String() string {
 	var buf bytes.Buffer
 	w := new(tabwriter.Writer)
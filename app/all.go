@@ -29,9 +29,17 @@ var AppCommands []*commander.Command = []*commander.Command{
 	MdCmd(),
 	JointCmd(),
 	MALearnCmd(),
+	OOVLearnCmd(),
 	MACmd(),
 	HebMACmd(),
+	LexCompileCmd(),
+	LexCheckCmd(),
+	LexExportCmd(),
+	LexPatchCmd(),
+	TokenizeCmd(),
 	FuseCmd(),
+	APICmd(),
+	PipelineCmd(),
 	// ValidateMAGoldCmd(),
 	// GenLemmasCmd(),
 	// GenUnAmbLemmasCmd(),
@@ -0,0 +1,236 @@
+package app
+
+import (
+	"yap/alg/search"
+	"yap/nlp/format/conll"
+	"yap/nlp/format/lattice"
+	"yap/nlp/format/raw"
+	nlp "yap/nlp/types"
+	"yap/pipeline"
+
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gonuts/commander"
+	"github.com/gonuts/flag"
+)
+
+var (
+	apiAddr    string
+	apiNoLemma bool
+
+	apiMA  *pipeline.Analyzer
+	apiMD  *pipeline.Disambiguator
+	apiDep *pipeline.Parser
+)
+
+type APIRequest struct {
+	Text    string `json:"text,omitempty"`
+	Lattice string `json:"lattice,omitempty"`
+}
+
+type APIResponse struct {
+	MALattice []lattice.JSONLattice `json:"ma_lattice,omitempty"`
+	MDLattice []lattice.JSONLattice `json:"md_lattice,omitempty"`
+	DepTree   []conll.JSONRow       `json:"dep_tree,omitempty"`
+	Error     string                `json:"error,omitempty"`
+}
+
+func readAPILattice(req *APIRequest) (lattice.Lattice, error) {
+	lats, err := lattice.Read(strings.NewReader(req.Lattice), 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(lats) != 1 {
+		return nil, errors.New(fmt.Sprintf("Expected a single lattice, got %d", len(lats)))
+	}
+	return lats[0], nil
+}
+
+// readAPITokens splits the request text on whitespace, keeping the
+// character offsets of each token in the text
+func readAPITokens(req *APIRequest) (nlp.RangedSentence, error) {
+	sent := raw.SplitFields(req.Text, 0)
+	if len(sent.BasicSentence) == 0 {
+		return sent, errors.New("No tokens in text")
+	}
+	return sent, nil
+}
+
+func APIAnalyze(req *APIRequest) (*APIResponse, error) {
+	tokens, err := readAPITokens(req)
+	if err != nil {
+		return nil, err
+	}
+	maLat := apiMA.AnalyzeRanged(tokens)
+	return &APIResponse{MALattice: lattice.Lattice2JSON(maLat)}, nil
+}
+
+func APIDisambiguate(req *APIRequest) (*APIResponse, error) {
+	maLat, err := readAPILattice(req)
+	if err != nil {
+		return nil, err
+	}
+	mdLat, _, err := apiMD.Disambiguate(maLat)
+	if err != nil {
+		return nil, err
+	}
+	return &APIResponse{MDLattice: lattice.Lattice2JSON(mdLat)}, nil
+}
+
+func APIDepParse(req *APIRequest) (*APIResponse, error) {
+	mdLat, err := readAPILattice(req)
+	if err != nil {
+		return nil, err
+	}
+	parsed := apiDep.Conll(apiDep.Parse(mdLat))
+	return &APIResponse{DepTree: conll.Sentence2JSON(parsed)}, nil
+}
+
+func APIPipeline(req *APIRequest) (*APIResponse, error) {
+	tokens, err := readAPITokens(req)
+	if err != nil {
+		return nil, err
+	}
+	maLat := apiMA.AnalyzeRanged(tokens)
+	mdLat, _, err := apiMD.Disambiguate(maLat)
+	if err != nil {
+		return nil, err
+	}
+	parsed := apiDep.Conll(apiDep.Parse(mdLat))
+	return &APIResponse{
+		MALattice: lattice.Lattice2JSON(maLat),
+		MDLattice: lattice.Lattice2JSON(mdLat),
+		DepTree:   conll.Sentence2JSON(parsed),
+	}, nil
+}
+
+func writeAPIResponse(w http.ResponseWriter, status int, resp *APIResponse) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resp); err != nil {
+		log.Println("Failed writing response:", err)
+	}
+}
+
+func APIHandler(f func(*APIRequest) (*APIResponse, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				log.Println("Recovered error", rec, "while serving", r.URL.Path)
+				writeAPIResponse(w, http.StatusInternalServerError, &APIResponse{Error: fmt.Sprintf("%v", rec)})
+			}
+		}()
+		req := new(APIRequest)
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			writeAPIResponse(w, http.StatusBadRequest, &APIResponse{Error: fmt.Sprintf("Failed decoding request: %v", err)})
+			return
+		}
+		resp, err := f(req)
+		if err != nil {
+			writeAPIResponse(w, http.StatusBadRequest, &APIResponse{Error: err.Error()})
+			return
+		}
+		writeAPIResponse(w, http.StatusOK, resp)
+	}
+}
+
+func APIConfigOut() {
+	log.Println("Configuration")
+	log.Printf("Address:\t\t%s", apiAddr)
+	if len(compiledLexFile) > 0 {
+		log.Printf("Compiled Lexicon:\t%s", compiledLexFile)
+	} else {
+		log.Printf("Heb Lexicon:\t\t%s", lexiconFile)
+		log.Printf("Heb Prefix:\t\t%s", prefixFile)
+	}
+	log.Printf("Normalization:\t%s", normalizeStr)
+	if len(userLexFiles) > 0 {
+		log.Printf("User Lexicons:\t%s (%s)", userLexFiles, userLexMode)
+	}
+	OOVGuesserConfigOut()
+	log.Printf("MD Model:\t\t%s", mdModelName)
+	log.Printf("MD Features:\t\t%s", mdFeaturesFile)
+	log.Printf("MD Beam Size:\t\t%d", mdBeamSize)
+	log.Printf("Dep Model:\t\t%s", depModelName)
+	log.Printf("Dep Features:\t\t%s", depFeaturesFile)
+	log.Printf("Dep Labels:\t\t%s", depLabelsFile)
+	log.Printf("Dep Beam Size:\t\t%d", DepBeamSize)
+	log.Printf("Arc System:\t\t%s", arcSystemStr)
+	log.Printf("Use Lemmas:\t\t%v", !apiNoLemma)
+	MDConfidenceConfigOut()
+	log.Println()
+}
+
+func API(cmd *commander.Command, args []string) error {
+	APIConfigOut()
+	options := lattice.Options{IgnoreLemma: apiNoLemma}
+	apiMA = LoadHebMA(options)
+	apiMD = LoadMDParser(options)
+	apiDep = LoadDepParser(options)
+	log.Println()
+
+	http.HandleFunc("/yap/heb/ma", APIHandler(APIAnalyze))
+	http.HandleFunc("/yap/heb/md", APIHandler(APIDisambiguate))
+	http.HandleFunc("/yap/heb/dep", APIHandler(APIDepParse))
+	http.HandleFunc("/yap/heb/pipeline", APIHandler(APIPipeline))
+	log.Println("Serving on", apiAddr)
+	return http.ListenAndServe(apiAddr, nil)
+}
+
+func APICmd() *commander.Command {
+	cmd := &commander.Command{
+		Run:       API,
+		UsageLine: "api [options]",
+		Short:     "serve morphological analysis, disambiguation and parsing over HTTP/JSON",
+		Long: `
+serve morphological analysis, disambiguation and parsing over HTTP/JSON
+
+	$ ./yap api [-addr localhost:8000] [options]
+
+Models are loaded once on startup. Endpoints accept a POST of a JSON object:
+
+	/yap/heb/ma        {"text": "<space separated tokens>"}
+	/yap/heb/md        {"lattice": "<ambiguous lattice>"}
+	/yap/heb/dep       {"lattice": "<disambiguated lattice>"}
+	/yap/heb/pipeline  {"text": "<space separated tokens>"}
+
+Lattice edges and dependency rows of text requests include the token_range
+(start:end character offsets) of their token in the text
+
+With -confidence, edges of md_lattice include the disambiguation confidence
+of their morpheme
+
+`,
+		Flag: *flag.NewFlagSet("api", flag.ExitOnError),
+	}
+	cmd.Flag.StringVar(&apiAddr, "addr", "localhost:8000", "Address to listen on")
+	cmd.Flag.BoolVar(&ConcurrentBeam, "bconc", true, "Concurrent Beam")
+	cmd.Flag.StringVar(&prefixFile, "prefix", "bgupreflex_withdef.utf8.hr", "Prefix file for morphological analyzer")
+	cmd.Flag.StringVar(&lexiconFile, "lexicon", "bgulex.utf8.hr", "Lexicon file for morphological analyzer")
+	cmd.Flag.StringVar(&compiledLexFile, "lexbin", "", "Optional - Compiled prefix and lexicon file (see lexcompile), instead of -prefix and -lexicon")
+	cmd.Flag.BoolVar(&alwaysnnp, "alwaysnnp", false, "Always add NNP to tokens and prefixed subtokens")
+	cmd.Flag.BoolVar(&nnpnofeats, "addnnpnofeats", false, "Add NNP in lex but without features")
+	cmd.Flag.StringVar(&normalizeStr, "normalize", "none", "Normalize tokens before lookup [niqqud,cantillation,geresh,bidi,presentation|all|none]")
+	UserLexFlags(cmd)
+	OOVGuesserFlags(cmd)
+	cmd.Flag.IntVar(&mdBeamSize, "mdb", 32, "MD Beam Size")
+	MDConfidenceFlags(cmd)
+	cmd.Flag.StringVar(&mdModelName, "mdmn", "hebmd.b32", "MD Modelfile")
+	cmd.Flag.StringVar(&mdFeaturesFile, "mdf", "standalone.md.yaml", "MD Features Configuration File")
+	cmd.Flag.StringVar(&paramFuncName, "p", "Funcs_Main_POS_Both_Prop", "Param Func types: ["+nlp.AllParamFuncNames+"]")
+	cmd.Flag.BoolVar(&UsePOP, "pop", true, "Add POP operation to MD")
+	cmd.Flag.BoolVar(&apiNoLemma, "nolemma", true, "Ignore lemmas")
+	cmd.Flag.IntVar(&DepBeamSize, "depb", 64, "Dependency Beam Size")
+	cmd.Flag.StringVar(&depModelName, "depmn", "dep.b64", "Dependency Modelfile")
+	cmd.Flag.StringVar(&depFeaturesFile, "depf", "zhangnivre2011.yaml", "Dependency Features Configuration File")
+	cmd.Flag.StringVar(&depLabelsFile, "l", "hebtb.labels.conf", "Dependency Labels Configuration File")
+	cmd.Flag.StringVar(&arcSystemStr, "a", "eager", "Optional - Arc System [standard, eager, swap, hybrid]")
+	cmd.Flag.BoolVar(&search.AllOut, "showbeam", false, "Show candidates in beam")
+	return cmd
+}
@@ -12,6 +12,7 @@ import (
 	"yap/nlp/format/lattice"
 	. "yap/nlp/parser/dependency/transition"
 	nlp "yap/nlp/types"
+	"yap/pipeline"
 	"yap/util"
 	"yap/util/conf"
 
@@ -27,6 +28,10 @@ var (
 	depModelName    string
 	depFeaturesFile string
 	depLabelsFile   string
+
+	depDynamic      bool
+	depExploreAfter int
+	depExploreProb  float64
 )
 
 func SetupDepEnum(relations []string) {
@@ -51,7 +56,11 @@ func DepConfigOut(outModelFile string, b search.Interface, t transition.Transiti
 	log.Printf("Transition System:\t%s", t.Name())
 	log.Printf("Iterations:\t\t%d", Iterations)
 	log.Printf("Beam Size:\t\t%d", DepBeamSize)
+	if depDynamic {
+		log.Printf("Dynamic Oracle:\t\texplore after %d iteration(s) with p=%.2f", depExploreAfter, depExploreProb)
+	}
 	log.Printf("Beam Concurrent:\t%v", ConcurrentBeam)
+	log.Printf("Parse Workers:\t\t%d", ParseWorkers)
 	log.Printf("Model file:\t\t%s", outModelFile)
 	log.Printf("Use Lemmas:\t\t%v", !conll.IGNORE_LEMMA)
 	log.Printf("Word Type:\t\t%v", conll.WORD_TYPE)
@@ -87,6 +96,9 @@ func DepConfigOut(outModelFile string, b search.Interface, t transition.Transiti
 }
 
 func DepTrainAndParse(cmd *commander.Command, args []string) error {
+	if arcSystemStr == "mst" {
+		return DepMSTTrainAndParse(cmd, args)
+	}
 	// instantiate the arc system for config output only
 	// it will be reinstantiated later on with struct values
 
@@ -101,6 +113,12 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 	case "eager":
 		arcSystem = &ArcEager{}
 		terminalStack = 0
+	case "swap":
+		arcSystem = &ArcSwap{}
+		terminalStack = 1
+	case "hybrid":
+		arcSystem = &ArcHybrid{}
+		terminalStack = 1
 	default:
 		panic("Unknown arc system")
 	}
@@ -162,7 +180,8 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 		// start processing - setup enumerations
 		log.Println("Setup enumerations")
 	}
-	SetupDepEnum(relations.Values)
+	depRelations := SetupPseudoProjective(relations.Values, outModelFile, modelExists)
+	SetupDepEnum(depRelations)
 
 	// after calling SetupDepEnum, enums are instantiated and set according to the relations
 	// therefore we re-instantiate the arc system with the right parameters
@@ -187,11 +206,42 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 			REDUCE:  RE.Value(),
 			POPROOT: PR.Value(),
 		}
+	case "swap":
+		SetupSwapTransEnum()
+		arcSystem = &ArcSwap{
+			ArcStandard: ArcStandard{
+				SHIFT:       SH.Value(),
+				LEFT:        LA.Value(),
+				RIGHT:       RA.Value(),
+				Relations:   ERel,
+				Transitions: ETrans,
+			},
+			SWAP: SW.Value(),
+		}
+	case "hybrid":
+		arcSystem = &ArcHybrid{
+			ArcStandard: ArcStandard{
+				SHIFT:       SH.Value(),
+				LEFT:        LA.Value(),
+				RIGHT:       RA.Value(),
+				Relations:   ERel,
+				Transitions: ETrans,
+			},
+		}
 	default:
 		panic("Unknown arc system")
 	}
 
 	arcSystem.AddDefaultOracle()
+	if depDynamic {
+		dynamicSystem, ok := arcSystem.(interface {
+			AddDynamicOracle()
+		})
+		if !ok {
+			log.Fatalln("Dynamic oracle training requires the eager or hybrid arc system")
+		}
+		dynamicSystem.AddDynamicOracle()
+	}
 
 	transitionSystem = transition.TransitionSystem(arcSystem)
 
@@ -277,6 +327,7 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 				log.Println("Conll:\tRead", len(s), "sentences")
 				log.Println("Conll:\tConverting from conll to internal structure")
 			}
+			projectivizeConllU(s)
 			goldGraphs = conllu.ConllU2GraphCorpus(s, EWord, EPOS, EWPOS, ERel, EMHost, EMSuffix)
 		} else {
 			s, e := conll.ReadFile(tConll, limit)
@@ -288,6 +339,7 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 				log.Println("Conll:\tRead", len(s), "sentences")
 				log.Println("Conll:\tConverting from conll to internal structure")
 			}
+			projectivizeConll(s)
 			goldGraphs = conll.Conll2GraphCorpus(s, EWord, EPOS, EWPOS, ERel, EMHost, EMSuffix)
 		}
 		if allOut {
@@ -403,7 +455,17 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 			}
 			evaluator = MakeDepEvalStopCondition(sents, goldSents, testSents, decodeTestBeam, perceptron.InstanceDecoder(deterministic), DepBeamSize)
 		}
-		_ = Train(goldSequences, Iterations, modelFile, model, perceptron.EarlyUpdateInstanceDecoder(beam), perceptron.InstanceDecoder(deterministic), evaluator)
+		if depDynamic {
+			dynamic := &search.Dynamic{
+				Deterministic: *deterministic,
+				ExploreAfter:  depExploreAfter,
+				ExploreProb:   depExploreProb,
+				Instances:     len(goldSequences),
+			}
+			_ = Train(goldSequences, Iterations, modelFile, model, perceptron.EarlyUpdateInstanceDecoder(dynamic), perceptron.InstanceDecoder(dynamic), evaluator)
+		} else {
+			_ = Train(goldSequences, Iterations, modelFile, model, perceptron.EarlyUpdateInstanceDecoder(beam), perceptron.InstanceDecoder(deterministic), evaluator)
+		}
 		if allOut {
 			log.Println("Done Training")
 			log.Println()
@@ -417,17 +479,25 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 		if allOut {
 			log.Println("Done writing model")
 		}
-	} else {
-		if allOut && !parseOut {
-			log.Println("Found model file", outModelFile, " ... loading model")
-		}
-		serialization := ReadModel(outModelFile)
-		model.Deserialize(serialization.WeightModel)
-		EWord, EPOS, EWPOS, EMHost, EMSuffix = serialization.EWord, serialization.EPOS, serialization.EWPOS, serialization.EMHost, serialization.EMSuffix
-		if allOut && !parseOut {
-			log.Println("Loaded model")
-		}
-		// model.Log = true
+	} else if allOut && !parseOut {
+		log.Println("Found model file", outModelFile, " ... loading model")
+	}
+	// parsing is done by the parser of the pipeline, whether the model was
+	// just trained or found
+	depParser := &pipeline.Parser{
+		BeamSize:   DepBeamSize,
+		ArcSystem:  arcSystemStr,
+		Concurrent: ConcurrentBeam,
+	}
+	if err := depParser.LoadRelations(outModelFile, featuresFile, depRelations); err != nil {
+		log.Println("Failed loading dependency model", outModelFile)
+		log.Fatalln(err)
+	}
+	EWord, EPOS, EWPOS, EMHost, EMSuffix, EMorphProp = depParser.EWord, depParser.EPOS, depParser.EWPOS, depParser.EMHost, depParser.EMSuffix, depParser.EMorphProp
+	ERel, ETrans = depParser.ERel, depParser.ETrans
+	model = depParser.Beam.Model.(*transitionmodel.AvgMatrixSparse)
+	if allOut && !parseOut {
+		log.Println("Loaded model")
 	}
 	if allOut {
 		log.Println()
@@ -453,16 +523,19 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 				log.Println("Streaming lattice conversion to sentence")
 			}
 			internalStream := lattice.Lattice2SentenceStream(lDisamb, EWord, EPOS, EWPOS, EMorphProp, EMHost, EMSuffix)
-			sentsStream = make(chan interface{}, 2)
+			// sentsStream may be replaced by the constrained stream, the
+			// conversion writes to the stream it was started with
+			tagged := make(chan interface{}, 2)
+			sentsStream = tagged
 			if allOut {
 				log.Println("Streaming sentence conversion to taggged sentence")
 			}
 			go func() {
 				for instance := range internalStream {
 					converted := instance.(nlp.LatticeSentence).TaggedSentence()
-					sentsStream <- converted
+					tagged <- converted
 				}
-				close(sentsStream)
+				close(tagged)
 			}()
 		} else {
 			lDisamb, lDisambE := lattice.ReadFile(inputLat, limit)
@@ -518,37 +591,41 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 		}
 	}
 
-	conf := &SimpleConfiguration{
-		EWord:         EWord,
-		EPOS:          EPOS,
-		EWPOS:         EWPOS,
-		EMHost:        EMHost,
-		EMSuffix:      EMSuffix,
-		ERel:          ERel,
-		ETrans:        ETrans,
-		TerminalStack: terminalStack,
-		TerminalQueue: 0,
+	if fixed := readDepFixed(); sentsStream != nil {
+		sentsStream = constrainStream(sentsStream, fixed)
+	} else {
+		constrainSents(sents, fixed)
 	}
 
-	beam := &search.Beam{
-		TransFunc:            transitionSystem,
-		FeatExtractor:        extractor,
-		Base:                 conf,
-		Model:                model,
-		Size:                 DepBeamSize,
-		ConcurrentExec:       ConcurrentBeam,
-		ShortTempAgenda:      true,
-		EstimatedTransitions: EstimatedBeamTransitions(),
-		ScoredStoreDense:     true,
+	var parser Parser = depParser.Beam
+	if depDynamic {
+		// models trained with a dynamic oracle are greedy
+		parser = &search.Deterministic{
+			Model:            model,
+			TransFunc:        depParser.TransitionSystem,
+			FeatExtractor:    depParser.Beam.FeatExtractor,
+			Base:             depParser.Beam.Base,
+			DefaultTransType: 'A',
+		}
 	}
+	VerifyKBest(parser)
 	if Stream {
 		parsedStream := make(chan interface{}, 2)
 		if allOut {
 			log.Println("Starting parser")
 		}
-		go ParseStream(sentsStream, parsedStream, beam)
+		if KBest > 1 {
+			go ParseKBestStream(sentsStream, parsedStream, parser, KBest)
+			written, err := WriteKBestStreamToFile(outConll, reportConstraintsStream(parsedStream), depKBestConverter(nil))
+			if err != nil {
+				log.Fatalln("Failed writing k-best parses:", err)
+			}
+			log.Println("Wrote", written, "parses (up to", KBest, "per sentence) to", outConll)
+			return nil
+		}
+		go ParseStream(sentsStream, parsedStream, parser)
 		log.Println("Streaming conversion to conll")
-		graphAsConllStream := conll.Graph2ConllStream(parsedStream, EMHost, EMSuffix)
+		graphAsConllStream := deprojectivizeStream(conll.Graph2ConllStream(reportConstraintsStream(parsedStream), EMHost, EMSuffix))
 		if allOut {
 			log.Println("Creating writer stream to", outConll)
 		}
@@ -564,19 +641,28 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 			log.Print("Parsing")
 		}
 
-		parsedGraphs := Parse(sents, beam)
+		parsedGraphs, kBest := parseKBestOrBest(sents, parser)
+		reportConstraints(parsedGraphs)
 		if !parseOut {
 			log.Println("Converting to conll")
 		}
-		if useConllU {
-			graphAsConll := conllu.Graph2ConllUCorpus(parsedGraphs, EMHost, EMSuffix)
+		if kBest != nil {
+			written, err := WriteKBestFile(outConll, kBest, depKBestConverter(asMorphGraphs))
+			if err != nil {
+				log.Fatalln("Failed writing k-best parses:", err)
+			}
+			if !parseOut {
+				log.Println("Wrote", written, "parses (up to", KBest, "per sentence) of", len(parsedGraphs), "sentences to", outConll)
+			}
+		} else if useConllU {
+			graphAsConll := deprojectivize(conllu.Graph2ConllUCorpus(parsedGraphs, EMHost, EMSuffix))
 			morphGraphs := conllu.MergeGraphAndMorphCorpus(graphAsConll, asMorphGraphs)
 			conllu.WriteFile(outConll, morphGraphs)
 			if !parseOut {
 				log.Println("Wrote", len(parsedGraphs), "in conllu format to", outConll)
 			}
 		} else {
-			graphAsConll := conll.Graph2ConllCorpus(parsedGraphs, EMHost, EMSuffix)
+			graphAsConll := deprojectivize(conll.Graph2ConllCorpus(parsedGraphs, EMHost, EMSuffix))
 			conll.WriteFile(outConll, graphAsConll)
 			if !parseOut {
 				log.Println("Wrote", len(parsedGraphs), "in conll format to", outConll)
@@ -590,14 +676,39 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 		log.SetPrefix("")
 		log.SetFlags(0)
 		log.Print("Parsing started")
-		parsedGraphs := Parse(sents, beam)
-		graphAsConll := conll.Graph2ConllCorpus(parsedGraphs, EMHost, EMSuffix)
-		conll.WriteFile(outConll, graphAsConll)
-		log.Println("Wrote", len(parsedGraphs), "in conll format to", outConll)
+		parsedGraphs, kBest := parseKBestOrBest(sents, parser)
+		reportConstraints(parsedGraphs)
+		if kBest != nil {
+			written, err := WriteKBestFile(outConll, kBest, depKBestConverter(asMorphGraphs))
+			if err != nil {
+				log.Fatalln("Failed writing k-best parses:", err)
+			}
+			log.Println("Wrote", written, "parses (up to", KBest, "per sentence) of", len(parsedGraphs), "sentences to", outConll)
+		} else {
+			graphAsConll := deprojectivize(conll.Graph2ConllCorpus(parsedGraphs, EMHost, EMSuffix))
+			conll.WriteFile(outConll, graphAsConll)
+			log.Println("Wrote", len(parsedGraphs), "in conll format to", outConll)
+		}
 	}
 	return nil
 }
 
+// depKBestConverter converts k-best trees to CoNLL, or to CoNLL-U merged
+// with the morphological graph of their sentence
+func depKBestConverter(morphGraphs []interface{}) kBestConverter {
+	return func(i int, graphs []interface{}) []interface{} {
+		if useConllU && len(morphGraphs) > 0 {
+			graphAsConll := deprojectivize(conllu.Graph2ConllUCorpus(graphs, EMHost, EMSuffix))
+			sentMorphGraphs := make([]interface{}, len(graphs))
+			for j := range sentMorphGraphs {
+				sentMorphGraphs[j] = morphGraphs[i]
+			}
+			return conllu.MergeGraphAndMorphCorpus(graphAsConll, sentMorphGraphs)
+		}
+		return deprojectivize(conll.Graph2ConllCorpus(graphs, EMHost, EMSuffix))
+	}
+}
+
 func DepCmd() *commander.Command {
 	cmd := &commander.Command{
 		Run:       DepTrainAndParse,
@@ -606,17 +717,28 @@ func DepCmd() *commander.Command {
 		Long: `
 runs dependency training/parsing
 
-	$ ./yap dep -f <features> -l <labels> -tc <conll> -in <input tagged> -oc <out conll> [-a eager|standard] [options]
+	$ ./yap dep -f <features> -l <labels> -tc <conll> -in <input tagged> -oc <out conll> [-a eager|standard|swap|hybrid|mst] [options]
 
 `,
 		Flag: *flag.NewFlagSet("dep", flag.ExitOnError),
 	}
 	cmd.Flag.BoolVar(&ConcurrentBeam, "bconc", true, "Concurrent Beam")
+	cmd.Flag.IntVar(&ParseWorkers, "workers", 1, "Number of sentences parsed concurrently, each with its own beam")
 	cmd.Flag.IntVar(&Iterations, "it", 1, "Number of Perceptron Iterations")
 	cmd.Flag.IntVar(&DepBeamSize, "b", 64, "Dependency Beam Size")
 	cmd.Flag.StringVar(&modelFile, "m", "model", "Prefix for model file ({m}.b{b}.i{it}.model)")
 	cmd.Flag.StringVar(&depModelName, "mn", "dep.b64", "Modelfile")
-	cmd.Flag.StringVar(&arcSystemStr, "a", "eager", "Optional - Arc System [standard, eager]")
+	cmd.Flag.StringVar(&arcSystemStr, "a", "eager", "Optional - Arc System [standard, eager, swap, hybrid], or mst for the graph based parser")
+	cmd.Flag.StringVar(&depMSTFeaturesFile, "mstf", "mst.yaml", "MST Features Configuration File (-a mst)")
+	cmd.Flag.BoolVar(&depNonProjective, "nonproj", false, "MST: decode non-projective trees with Chu-Liu-Edmonds instead of Eisner")
+	cmd.Flag.IntVar(&KBest, "kbest", 1, "Number of distinct trees to output per sentence, from the final beam")
+	cmd.Flag.StringVar(&depFixedFile, "fixed", "", "Optional - CoNLL file aligned with the input, fixing the heads and labels (HEAD, DEPREL columns) of tokens not marked _ (eager and standard arc systems)")
+	cmd.Flag.BoolVar(&depFixedCol, "fixedcol", false, "Optional - fix the heads and labels of the CoNLL input tokens given in its PHEAD, PDEPREL columns")
+	cmd.Flag.StringVar(&pseudoProjective, "pproj", "", "Optional - Pseudo-projective transformation of training trees and parser output, label encoding [head, path, headpath]")
+
+	cmd.Flag.BoolVar(&depDynamic, "dynamic", false, "Train and parse greedily, training with a dynamic oracle (eager and hybrid arc systems)")
+	cmd.Flag.IntVar(&depExploreAfter, "explorek", 2, "Dynamic oracle: explore model errors after this number of iterations")
+	cmd.Flag.Float64Var(&depExploreProb, "explorep", 0.9, "Dynamic oracle: probability of following a model error when exploring")
 
 	cmd.Flag.StringVar(&tConll, "tc", "", "Training Conll File")
 	cmd.Flag.StringVar(&input, "in", "", "Dev Tagged Sentences File")
@@ -0,0 +1,230 @@
+package app
+
+import (
+	"yap/alg/search"
+	dep "yap/nlp/parser/dependency/transition"
+	nlp "yap/nlp/types"
+
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	depFixedFile string
+	depFixedCol  bool
+)
+
+// fixedRow is the fixed head and relation of a token, empty when free
+type fixedRow struct {
+	head, rel string
+}
+
+// readFixed reads the head and relation columns (0 based) of the tokens
+// of each sentence of a CoNLL file, skipping comments and multi-word or
+// empty nodes of CoNLL-U
+func readFixed(filename string, headCol, relCol int) ([][]fixedRow, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var (
+		sents   [][]fixedRow
+		current []fixedRow
+	)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 16384), 1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if len(line) == 0 {
+			sents = append(sents, current)
+			current = nil
+			if limit > 0 && len(sents) >= limit {
+				break
+			}
+			continue
+		}
+		if line[0] == '#' {
+			continue
+		}
+		record := strings.Split(line, "\t")
+		if len(record[0]) == 0 {
+			return nil, fmt.Errorf("Line %d of %s has no token ID", lineNum, filename)
+		}
+		if strings.ContainsAny(record[0], "-.") {
+			continue
+		}
+		var row fixedRow
+		if len(record) > headCol && record[headCol] != "_" {
+			row.head = record[headCol]
+		}
+		if len(record) > relCol && record[relCol] != "_" {
+			row.rel = record[relCol]
+		}
+		current = append(current, row)
+	}
+	if len(current) > 0 {
+		sents = append(sents, current)
+	}
+	return sents, scanner.Err()
+}
+
+// sentenceConstraints returns the constraints of a sentence of numNodes
+// nodes from its fixed rows; the root relation is left free, it is set by
+// the arc system
+func sentenceConstraints(rows []fixedRow, numNodes int) (*dep.Constraints, error) {
+	if len(rows) != numNodes {
+		return nil, fmt.Errorf("Expected %d fixed rows, got %d", numNodes, len(rows))
+	}
+	constraints := dep.NewConstraints(numNodes)
+	var fixed bool
+	for i, row := range rows {
+		if len(row.head) > 0 {
+			head, err := strconv.Atoi(row.head)
+			if err != nil {
+				return nil, fmt.Errorf("Error parsing fixed head of node %d (%s): %s", i+1, row.head, err.Error())
+			}
+			constraints.Heads[i] = head - 1
+			fixed = true
+		}
+		if len(row.rel) > 0 && constraints.Heads[i] != -1 {
+			rel, exists := ERel.IndexOf(nlp.DepRel(row.rel))
+			if !exists {
+				return nil, fmt.Errorf("Unknown fixed relation of node %d: %s", i+1, row.rel)
+			}
+			constraints.Relations[i] = rel
+			fixed = true
+		}
+	}
+	if !fixed {
+		return nil, nil
+	}
+	rootRel, _ := ERel.IndexOf(nlp.DepRel(nlp.ROOT_LABEL))
+	if err := constraints.Validate(rootRel, true); err != nil {
+		return nil, err
+	}
+	return constraints, nil
+}
+
+// readDepFixed returns the fixed rows of the input sentences with -fixed
+// (HEAD and DEPREL of a side file) or -fixedcol (PHEAD and PDEPREL of the
+// CoNLL input), nil without them
+func readDepFixed() [][]fixedRow {
+	var (
+		fixed [][]fixedRow
+		err   error
+	)
+	switch {
+	case len(depFixedFile) > 0:
+		fixed, err = readFixed(depFixedFile, 6, 7)
+	case depFixedCol:
+		if useConllU || len(inputLat) > 0 {
+			log.Fatalln("-fixedcol requires CoNLL input (-in), use -fixed with a side file")
+		}
+		fixed, err = readFixed(input, 8, 9)
+	default:
+		return nil
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+	switch arcSystemStr {
+	case "eager", "standard":
+	default:
+		log.Fatalln("Fixed heads and relations require the eager or standard arc system")
+	}
+	if depDynamic {
+		// the greedy parser doesn't filter the transitions
+		log.Fatalln("Fixed heads and relations require the beam parser, not -dynamic")
+	}
+	return fixed
+}
+
+// constrain returns a sentence with its constraints, or the sentence as is
+// (reporting the error) if its constraints can't be kept
+func constrain(i int, sent interface{}, fixed [][]fixedRow) interface{} {
+	if i >= len(fixed) {
+		log.Println("Sentence", i+1, "has no fixed rows, parsing without constraints")
+		return sent
+	}
+	tagged := sent.(nlp.EnumTaggedSentence)
+	constraints, err := sentenceConstraints(fixed[i], len(tagged.TaggedTokens()))
+	if err != nil {
+		log.Println("Sentence", i+1, "constraints ignored:", err)
+		return sent
+	}
+	if constraints == nil {
+		return sent
+	}
+	return &dep.ConstrainedSentence{EnumTaggedSentence: tagged, Constraints: constraints}
+}
+
+func constrainSents(sents []interface{}, fixed [][]fixedRow) {
+	if fixed == nil {
+		return
+	}
+	for i, sent := range sents {
+		sents[i] = constrain(i, sent, fixed)
+	}
+}
+
+func constrainStream(sents chan interface{}, fixed [][]fixedRow) chan interface{} {
+	if fixed == nil {
+		return sents
+	}
+	out := make(chan interface{}, 2)
+	go func() {
+		var i int
+		for sent := range sents {
+			out <- constrain(i, sent, fixed)
+			i++
+		}
+		close(out)
+	}()
+	return out
+}
+
+// reportConstraint logs a parsed sentence not keeping its constraints, or
+// the best parse of its k-best results
+func reportConstraint(i int, parsed interface{}) {
+	if result, isKBest := parsed.(*search.KBestResult); isKBest {
+		parsed = result.Configurations[0]
+	}
+	conf, ok := parsed.(*dep.SimpleConfiguration)
+	if !ok || conf.Constraints == nil {
+		return
+	}
+	if violated := conf.Constraints.Violated(conf, ERel); len(violated) > 0 {
+		for j := range violated {
+			violated[j]++
+		}
+		log.Println("Sentence", i+1, "constraints not kept for nodes", violated)
+	}
+}
+
+// reportConstraints logs the parsed sentences not keeping their constraints
+func reportConstraints(parsed []interface{}) {
+	for i, graph := range parsed {
+		reportConstraint(i, graph)
+	}
+}
+
+// reportConstraintsStream logs the parsed sentences of a stream not keeping
+// their constraints, passing them on
+func reportConstraintsStream(parsed chan interface{}) chan interface{} {
+	out := make(chan interface{}, 2)
+	go func() {
+		var i int
+		for graph := range parsed {
+			reportConstraint(i, graph)
+			out <- graph
+			i++
+		}
+		close(out)
+	}()
+	return out
+}
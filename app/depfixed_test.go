@@ -0,0 +1,97 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	dep "yap/nlp/parser/dependency/transition"
+	nlp "yap/nlp/types"
+	"yap/pipeline"
+)
+
+func writeTempFile(t *testing.T, content string) string {
+	file, err := ioutil.TempFile("", "depfixed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return file.Name()
+}
+
+func TestReadFixed(t *testing.T) {
+	filename := writeTempFile(t, "# sent_id = 1\n"+
+		"1-2\tab\t_\t_\t_\t_\t_\t_\t_\t_\n"+
+		"1\ta\t_\t_\t_\t_\t2\tnsubj\t_\t_\n"+
+		"2\tb\t_\t_\t_\t_\t0\troot\t_\t_\n"+
+		"2.1\tc\t_\t_\t_\t_\t_\t_\t_\t_\n"+
+		"\n"+
+		"1\td\t_\t_\t_\t_\t_\t_\t_\t_\n"+
+		"2\te\t_\t_\t_\t_\t1\t_\t_\t_\n")
+	defer os.Remove(filename)
+	sents, err := readFixed(filename, 6, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]fixedRow{
+		{{"2", "nsubj"}, {"0", "root"}},
+		{{"", ""}, {"1", ""}},
+	}
+	if len(sents) != len(expected) {
+		t.Fatalf("Got %d sentences, expected %d", len(sents), len(expected))
+	}
+	for i, rows := range expected {
+		if len(sents[i]) != len(rows) {
+			t.Fatalf("Sentence %d: got %v, expected %v", i+1, sents[i], rows)
+		}
+		for j, row := range rows {
+			if sents[i][j] != row {
+				t.Errorf("Sentence %d row %d: got %v, expected %v", i+1, j+1, sents[i][j], row)
+			}
+		}
+	}
+}
+
+func TestReadFixedNoID(t *testing.T) {
+	filename := writeTempFile(t, "1\ta\t_\t_\t_\t_\t0\troot\t_\t_\n\tb\t_\t_\t_\t_\t1\tobj\t_\t_\n")
+	defer os.Remove(filename)
+	if _, err := readFixed(filename, 6, 7); err == nil {
+		t.Error("Expected an error for a line without a token ID")
+	}
+}
+
+func TestSentenceConstraints(t *testing.T) {
+	ERel = pipeline.NewRelationEnum([]string{"nsubj", "obj"})
+	constraints, err := sentenceConstraints([]fixedRow{{"2", "nsubj"}, {"0", "root"}, {"", "obj"}}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nsubj, _ := ERel.IndexOf(nlp.DepRel("nsubj"))
+	obj, _ := ERel.IndexOf(nlp.DepRel("obj"))
+	var (
+		heads     = []int{1, -1, dep.FREE}
+		relations = []int{nsubj, dep.FREE, obj}
+	)
+	for i := range heads {
+		if constraints.Heads[i] != heads[i] || constraints.Relations[i] != relations[i] {
+			t.Errorf("Node %d: got head %d relation %d, expected %d %d", i+1, constraints.Heads[i], constraints.Relations[i], heads[i], relations[i])
+		}
+	}
+	if constraints, err := sentenceConstraints([]fixedRow{{"", ""}, {"", ""}}, 2); constraints != nil || err != nil {
+		t.Errorf("Free rows: got %v %v, expected no constraints", constraints, err)
+	}
+	for _, rows := range [][]fixedRow{
+		{{"2", ""}, {"1", ""}},
+		{{"0", ""}, {"0", ""}},
+		{{"x", ""}, {"0", ""}},
+		{{"2", "unknown"}, {"0", ""}},
+		{{"0", ""}},
+	} {
+		if _, err := sentenceConstraints(rows, 2); err == nil {
+			t.Errorf("Rows %v: expected an error", rows)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package app
+
+import (
+	"yap/alg/perceptron"
+	"yap/alg/transition"
+	transitionmodel "yap/alg/transition/model"
+	"yap/nlp/format/conll"
+	"yap/nlp/format/conllu"
+	"yap/nlp/format/lattice"
+	"yap/nlp/parser/dependency/mst"
+	nlp "yap/nlp/types"
+	"yap/util"
+	"yap/util/conf"
+
+	"fmt"
+	"log"
+
+	"github.com/gonuts/commander"
+)
+
+var (
+	depMSTFeaturesFile string
+	depNonProjective   bool
+)
+
+// readDepGraphs reads a CoNLL (CoNLL-U with -conllu) file as dependency
+// graphs, with the morphological graphs of CoNLL-U files; training graphs
+// are projectivized with -pproj
+func readDepGraphs(filename string, training bool) (graphs, morphGraphs []interface{}) {
+	if useConllU {
+		sents, _, err := conllu.ReadFile(filename, limit)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if allOut {
+			log.Println("Read", len(sents), "sentences from", filename)
+		}
+		if training {
+			projectivizeConllU(sents)
+		}
+		graphs = conllu.ConllU2GraphCorpus(sents, EWord, EPOS, EWPOS, ERel, EMHost, EMSuffix)
+		morphGraphs = conllu.ConllU2MorphGraphCorpus(sents, EWord, EPOS, EWPOS, ERel, EMorphProp, EMHost, EMSuffix)
+		return
+	}
+	sents, err := conll.ReadFile(filename, limit)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if allOut {
+		log.Println("Read", len(sents), "sentences from", filename)
+	}
+	if training {
+		projectivizeConll(sents)
+	}
+	graphs = conll.Conll2GraphCorpus(sents, EWord, EPOS, EWPOS, ERel, EMHost, EMSuffix)
+	return
+}
+
+// DepMSTTrainAndParse trains and parses with the first-order graph based
+// parser (-a mst) instead of a transition system
+func DepMSTTrainAndParse(cmd *commander.Command, args []string) error {
+	REQUIRED_FLAGS := []string{"oc"}
+	if len(depFixedFile) > 0 || depFixedCol {
+		log.Fatalln("Fixed heads and relations require the eager or standard arc system")
+	}
+	if KBest > 1 {
+		log.Fatalln("K-best parsing (-kbest) requires a beam parser")
+	}
+
+	featuresLocation, found := util.LocateFile(depMSTFeaturesFile, DEFAULT_CONF_DIRS)
+	if found {
+		featuresFile = featuresLocation
+	} else {
+		REQUIRED_FLAGS = append(REQUIRED_FLAGS, "mstf")
+	}
+	labelsLocation, found := util.LocateFile(depLabelsFile, DEFAULT_CONF_DIRS)
+	if found {
+		labelsFile = labelsLocation
+	} else {
+		REQUIRED_FLAGS = append(REQUIRED_FLAGS, "l")
+	}
+	if VerifyExists(inputLat) {
+		REQUIRED_FLAGS = append(REQUIRED_FLAGS, "inl")
+	} else {
+		REQUIRED_FLAGS = append(REQUIRED_FLAGS, "in")
+	}
+
+	var (
+		outModelFile string                           = fmt.Sprintf("%s.mst", modelFile)
+		model        *transitionmodel.AvgMatrixSparse = &transitionmodel.AvgMatrixSparse{}
+		modelExists  bool                             = VerifyExists(outModelFile)
+	)
+	if !modelExists {
+		log.Println("No model found, training")
+		REQUIRED_FLAGS = []string{"it", "tc"}
+	}
+	VerifyFlags(cmd, REQUIRED_FLAGS)
+
+	relations, err := conf.ReadFile(labelsFile)
+	if err != nil {
+		log.Println("Failed reading dependency labels configuration file:", labelsFile)
+		log.Fatalln(err)
+	}
+	SetupDepEnum(SetupPseudoProjective(relations.Values, outModelFile, modelExists))
+
+	featureSetup, err := transition.LoadFeatureConfFile(featuresFile)
+	if err != nil {
+		log.Println("Failed reading feature configuration file:", featuresFile)
+		log.Fatalln(err)
+	}
+	extractor := SetupExtractor(featureSetup, []byte("A"))
+	group, _ := extractor.TransTypeGroups['A']
+	formatters := make([]util.Format, len(group.FeatureTemplates))
+	for i, formatter := range group.FeatureTemplates {
+		formatters[i] = formatter
+	}
+
+	parser := &mst.Parser{
+		FeatExtractor: extractor,
+		Relations:     ERel,
+		TransType:     'A',
+		NonProjective: depNonProjective,
+	}
+	if allOut && !parseOut {
+		log.Println("Configuration")
+		log.Printf("Parser:\t\t\t%s", parser.Name())
+		log.Printf("Iterations:\t\t%d", Iterations)
+		log.Printf("Model file:\t\t%s", outModelFile)
+		log.Printf("Features File:\t%s", featuresFile)
+		log.Printf("Labels File:\t\t%s", labelsFile)
+		log.Println()
+	}
+
+	if !modelExists {
+		goldGraphs, _ := readDepGraphs(tConll, true)
+		goldSequences := TrainingSequences(goldGraphs, GetAsTaggedSentence, GetAsLabeledDepGraph)
+		if allOut {
+			log.Println("Training", Iterations, "iteration(s) on", len(goldSequences), "sentences")
+		}
+		model = transitionmodel.NewAvgMatrixSparse(featureSetup.NumFeatures(), formatters, true)
+		_ = Train(goldSequences, Iterations, modelFile, model, perceptron.EarlyUpdateInstanceDecoder(parser), perceptron.InstanceDecoder(parser), nil)
+		if allOut {
+			log.Println("Done Training")
+			log.Println("Writing model to", outModelFile)
+		}
+		serialization := &Serialization{
+			model.Serialize(-1),
+			EWord, EPOS, EWPOS, EMHost, EMSuffix, EMorphProp, ETrans, ETokens,
+		}
+		WriteModel(outModelFile, serialization)
+	} else {
+		if allOut && !parseOut {
+			log.Println("Found model file", outModelFile, " ... loading model")
+		}
+		serialization := ReadModel(outModelFile)
+		model.Deserialize(serialization.WeightModel)
+		EWord, EPOS, EWPOS, EMHost, EMSuffix = serialization.EWord, serialization.EPOS, serialization.EWPOS, serialization.EMHost, serialization.EMSuffix
+	}
+	parser.Model = model
+
+	var (
+		sents                 []interface{}
+		asGraphs, morphGraphs []interface{}
+	)
+	if len(inputLat) > 0 {
+		lDisamb, lDisambE := lattice.ReadFile(inputLat, limit)
+		if lDisambE != nil {
+			log.Fatalln(lDisambE)
+		}
+		internalSents := lattice.Lattice2SentenceCorpus(lDisamb, EWord, EPOS, EWPOS, EMorphProp, EMHost, EMSuffix)
+		sents = make([]interface{}, len(internalSents))
+		for i, instance := range internalSents {
+			sents[i] = instance.(nlp.LatticeSentence).TaggedSentence()
+		}
+	} else {
+		asGraphs, morphGraphs = readDepGraphs(input, false)
+		sents = make([]interface{}, len(asGraphs))
+		for i, instance := range asGraphs {
+			sents[i] = GetAsTaggedSentence(instance)
+		}
+	}
+	if allOut {
+		log.Println("Parsing")
+	}
+	parsedGraphs := Parse(sents, parser)
+	if useConllU && len(morphGraphs) > 0 {
+		graphAsConll := deprojectivize(conllu.Graph2ConllUCorpus(parsedGraphs, EMHost, EMSuffix))
+		conllu.WriteFile(outConll, conllu.MergeGraphAndMorphCorpus(graphAsConll, morphGraphs))
+	} else {
+		conll.WriteFile(outConll, deprojectivize(conll.Graph2ConllCorpus(parsedGraphs, EMHost, EMSuffix)))
+	}
+	if allOut {
+		log.Println("Wrote", len(parsedGraphs), "parsed sentences to", outConll)
+	}
+	return nil
+}
@@ -47,6 +47,8 @@ func GenSegSequence(iAmbLat, iGoldLat interface{}) nlp.LatticeSentence {
 			nil,
 			sharedSpellouts[0][0].From(),
 			sharedSpellouts[0][len(sharedSpellouts[0])-1].To(),
+			aLat.Range,
+			nil,
 		}
 
 		newLat.GenNexts(false)
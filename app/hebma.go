@@ -10,11 +10,13 @@ import (
 	"yap/nlp/parser/ma"
 	"yap/nlp/parser/xliter8"
 	nlp "yap/nlp/types"
+	"yap/pipeline"
 	// "yap/util"
 
 	"fmt"
 	"log"
-	// "os"
+	"os"
+	"strings"
 
 	"github.com/gonuts/commander"
 	"github.com/gonuts/flag"
@@ -26,14 +28,28 @@ var (
 	nnpnofeats              bool
 	showoov                 bool
 	outJSON                 bool
+	normalizeStr            string
+	shownorm                bool
+	compiledLexFile         string
+	userLexFiles            string
+	userLexMode             string
+	userLexReport           string
 	DEFAULT_DATA_DIRS       = []string{".", "data/bgulex"}
 )
 
 func HebMAConfigOut() {
 	log.Println("Configuration")
-	log.Printf("Heb Lexicon:\t\t%s", prefixFile)
-	log.Printf("Heb Prefix:\t\t%s", lexiconFile)
-	log.Printf("OOV Strategy:\t%v", "Const:NNP")
+	if len(compiledLexFile) > 0 {
+		log.Printf("Compiled Lexicon:\t%s", compiledLexFile)
+	} else {
+		log.Printf("Heb Lexicon:\t\t%s", prefixFile)
+		log.Printf("Heb Prefix:\t\t%s", lexiconFile)
+	}
+	if len(userLexFiles) > 0 {
+		log.Printf("User Lexicons:\t%s (%s)", userLexFiles, userLexMode)
+	}
+	OOVGuesserConfigOut()
+	log.Printf("Normalization:\t%v", normalizeStr)
 	log.Printf("xliter8 out:\t\t%v", xliter8out)
 	log.Println()
 	if useConllU {
@@ -45,6 +61,95 @@ func HebMAConfigOut() {
 	log.Println()
 }
 
+// SetupUDLex sets the lexicon reader to keep the features needed for UD
+// output
+func SetupUDLex() {
+	// override all skips in HEBLEX
+	lex.SKIP_POLAR = false
+	lex.SKIP_BINYAN = false
+	lex.SKIP_ALL_TYPE = false
+	lex.SKIP_TYPES = make(map[string]bool)
+	lattice.IGNORE_LEMMA = false
+	// Compatibility: No features for PROPN in UD Hebrew
+	lex.STRIP_ALL_NNP_OF_FEATS = true
+}
+
+// MergeUserLexicons merges the comma separated user lexicon files of
+// -userlex into the lexicon, writing their conflicts with it to the
+// -userlexreport file
+func MergeUserLexicons(maData *ma.BGULex) {
+	if len(userLexFiles) == 0 {
+		return
+	}
+	var override bool
+	switch userLexMode {
+	case "add":
+	case "override":
+		override = true
+	default:
+		log.Fatalln("Unknown user lexicon mode", userLexMode)
+	}
+	var conflicts []ma.UserLexConflict
+	for _, file := range strings.Split(userLexFiles, ",") {
+		location, found := util.LocateFile(file, DEFAULT_DATA_DIRS)
+		if !found {
+			log.Fatalln("User lexicon file", file, "not found")
+		}
+		log.Println("Merging user lexicon", location)
+		conflicts = append(conflicts, maData.LoadUserLex(location, override)...)
+	}
+	kinds := make(map[string]int)
+	for _, conflict := range conflicts {
+		kinds[conflict.Kind]++
+	}
+	log.Println("User lexicon conflicts:", len(conflicts))
+	for _, kind := range []string{ma.USER_LEX_ADDED, ma.USER_LEX_OVERRIDE, ma.USER_LEX_DUPLICATE} {
+		log.Printf("\t%s:\t%d", kind, kinds[kind])
+	}
+	if len(userLexReport) > 0 {
+		file, err := os.Create(userLexReport)
+		if err != nil {
+			log.Fatalln("Failed creating user lexicon report -", err)
+		}
+		defer file.Close()
+		if err := ma.WriteUserLexConflicts(file, conflicts); err != nil {
+			log.Fatalln("Failed writing user lexicon report -", err)
+		}
+		log.Println("Wrote user lexicon conflicts to", userLexReport)
+	}
+}
+
+// LoadOOVGuesser sets the -oovguesser guesser of the analyzer, if given
+func LoadOOVGuesser(analyzer *pipeline.Analyzer) {
+	if len(oovGuesserFile) == 0 {
+		return
+	}
+	log.Println("Reading OOV guesser", oovGuesserFile)
+	if err := analyzer.LoadOOVGuesser(oovGuesserFile); err != nil {
+		log.Fatalln("Failed reading OOV guesser -", err)
+	}
+}
+
+func OOVGuesserConfigOut() {
+	if len(oovGuesserFile) > 0 {
+		log.Printf("OOV Strategy:\t%v (%s)", "Guess:Suffix", oovGuesserFile)
+	} else {
+		log.Printf("OOV Strategy:\t%v", "Const:NNP")
+	}
+}
+
+// OOVGuesserFlags adds the flag of LoadOOVGuesser to cmd
+func OOVGuesserFlags(cmd *commander.Command) {
+	cmd.Flag.StringVar(&oovGuesserFile, "oovguesser", "", "Optional - Guess analyses of OOV hosts with a guesser trained by oovlearn, instead of Const:NNP")
+}
+
+// UserLexFlags adds the flags of MergeUserLexicons to cmd
+func UserLexFlags(cmd *commander.Command) {
+	cmd.Flag.StringVar(&userLexFiles, "userlex", "", "Optional - Comma separated user lexicon files merged into the lexicon")
+	cmd.Flag.StringVar(&userLexMode, "userlexmode", "add", "User lexicon precedence [add|override]")
+	cmd.Flag.StringVar(&userLexReport, "userlexreport", "", "Optional - Output file for conflicts of user lexicons with the lexicon")
+}
+
 func HebMA(cmd *commander.Command, args []string) error {
 	useConllU = len(conlluFile) > 0
 	var REQUIRED_FLAGS []string
@@ -54,42 +159,42 @@ func HebMA(cmd *commander.Command, args []string) error {
 	} else {
 		REQUIRED_FLAGS = []string{"raw", "out"}
 	}
-	prefixLocation, found := util.LocateFile(prefixFile, DEFAULT_DATA_DIRS)
-	if found {
-		prefixFile = prefixLocation
-	} else {
-		REQUIRED_FLAGS = append(REQUIRED_FLAGS, "prefix")
-	}
-	lexiconLocation, found := util.LocateFile(lexiconFile, DEFAULT_DATA_DIRS)
-	if found {
-		lexiconFile = lexiconLocation
+	if len(compiledLexFile) > 0 {
+		compiledLocation, found := util.LocateFile(compiledLexFile, DEFAULT_DATA_DIRS)
+		if !found {
+			log.Fatalln("Compiled lexicon file", compiledLexFile, "not found")
+		}
+		compiledLexFile = compiledLocation
 	} else {
-		REQUIRED_FLAGS = append(REQUIRED_FLAGS, "lexicon")
+		prefixLocation, found := util.LocateFile(prefixFile, DEFAULT_DATA_DIRS)
+		if found {
+			prefixFile = prefixLocation
+		} else {
+			REQUIRED_FLAGS = append(REQUIRED_FLAGS, "prefix")
+		}
+		lexiconLocation, found := util.LocateFile(lexiconFile, DEFAULT_DATA_DIRS)
+		if found {
+			lexiconFile = lexiconLocation
+		} else {
+			REQUIRED_FLAGS = append(REQUIRED_FLAGS, "lexicon")
+		}
 	}
 	VerifyFlags(cmd, REQUIRED_FLAGS)
+	normalization, err := ma.ParseNormalization(normalizeStr)
+	if err != nil {
+		log.Fatalln(err)
+	}
 	HebMAConfigOut()
 	if outFormat == "ud" {
-		// override all skips in HEBLEX
-		lex.SKIP_POLAR = false
-		lex.SKIP_BINYAN = false
-		lex.SKIP_ALL_TYPE = false
-		lex.SKIP_TYPES = make(map[string]bool)
-		lattice.IGNORE_LEMMA = false
-		// Compatibility: No features for PROPN in UD Hebrew
-		lex.STRIP_ALL_NNP_OF_FEATS = true
-	}
-	maData := new(ma.BGULex)
-	maData.MAType = outFormat
-	log.Println("Reading Morphological Analyzer BGU Prefixes")
-	maData.LoadPrefixes(prefixFile)
-	log.Println("Reading Morphological Analyzer BGU Lexicon")
-	maData.LoadLex(lexiconFile, nnpnofeats)
+		SetupUDLex()
+	}
+	analyzer := NewHebMA(prefixFile, lexiconFile, compiledLexFile, outFormat, lattice.Options{})
+	maData := analyzer.Lex
 	log.Println()
 	var (
-		sents        []nlp.BasicSentence
+		sents        []nlp.RangedSentence
 		sentComments [][]string
-		sentsStream  chan nlp.BasicSentence
-		err          error
+		sentsStream  chan nlp.RangedSentence
 	)
 	if Stream {
 		if useConllU {
@@ -98,7 +203,7 @@ func HebMA(cmd *commander.Command, args []string) error {
 			if err != nil {
 				panic(fmt.Sprintf("Failed reading CoNLL-U file - %v", err))
 			}
-			sentsStream = make(chan nlp.BasicSentence, 2)
+			sentsStream = make(chan nlp.RangedSentence, 2)
 			go func() {
 				var i int
 				for sent := range conllStream {
@@ -107,14 +212,14 @@ func HebMA(cmd *commander.Command, args []string) error {
 						newSent[j] = nlp.Token(token)
 					}
 					i++
-					sentsStream <- newSent
+					sentsStream <- nlp.RangedSentence{BasicSentence: newSent, Ranges: sent.Ranges}
 				}
 				close(sentsStream)
 			}()
 
 		} else {
 			log.Println("Piping raw file to analyzer", inRawFile)
-			sentsStream, err = raw.ReadFileAsStream(inRawFile, limit)
+			sentsStream, err = raw.ReadFileAsStreamRanged(inRawFile, limit)
 			if err != nil {
 				panic(fmt.Sprintf("Failed reading raw file - %v", err))
 			}
@@ -125,7 +230,7 @@ func HebMA(cmd *commander.Command, args []string) error {
 			if err != nil {
 				panic(fmt.Sprintf("Failed reading CoNLL-U file - %v", err))
 			}
-			sents = make([]nlp.BasicSentence, len(conllSents))
+			sents = make([]nlp.RangedSentence, len(conllSents))
 			sentComments = make([][]string, len(conllSents))
 			for i, sent := range conllSents {
 				newSent := make([]nlp.Token, len(sent.Tokens))
@@ -133,21 +238,19 @@ func HebMA(cmd *commander.Command, args []string) error {
 					newSent[j] = nlp.Token(token)
 				}
 				sentComments[i] = sent.Comments
-				sents[i] = newSent
+				sents[i] = nlp.RangedSentence{BasicSentence: newSent, Ranges: sent.Ranges}
 			}
 		} else {
-			sents, err = raw.ReadFile(inRawFile, limit)
+			sents, err = raw.ReadFileRanged(inRawFile, limit)
 			if err != nil {
 				panic(fmt.Sprintf("Failed reading raw file - %v", err))
 			}
 		}
 	}
 	log.Println("Running Hebrew Morphological Analysis")
-	stats := new(ma.AnalyzeStats)
-	stats.Init()
-	maData.Stats = stats
-	maData.AlwaysNNP = alwaysnnp
+	stats := analyzer.Stats
 	maData.LogOOV = showoov
+	maData.LogNormalization = shownorm
 	prefix := log.Prefix()
 	if Stream {
 		lattices := make(chan nlp.LatticeSentence, 2)
@@ -157,6 +260,7 @@ func HebMA(cmd *commander.Command, args []string) error {
 			for sent := range sentsStream {
 				// log.SetPrefix(fmt.Sprintf("%v graph# %v ", prefix, i))
 				lattice, ind := maData.Analyze(sent.Tokens())
+				lattice.SetRanges(sent.Ranges)
 				oovInd = append(oovInd, ind)
 				if i%100 == 0 {
 					log.Println("At sent", i)
@@ -183,6 +287,7 @@ func HebMA(cmd *commander.Command, args []string) error {
 		for i, sent := range sents {
 			log.SetPrefix(fmt.Sprintf("%v graph# %v ", prefix, i))
 			lattices[i], oovInd[i] = maData.Analyze(sent.Tokens())
+			lattices[i].SetRanges(sent.Ranges)
 		}
 		var hebrew xliter8.Interface
 		if xliter8out {
@@ -211,6 +316,14 @@ func HebMA(cmd *commander.Command, args []string) error {
 	log.SetPrefix(prefix)
 	log.Println("Analyzed", stats.TotalTokens, "occurences of", len(stats.UniqTokens), "unique tokens")
 	log.Println("Encountered", stats.OOVTokens, "occurences of", len(stats.UniqOOVTokens), "unknown tokens")
+	if normalization != ma.NORMALIZE_NONE {
+		log.Println("Normalized", stats.NormalizedTokens, "occurences of tokens")
+		for _, norm := range ma.NORMALIZATION_NAMES {
+			if count := stats.Normalizations[norm.Norm]; count > 0 {
+				log.Printf("\t%s:\t%d", norm.Name, count)
+			}
+		}
+	}
 	return nil
 }
 
@@ -224,11 +337,19 @@ run lexicon-based morphological analyzer on raw input
 
 	$ ./yap hebma -prefix <prefix file> -lexicon <lexicon file> -raw <raw file> -out <output file> [options]
 
+tokens with niqqud, cantillation, Hebrew geresh/gershayim, bidi marks or
+presentation form letters may be normalized before lookup with -normalize;
+the output lattice keeps the original tokens
+
+user lexicon files (see README) may be merged into the lexicon with
+-userlex, conflicts with the lexicon are written to -userlexreport
+
 `,
 		Flag: *flag.NewFlagSet("ma", flag.ExitOnError),
 	}
 	cmd.Flag.StringVar(&prefixFile, "prefix", "bgupreflex_withdef.utf8.hr", "Prefix file for morphological analyzer")
 	cmd.Flag.StringVar(&lexiconFile, "lexicon", "bgulex.utf8.hr", "Lexicon file for morphological analyzer")
+	cmd.Flag.StringVar(&compiledLexFile, "lexbin", "", "Optional - Compiled prefix and lexicon file (see lexcompile), instead of -prefix and -lexicon")
 	cmd.Flag.StringVar(&inRawFile, "raw", "", "Input raw (tokenized) file")
 	cmd.Flag.StringVar(&conlluFile, "conllu", "", "CoNLL-U-format input file")
 	cmd.Flag.StringVar(&outLatticeFile, "out", "", "Output lattice file")
@@ -238,9 +359,13 @@ run lexicon-based morphological analyzer on raw input
 	cmd.Flag.IntVar(&limit, "limit", 0, "Limit input set")
 	cmd.Flag.BoolVar(&showoov, "showoov", false, "Output OOV tokens")
 	cmd.Flag.StringVar(&oovFile, "oov", "", "Output OOV File")
+	OOVGuesserFlags(cmd)
+	cmd.Flag.StringVar(&normalizeStr, "normalize", "none", "Normalize tokens before lookup [niqqud,cantillation,geresh,bidi,presentation|all|none]")
+	cmd.Flag.BoolVar(&shownorm, "shownorm", false, "Output normalized tokens")
 	cmd.Flag.BoolVar(&lex.LOG_FAILURES, "showlexerror", false, "Log errors encountered when loading the lexicon")
 	cmd.Flag.StringVar(&outFormat, "format", "spmrl", "Output lattice format [spmrl|ud]")
 	cmd.Flag.BoolVar(&outJSON, "json", false, "Output using JSON")
 	cmd.Flag.BoolVar(&Stream, "stream", false, "Stream data from input through parser to output")
+	UserLexFlags(cmd)
 	return cmd
 }
@@ -98,6 +98,7 @@ func JointConfigOut(outModelFile string, b search.Interface, t transition.Transi
 	log.Printf("Iterations:\t\t%d", Iterations)
 	log.Printf("Beam Size:\t\t%d", BeamSize)
 	log.Printf("Beam Concurrent:\t%v", ConcurrentBeam)
+	log.Printf("Parse Workers:\t\t%d", ParseWorkers)
 	log.Printf("Parameter Func:\t%v", paramFuncName)
 	log.Printf("Use Lemmas:\t\t%v", !lattice.IGNORE_LEMMA)
 	log.Printf("Use POP:\t\t%v", UsePOP)
@@ -172,6 +173,9 @@ func JointTrainAndParse(cmd *commander.Command, args []string) error {
 			ArcStandard: ArcStandard{},
 		}
 		terminalStack = 0
+	case "swap":
+		arcSystem = &ArcSwap{}
+		terminalStack = 1
 	default:
 		panic("Unknown arc system")
 	}
@@ -217,7 +221,7 @@ func JointTrainAndParse(cmd *commander.Command, args []string) error {
 		// start processing - setup enumerations
 		log.Println("Setup enumerations")
 	}
-	SetupEnum(relations.Values)
+	SetupEnum(SetupPseudoProjective(relations.Values, outModelFile, modelExists))
 
 	// after calling SetupEnum, enums are instantiated and set according to the relations
 	// therefore we re-instantiate the arc system with the right parameters
@@ -243,6 +247,20 @@ func JointTrainAndParse(cmd *commander.Command, args []string) error {
 			REDUCE:  RE.Value(),
 			POPROOT: PR.Value(),
 		}
+	case "swap":
+		// the swap transition follows POP, MD transitions are enumerated after it
+		SetupSwapTransEnum()
+		MD = transition.ConstTransition(ETrans.Len())
+		arcSystem = &ArcSwap{
+			ArcStandard: ArcStandard{
+				SHIFT:       SH.Value(),
+				LEFT:        LA.Value(),
+				RIGHT:       RA.Value(),
+				Relations:   ERel,
+				Transitions: ETrans,
+			},
+			SWAP: SW.Value(),
+		}
 	default:
 		panic("Unknown arc system")
 	}
@@ -318,6 +336,7 @@ func JointTrainAndParse(cmd *commander.Command, args []string) error {
 				log.Println("Conll:\tRead", len(s), "sentences")
 				log.Println("Conll:\tConverting from conll to internal structure")
 			}
+			projectivizeConllU(s)
 			goldConll = conllu.ConllU2MorphGraphCorpus(s, EWord, EPOS, EWPOS, ERel, EMorphProp, EMHost, EMSuffix)
 		} else {
 			s, e := conll.ReadFile(tConll, limit)
@@ -329,6 +348,7 @@ func JointTrainAndParse(cmd *commander.Command, args []string) error {
 				log.Println("Conll:\tRead", len(s), "sentences")
 				log.Println("Conll:\tConverting from conll to internal structure")
 			}
+			projectivizeConll(s)
 			goldConll = conll.Conll2GraphCorpus(s, EWord, EPOS, EWPOS, ERel, EMHost, EMSuffix)
 		}
 
@@ -641,6 +661,17 @@ func JointTrainAndParse(cmd *commander.Command, args []string) error {
 				REDUCE:  RE.Value(),
 				POPROOT: PR.Value(),
 			}
+		case "swap":
+			arcSystem = &ArcSwap{
+				ArcStandard: ArcStandard{
+					SHIFT:       SH.Value(),
+					LEFT:        LA.Value(),
+					RIGHT:       RA.Value(),
+					Relations:   ERel,
+					Transitions: ETrans,
+				},
+				SWAP: SW.Value(),
+			}
 		default:
 			panic("Unknown arc system")
 		}
@@ -752,7 +783,7 @@ func JointTrainAndParse(cmd *commander.Command, args []string) error {
 	}
 	beam.Model = model
 	beam.ShortTempAgenda = true
-	parsedGraphs := Parse(predAmbLat, beam)
+	parsedGraphs, kBest := parseKBestOrBest(predAmbLat, beam)
 
 	if allOut {
 		log.Println("Converting", len(parsedGraphs), "to conll")
@@ -760,16 +791,28 @@ func JointTrainAndParse(cmd *commander.Command, args []string) error {
 	if allOut {
 		log.Println("Writing to output file")
 	}
-	var graphAsConll []interface{}
-	if useConllU {
-		graphAsConll = conllu.MorphGraph2ConllCorpus(parsedGraphs)
-		conllu.WriteFile(outConll, graphAsConll)
+	if kBest != nil {
+		written, err := WriteKBestFile(outConll, kBest, func(i int, graphs []interface{}) []interface{} {
+			if useConllU {
+				return deprojectivize(conllu.MorphGraph2ConllCorpus(graphs))
+			}
+			return deprojectivize(conll.MorphGraph2ConllCorpus(graphs))
+		})
+		if err != nil {
+			log.Fatalln("Failed writing k-best parses:", err)
+		}
+		if allOut {
+			log.Println("Wrote", written, "parses (up to", KBest, "per sentence) of", len(parsedGraphs), "sentences to", outConll)
+		}
+	} else if useConllU {
+		conllu.WriteFile(outConll, deprojectivize(conllu.MorphGraph2ConllCorpus(parsedGraphs)))
 	} else {
-		graphAsConll = conll.MorphGraph2ConllCorpus(parsedGraphs)
-		conll.WriteFile(outConll, graphAsConll)
+		conll.WriteFile(outConll, deprojectivize(conll.MorphGraph2ConllCorpus(parsedGraphs)))
 	}
 	if allOut {
-		log.Println("Wrote", len(graphAsConll), "in conll format to", outConll)
+		if kBest == nil {
+			log.Println("Wrote", len(parsedGraphs), "in conll format to", outConll)
+		}
 
 		log.Println("Writing to segmentation file")
 	}
@@ -802,10 +845,11 @@ runs morpho-syntactic training and parsing
 		Flag: *flag.NewFlagSet("joint", flag.ExitOnError),
 	}
 	cmd.Flag.BoolVar(&ConcurrentBeam, "bconc", true, "Concurrent Beam")
+	cmd.Flag.IntVar(&ParseWorkers, "workers", 1, "Number of sentences parsed concurrently, each with its own beam")
 	cmd.Flag.IntVar(&Iterations, "it", 1, "Number of Perceptron Iterations")
 	cmd.Flag.IntVar(&BeamSize, "b", 64, "Beam Size")
 	cmd.Flag.StringVar(&modelFile, "m", "model", "Prefix for model file ({m}.b{b}.i{it}.model)")
-	cmd.Flag.StringVar(&arcSystemStr, "a", "eager", "Optional - Arc System [standard, eager]")
+	cmd.Flag.StringVar(&arcSystemStr, "a", "eager", "Optional - Arc System [standard, eager, swap]")
 
 	cmd.Flag.StringVar(&tConll, "tc", "", "Training Conll File")
 	cmd.Flag.StringVar(&tLatDis, "td", "", "Training Disambiguated Lattices File")
@@ -833,6 +877,8 @@ runs morpho-syntactic training and parsing
 	cmd.Flag.IntVar(&limit, "limit", 0, "limit training set (in thousands)")
 	cmd.Flag.IntVar(&limitdev, "limitdev", 0, "limit dev set (in thousands)")
 	cmd.Flag.BoolVar(&useConllU, "conllu", false, "use CoNLL-U-format input file (for disamb lattices)")
+	cmd.Flag.IntVar(&KBest, "kbest", 1, "Number of distinct parses to output per sentence, from the final beam (the best is used for the segmentation and mapping output)")
+	cmd.Flag.StringVar(&pseudoProjective, "pproj", "", "Optional - Pseudo-projective transformation of training trees and parser output, label encoding [head, path, headpath]")
 	cmd.Flag.BoolVar(&hebMACompat, "hebcompat", false, "Read CoNLLu with hebrew lexicon compatability")
 	// cmd.Flag.BoolVar(&AlignBeam, "align", false, "Use Beam Alignment")
 	// cmd.Flag.BoolVar(&AverageScores, "average", false, "Use Average Scoring")
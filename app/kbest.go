@@ -0,0 +1,104 @@
+package app
+
+import (
+	"yap/alg/search"
+	"yap/nlp/format/conll"
+	"yap/nlp/format/conllu"
+
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+)
+
+// kBestConverter returns the CoNLL (or CoNLL-U) sentences of the ranked
+// configurations of the i'th sentence
+type kBestConverter func(i int, confs []interface{}) []interface{}
+
+// parseKBestOrBest parses the k best results of each instance with -kbest
+// above 1, returning the best configurations with the k-best results, or
+// only the best configurations (and nil)
+func parseKBestOrBest(instances []interface{}, parser Parser) ([]interface{}, []interface{}) {
+	if KBest <= 1 {
+		return Parse(instances, parser), nil
+	}
+	kBest := ParseKBest(instances, parser, KBest)
+	best := make([]interface{}, len(kBest))
+	for i, result := range kBest {
+		best[i] = result.(*search.KBestResult).Configurations[0]
+	}
+	return best, kBest
+}
+
+// VerifyKBest exits if k-best parsing is requested without a beam
+func VerifyKBest(parser Parser) {
+	if _, isBeam := parser.(*search.Beam); KBest > 1 && !isBeam {
+		log.Fatalln("K-best parsing (-kbest) requires a beam parser")
+	}
+}
+
+// writeKBestResult writes the ranked results of a sentence as CoNLL blocks,
+// each preceded by comment lines with its sentence id (from 1), rank and
+// model score. Results converted to the same sentence (e.g. trees which
+// differ only in their pseudo-projective encoding) are written once, and the
+// number of blocks written is returned
+func writeKBestResult(writer io.Writer, i int, result *search.KBestResult, convert kBestConverter) int {
+	confs := make([]interface{}, len(result.Configurations))
+	for j, conf := range result.Configurations {
+		confs[j] = conf
+	}
+	var written []interface{}
+	for j, sent := range convert(i, confs) {
+		if containsSentence(written, sent) {
+			continue
+		}
+		written = append(written, sent)
+		fmt.Fprintf(writer, "# sent_id = %d\n# rank = %d\n# score = %s\n", i+1, len(written), strconv.FormatFloat(result.Scores[j], 'f', -1, 64))
+		if _, isConllU := sent.(conllu.Sentence); isConllU {
+			conllu.Write(writer, []interface{}{sent})
+		} else {
+			conll.Write(writer, []interface{}{sent})
+		}
+	}
+	return len(written)
+}
+
+func containsSentence(sents []interface{}, sent interface{}) bool {
+	for _, other := range sents {
+		if reflect.DeepEqual(other, sent) {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteKBestFile writes the k-best results of sentences, returning the
+// number of parses written
+func WriteKBestFile(filename string, results []interface{}, convert kBestConverter) (int, error) {
+	file, err := os.Create(filename)
+	defer file.Close()
+	if err != nil {
+		return 0, err
+	}
+	var written int
+	for i, result := range results {
+		written += writeKBestResult(file, i, result.(*search.KBestResult), convert)
+	}
+	return written, nil
+}
+
+func WriteKBestStreamToFile(filename string, results chan interface{}, convert kBestConverter) (int, error) {
+	file, err := os.Create(filename)
+	defer file.Close()
+	if err != nil {
+		return 0, err
+	}
+	var i, written int
+	for result := range results {
+		written += writeKBestResult(file, i, result.(*search.KBestResult), convert)
+		i++
+	}
+	return written, nil
+}
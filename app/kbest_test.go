@@ -0,0 +1,48 @@
+package app
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"yap/alg/search"
+	"yap/alg/transition"
+	"yap/nlp/format/conll"
+	dep "yap/nlp/parser/dependency/transition"
+)
+
+func TestWriteKBestResult(t *testing.T) {
+	result := &search.KBestResult{
+		Configurations: []transition.Configuration{&dep.SimpleConfiguration{}, &dep.SimpleConfiguration{}, &dep.SimpleConfiguration{}},
+		Scores:         []float64{3.5, 2, 1},
+	}
+	// the first two results are the same tree once converted, as trees
+	// differing only in their pseudo-projective encoding
+	var (
+		first  = conll.Sentence{1: {ID: 1, Form: "a", Head: 0, DepRel: "root"}}
+		second = conll.Sentence{1: {ID: 1, Form: "a", Head: 0, DepRel: "other"}}
+		buf    bytes.Buffer
+	)
+	written := writeKBestResult(&buf, 1, result, func(i int, confs []interface{}) []interface{} {
+		if i != 1 || len(confs) != 3 {
+			t.Errorf("Converting sentence %d with %d results, expected sentence 1 with 3", i, len(confs))
+		}
+		return []interface{}{first, first, second}
+	})
+	var comments []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.HasPrefix(line, "#") {
+			comments = append(comments, line)
+		}
+	}
+	expected := []string{
+		"# sent_id = 2", "# rank = 1", "# score = 3.5",
+		"# sent_id = 2", "# rank = 2", "# score = 1",
+	}
+	if strings.Join(comments, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Got comments %q, expected %q", comments, expected)
+	}
+	if blocks := strings.Count(buf.String(), "\n\n"); blocks != 2 || written != 2 {
+		t.Errorf("Got %d blocks (%d reported), expected the duplicate tree written once", blocks, written)
+	}
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"yap/nlp/format/lex"
+	"yap/util"
+
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/gonuts/commander"
+	"github.com/gonuts/flag"
+)
+
+var (
+	lexCheckReport string
+)
+
+func LexCheckConfigOut() {
+	log.Println("Configuration")
+	log.Printf("Heb Lexicon:\t\t%s", lexiconFile)
+	log.Printf("Heb Prefix:\t\t%s", prefixFile)
+	if len(lexCheckReport) > 0 {
+		log.Printf("Report:\t\t%s", lexCheckReport)
+	} else {
+		log.Printf("Report:\t\t%s", "stdout")
+	}
+	log.Printf("JSON:\t\t%v", outJSON)
+	log.Println()
+}
+
+func LexCheck(cmd *commander.Command, args []string) error {
+	var REQUIRED_FLAGS []string
+	prefixLocation, found := util.LocateFile(prefixFile, DEFAULT_DATA_DIRS)
+	if found {
+		prefixFile = prefixLocation
+	} else {
+		REQUIRED_FLAGS = append(REQUIRED_FLAGS, "prefix")
+	}
+	lexiconLocation, found := util.LocateFile(lexiconFile, DEFAULT_DATA_DIRS)
+	if found {
+		lexiconFile = lexiconLocation
+	} else {
+		REQUIRED_FLAGS = append(REQUIRED_FLAGS, "lexicon")
+	}
+	VerifyFlags(cmd, REQUIRED_FLAGS)
+	LexCheckConfigOut()
+	checker := lex.NewChecker()
+	log.Println("Checking prefixes", prefixFile)
+	if err := checker.CheckFile(prefixFile, "prefix"); err != nil {
+		panic(fmt.Sprintf("Failed reading prefix file - %v", err))
+	}
+	log.Println("Checking lexicon", lexiconFile)
+	if err := checker.CheckFile(lexiconFile, "lexicon"); err != nil {
+		panic(fmt.Sprintf("Failed reading lexicon file - %v", err))
+	}
+	issues := checker.Report()
+	kinds := make(map[string]int)
+	for _, issue := range issues {
+		kinds[issue.Kind]++
+	}
+	log.Println("Found", len(issues), "issues")
+	for _, kind := range []string{lex.ISSUE_MALFORMED, lex.ISSUE_UNKNOWN_POS, lex.ISSUE_UNKNOWN_FEATURE, lex.ISSUE_DUPLICATE, lex.ISSUE_PREFIX_FORMS, lex.ISSUE_UNMATCHABLE_PREFIX} {
+		log.Printf("\t%s:\t%d", kind, kinds[kind])
+	}
+	out := os.Stdout
+	if len(lexCheckReport) > 0 {
+		file, err := os.Create(lexCheckReport)
+		if err != nil {
+			panic(fmt.Sprintf("Failed creating report file - %v", err))
+		}
+		defer file.Close()
+		out = file
+	}
+	return lex.WriteIssues(out, issues, outJSON)
+}
+
+func LexCheckCmd() *commander.Command {
+	cmd := &commander.Command{
+		Run:       LexCheck,
+		UsageLine: "lexcheck <file options> [arguments]",
+		Short:     "check the BGU prefix and lexicon files for errors",
+		Long: `
+check the BGU prefix and lexicon files for malformed lines and MSRs, POS
+tags and feature values with no UD conversion, duplicate analyses and
+prefixes which the analyzer never matches
+
+	$ ./yap lexcheck -prefix <prefix file> -lexicon <lexicon file> [-out <report file>] [options]
+
+the report has an issue per line, as tab separated file, line, token, kind
+and detail, or a JSON array with -json
+
+`,
+		Flag: *flag.NewFlagSet("lexcheck", flag.ExitOnError),
+	}
+	cmd.Flag.StringVar(&prefixFile, "prefix", "bgupreflex_withdef.utf8.hr", "Prefix file for morphological analyzer")
+	cmd.Flag.StringVar(&lexiconFile, "lexicon", "bgulex.utf8.hr", "Lexicon file for morphological analyzer")
+	cmd.Flag.StringVar(&lexCheckReport, "out", "", "Output report file (default stdout)")
+	cmd.Flag.BoolVar(&outJSON, "json", false, "Output using JSON")
+	return cmd
+}
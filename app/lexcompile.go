@@ -0,0 +1,88 @@
+package app
+
+import (
+	"yap/nlp/format/lex"
+	"yap/nlp/parser/ma"
+	"yap/util"
+
+	"fmt"
+	"log"
+
+	"github.com/gonuts/commander"
+	"github.com/gonuts/flag"
+)
+
+var (
+	outCompiledLexFile string
+)
+
+func LexCompileConfigOut() {
+	log.Println("Configuration")
+	log.Printf("Heb Lexicon:\t\t%s", lexiconFile)
+	log.Printf("Heb Prefix:\t\t%s", prefixFile)
+	log.Printf("Format:\t\t%s", outFormat)
+	log.Printf("NNP no feats:\t%v", nnpnofeats)
+	log.Printf("Output:\t\t%s", outCompiledLexFile)
+	log.Println()
+}
+
+func LexCompile(cmd *commander.Command, args []string) error {
+	REQUIRED_FLAGS := []string{"out"}
+	prefixLocation, found := util.LocateFile(prefixFile, DEFAULT_DATA_DIRS)
+	if found {
+		prefixFile = prefixLocation
+	} else {
+		REQUIRED_FLAGS = append(REQUIRED_FLAGS, "prefix")
+	}
+	lexiconLocation, found := util.LocateFile(lexiconFile, DEFAULT_DATA_DIRS)
+	if found {
+		lexiconFile = lexiconLocation
+	} else {
+		REQUIRED_FLAGS = append(REQUIRED_FLAGS, "lexicon")
+	}
+	VerifyFlags(cmd, REQUIRED_FLAGS)
+	LexCompileConfigOut()
+	if outFormat == "ud" {
+		SetupUDLex()
+	}
+	maData := new(ma.BGULex)
+	maData.MAType = outFormat
+	log.Println("Reading Morphological Analyzer BGU Prefixes")
+	maData.LoadPrefixes(prefixFile)
+	log.Println("Reading Morphological Analyzer BGU Lexicon")
+	maData.LoadLex(lexiconFile, nnpnofeats)
+	compiled := maData.Compile(nnpnofeats)
+	log.Println("Compiled", len(compiled.Prefixes), "prefixes and", len(compiled.Lex), "tokens with", len(compiled.Morphemes), "distinct morphemes")
+	log.Println("Writing compiled lexicon to", outCompiledLexFile)
+	if err := compiled.WriteFile(outCompiledLexFile); err != nil {
+		panic(fmt.Sprintf("Failed writing compiled lexicon - %v", err))
+	}
+	return nil
+}
+
+func LexCompileCmd() *commander.Command {
+	cmd := &commander.Command{
+		Run:       LexCompile,
+		UsageLine: "lexcompile <file options> [arguments]",
+		Short:     "compile the BGU prefix and lexicon files to a binary lexicon",
+		Long: `
+compile the BGU prefix and lexicon files to a binary lexicon, loaded with
+-lexbin in hebma, pipeline and api in place of -prefix and -lexicon
+
+	$ ./yap lexcompile -prefix <prefix file> -lexicon <lexicon file> -out <output file> [options]
+
+the lexicon is read with the settings given here (-format, -addnnpnofeats),
+which are recorded in the output; loading it with other settings logs a
+warning
+
+`,
+		Flag: *flag.NewFlagSet("lexcompile", flag.ExitOnError),
+	}
+	cmd.Flag.StringVar(&prefixFile, "prefix", "bgupreflex_withdef.utf8.hr", "Prefix file for morphological analyzer")
+	cmd.Flag.StringVar(&lexiconFile, "lexicon", "bgulex.utf8.hr", "Lexicon file for morphological analyzer")
+	cmd.Flag.StringVar(&outCompiledLexFile, "out", "", "Output compiled lexicon file")
+	cmd.Flag.BoolVar(&nnpnofeats, "addnnpnofeats", false, "Add NNP in lex but without features")
+	cmd.Flag.BoolVar(&lex.LOG_FAILURES, "showlexerror", false, "Log errors encountered when loading the lexicon")
+	cmd.Flag.StringVar(&outFormat, "format", "spmrl", "Lexicon format [spmrl|ud]")
+	return cmd
+}
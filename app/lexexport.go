@@ -0,0 +1,111 @@
+package app
+
+import (
+	"yap/nlp/parser/ma"
+	"yap/util"
+
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/gonuts/commander"
+	"github.com/gonuts/flag"
+)
+
+var (
+	lexExportFile, lexExportDict string
+)
+
+func LexExportConfigOut() {
+	log.Println("Configuration")
+	if len(lexExportDict) > 0 {
+		log.Printf("MA Dictionary:\t%s", lexExportDict)
+	} else {
+		log.Printf("Heb Lexicon:\t\t%s", lexiconFile)
+		log.Printf("Heb Prefix:\t\t%s", prefixFile)
+		log.Printf("Format:\t\t%s", outFormat)
+	}
+	log.Printf("Output:\t\t%s", lexExportFile)
+	log.Printf("JSON:\t\t%v", outJSON)
+	log.Println()
+}
+
+func LexExport(cmd *commander.Command, args []string) error {
+	REQUIRED_FLAGS := []string{"out"}
+	if len(lexExportDict) == 0 {
+		prefixLocation, found := util.LocateFile(prefixFile, DEFAULT_DATA_DIRS)
+		if found {
+			prefixFile = prefixLocation
+		} else {
+			REQUIRED_FLAGS = append(REQUIRED_FLAGS, "prefix")
+		}
+		lexiconLocation, found := util.LocateFile(lexiconFile, DEFAULT_DATA_DIRS)
+		if found {
+			lexiconFile = lexiconLocation
+		} else {
+			REQUIRED_FLAGS = append(REQUIRED_FLAGS, "lexicon")
+		}
+	}
+	VerifyFlags(cmd, REQUIRED_FLAGS)
+	LexExportConfigOut()
+	var exported []ma.ExportedToken
+	if len(lexExportDict) > 0 {
+		maData := new(ma.MADict)
+		log.Println("Reading MA dictionary")
+		if err := maData.ReadFile(lexExportDict); err != nil {
+			panic(fmt.Sprintf("Failed reading MA dictionary - %v", err))
+		}
+		exported = ma.ExportTokens("dict", maData.Data)
+	} else {
+		if outFormat == "ud" {
+			SetupUDLex()
+		}
+		maData := new(ma.BGULex)
+		maData.MAType = outFormat
+		log.Println("Reading Morphological Analyzer BGU Prefixes")
+		maData.LoadPrefixes(prefixFile)
+		log.Println("Reading Morphological Analyzer BGU Lexicon")
+		maData.LoadLex(lexiconFile, nnpnofeats)
+		exported = append(ma.ExportTokens("prefix", maData.Prefixes), ma.ExportTokens("lexicon", maData.Lex)...)
+	}
+	log.Println("Writing", len(exported), "tokens to", lexExportFile)
+	file, err := os.Create(lexExportFile)
+	if err != nil {
+		panic(fmt.Sprintf("Failed creating output file - %v", err))
+	}
+	defer file.Close()
+	if outJSON {
+		return ma.WriteExportedJSON(file, exported)
+	}
+	return ma.WriteExportedTSV(file, exported, true)
+}
+
+func LexExportCmd() *commander.Command {
+	cmd := &commander.Command{
+		Run:       LexExport,
+		UsageLine: "lexexport <file options> [arguments]",
+		Short:     "export the BGU lexicon or an MA dictionary to TSV or JSON",
+		Long: `
+export the analyses of the BGU prefix and lexicon files, as read by hebma,
+or of a data-driven MA dictionary written by malearn, to TSV or JSON
+
+	$ ./yap lexexport -prefix <prefix file> -lexicon <lexicon file> -out <output file> [options]
+	$ ./yap lexexport -dict <dictionary file> -out <output file> [options]
+
+TSV output has a line per morpheme of each analysis (source, token,
+analysis, morpheme, form, lemma, cpos, pos, feats, upos, ufeats), JSON
+output (-json) an object per token; upos and ufeats are the POS and
+features mapped to UD
+
+`,
+		Flag: *flag.NewFlagSet("lexexport", flag.ExitOnError),
+	}
+	cmd.Flag.StringVar(&prefixFile, "prefix", "bgupreflex_withdef.utf8.hr", "Prefix file for morphological analyzer")
+	cmd.Flag.StringVar(&lexiconFile, "lexicon", "bgulex.utf8.hr", "Lexicon file for morphological analyzer")
+	cmd.Flag.StringVar(&lexExportDict, "dict", "", "Optional - MA dictionary (see malearn) to export instead of the lexicon")
+	cmd.Flag.StringVar(&lexExportFile, "out", "", "Output file")
+	cmd.Flag.BoolVar(&nnpnofeats, "addnnpnofeats", false, "Add NNP in lex but without features")
+	cmd.Flag.StringVar(&outFormat, "format", "spmrl", "Lexicon format [spmrl|ud]")
+	cmd.Flag.BoolVar(&outJSON, "json", false, "Output using JSON")
+	return cmd
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"yap/nlp/format/lex"
+
+	"fmt"
+	"log"
+
+	"github.com/gonuts/commander"
+	"github.com/gonuts/flag"
+)
+
+var (
+	lexPatchIn, lexPatchFile, lexPatchOut, lexPatchFormat string
+)
+
+func LexPatchConfigOut() {
+	log.Println("Configuration")
+	log.Printf("Input:\t\t%s", lexPatchIn)
+	log.Printf("Format:\t\t%s", lexPatchFormat)
+	log.Printf("Patch:\t\t%s", lexPatchFile)
+	log.Printf("Output:\t\t%s", lexPatchOut)
+	log.Println()
+}
+
+func LexPatch(cmd *commander.Command, args []string) error {
+	VerifyFlags(cmd, []string{"in", "patch", "out"})
+	if lexPatchFormat != "lexicon" && lexPatchFormat != "prefix" {
+		log.Fatalln("Unknown lexicon file format", lexPatchFormat)
+	}
+	LexPatchConfigOut()
+	lexFile, err := lex.ReadLexFileName(lexPatchIn, lexPatchFormat)
+	if err != nil {
+		panic(fmt.Sprintf("Failed reading lexicon file - %v", err))
+	}
+	ops, err := lex.ReadPatchFile(lexPatchFile)
+	if err != nil {
+		panic(fmt.Sprintf("Failed reading patch file - %v", err))
+	}
+	log.Println("Applying", len(ops), "patch operations")
+	if err := lexFile.Apply(ops); err != nil {
+		log.Fatalln(err)
+	}
+	log.Println("Writing patched file to", lexPatchOut)
+	return lexFile.WriteFile(lexPatchOut)
+}
+
+func LexPatchCmd() *commander.Command {
+	cmd := &commander.Command{
+		Run:       LexPatch,
+		UsageLine: "lexpatch <file options> [arguments]",
+		Short:     "add, remove or modify analyses of a BGU prefix or lexicon file",
+		Long: `
+add, remove or modify analyses of a BGU prefix or lexicon file, writing a
+new file in the same format
+
+	$ ./yap lexpatch -in <lexicon file> -patch <patch file> -out <output file> [options]
+
+the patch file has an operation per line, with tab separated fields:
+
+	add	<token>	<MSR>	<lemma>
+	remove	<token>	<MSR>	<lemma>
+	modify	<token>	<MSR>	<lemma>	<new MSR>	<new lemma>
+
+for prefix files (-format prefix) the MSR and lemma fields are the forms
+and MSRs of the prefix (e.g. ב^ה PREPOSITION+DEF); patching stops at the
+first operation which does not apply, or results in a line the lexicon
+reader rejects
+
+`,
+		Flag: *flag.NewFlagSet("lexpatch", flag.ExitOnError),
+	}
+	cmd.Flag.StringVar(&lexPatchIn, "in", "", "Input prefix or lexicon file")
+	cmd.Flag.StringVar(&lexPatchFile, "patch", "", "Patch file")
+	cmd.Flag.StringVar(&lexPatchOut, "out", "", "Output prefix or lexicon file")
+	cmd.Flag.StringVar(&lexPatchFormat, "format", "lexicon", "Input file format [lexicon|prefix]")
+	return cmd
+}
@@ -13,8 +13,10 @@ import (
 	"yap/nlp/parser/disambig"
 
 	nlp "yap/nlp/types"
+	"yap/pipeline"
 	"yap/util"
 
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -31,6 +33,11 @@ var (
 	mdModelName    string
 	mdFeaturesFile string
 	mdBeamSize     int
+	mdKBestFormat  string
+	mdFixedFile    string
+
+	mdConfidence     bool
+	mdConfidenceTemp float64
 )
 
 func SetupMDEnum() {
@@ -66,32 +73,13 @@ func CombineToGoldMorph(goldLat, ambLat nlp.LatticeSentence) (m *disambig.MDConf
 			continue
 		}
 		mapping := &nlp.Mapping{
-			lat.Token,
-			lat.Spellouts[0],
+			Token:    lat.Token,
+			Spellout: lat.Spellouts[0],
+			Range:    lat.Range,
 		}
 		// if the gold spellout doesn't exist in the lattice, add it
-		if len(ambLat[i].Spellouts) == 0 {
-			ambLat[i].GenSpellouts()
-		}
-		_, exists := ambLat[i].Spellouts.Find(mapping.Spellout)
-		if !exists {
-			// log.Println(mapping.Spellout, "Spellout not found")
-			log.Println(i+1, lat.Spellouts[0].AsString())
-			ambLat[i].Spellouts = append(ambLat[i].Spellouts, mapping.Spellout)
+		if infuseSpellout(ambLat, i, mapping.Spellout) {
 			spelloutsAdded++
-			prevTop := ambLat[i].Top()
-			ambLat[i].AddAnalysis(nil, []nlp.BasicMorphemes{nlp.Morphemes(lat.Spellouts[0]).AsBasic()}, i)
-			// log.Println("Lattice is now:")
-			// log.Println(ambLat)
-			diff := ambLat[i].Top() - prevTop
-			if diff > 0 {
-				for j := i + 1; j < len(ambLat); j++ {
-					ambLat[j].BumpAll(diff)
-				}
-			}
-			// ambLat[i].UnionPath(&lat)
-		} else {
-			// log.Println(mapping.Spellout, "Spellout found")
 		}
 		// ambLat[i].BridgeMissingMorphemes()
 
@@ -105,6 +93,156 @@ func CombineToGoldMorph(goldLat, ambLat nlp.LatticeSentence) (m *disambig.MDConf
 	return m, spelloutsAdded
 }
 
+// infuseSpellout adds a spellout to the i-th lattice of a sentence if it is
+// not one of the lattice's paths, bumping the nodes of the following
+// lattices, and returns whether it was added
+func infuseSpellout(ambLat nlp.LatticeSentence, i int, spellout nlp.Spellout) bool {
+	if len(ambLat[i].Spellouts) == 0 {
+		ambLat[i].GenSpellouts()
+	}
+	if _, exists := ambLat[i].Spellouts.Find(spellout); exists {
+		return false
+	}
+	log.Println(i+1, spellout.AsString())
+	ambLat[i].Spellouts = append(ambLat[i].Spellouts, spellout)
+	prevTop := ambLat[i].Top()
+	ambLat[i].AddAnalysis(nil, []nlp.BasicMorphemes{nlp.Morphemes(spellout).AsBasic()}, i)
+	diff := ambLat[i].Top() - prevTop
+	if diff > 0 {
+		for j := i + 1; j < len(ambLat); j++ {
+			ambLat[j].BumpAll(diff)
+		}
+	}
+	return true
+}
+
+// ConstrainToFixed constrains the disambiguation of the lattices of an
+// ambiguous sentence to the spellouts of a partially disambiguated one,
+// infusing the spellouts missing from the ambiguous lattices: those no path
+// of the lattice matches by paramFunc (as FixedAllowsSpellout). Lattices of
+// the partial sentence without morphemes are not constrained. The ambiguous
+// lattices are left as they are on error
+func ConstrainToFixed(fixedLat, ambLat nlp.LatticeSentence, paramFunc nlp.MDParam) (numFixed, spelloutsAdded int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			numFixed, spelloutsAdded, err = 0, 0, errors.New(fmt.Sprintf("%v", r))
+		}
+	}()
+	if len(fixedLat) > len(ambLat) {
+		return 0, 0, errors.New(fmt.Sprintf("%d fixed tokens for %d tokens", len(fixedLat), len(ambLat)))
+	}
+	for i := range fixedLat {
+		fixedLat[i].GenSpellouts()
+		lat := fixedLat[i]
+		if len(lat.Spellouts) == 0 {
+			continue
+		}
+		if len(lat.Spellouts) > 1 {
+			return 0, 0, errors.New(fmt.Sprintf("token %d (%s) has %d fixed spellouts", i+1, lat.Token, len(lat.Spellouts)))
+		}
+		if len(lat.Token) > 0 && lat.Token != ambLat[i].Token {
+			return 0, 0, errors.New(fmt.Sprintf("token %d is %s, fixed for %s", i+1, ambLat[i].Token, lat.Token))
+		}
+	}
+	constrained := ambLat.Copy()
+	for i, lat := range fixedLat {
+		if len(lat.Spellouts) == 0 {
+			continue
+		}
+		constrained[i].Fixed = lat.Spellouts[0]
+		if !fixedAllowsAnySpellout(&constrained[i], paramFunc) && infuseSpellout(constrained, i, lat.Spellouts[0]) {
+			spelloutsAdded++
+		}
+		numFixed++
+	}
+	copy(ambLat, constrained)
+	return numFixed, spelloutsAdded, nil
+}
+
+// fixedAllowsAnySpellout returns whether one of the spellouts of a lattice
+// is allowed by its fixed spellout
+func fixedAllowsAnySpellout(lat *nlp.Lattice, paramFunc nlp.MDParam) bool {
+	if len(lat.Spellouts) == 0 {
+		lat.GenSpellouts()
+	}
+	for _, spellout := range lat.Spellouts {
+		if disambig.FixedAllowsSpellout(lat, spellout, paramFunc) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadFixedLattices reads partially disambiguated lattices, as lattices of
+// the fixed tokens only or, for CoNLL-U, as the tokens with a word marked
+// Fixed=Yes in the MISC column
+func ReadFixedLattices(filename string, limit int) ([]interface{}, error) {
+	if !useConllU {
+		lFixed, err := lattice.ReadFile(filename, limit)
+		if err != nil {
+			return nil, err
+		}
+		return lattice.Lattice2SentenceCorpus(lFixed, EWord, EPOS, EWPOS, EMorphProp, EMHost, EMSuffix), nil
+	}
+	conllus, _, err := conllu.ReadFile(filename, limit)
+	if err != nil {
+		return nil, err
+	}
+	eRel := util.NewEnumSet(100, "ERel")
+	fixedLats := make([]interface{}, len(conllus))
+	for i, sent := range conllus {
+		lats := conllu.ConllU2MorphGraph(sent, EWord, EPOS, EWPOS, eRel, EMorphProp, EMHost, EMSuffix).Lattice
+		fixed := conllu.FixedTokens(sent)
+		for j := range lats {
+			if !fixed[j] {
+				lats[j] = nlp.Lattice{Token: lats[j].Token}
+			}
+		}
+		fixedLats[i] = lats
+	}
+	return fixedLats, nil
+}
+
+func constrainLattices(i int, fixedLat, ambLat interface{}, paramFunc nlp.MDParam) (int, int) {
+	numFixed, spelloutsAdded, err := ConstrainToFixed(fixedLat.(nlp.LatticeSentence), ambLat.(nlp.LatticeSentence), paramFunc)
+	if err != nil {
+		log.Println("Sentence", i+1, "not constrained:", err)
+	}
+	return numFixed, spelloutsAdded
+}
+
+// ConstrainLatticesCorpus constrains ambiguous lattices to partially
+// disambiguated ones by ConstrainToFixed, sentences beyond the partial
+// corpus are not constrained
+func ConstrainLatticesCorpus(fixedLats, ambLats []interface{}, paramFunc nlp.MDParam) {
+	var totalFixed, totalAdded int
+	for i, fixedLat := range fixedLats {
+		if i >= len(ambLats) {
+			break
+		}
+		numFixed, spelloutsAdded := constrainLattices(i, fixedLat, ambLats[i], paramFunc)
+		totalFixed += numFixed
+		totalAdded += spelloutsAdded
+	}
+	log.Println("Fixed", totalFixed, "tokens, infusing", totalAdded, "spellouts missing from the ambiguous lattices")
+}
+
+func ConstrainLatticesStream(fixedLats []interface{}, ambLats chan interface{}, paramFunc nlp.MDParam) chan interface{} {
+	constrained := make(chan interface{}, 2)
+	go func() {
+		var i int
+		for ambLat := range ambLats {
+			if i < len(fixedLats) {
+				constrainLattices(i, fixedLats[i], ambLat, paramFunc)
+			}
+			constrained <- ambLat
+			i++
+		}
+		close(constrained)
+	}()
+	return constrained
+}
+
 func CombineLattices(goldLat, ambLat interface{}) (interface{}, int, int) {
 	ambigSent := ambLat.(nlp.LatticeSentence)
 	disambSent := goldLat.(nlp.LatticeSentence)
@@ -181,6 +319,7 @@ func MDConfigOut(outModelFile string, b search.Interface, t transition.Transitio
 	log.Printf("Iterations:\t\t%d", Iterations)
 	log.Printf("Beam Size:\t\t%d", BeamSize)
 	log.Printf("Beam Concurrent:\t%v", ConcurrentBeam)
+	log.Printf("Parse Workers:\t\t%d", ParseWorkers)
 	log.Printf("Parameter Func:\t%v", paramFuncName)
 	log.Printf("Use POP:\t\t%v", UsePOP)
 	log.Printf("Infuse Gold Dev:\t%v", combineGold)
@@ -188,9 +327,16 @@ func MDConfigOut(outModelFile string, b search.Interface, t transition.Transitio
 	log.Printf("Use CoNLL-U:\t\t%v", useConllU)
 	log.Printf("No NNP Feat:\t\t%v", lattice.IGNORE_NNP_FEATS)
 	log.Printf("Limit:\t\t%v", limit)
+	if KBest > 1 {
+		log.Printf("K-Best:\t\t%d (%s)", KBest, mdKBestFormat)
+	}
+	MDConfidenceConfigOut()
 	if len(outModelFile) > 0 {
 		log.Printf("Model file:\t\t%s", outModelFile)
 	}
+	if mdLemmatize {
+		log.Printf("Lemmatize:\t\t%v (%s)", mdLemmatize, LemmaModelFile(outModelFile))
+	}
 
 	log.Println()
 	log.Printf("Features File:\t%s", featuresFile)
@@ -217,6 +363,12 @@ func MDConfigOut(outModelFile string, b search.Interface, t transition.Transitio
 			return
 		}
 	}
+	if len(mdFixedFile) > 0 {
+		log.Printf("Fixed analyses file:\t\t%s", mdFixedFile)
+		if !VerifyExists(mdFixedFile) {
+			return
+		}
+	}
 	log.Printf("Out (disamb.) file:\t\t\t%s", outMap)
 }
 
@@ -255,6 +407,12 @@ func MDTrainAndParse(cmd *commander.Command, args []string) error {
 		REQUIRED_FLAGS = append(REQUIRED_FLAGS, "f")
 	}
 	VerifyFlags(cmd, REQUIRED_FLAGS)
+	if mdKBestFormat != "mapping" && mdKBestFormat != "lattice" {
+		log.Fatalln("Unknown k-best output format", mdKBestFormat)
+	}
+	if KBest > BeamSize {
+		log.Println("Warning: k-best results are taken from the final beam, at most", BeamSize, "will be written")
+	}
 
 	var (
 		outModelFile string = fmt.Sprintf("%s.b%d", modelFile, BeamSize)
@@ -276,6 +434,23 @@ func MDTrainAndParse(cmd *commander.Command, args []string) error {
 		VerifyFlags(cmd, REQUIRED_FLAGS)
 	}
 
+	var lemmaFeaturesFile, lemmaModelFile string
+	if mdLemmatize {
+		if KBest > 1 || Stream {
+			log.Fatalln("Lemmatization is not supported with -kbest or -stream")
+		}
+		lemmaFeaturesLocation, found := util.LocateFile(mdLemmaFeaturesFile, DEFAULT_CONF_DIRS)
+		if !found {
+			log.Fatalln("Lemma features file", mdLemmaFeaturesFile, "not found")
+		}
+		lemmaFeaturesFile = lemmaFeaturesLocation
+		lemmaModelFile = LemmaModelFile(outModelFile)
+		if !VerifyExists(lemmaModelFile) {
+			log.Println("No lemma model found, training")
+			VerifyFlags(cmd, []string{"it", "td", "tl"})
+		}
+	}
+
 	// RegisterTypes()
 
 	confBeam := &search.Beam{}
@@ -320,6 +495,12 @@ func MDTrainAndParse(cmd *commander.Command, args []string) error {
 	}
 	log.Println()
 
+	if mdLemmatize && !VerifyExists(lemmaModelFile) {
+		if err := TrainLemmaModel(lemmaFeaturesFile, lemmaModelFile); err != nil {
+			return err
+		}
+	}
+
 	if !modelExists {
 		if allOut {
 			log.Println("Generating Gold Sequences For Training")
@@ -631,46 +812,28 @@ func MDTrainAndParse(cmd *commander.Command, args []string) error {
 	if allOut {
 		log.Println("Found model file", outModelFile, " ... loading model")
 	}
-	serialization := ReadModel(outModelFile)
-	model.Deserialize(serialization.WeightModel)
-	EWord, EPOS, EWPOS, EMHost, EMSuffix, EMorphProp, ETrans, ETokens = serialization.EWord, serialization.EPOS, serialization.EWPOS, serialization.EMHost, serialization.EMSuffix, serialization.EMorphProp, serialization.ETrans, serialization.ETokens
-
-	if UseWB {
-		mdTrans = &disambig.MDWBTrans{
-			ParamFunc:   paramFunc,
-			UsePOP:      UsePOP,
-			POP:         POP,
-			Transitions: ETrans,
-		}
-	} else {
-		mdTrans = &disambig.MDTrans{
-			ParamFunc:   paramFunc,
-			UsePOP:      UsePOP,
-			POP:         POP,
-			Transitions: ETrans,
-		}
+	disambiguator := &pipeline.Disambiguator{
+		BeamSize:      BeamSize,
+		ParamFuncName: paramFuncName,
+		UsePOP:        UsePOP,
+		UseWB:         UseWB,
+		Concurrent:    ConcurrentBeam,
+		OpenFamily:    "HEBTB",
+
+		LatticeOptions: lattice.Options{IgnoreLemma: lattice.IGNORE_LEMMA},
 	}
-
-	transitionSystem = transition.TransitionSystem(mdTrans)
-	extractor = SetupExtractor(featureSetup, []byte("MPL"))
-
-	// setup configuration and beam
-	conf := &disambig.MDConfig{
-		ETokens:     ETokens,
-		POP:         POP,
-		Transitions: ETrans,
-		ParamFunc:   paramFunc,
+	if useConllU {
+		disambiguator.OpenFamily = "UD"
 	}
-
-	beam := &search.Beam{
-		TransFunc:            transitionSystem,
-		FeatExtractor:        extractor,
-		Base:                 conf,
-		Size:                 BeamSize,
-		ConcurrentExec:       ConcurrentBeam,
-		Transitions:          ETrans,
-		EstimatedTransitions: 1000, // chosen by random dice roll
+	if err := disambiguator.Load(outModelFile, featuresFile); err != nil {
+		log.Println("Failed loading MD model", outModelFile)
+		log.Fatalln(err)
 	}
+	// lattices are converted with the enumerations of the model
+	EWord, EPOS, EWPOS, EMHost, EMSuffix, EMorphProp, ETrans, ETokens = disambiguator.EWord, disambiguator.EPOS, disambiguator.EWPOS, disambiguator.EMHost, disambiguator.EMSuffix, disambiguator.EMorphProp, disambiguator.ETrans, disambiguator.ETokens
+	POP = disambiguator.POP
+	paramFunc = disambiguator.ParamFunc
+	beam := disambiguator.Beam
 	if Stream {
 
 		if allOut {
@@ -685,16 +848,39 @@ func MDTrainAndParse(cmd *commander.Command, args []string) error {
 			log.Println("Streaming to lattice conversion")
 		}
 		predAmbLatStream := lattice.Lattice2SentenceStream(lAmb, EWord, EPOS, EWPOS, EMorphProp, EMHost, EMSuffix)
-		beam.ShortTempAgenda = true
-		beam.Model = model
+		if len(mdFixedFile) > 0 {
+			fixedLats, err := ReadFixedLattices(mdFixedFile, limit)
+			if err != nil {
+				log.Println(err)
+				return err
+			}
+			predAmbLatStream = ConstrainLatticesStream(fixedLats, predAmbLatStream, paramFunc)
+		}
 		mappings := make(chan interface{}, 2)
 		if allOut {
 			log.Println("Starting parser")
 		}
-		go ParseStream(predAmbLatStream, mappings, beam)
+		if mdConfidence {
+			go parseStream(predAmbLatStream, mappings, beam, parseMDConfidence(KBest))
+		} else if KBest > 1 {
+			go ParseKBestStream(predAmbLatStream, mappings, beam, KBest)
+		} else {
+			go ParseStream(predAmbLatStream, mappings, beam)
+		}
 		if allOut {
 			log.Println("Creating writer stream to", outMap)
 		}
+		if KBest > 1 && mdKBestFormat == "lattice" {
+			lattices := make(chan lattice.Lattice, 2)
+			go func() {
+				for result := range mappings {
+					lattices <- KBestMDLattice(result)
+				}
+				close(lattices)
+			}()
+			lattice.WriteStreamToFile(outMap, lattices)
+			return nil
+		}
 		mapping.WriteStreamToFile(outMap, mappings)
 
 		return nil
@@ -780,10 +966,26 @@ func MDTrainAndParse(cmd *commander.Command, args []string) error {
 			log.Println()
 		}
 	}
-	beam.ShortTempAgenda = true
-	beam.Model = model
+	if len(mdFixedFile) > 0 {
+		if allOut {
+			log.Println("Reading fixed analyses from", mdFixedFile)
+		}
+		fixedLats, err := ReadFixedLattices(mdFixedFile, limit)
+		if err != nil {
+			log.Println(err)
+			return err
+		}
+		ConstrainLatticesCorpus(fixedLats, predAmbLat, paramFunc)
+	}
 
-	mappings := Parse(predAmbLat, beam)
+	var mappings []interface{}
+	if mdConfidence {
+		mappings = parse(predAmbLat, beam, parseMDConfidence(KBest))
+	} else if KBest > 1 {
+		mappings = ParseKBest(predAmbLat, beam, KBest)
+	} else {
+		mappings = Parse(predAmbLat, beam)
+	}
 
 	/*	if allOut {
 			log.Println("Converting", len(parsedGraphs), "to conll")
@@ -806,6 +1008,25 @@ func MDTrainAndParse(cmd *commander.Command, args []string) error {
 	// }
 	// segmentation.WriteFile(tSeg, ToMorphGraphs(combined))
 
+	if KBest > 1 && mdKBestFormat == "lattice" {
+		if allOut {
+			log.Println("Writing to pruned lattice file")
+		}
+		lattices := make([]lattice.Lattice, len(mappings))
+		for i, result := range mappings {
+			lattices[i] = KBestMDLattice(result)
+		}
+		lattice.WriteFile(outMap, lattices)
+		if allOut {
+			log.Println("Wrote", len(lattices), "in lattice format to", outMap)
+		}
+		return nil
+	}
+	if mdLemmatize {
+		if err := LemmatizeParsed(lemmaFeaturesFile, lemmaModelFile, mappings); err != nil {
+			return err
+		}
+	}
 	if allOut {
 		log.Println("Writing to mapping file")
 	}
@@ -817,6 +1038,46 @@ func MDTrainAndParse(cmd *commander.Command, args []string) error {
 	return nil
 }
 
+// parseMDConfidence returns a parseFunc of disambiguations with the
+// confidence of their tokens and morphemes computed over the final beam,
+// as the best *disambig.MDConfig or, for k above 1, a *search.KBestResult
+// whose best result has confidences
+func parseMDConfidence(k int) parseFunc {
+	return func(parser Parser, instance interface{}) interface{} {
+		beam, ok := parser.(*search.Beam)
+		if !ok {
+			panic("Confidence requires a beam")
+		}
+		result := beam.ParseKBest(instance, beam.Size)
+		best := disambig.SetConfidence(result, mdConfidenceTemp)
+		if k <= 1 {
+			return best
+		}
+		if len(result.Configurations) > k {
+			result.Configurations = result.Configurations[:k]
+			result.Scores = result.Scores[:k]
+		}
+		return result
+	}
+}
+
+func MDConfidenceConfigOut() {
+	if mdConfidence {
+		log.Printf("MD Confidence:\t%v (temperature %v)", mdConfidence, mdConfidenceTemp)
+	}
+}
+
+func MDConfidenceFlags(cmd *commander.Command) {
+	cmd.Flag.BoolVar(&mdConfidence, "confidence", false, "Output the disambiguation confidence of each token and morpheme")
+	cmd.Flag.Float64Var(&mdConfidenceTemp, "conftemp", disambig.DefaultConfidenceTemperature, "Softmax temperature of beam scores per token for -confidence")
+}
+
+// KBestMDLattice returns the lattice of a k-best MD result, pruned to the
+// morphemes of its k best paths
+func KBestMDLattice(result interface{}) lattice.Lattice {
+	return lattice.Sentence2Lattice(disambig.KBestLattice(result.(*search.KBestResult)), nil)
+}
+
 func MdCmd() *commander.Command {
 	cmd := &commander.Command{
 		Run:       MDTrainAndParse,
@@ -827,10 +1088,35 @@ runs standalone morphological disambiguation training and parsing
 
 	$ ./yap md -td <train disamb. lat> -tl <train amb. lat> -in <input lat> [-ing <input lat>] -om <out disamb> -f <feature file> [-p <param func>] [options]
 
+with -kbest K, the K best distinct disambiguations in the final beam are
+written per sentence, as ranked mapping blocks each preceded by
+"# sent_id = <n>", "# rank = <r>" and "# score = <s>" comments, or with
+-kbestformat lattice as a lattice pruned to the morphemes of the K best paths
+
+with -confidence, the confidence of each morpheme is written as an
+additional last column of the mapping output, computed by a softmax (with
+temperature -conftemp) of the scores per token of the distinct results in
+the final beam, as the probability of the results including the morpheme in
+its token; the temperature should be calibrated on held out data
+
+with -fixed, the analyses of some tokens are fixed in advance (e.g. by a
+gazetteer or an annotator) and the rest are disambiguated consistently with
+them; the file holds a lattice of the fixed analysis of each fixed token
+(other tokens are omitted), or with -conllu, a CoNLL-U file whose fixed
+tokens have a word marked Fixed=Yes in the MISC column. Fixed analyses
+missing from the ambiguous lattices are added to them
+
+with -lemmatize, the lemma of each disambiguated morpheme is chosen among
+the lemmas of the analyses in its lattice with the same form, POS and
+features, by a lemma model ({m}.b{b}.lemma, with features -lf) trained on
+the lemmas of the training lattices if missing. Lemma accuracy is logged
+when -ing is given
+
 `,
 		Flag: *flag.NewFlagSet("md", flag.ExitOnError),
 	}
 	cmd.Flag.BoolVar(&ConcurrentBeam, "bconc", true, "Concurrent Beam")
+	cmd.Flag.IntVar(&ParseWorkers, "workers", 1, "Number of sentences parsed concurrently, each with its own beam")
 	cmd.Flag.IntVar(&Iterations, "it", 1, "Minimum Number of Perceptron Iterations")
 	cmd.Flag.IntVar(&mdBeamSize, "b", 32, "Beam Size")
 	cmd.Flag.StringVar(&modelFile, "m", "model", "Prefix for model file ({m}.b{b}.model)")
@@ -843,6 +1129,7 @@ runs standalone morphological disambiguation training and parsing
 	cmd.Flag.StringVar(&test, "test", "", "Test Ambiguous Lattices File")
 	cmd.Flag.StringVar(&testGold, "testgold", "", "Optional - Gold Test Lattices File (for infusion into test ambiguous)")
 	cmd.Flag.StringVar(&outMap, "om", "", "Output Mapping File")
+	cmd.Flag.StringVar(&mdFixedFile, "fixed", "", "Optional - Partially disambiguated lattices (CoNLL-U with -conllu) of analyses fixed in parsing")
 	cmd.Flag.StringVar(&mdFeaturesFile, "f", "standalone.md.yaml", "Features Configuration File")
 	cmd.Flag.StringVar(&paramFuncName, "p", "Funcs_Main_POS_Both_Prop", "Param Func types: ["+nlp.AllParamFuncNames+"]")
 	cmd.Flag.BoolVar(&AlignBeam, "align", false, "Use Beam Alignment")
@@ -860,5 +1147,10 @@ runs standalone morphological disambiguation training and parsing
 	cmd.Flag.IntVar(&limit, "limit", 0, "limit training set")
 	cmd.Flag.BoolVar(&noconverge, "noconverge", false, "don't test convergence (run -it number of iterations)")
 	cmd.Flag.BoolVar(&Stream, "stream", false, "Stream data from input through parser to output")
+	cmd.Flag.IntVar(&KBest, "kbest", 1, "Number of distinct disambiguations to output per sentence")
+	cmd.Flag.StringVar(&mdKBestFormat, "kbestformat", "mapping", "K-best output format [mapping|lattice]")
+	cmd.Flag.BoolVar(&mdLemmatize, "lemmatize", false, "Choose the lemmas of the disambiguated morphemes with a lemma model ({m}.b{b}.lemma)")
+	cmd.Flag.StringVar(&mdLemmaFeaturesFile, "lf", "lemma.yaml", "Lemma Features Configuration File")
+	MDConfidenceFlags(cmd)
 	return cmd
 }
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"testing"
+
+	"yap/alg/graph"
+	nlp "yap/nlp/types"
+)
+
+func testLatticeMorph(id, from, to int, form, lemma, CPOS, features string) *nlp.EMorpheme {
+	return &nlp.EMorpheme{Morpheme: nlp.Morpheme{
+		BasicDirectedEdge: graph.BasicDirectedEdge{id, from, to},
+		Form:              form,
+		Lemma:             lemma,
+		CPOS:              CPOS,
+		POS:               CPOS,
+		Features:          make(map[string]string),
+		FeatureStr:        features,
+	}}
+}
+
+// testAmbLattices returns the lattices of הבית (ה+בית or הבית) and גדול
+func testAmbLattices() nlp.LatticeSentence {
+	return nlp.LatticeSentence{
+		nlp.Lattice{
+			Token: "הבית",
+			Morphemes: nlp.Morphemes{
+				testLatticeMorph(0, 0, 1, "ה", "ה", "DEF", "_"),
+				testLatticeMorph(1, 1, 2, "בית", "בית", "NN", "gen=M|num=S"),
+				testLatticeMorph(2, 0, 2, "הבית", "הבית", "NNP", "_"),
+			},
+			Next:     map[int][]int{0: {0, 2}, 1: {1}},
+			BottomId: 0,
+			TopId:    2,
+		},
+		nlp.Lattice{
+			Token:     "גדול",
+			Morphemes: nlp.Morphemes{testLatticeMorph(0, 2, 3, "גדול", "גדול", "JJ", "gen=M|num=S")},
+			Next:      map[int][]int{2: {0}},
+			BottomId:  2,
+			TopId:     3,
+		},
+	}
+}
+
+func testFixedLattice(token string, spellout nlp.Spellout) nlp.Lattice {
+	return nlp.Lattice{Token: nlp.Token(token), Spellouts: nlp.Spellouts{spellout}}
+}
+
+func TestConstrainToFixed(t *testing.T) {
+	paramFunc := nlp.MDParams["Funcs_Main_POS_Both_Prop"]
+
+	// a lemma wildcard matches the lattice's path, nothing is infused
+	ambLat := testAmbLattices()
+	fixedLat := nlp.LatticeSentence{testFixedLattice("הבית", nlp.Spellout{
+		testLatticeMorph(0, 0, 1, "ה", "_", "DEF", "_"),
+		testLatticeMorph(1, 1, 2, "בית", "_", "NN", "gen=M|num=S"),
+	})}
+	numFixed, added, err := ConstrainToFixed(fixedLat, ambLat, paramFunc)
+	if err != nil || numFixed != 1 || added != 0 {
+		t.Fatalf("Got %d fixed %d added (%v), expected 1 fixed with no spellout added", numFixed, added, err)
+	}
+	if len(ambLat[0].Morphemes) != 3 || len(ambLat[0].Fixed) != 2 {
+		t.Errorf("Got %d morphemes fixed to %v, expected the lattice fixed as is", len(ambLat[0].Morphemes), ambLat[0].Fixed)
+	}
+
+	// a missing spellout is infused, bumping the following lattice
+	ambLat = testAmbLattices()
+	fixedLat = nlp.LatticeSentence{testFixedLattice("הבית", nlp.Spellout{
+		testLatticeMorph(0, 0, 1, "ה", "_", "DEF", "_"),
+		testLatticeMorph(1, 1, 2, "בית", "_", "VB", "_"),
+	})}
+	numFixed, added, err = ConstrainToFixed(fixedLat, ambLat, paramFunc)
+	if err != nil || numFixed != 1 || added != 1 {
+		t.Fatalf("Got %d fixed %d added (%v), expected 1 fixed with a spellout added", numFixed, added, err)
+	}
+	if len(ambLat[0].Morphemes) <= 3 || ambLat[1].Bottom() != ambLat[0].Top() {
+		t.Errorf("Got lattices %v %v, expected the infused spellout", ambLat[0].Morphemes, ambLat[1].Morphemes)
+	}
+
+	// the lattices are left as they are on error
+	ambLat = testAmbLattices()
+	fixedLat = nlp.LatticeSentence{
+		fixedLat[0],
+		testFixedLattice("גדול", nlp.Spellout{nil}),
+	}
+	if _, _, err = ConstrainToFixed(fixedLat, ambLat, paramFunc); err == nil {
+		t.Fatal("Expected an error for a nil fixed morpheme")
+	}
+	if len(ambLat[0].Morphemes) != 3 || ambLat[0].Fixed != nil || ambLat[1].Bottom() != 2 {
+		t.Errorf("Got lattices %v %v changed on error", ambLat[0].Morphemes, ambLat[1].Morphemes)
+	}
+
+	if _, _, err = ConstrainToFixed(nlp.LatticeSentence{testFixedLattice("בית", fixedLat[0].Spellouts[0])}, testAmbLattices(), paramFunc); err == nil {
+		t.Error("Expected an error for a fixed token mismatch")
+	}
+}
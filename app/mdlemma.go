@@ -0,0 +1,264 @@
+package app
+
+import (
+	"yap/alg/perceptron"
+	"yap/alg/search"
+	"yap/alg/transition"
+	transitionmodel "yap/alg/transition/model"
+	"yap/eval"
+	"yap/nlp/format/conllu"
+	"yap/nlp/format/lattice"
+	"yap/nlp/parser/dependency/transition/morph"
+	"yap/nlp/parser/disambig"
+	"yap/nlp/parser/lemma"
+	"yap/pipeline"
+
+	nlp "yap/nlp/types"
+	"yap/util"
+
+	"fmt"
+	"log"
+)
+
+var (
+	mdLemmatize         bool
+	mdLemmaFeaturesFile string
+)
+
+// LemmaModelFile returns the lemma model file of an MD model file
+func LemmaModelFile(mdModelFile string) string {
+	return mdModelFile + ".lemma"
+}
+
+// readLemmaLattices reads ambiguous lattices with their lemmas, regardless
+// of -nolemma, into enumerations of their own so that the MD enumerations
+// are left untouched
+func readLemmaLattices(filename string, limit int, ul bool) ([]interface{}, error) {
+	var (
+		lats lattice.Lattices
+		err  error
+	)
+	opts := lattice.Options{IgnoreLemma: false, IgnoreNNPFeats: lattice.IGNORE_NNP_FEATS}
+	if ul {
+		lats, err = opts.ReadULFile(filename, limit)
+	} else {
+		lats, err = opts.ReadFile(filename, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	eWord, ePOS, eWPOS, eMHost, eMSuffix, eMorphProp := lemmaEnums()
+	return lattice.Lattice2SentenceCorpus(lats, eWord, ePOS, eWPOS, eMorphProp, eMHost, eMSuffix), nil
+}
+
+// readLemmaGold reads disambiguated lattices (CoNLL-U with -conllu) with
+// their lemmas, regardless of -nolemma
+func readLemmaGold(filename string, limit int) ([]interface{}, error) {
+	if !useConllU {
+		return readLemmaLattices(filename, limit, false)
+	}
+	conllus, _, err := conllu.Options{IgnoreLemma: false}.ReadFile(filename, limit)
+	if err != nil {
+		return nil, err
+	}
+	eWord, ePOS, eWPOS, eMHost, eMSuffix, eMorphProp := lemmaEnums()
+	eRel := util.NewEnumSet(100, "ERel")
+	morphGraphs := conllu.ConllU2MorphGraphCorpus(conllus, eWord, ePOS, eWPOS, eRel, eMorphProp, eMHost, eMSuffix)
+	lats := make([]interface{}, len(morphGraphs))
+	for i, val := range morphGraphs {
+		lats[i] = val.(*morph.BasicMorphGraph).Lattice
+	}
+	return lats, nil
+}
+
+func lemmaEnums() (eWord, ePOS, eWPOS, eMHost, eMSuffix, eMorphProp *util.EnumSet) {
+	return util.NewEnumSet(APPROX_WORDS, "EWord"), util.NewEnumSet(APPROX_POS, "EPOS"), util.NewEnumSet(APPROX_WORDS*5, "EWPOS"),
+		util.NewEnumSet(APPROX_MHOSTS, "EMHost"), util.NewEnumSet(APPROX_MSUFFIXES, "EMSuffix"), util.NewEnumSet(10000, "EMorphProp")
+}
+
+func GetLemmaInstance(instance interface{}) util.Equaler {
+	return instance.(*lemma.Instance)
+}
+
+func GetLemmaGold(instance interface{}) util.Equaler {
+	return lemma.GoldLemmas(instance.(*lemma.Instance).Mappings)
+}
+
+// LemmaInstances returns lemma instances of disambiguations (MD configs)
+// and the lattices with lemmas of their sentences, or the disambiguations'
+// own lattices if lats is nil
+func LemmaInstances(configs []interface{}, lats []interface{}) []interface{} {
+	instances := make([]interface{}, len(configs))
+	for i, val := range configs {
+		conf := val.(*disambig.MDConfig)
+		instance := &lemma.Instance{Mappings: conf.Mappings, Lattices: conf.Lattices}
+		if lats != nil {
+			instance.Lattices = lats[i].(nlp.LatticeSentence)
+		}
+		instances[i] = instance
+	}
+	return instances
+}
+
+// LemmaModel is a lemma selection model with its transition system and
+// feature extractor
+type LemmaModel struct {
+	Model       *transitionmodel.AvgMatrixSparse
+	Transitions *util.EnumSet
+	TransSystem *lemma.LemmaTrans
+	Extractor   *transition.GenericExtractor
+}
+
+func NewLemmaModel(featuresFile string, transitions *util.EnumSet) *LemmaModel {
+	featureSetup, err := transition.LoadFeatureConfFile(featuresFile)
+	if err != nil {
+		log.Println("Failed reading lemma feature configuration file:", featuresFile)
+		log.Fatalln(err)
+	}
+	m := &LemmaModel{
+		Model:       &transitionmodel.AvgMatrixSparse{},
+		Transitions: transitions,
+		TransSystem: &lemma.LemmaTrans{Transitions: transitions},
+		Extractor:   pipeline.NewExtractor(featureSetup, []byte("L"), EWord, EPOS, EWPOS, ERel, EMHost, EMSuffix, EMorphProp, ETokens, POP),
+	}
+	m.TransSystem.AddDefaultOracle()
+	group, _ := m.Extractor.TransTypeGroups['L']
+	formatters := make([]util.Format, len(group.FeatureTemplates))
+	for i, formatter := range group.FeatureTemplates {
+		formatters[i] = formatter
+	}
+	m.Model = transitionmodel.NewAvgMatrixSparse(featureSetup.NumFeatures(), formatters, false)
+	return m
+}
+
+func (m *LemmaModel) Beam() *search.Beam {
+	return &search.Beam{
+		TransFunc:            m.TransSystem,
+		FeatExtractor:        m.Extractor,
+		Base:                 &lemma.Config{Transitions: m.Transitions},
+		Model:                m.Model,
+		Size:                 BeamSize,
+		ConcurrentExec:       ConcurrentBeam,
+		Transitions:          m.Transitions,
+		EstimatedTransitions: 100,
+	}
+}
+
+// TrainLemmaModel trains a lemma selection model on the gold
+// disambiguations of the training set and writes it to modelFile
+func TrainLemmaModel(featuresFile, modelFile string) error {
+	if allOut {
+		log.Println("Lemma:\tReading training lattices with lemmas from", tLatDis, "and", tLatAmb)
+	}
+	goldLats, err := readLemmaGold(tLatDis, limit)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	ambLats, err := readLemmaLattices(tLatAmb, limit, useConllU)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	combined, _, _, _ := CombineLatticesCorpus(goldLats, ambLats)
+	goldSequences := TrainingSequences(LemmaInstances(combined, nil), GetLemmaInstance, GetLemmaGold)
+	if allOut {
+		log.Println("Lemma:\tGenerated", len(goldSequences), "training sequences")
+		log.Println("Lemma:\tTraining", Iterations, "iteration(s)")
+	}
+
+	m := NewLemmaModel(featuresFile, util.NewEnumSet(1000, "ELemma"))
+	beam := m.Beam()
+	deterministic := &search.Deterministic{
+		TransFunc:          m.TransSystem,
+		FeatExtractor:      m.Extractor,
+		ReturnModelValue:   false,
+		ReturnSequence:     true,
+		ShowConsiderations: false,
+		Base:               &lemma.Config{Transitions: m.Transitions},
+		NoRecover:          false,
+		DefaultTransType:   'L',
+	}
+	_ = Train(goldSequences, Iterations, modelFile, m.Model, perceptron.EarlyUpdateInstanceDecoder(beam), perceptron.InstanceDecoder(deterministic), nil)
+
+	if allOut {
+		log.Println("Lemma:\tWriting lemma model to", modelFile)
+	}
+	WriteModel(modelFile, &Serialization{WeightModel: m.Model.Serialize(-1), ETrans: m.Transitions})
+	return nil
+}
+
+// LoadLemmaModel reads a lemma selection model written by TrainLemmaModel
+func LoadLemmaModel(featuresFile, modelFile string) *LemmaModel {
+	serialization := ReadModel(modelFile)
+	m := NewLemmaModel(featuresFile, serialization.ETrans)
+	m.Model.Deserialize(serialization.WeightModel)
+	return m
+}
+
+// Lemmatize chooses the lemmas of the morphemes of disambiguations (MD
+// configs), among the lemmas of the analyses of the input lattices, and
+// sets them in the disambiguations' mappings. With gold disambiguations,
+// the accuracy of the chosen lemmas is logged
+func Lemmatize(m *LemmaModel, configs []interface{}, lats []interface{}, gold []nlp.Mappings) {
+	beam := m.Beam()
+	beam.ShortTempAgenda = true
+	results := Parse(LemmaInstances(configs, lats), beam)
+	var (
+		total     = &eval.Result{}
+		ambiguous = &eval.Result{}
+	)
+	for i, result := range results {
+		conf := result.(*lemma.Config)
+		if i < len(gold) && gold[i] != nil {
+			sentResult := lemma.Evaluate(conf, gold[i])
+			total.TP += sentResult.TP
+			total.FP += sentResult.FP
+			ambiguous.TP += sentResult.Other.(*eval.Result).TP
+			ambiguous.FP += sentResult.Other.(*eval.Result).FP
+		}
+		configs[i].(*disambig.MDConfig).Mappings = conf.Result()
+	}
+	if len(gold) > 0 {
+		log.Println("Lemma accuracy:", fmt.Sprintf("%.2f", 100*total.Accuracy()), "(", total.TP, "of", total.All(), ")")
+		log.Println("Lemma accuracy (ambiguous):", fmt.Sprintf("%.2f", 100*ambiguous.Accuracy()), "(", ambiguous.TP, "of", ambiguous.All(), ")")
+	}
+}
+
+// LemmatizeParsed lemmatizes the results of MD parsing of the input
+// lattices, evaluating them against -ing if given
+func LemmatizeParsed(featuresFile, modelFile string, results []interface{}) error {
+	if allOut {
+		log.Println("Lemma:\tLoading lemma model", modelFile)
+	}
+	m := LoadLemmaModel(featuresFile, modelFile)
+	lats, err := readLemmaLattices(input, limit, useConllU)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	var gold []nlp.Mappings
+	if len(inputGold) > 0 {
+		goldLats, err := readLemmaGold(inputGold, limit)
+		if err != nil {
+			log.Println(err)
+			return err
+		}
+		gold = make([]nlp.Mappings, len(goldLats))
+		for i, goldLat := range goldLats {
+			if conf, _ := CombineToGoldMorph(goldLat.(nlp.LatticeSentence), goldLat.(nlp.LatticeSentence)); conf != nil {
+				gold[i] = conf.Mappings
+			}
+		}
+	}
+	configs := make([]interface{}, len(results))
+	for i, result := range results {
+		conf, ok := result.(*disambig.MDConfig)
+		if !ok {
+			log.Fatalln("Lemmatization requires a single disambiguation per sentence")
+		}
+		configs[i] = conf
+	}
+	Lemmatize(m, configs, lats, gold)
+	return nil
+}
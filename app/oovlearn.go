@@ -0,0 +1,98 @@
+package app
+
+import (
+	"yap/nlp/parser/ma"
+
+	"fmt"
+	"log"
+
+	"github.com/gonuts/commander"
+	"github.com/gonuts/flag"
+)
+
+var (
+	oovGuesserFile           string
+	oovMaxSuffix, oovMaxFreq int
+	oovMaxGuesses            int
+)
+
+func OOVLearnConfigOut() {
+	log.Println("Configuration")
+	if useConllU {
+		log.Printf("CoNLL-U:\t\t%s", conlluFile)
+	} else {
+		log.Printf("Lattice:\t\t%s", latFile)
+	}
+	log.Printf("Limit:\t\t%v", limit)
+	log.Printf("Max Suffix:\t\t%d", oovMaxSuffix)
+	log.Printf("Max Host Freq:\t%d", oovMaxFreq)
+	log.Printf("Max Guesses:\t%d", oovMaxGuesses)
+	log.Println()
+	log.Printf("Output:\t\t%s", oovGuesserFile)
+	log.Println()
+}
+
+func OOVLearn(cmd *commander.Command, args []string) error {
+	var REQUIRED_FLAGS []string
+	useConllU = len(conlluFile) > 0
+	if useConllU {
+		REQUIRED_FLAGS = []string{"conllu", "out"}
+	} else {
+		REQUIRED_FLAGS = []string{"lattice", "out"}
+	}
+	VerifyFlags(cmd, REQUIRED_FLAGS)
+	OOVLearnConfigOut()
+	guesser := &ma.OOVGuesser{
+		MaxSuffix:  oovMaxSuffix,
+		MaxFreq:    oovMaxFreq,
+		MaxGuesses: oovMaxGuesses,
+	}
+	var (
+		numHosts int
+		err      error
+	)
+	if useConllU {
+		numHosts, err = guesser.LearnFromConllU(conlluFile, limit)
+	} else {
+		numHosts, err = guesser.LearnFromLat(latFile, limit)
+	}
+	if err != nil {
+		log.Println("Got error learning", err)
+		return err
+	}
+	log.Println("Read", numHosts, "hosts")
+	guesser.Train()
+	if err := guesser.WriteFile(oovGuesserFile); err != nil {
+		panic(fmt.Sprintf("Failed writing OOV guesser - %v", err))
+	}
+	return nil
+}
+
+func OOVLearnCmd() *commander.Command {
+	cmd := &commander.Command{
+		Run:       OOVLearn,
+		UsageLine: "oovlearn <file options> [arguments]",
+		Short:     "train a suffix based guesser of analyses for OOV hosts",
+		Long: `
+train a suffix based guesser of analyses for OOV hosts from a
+morphologically disambiguated treebank, used with -oovguesser in hebma in
+place of the constant NNP/NN analyses
+
+	$ ./yap oovlearn -conllu <conllu file> -out <output file> [options]
+	$ ./yap oovlearn -lattice <gold lattice file> -out <output file> [options]
+
+the guesser records the tag set of the treebank, ud for CoNLL-U and spmrl for
+lattices, and the analyzer rejects a guesser of another output format (-format)
+
+`,
+		Flag: *flag.NewFlagSet("oovlearn", flag.ExitOnError),
+	}
+	cmd.Flag.StringVar(&latFile, "lattice", "", "Lattice-format input file")
+	cmd.Flag.StringVar(&conlluFile, "conllu", "", "CoNLL-U-format input file")
+	cmd.Flag.StringVar(&oovGuesserFile, "out", "", "output file")
+	cmd.Flag.IntVar(&oovMaxSuffix, "maxsuffix", 4, "Longest host suffix considered")
+	cmd.Flag.IntVar(&oovMaxFreq, "maxfreq", 10, "Ignore hosts occuring more often in training")
+	cmd.Flag.IntVar(&oovMaxGuesses, "maxguesses", 10, "Max analyses proposed per OOV host")
+	cmd.Flag.IntVar(&limit, "limit", 0, "limit training set")
+	return cmd
+}
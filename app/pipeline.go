@@ -0,0 +1,300 @@
+package app
+
+import (
+	"yap/alg/search"
+	"yap/nlp/format/conllu"
+	"yap/nlp/format/lattice"
+	"yap/nlp/format/raw"
+	"yap/nlp/parser/ma"
+	nlp "yap/nlp/types"
+	"yap/pipeline"
+	"yap/util"
+
+	"fmt"
+	"log"
+
+	"github.com/gonuts/commander"
+	"github.com/gonuts/flag"
+)
+
+var (
+	pipelineNoLemma bool
+)
+
+func LoadHebMA(options lattice.Options) *pipeline.Analyzer {
+	var prefixLocation, lexiconLocation, compiledLocation string
+	if len(compiledLexFile) > 0 {
+		location, found := util.LocateFile(compiledLexFile, DEFAULT_DATA_DIRS)
+		if !found {
+			log.Fatalln("Compiled lexicon file", compiledLexFile, "not found")
+		}
+		compiledLocation = location
+	} else {
+		location, found := util.LocateFile(prefixFile, DEFAULT_DATA_DIRS)
+		if !found {
+			log.Fatalln("Prefix file", prefixFile, "not found")
+		}
+		prefixLocation = location
+		location, found = util.LocateFile(lexiconFile, DEFAULT_DATA_DIRS)
+		if !found {
+			log.Fatalln("Lexicon file", lexiconFile, "not found")
+		}
+		lexiconLocation = location
+	}
+	return NewHebMA(prefixLocation, lexiconLocation, compiledLocation, pipeline.DEFAULT_MA_TYPE, options)
+}
+
+// NewHebMA loads the analyzer of maType from the compiled lexicon file if
+// given, or else from the prefix and lexicon files, and merges the user
+// lexicons into it
+func NewHebMA(prefixLocation, lexiconLocation, compiledLocation, maType string, options lattice.Options) *pipeline.Analyzer {
+	var (
+		analyzer *pipeline.Analyzer
+		err      error
+	)
+	if len(compiledLocation) > 0 {
+		log.Println("Reading Morphological Analyzer compiled BGU Prefixes and Lexicon")
+		analyzer, err = pipeline.NewTypedAnalyzerFromCompiled(compiledLocation, maType, nnpnofeats)
+	} else {
+		log.Println("Reading Morphological Analyzer BGU Prefixes and Lexicon")
+		analyzer, err = pipeline.NewTypedAnalyzerFromFiles(prefixLocation, lexiconLocation, maType, nnpnofeats)
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+	normalization, err := ma.ParseNormalization(normalizeStr)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	MergeUserLexicons(analyzer.Lex)
+	LoadOOVGuesser(analyzer)
+	analyzer.Lex.AlwaysNNP = alwaysnnp
+	analyzer.Lex.Normalize = normalization
+	analyzer.LatticeOptions = options
+	return analyzer
+}
+
+func LoadMDParser(options lattice.Options) *pipeline.Disambiguator {
+	featuresLocation, found := util.LocateFile(mdFeaturesFile, DEFAULT_CONF_DIRS)
+	if !found {
+		log.Fatalln("MD features file", mdFeaturesFile, "not found")
+	}
+	modelLocation, found := util.LocateFile(mdModelName, DEFAULT_MODEL_DIRS)
+	if !found {
+		log.Fatalln("MD model file", mdModelName, "not found")
+	}
+	log.Println("Loading MD model", modelLocation)
+	disambiguator := &pipeline.Disambiguator{
+		BeamSize:      mdBeamSize,
+		ParamFuncName: paramFuncName,
+		UsePOP:        UsePOP,
+		Concurrent:    ConcurrentBeam,
+		OpenFamily:    "HEBTB",
+
+		Confidence:            mdConfidence,
+		ConfidenceTemperature: mdConfidenceTemp,
+
+		LatticeOptions: options,
+	}
+	if err := disambiguator.Load(modelLocation, featuresLocation); err != nil {
+		log.Println("Failed loading MD model", modelLocation)
+		log.Fatalln(err)
+	}
+	return disambiguator
+}
+
+func LoadDepParser(options lattice.Options) *pipeline.Parser {
+	featuresLocation, found := util.LocateFile(depFeaturesFile, DEFAULT_CONF_DIRS)
+	if !found {
+		log.Fatalln("Dependency features file", depFeaturesFile, "not found")
+	}
+	labelsLocation, found := util.LocateFile(depLabelsFile, DEFAULT_CONF_DIRS)
+	if !found {
+		log.Fatalln("Dependency labels file", depLabelsFile, "not found")
+	}
+	modelLocation, found := util.LocateFile(depModelName, DEFAULT_MODEL_DIRS)
+	if !found {
+		log.Fatalln("Dependency model file", depModelName, "not found")
+	}
+	log.Println("Loading dependency model", modelLocation)
+	parser := &pipeline.Parser{
+		BeamSize:   DepBeamSize,
+		ArcSystem:  arcSystemStr,
+		Concurrent: ConcurrentBeam,
+
+		LatticeOptions: options,
+	}
+	if err := parser.Load(modelLocation, featuresLocation, labelsLocation); err != nil {
+		log.Println("Failed loading dependency model", modelLocation)
+		log.Fatalln(err)
+	}
+	return parser
+}
+
+func PipelineConfigOut() {
+	log.Println("Configuration")
+	if len(compiledLexFile) > 0 {
+		log.Printf("Compiled Lexicon:\t%s", compiledLexFile)
+	} else {
+		log.Printf("Heb Lexicon:\t\t%s", lexiconFile)
+		log.Printf("Heb Prefix:\t\t%s", prefixFile)
+	}
+	log.Printf("Normalization:\t%s", normalizeStr)
+	if len(userLexFiles) > 0 {
+		log.Printf("User Lexicons:\t%s (%s)", userLexFiles, userLexMode)
+	}
+	OOVGuesserConfigOut()
+	log.Printf("MD Model:\t\t%s", mdModelName)
+	log.Printf("MD Features:\t\t%s", mdFeaturesFile)
+	log.Printf("MD Beam Size:\t\t%d", mdBeamSize)
+	log.Printf("Dep Model:\t\t%s", depModelName)
+	log.Printf("Dep Features:\t\t%s", depFeaturesFile)
+	log.Printf("Dep Labels:\t\t%s", depLabelsFile)
+	log.Printf("Dep Beam Size:\t\t%d", DepBeamSize)
+	log.Printf("Arc System:\t\t%s", arcSystemStr)
+	log.Printf("Use Lemmas:\t\t%v", !pipelineNoLemma)
+	MDConfidenceConfigOut()
+	log.Println()
+	if len(inTextFile) > 0 {
+		log.Printf("Text Input:\t\t%s", inTextFile)
+		if !VerifyExists(inTextFile) {
+			log.Fatalln("Text input file not found")
+		}
+	} else {
+		log.Printf("Raw Input:\t\t%s", inRawFile)
+		if !VerifyExists(inRawFile) {
+			log.Fatalln("Raw input file not found")
+		}
+	}
+	log.Printf("Out (conllu) file:\t%s", outConll)
+	log.Println()
+}
+
+func Pipeline(cmd *commander.Command, args []string) error {
+	if len(inTextFile) > 0 {
+		VerifyFlags(cmd, []string{"in", "oc"})
+	} else {
+		VerifyFlags(cmd, []string{"raw", "oc"})
+	}
+	PipelineConfigOut()
+	options := lattice.Options{IgnoreLemma: pipelineNoLemma}
+	analyzer := LoadHebMA(options)
+	mdParser := LoadMDParser(options)
+	depParser := LoadDepParser(options)
+	log.Println()
+
+	var sentsStream chan nlp.RangedSentence
+	if len(inTextFile) > 0 {
+		// running text is tokenized here, keeping the character offsets of tokens
+		stream, err := NewHebTokenizer().TokenizeFileAsStreamRanged(inTextFile, limit)
+		if err != nil {
+			panic(fmt.Sprintf("Failed reading text file - %v", err))
+		}
+		sentsStream = stream
+	} else {
+		// raw files keep the ranges written by tokenize -ranges
+		stream, err := raw.ReadFileAsStreamRanged(inRawFile, limit)
+		if err != nil {
+			panic(fmt.Sprintf("Failed reading raw file - %v", err))
+		}
+		sentsStream = stream
+	}
+
+	ambLattices := make(chan lattice.Lattice, 2)
+	go func() {
+		for sent := range sentsStream {
+			ambLattices <- analyzer.AnalyzeRanged(sent)
+		}
+		close(ambLattices)
+	}()
+
+	type disambiguation struct {
+		Lattice  lattice.Lattice
+		Mappings nlp.Mappings
+	}
+	disambiguated := make(chan disambiguation, 2)
+	go func() {
+		var i int
+		for ambLat := range ambLattices {
+			disLat, mappings, err := mdParser.Disambiguate(ambLat)
+			if err != nil {
+				log.Fatalln("Failed converting disambiguation of sentence", i, "-", err)
+			}
+			disambiguated <- disambiguation{disLat, mappings}
+			i++
+		}
+		close(disambiguated)
+	}()
+
+	parsed := make(chan interface{}, 2)
+	go func() {
+		var i int
+		for dis := range disambiguated {
+			if allOut {
+				log.Println("Parsing instance", i)
+			}
+			graph := depParser.Parse(dis.Lattice)
+			parsed <- depParser.ConllU(graph, dis.Mappings)
+			i++
+		}
+		close(parsed)
+	}()
+
+	if allOut {
+		log.Println("Creating writer stream to", outConll)
+	}
+	return conllu.WriteStreamToFile(outConll, parsed)
+}
+
+func PipelineCmd() *commander.Command {
+	cmd := &commander.Command{
+		Run:       Pipeline,
+		UsageLine: "pipeline <file options> [arguments]",
+		Short:     "runs morphological analysis, disambiguation and dependency parsing in memory",
+		Long: `
+runs morphological analysis, disambiguation and dependency parsing in memory
+
+	$ ./yap pipeline -raw <raw file> -oc <out conllu> [options]
+
+running text may be given instead of a raw file, in which case it is
+tokenized and the character offsets of each token are written to the MISC
+column as TokenRange=<start>:<end>, as are those of a raw file written by
+tokenize -ranges
+
+	$ ./yap pipeline -in <text file> -oc <out conllu> [options]
+
+with -confidence, the disambiguation confidence of each word is added to
+its MISC column as Confidence=<p>, and of the whole analysis of a token to
+the MISC column of its multi-word token line
+
+`,
+		Flag: *flag.NewFlagSet("pipeline", flag.ExitOnError),
+	}
+	cmd.Flag.BoolVar(&ConcurrentBeam, "bconc", true, "Concurrent Beam")
+	cmd.Flag.StringVar(&inRawFile, "raw", "", "Input raw (tokenized) file")
+	cmd.Flag.StringVar(&inTextFile, "in", "", "Optional - Input text file, instead of a raw file")
+	cmd.Flag.StringVar(&outConll, "oc", "", "Output CoNLL-U File")
+	cmd.Flag.IntVar(&limit, "limit", 0, "Limit input set")
+	cmd.Flag.StringVar(&prefixFile, "prefix", "bgupreflex_withdef.utf8.hr", "Prefix file for morphological analyzer")
+	cmd.Flag.StringVar(&lexiconFile, "lexicon", "bgulex.utf8.hr", "Lexicon file for morphological analyzer")
+	cmd.Flag.StringVar(&compiledLexFile, "lexbin", "", "Optional - Compiled prefix and lexicon file (see lexcompile), instead of -prefix and -lexicon")
+	cmd.Flag.BoolVar(&alwaysnnp, "alwaysnnp", false, "Always add NNP to tokens and prefixed subtokens")
+	cmd.Flag.BoolVar(&nnpnofeats, "addnnpnofeats", false, "Add NNP in lex but without features")
+	cmd.Flag.StringVar(&normalizeStr, "normalize", "none", "Normalize tokens before lookup [niqqud,cantillation,geresh,bidi,presentation|all|none]")
+	UserLexFlags(cmd)
+	OOVGuesserFlags(cmd)
+	cmd.Flag.IntVar(&mdBeamSize, "mdb", 32, "MD Beam Size")
+	MDConfidenceFlags(cmd)
+	cmd.Flag.StringVar(&mdModelName, "mdmn", "hebmd.b32", "MD Modelfile")
+	cmd.Flag.StringVar(&mdFeaturesFile, "mdf", "standalone.md.yaml", "MD Features Configuration File")
+	cmd.Flag.StringVar(&paramFuncName, "p", "Funcs_Main_POS_Both_Prop", "Param Func types: ["+nlp.AllParamFuncNames+"]")
+	cmd.Flag.BoolVar(&UsePOP, "pop", true, "Add POP operation to MD")
+	cmd.Flag.BoolVar(&pipelineNoLemma, "nolemma", true, "Ignore lemmas")
+	cmd.Flag.IntVar(&DepBeamSize, "depb", 64, "Dependency Beam Size")
+	cmd.Flag.StringVar(&depModelName, "depmn", "dep.b64", "Dependency Modelfile")
+	cmd.Flag.StringVar(&depFeaturesFile, "depf", "zhangnivre2011.yaml", "Dependency Features Configuration File")
+	cmd.Flag.StringVar(&depLabelsFile, "l", "hebtb.labels.conf", "Dependency Labels Configuration File")
+	cmd.Flag.StringVar(&arcSystemStr, "a", "eager", "Optional - Arc System [standard, eager, swap, hybrid]")
+	cmd.Flag.BoolVar(&search.AllOut, "showbeam", false, "Show candidates in beam")
+	return cmd
+}
@@ -0,0 +1,215 @@
+package app
+
+import (
+	"yap/nlp/format/conll"
+	"yap/nlp/format/conllu"
+	"yap/nlp/parser/dependency/pseudoproj"
+	dep "yap/nlp/parser/dependency/transition"
+	nlp "yap/nlp/types"
+	"yap/util/conf"
+
+	"fmt"
+	"log"
+	"os"
+	"sort"
+)
+
+var (
+	pseudoProjective string
+	pprojScheme      pseudoproj.Scheme
+)
+
+// PseudoProjectiveLabelsFile is the file of the relations of a model
+// trained on projectivized trees, including the encoded labels
+func PseudoProjectiveLabelsFile(modelFile string) string {
+	return fmt.Sprintf("%s.pproj.labels", modelFile)
+}
+
+// SetupPseudoProjective returns the relations to parse with -pproj: when
+// training, the base relations with the encoded labels of the projectivized
+// training set, written next to the model; otherwise those written when
+// training the model
+func SetupPseudoProjective(relations []string, outModelFile string, modelExists bool) []string {
+	if len(pseudoProjective) == 0 {
+		return relations
+	}
+	var err error
+	pprojScheme, err = pseudoproj.ParseScheme(pseudoProjective)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	labelsFile := PseudoProjectiveLabelsFile(outModelFile)
+	if modelExists {
+		pprojRelations, err := conf.ReadFile(labelsFile)
+		if err != nil {
+			log.Println("Failed reading pseudo-projective labels file:", labelsFile)
+			log.Fatalln(err)
+		}
+		return pprojRelations.Values
+	}
+	var encoded []string
+	if useConllU {
+		s, _, e := conllu.ReadFile(tConll, limit)
+		if e != nil {
+			log.Fatalln(e)
+		}
+		encoded = projectivizeConllU(s)
+	} else {
+		s, e := conll.ReadFile(tConll, limit)
+		if e != nil {
+			log.Fatalln(e)
+		}
+		encoded = projectivizeConll(s)
+	}
+	retval := append([]string{}, relations...)
+	known := make(map[string]bool, len(relations))
+	for _, rel := range relations {
+		known[rel] = true
+	}
+	for _, rel := range encoded {
+		if !known[rel] {
+			retval = append(retval, rel)
+		}
+	}
+	file, err := os.Create(labelsFile)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer file.Close()
+	for _, rel := range retval {
+		fmt.Fprintln(file, rel)
+	}
+	if allOut {
+		log.Println("Pseudo-projective:\t", len(retval)-len(relations), "encoded labels (", pprojScheme, "), written to", labelsFile)
+	}
+	return retval
+}
+
+// projectivize lifts the non-projective arcs of training sentences with
+// -pproj, returns the sorted encoded labels
+func projectivize(sents []interface{}) []string {
+	if len(pseudoProjective) == 0 {
+		return nil
+	}
+	var (
+		lifts, lifted int
+		encoded       = make(map[string]bool)
+	)
+	for _, sent := range sents {
+		if sentLifts := pseudoproj.ProjectivizeSentence(sent, pprojScheme); sentLifts > 0 {
+			lifts += sentLifts
+			lifted++
+		}
+		for _, label := range pseudoproj.EncodedLabels(sent) {
+			encoded[label] = true
+		}
+	}
+	if allOut {
+		log.Println("Pseudo-projective:\tlifted", lifts, "arcs in", lifted, "of", len(sents), "sentences")
+	}
+	retval := make([]string, 0, len(encoded))
+	for label := range encoded {
+		retval = append(retval, label)
+	}
+	sort.Strings(retval)
+	return retval
+}
+
+func projectivizeConll(sents []conll.Sentence) []string {
+	asInterface := make([]interface{}, len(sents))
+	for i, sent := range sents {
+		asInterface[i] = sent
+	}
+	return projectivize(asInterface)
+}
+
+func projectivizeConllU(sents []*conllu.Sentence) []string {
+	asInterface := make([]interface{}, len(sents))
+	for i, sent := range sents {
+		asInterface[i] = sent
+	}
+	return projectivize(asInterface)
+}
+
+// deprojectivize restores the lifted arcs of parsed CoNLL or CoNLL-U
+// sentences with -pproj
+func deprojectivize(sents []interface{}) []interface{} {
+	if len(pseudoProjective) > 0 {
+		for _, sent := range sents {
+			pseudoproj.DeprojectivizeSentence(sent, pprojScheme)
+		}
+	}
+	return sents
+}
+
+func deprojectivizeStream(sents chan interface{}) chan interface{} {
+	if len(pseudoProjective) == 0 {
+		return sents
+	}
+	out := make(chan interface{}, 2)
+	go func() {
+		for sent := range sents {
+			pseudoproj.DeprojectivizeSentence(sent, pprojScheme)
+			out <- sent
+		}
+		close(out)
+	}()
+	return out
+}
+
+// deprojectivizeGraphs restores the lifted arcs of parsed graphs with
+// -pproj, for evaluation against the original trees
+func deprojectivizeGraphs(graphs []interface{}) []interface{} {
+	if len(pseudoProjective) == 0 {
+		return graphs
+	}
+	retval := make([]interface{}, len(graphs))
+	for i, graph := range graphs {
+		retval[i] = deprojectivizeGraph(graph.(nlp.LabeledDependencyGraph))
+	}
+	return retval
+}
+
+func deprojectivizeGraph(graph nlp.LabeledDependencyGraph) *dep.BasicDepGraph {
+	var (
+		numNodes = graph.NumberOfNodes()
+		heads    = make([]int, numNodes+1)
+		labels   = make([]string, numNodes+1)
+		arcs     = make([]nlp.LabeledDepArc, 0, numNodes)
+		retval   = &dep.BasicDepGraph{
+			Nodes: make([]nlp.DepNode, numNodes),
+			Arcs:  make([]*dep.BasicDepArc, 0, numNodes),
+		}
+	)
+	heads[0] = -1
+	for _, arcID := range graph.GetEdges() {
+		arc := graph.GetLabeledArc(arcID)
+		if arc == nil {
+			continue
+		}
+		modifier, head := arc.GetModifier(), arc.GetHead()
+		if arc.GetRelation() == nlp.ROOT_LABEL {
+			head = -1
+		}
+		heads[modifier+1], labels[modifier+1] = head+1, string(arc.GetRelation())
+		arcs = append(arcs, arc)
+	}
+	pseudoproj.Deprojectivize(heads, labels, pprojScheme)
+	for _, node := range graph.GetVertices() {
+		retval.Nodes[node] = graph.GetNode(node)
+	}
+	for _, arc := range arcs {
+		modifier, head, relation := arc.GetModifier(), arc.GetHead(), arc.GetRelation()
+		if label := nlp.DepRel(labels[modifier+1]); label != relation {
+			head, relation = heads[modifier+1]-1, label
+		}
+		relIndex, _ := ERel.IndexOf(relation)
+		retval.Arcs = append(retval.Arcs, &dep.BasicDepArc{
+			Head:        head,
+			Relation:    relIndex,
+			Modifier:    modifier,
+			RawRelation: relation,
+		})
+	}
+	return retval
+}
@@ -0,0 +1,49 @@
+package app
+
+import (
+	"testing"
+
+	"yap/nlp/parser/dependency/pseudoproj"
+	dep "yap/nlp/parser/dependency/transition"
+	nlp "yap/nlp/types"
+	"yap/pipeline"
+)
+
+func TestDeprojectivizeGraph(t *testing.T) {
+	pseudoProjective, pprojScheme = "head", pseudoproj.HEAD
+	defer func() { pseudoProjective = "" }()
+	ERel = pipeline.NewRelationEnum([]string{"root", "a", "b", "c", "d", "c^a", "d^b"})
+
+	// the projectivized tree of 1 <- 2, 1 <- 3, 2 <- 4, 3 <- 5 whose last
+	// two arcs were lifted to 1
+	var (
+		heads  = []int{-1, 0, 0, 0, 0}
+		labels = []string{"root", "a", "b", "c^a", "d^b"}
+		parsed = &dep.BasicDepGraph{Nodes: make([]nlp.DepNode, len(heads))}
+	)
+	for i := range heads {
+		parsed.Nodes[i] = &dep.TaggedDepNode{Id: i}
+		relation, _ := ERel.IndexOf(nlp.DepRel(labels[i]))
+		parsed.Arcs = append(parsed.Arcs, &dep.BasicDepArc{Head: heads[i], Relation: relation, Modifier: i, RawRelation: nlp.DepRel(labels[i])})
+	}
+	graphs := deprojectivizeGraphs([]interface{}{parsed})
+	graph := graphs[0].(*dep.BasicDepGraph)
+	var (
+		expectedHeads  = []int{-1, 0, 0, 1, 2}
+		expectedLabels = []string{"root", "a", "b", "c", "d"}
+	)
+	for i, arc := range graph.Arcs {
+		relation, _ := ERel.IndexOf(nlp.DepRel(expectedLabels[i]))
+		if arc.Modifier != i || arc.Head != expectedHeads[i] || arc.RawRelation != nlp.DepRel(expectedLabels[i]) || arc.Relation != relation {
+			t.Errorf("Node %d: got arc %v, expected head %d label %s", i, arc, expectedHeads[i], expectedLabels[i])
+		}
+	}
+	if result := DepEvalConll(graph, graph); result.TP != len(heads) {
+		t.Errorf("Got %d labeled attachments of the graph with itself, expected %d", result.TP, len(heads))
+	}
+
+	pseudoProjective = ""
+	if graphs := deprojectivizeGraphs([]interface{}{parsed}); graphs[0] != parsed {
+		t.Error("Expected graphs to be left as they are without -pproj")
+	}
+}
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"yap/nlp/format/raw"
+	"yap/nlp/parser/ma"
+
+	"fmt"
+	"log"
+	"regexp"
+
+	"github.com/gonuts/commander"
+	"github.com/gonuts/flag"
+)
+
+var (
+	inTextFile, outRawFile string
+	tokenRanges            bool
+)
+
+func TokenizeConfigOut() {
+	log.Println("Configuration")
+	log.Printf("Text Input:\t\t%s", inTextFile)
+	if !VerifyExists(inTextFile) {
+		log.Fatalln("Text input file not found")
+	}
+	log.Printf("Raw Output:\t\t%s", outRawFile)
+	log.Printf("Token Ranges:\t\t%v", tokenRanges)
+	log.Println()
+}
+
+func NewHebTokenizer() *raw.Tokenizer {
+	numbers := make([]*regexp.Regexp, 0, len(ma.REGEX))
+	for _, curRegex := range ma.REGEX {
+		if curRegex.POS == "CD" {
+			numbers = append(numbers, curRegex.RE)
+		}
+	}
+	return raw.NewTokenizer(ma.PUNCT, numbers)
+}
+
+func Tokenize(cmd *commander.Command, args []string) error {
+	VerifyFlags(cmd, []string{"in", "out"})
+	TokenizeConfigOut()
+	tokenizer := NewHebTokenizer()
+	if tokenRanges {
+		sents, err := tokenizer.TokenizeFileAsStreamRanged(inTextFile, limit)
+		if err != nil {
+			panic(fmt.Sprintf("Failed reading text file - %v", err))
+		}
+		return raw.WriteStreamRangedToFile(outRawFile, sents)
+	}
+	sents, err := tokenizer.TokenizeFileAsStream(inTextFile, limit)
+	if err != nil {
+		panic(fmt.Sprintf("Failed reading text file - %v", err))
+	}
+	return raw.WriteStreamToFile(outRawFile, sents)
+}
+
+func TokenizeCmd() *commander.Command {
+	cmd := &commander.Command{
+		Run:       Tokenize,
+		UsageLine: "tokenize <file options> [arguments]",
+		Short:     "split running Hebrew text into sentences and tokens",
+		Long: `
+split running Hebrew text into sentences and tokens, writing the raw
+format used as input to hebma (a token per line, sentences separated by an
+empty line). Sentences end at sentence ending punctuation or at a blank line,
+and continue across single line breaks
+
+	$ ./yap tokenize -in <text file> -out <raw file> [options]
+
+with -ranges, each token is followed by a tab and its character range in the
+text file as TokenRange=<start>:<end>, which hebma carries to the lattice
+
+`,
+		Flag: *flag.NewFlagSet("tokenize", flag.ExitOnError),
+	}
+	cmd.Flag.StringVar(&inTextFile, "in", "", "Input text file")
+	cmd.Flag.StringVar(&outRawFile, "out", "", "Output raw (tokenized) file")
+	cmd.Flag.BoolVar(&tokenRanges, "ranges", false, "Write the character range of each token")
+	cmd.Flag.IntVar(&limit, "limit", 0, "Limit number of sentences")
+	return cmd
+}
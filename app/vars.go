@@ -15,6 +15,7 @@ import (
 	"yap/nlp/parser/disambig"
 	"yap/nlp/parser/joint"
 	nlp "yap/nlp/types"
+	"yap/pipeline"
 	"yap/util"
 
 	dep "yap/nlp/parser/dependency/transition"
@@ -25,6 +26,7 @@ import (
 	"log"
 	"os"
 	// "runtime"
+	"sync"
 	"time"
 	// "strings"
 
@@ -32,7 +34,6 @@ import (
 )
 
 func init() {
-	gob.Register(&Serialization{})
 	var t nlp.Token
 	gob.Register(&t)
 }
@@ -45,10 +46,12 @@ var (
 	Iterations, BeamSize int
 	DepBeamSize          int
 	ConcurrentBeam       bool
+	ParseWorkers         int = 1
 	NumFeatures          int
 	UsePOP               bool
 	limit                int
 	Stream               bool
+	KBest                int = 1
 
 	// global enumerations
 	ERel, ETrans, EWord, EPOS, EWPOS, EMHost, EMSuffix *util.EnumSet
@@ -56,7 +59,7 @@ var (
 	EMorphProp                                         *util.EnumSet
 
 	// enumeration offsets of transitions
-	SH, RE, PR, LA, RA, IDLE, POP, MD transition.Transition
+	SH, RE, PR, LA, RA, SW, IDLE, POP, MD transition.Transition
 
 	// file names
 	tConll           string
@@ -88,19 +91,14 @@ var (
 // An approximation of the number of different MD-X:Y:Z transitions
 // Pre-allocating the enumeration saves frequent reallocation during training and parsing
 const (
-	APPROX_MORPH_TRANSITIONS        = 100
-	APPROX_WORDS, APPROX_POS        = 100, 100
-	WORDS_POS_FACTOR                = 5
-	APPROX_MHOSTS, APPROX_MSUFFIXES = 128, 16
+	APPROX_MORPH_TRANSITIONS        = pipeline.APPROX_MORPH_TRANSITIONS
+	APPROX_WORDS, APPROX_POS        = pipeline.APPROX_WORDS, pipeline.APPROX_POS
+	WORDS_POS_FACTOR                = pipeline.WORDS_POS_FACTOR
+	APPROX_MHOSTS, APPROX_MSUFFIXES = pipeline.APPROX_MHOSTS, pipeline.APPROX_MSUFFIXES
 )
 
-type Serialization struct {
-	WeightModel                          *model.AvgMatrixSparseSerialized
-	EWord, EPOS, EWPOS, EMHost, EMSuffix *util.EnumSet
-	EMorphProp                           *util.EnumSet
-	ETrans                               *util.EnumSet
-	ETokens                              *util.EnumSet
-}
+// Models are shared with the pipeline package, which registers the type with gob
+type Serialization = pipeline.Serialization
 
 func WriteModel(file string, data *Serialization) {
 	fObj, err := os.Create(file)
@@ -131,38 +129,20 @@ func ReadModel(file string) *Serialization {
 }
 
 func SetupRelationEnum(labels []string) {
-	if ERel != nil {
-		return
-	}
-	ERel = util.NewEnumSet(len(labels)+1, "ERel")
-	ERel.Add(nlp.DepRel(nlp.ROOT_LABEL))
-	for _, label := range labels {
-		ERel.Add(nlp.DepRel(label))
-	}
-	ERel.Frozen = true
+	ERel = pipeline.NewRelationEnum(labels)
 }
 
+// SetupTransEnum enumerates the transitions of arc standard, arc eager and
+// arc hybrid, which share SH, LA-* and RA-*
 func SetupTransEnum(relations []string) {
-	ETrans = util.NewEnumSet((len(relations)+1)*2+2, "ETrans")
-	_, _ = ETrans.Add("IDLE") // dummy no action transition for zpar equivalence
-	iSH, _ := ETrans.Add("SH")
-	iRE, _ := ETrans.Add("RE")
-	_, _ = ETrans.Add("AL") // dummy action transition for zpar equivalence
-	_, _ = ETrans.Add("AR") // dummy action transition for zpar equivalence
-	iPR, _ := ETrans.Add("PR")
-	SH = transition.ConstTransition(iSH)
-	RE = transition.ConstTransition(iRE)
-	PR = transition.ConstTransition(iPR)
-	LA = transition.ConstTransition(iPR + 1)
-	ETrans.Add("LA-" + string(nlp.ROOT_LABEL))
-	for _, transition := range relations {
-		ETrans.Add("LA-" + string(transition))
-	}
-	RA = transition.ConstTransition(ETrans.Len())
-	ETrans.Add("RA-" + string(nlp.ROOT_LABEL))
-	for _, transition := range relations {
-		ETrans.Add("RA-" + string(transition))
-	}
+	ETrans, SH, RE, PR, LA, RA = pipeline.NewTransEnum(relations)
+}
+
+// SetupSwapTransEnum adds the swap transition of arc standard with swap
+// after the already enumerated transitions
+func SetupSwapTransEnum() {
+	iSW, _ := ETrans.Add("SW")
+	SW = transition.ConstTransition(iSW)
 }
 
 func SetupMorphTransEnum(relations []string) {
@@ -217,26 +197,7 @@ func VerifyFlags(cmd *commander.Command, required []string) {
 }
 
 func SetupExtractor(setup *transition.FeatureSetup, transTypes []byte) *transition.GenericExtractor {
-	extractor := &transition.GenericExtractor{
-		EFeatures:  util.NewEnumSet(setup.NumFeatures(), "EFeatures"),
-		Concurrent: false,
-		EWord:      EWord,
-		EPOS:       EPOS,
-		EWPOS:      EWPOS,
-		ERel:       ERel,
-		EMHost:     EMHost,
-		EMSuffix:   EMSuffix,
-		EMorphProp: EMorphProp,
-		EToken:     ETokens,
-		POPTrans:   POP,
-		// Log:        true,
-	}
-	if transTypes == nil {
-		extractor.Init()
-	} else {
-		extractor.InitTypes(transTypes)
-	}
-	extractor.LoadFeatureSetup(setup)
+	extractor := pipeline.NewExtractor(setup, transTypes, EWord, EPOS, EWPOS, ERel, EMHost, EMSuffix, EMorphProp, ETokens, POP)
 
 	NumFeatures = setup.NumFeatures()
 	return extractor
@@ -420,56 +381,144 @@ type Parser interface {
 	Parse(search.Problem) (transition.Configuration, interface{})
 }
 
+// ParserCopies returns a parser per worker; only beams can be copied, other
+// parsers are used by a single worker
+func ParserCopies(parser Parser, workers int) []Parser {
+	beam, ok := parser.(*search.Beam)
+	if workers <= 1 || !ok {
+		return []Parser{parser}
+	}
+	parsers := make([]Parser, workers)
+	for i := range parsers {
+		parsers[i] = beam.Copy()
+	}
+	return parsers
+}
+
+type indexedInstance struct {
+	Index    int
+	Instance interface{}
+}
+
+// parseFunc parses an instance with a worker's parser
+type parseFunc func(Parser, interface{}) interface{}
+
+func parseBest(parser Parser, instance interface{}) interface{} {
+	result, _ := parser.Parse(instance)
+	return result
+}
+
+// parseKBest returns a parseFunc of the k best results of a beam, as a
+// *search.KBestResult
+func parseKBest(k int) parseFunc {
+	return func(parser Parser, instance interface{}) interface{} {
+		beam, ok := parser.(*search.Beam)
+		if !ok {
+			panic("K-best parsing requires a beam")
+		}
+		return beam.ParseKBest(instance, k)
+	}
+}
+
 func ParseStream(instances chan interface{}, writeStream chan interface{}, parser Parser) {
+	parseStream(instances, writeStream, parser, parseBest)
+}
+
+func ParseKBestStream(instances chan interface{}, writeStream chan interface{}, parser Parser, k int) {
+	parseStream(instances, writeStream, parser, parseKBest(k))
+}
+
+func parseStream(instances chan interface{}, writeStream chan interface{}, parser Parser, parseInstance parseFunc) {
 	// runtime.GOMAXPROCS(1)
 	// Search.AllOut = true
 	startTime := time.Now()
 
-	// prevGC := debug.SetGCPercent(-1)
-	var i int
-	for instance := range instances {
-		// if i%50 == 0 {
-		// 	debug.SetGCPercent(100)
-		// 	runtime.GC()
-		// 	debug.SetGCPercent(-1)
-		// }
-		log.Println("Parsing instance", i) //, "len", len(sent.Tokens()))
-		// }
-		result, _ := parser.Parse(instance)
-		writeStream <- result
-		i++
+	parsers := ParserCopies(parser, ParseWorkers)
+	toParse := make(chan indexedInstance, len(parsers))
+	parsed := make(chan indexedInstance, len(parsers))
+	go func() {
+		var i int
+		for instance := range instances {
+			toParse <- indexedInstance{i, instance}
+			i++
+		}
+		close(toParse)
+	}()
+	var wg sync.WaitGroup
+	for _, workerParser := range parsers {
+		wg.Add(1)
+		go func(workerParser Parser) {
+			defer wg.Done()
+			for indexed := range toParse {
+				log.Println("Parsing instance", indexed.Index) //, "len", len(sent.Tokens()))
+				parsed <- indexedInstance{indexed.Index, parseInstance(workerParser, indexed.Instance)}
+			}
+		}(workerParser)
+	}
+	go func() {
+		wg.Wait()
+		close(parsed)
+	}()
+
+	// results arrive out of order when parsing concurrently,
+	// hold them until all preceding instances were written
+	var next int
+	pending := make(map[int]interface{}, len(parsers))
+	for indexed := range parsed {
+		pending[indexed.Index] = indexed.Instance
+		for result, exists := pending[next]; exists; result, exists = pending[next] {
+			writeStream <- result
+			delete(pending, next)
+			next++
+		}
 	}
 	if allOut {
 		parseTime := time.Since(startTime)
 		log.Println("PARSE Total Time:", parseTime)
 	}
-	// debug.SetGCPercent(prevGC)
 	close(writeStream)
 }
 
 func Parse(instances []interface{}, parser Parser) []interface{} {
+	return parse(instances, parser, parseBest)
+}
+
+// ParseKBest returns the k best results of each instance, as a
+// *search.KBestResult
+func ParseKBest(instances []interface{}, parser Parser, k int) []interface{} {
+	return parse(instances, parser, parseKBest(k))
+}
+
+func parse(instances []interface{}, parser Parser, parseInstance parseFunc) []interface{} {
 	// runtime.GOMAXPROCS(1)
 	// Search.AllOut = true
 	startTime := time.Now()
 
-	// prevGC := debug.SetGCPercent(-1)
+	parsers := ParserCopies(parser, ParseWorkers)
 	parsed := make([]interface{}, len(instances))
-	for i, instance := range instances {
-		// if i%50 == 0 {
-		// 	debug.SetGCPercent(100)
-		// 	runtime.GC()
-		// 	debug.SetGCPercent(-1)
-		// }
-		log.Println("Parsing instance", i) //, "len", len(sent.Tokens()))
-		// }
-		result, _ := parser.Parse(instance)
-		parsed[i] = result
+	toParse := make(chan int, len(parsers))
+	go func() {
+		for i := range instances {
+			toParse <- i
+		}
+		close(toParse)
+	}()
+	var wg sync.WaitGroup
+	for _, workerParser := range parsers {
+		wg.Add(1)
+		go func(workerParser Parser) {
+			defer wg.Done()
+			for i := range toParse {
+				log.Println("Parsing instance", i) //, "len", len(sent.Tokens()))
+				parsed[i] = parseInstance(workerParser, instances[i])
+			}
+		}(workerParser)
 	}
+	wg.Wait()
 	if allOut {
 		parseTime := time.Since(startTime)
 		log.Println("PARSE Total Time:", parseTime)
 	}
-	// debug.SetGCPercent(prevGC)
 	return parsed
 }
 
@@ -641,7 +690,7 @@ func MakeDepEvalStopCondition(instances []interface{}, goldInstances []interface
 		parser.(*search.Beam).IntegrationGeneration = generations
 		oldparseOut := parseOut
 		parseOut = true
-		parsed := Parse(instances, parser)
+		parsed := deprojectivizeGraphs(Parse(instances, parser))
 		parseOut = oldparseOut
 		goldInstances := TrainingSequences(goldInstances, GetAsTaggedSentence, GetAsLabeledDepGraph)
 		// log.Println("START Evaluation")
@@ -652,7 +701,12 @@ func MakeDepEvalStopCondition(instances []interface{}, goldInstances []interface
 			// log.Println("Evaluating", i)
 			goldInstance := goldInstances[i]
 			if goldInstance != nil {
-				result := DepEval(instance, goldInstance.Decoded())
+				var result *eval.Result
+				if len(pseudoProjective) > 0 {
+					result = DepEvalConll(instance, goldInstance.Decoded())
+				} else {
+					result = DepEval(instance, goldInstance.Decoded())
+				}
 				// log.Println("Correct: ", result.TP)
 				total.Add(result)
 				utotal.Add(result.Other.(*eval.Result))
@@ -681,7 +735,7 @@ func MakeDepEvalStopCondition(instances []interface{}, goldInstances []interface
 		conll.WriteFile(fmt.Sprintf("interm.i%v.b%v.%v", curIteration, beamSize, outConll), graphs)
 		if testInstances != nil {
 			log.Println("Parsing test")
-			testParsed := Parse(testInstances, parser)
+			testParsed := deprojectivizeGraphs(Parse(testInstances, parser))
 			log.Println("Writing test results to", fmt.Sprintf("test.i%v.b%v.%v", curIteration, beamSize, test))
 			testGraphs := conll.Graph2ConllCorpus(testParsed, EMHost, EMSuffix)
 			conll.WriteFile(fmt.Sprintf("test.i%v.b%v.conll", curIteration, beamSize), testGraphs)
@@ -769,7 +823,7 @@ func MakeJointEvalStopCondition(instances []interface{}, goldInstances []interfa
 			log.Println("Continuing")
 		}
 		prevResult = curResult
-		graphs := conll.MorphGraph2ConllCorpus(parsedGraphs)
+		graphs := deprojectivize(conll.MorphGraph2ConllCorpus(parsedGraphs))
 		log.Println("Writing interm results to conll:", fmt.Sprintf("interm.i%v.b%v.%v", curIteration, beamSize, outConll))
 		conll.WriteFile(fmt.Sprintf("interm.i%v.b%v.%v", curIteration, beamSize, outConll), graphs)
 		log.Println("Writing interm results to segmentation:", fmt.Sprintf("interm.i%v.b%v.%v", curIteration, beamSize, outSeg))
@@ -801,7 +855,7 @@ func MakeJointEvalStopCondition(instances []interface{}, goldInstances []interfa
 					testposonlytotal.Add(posresult)
 				}
 			}
-			graphs := conll.MorphGraph2ConllCorpus(testParsed)
+			graphs := deprojectivize(conll.MorphGraph2ConllCorpus(testParsed))
 			log.Println("Test Result (F1): ", testTotal.F1(), "Exact:", testTotal.Exact, "TruePos:", testTotal.TP, "in", testTotal.Population, "POS F1:", testposonlytotal.F1())
 			log.Println("Writing test results to conll:", fmt.Sprintf("test.i%v.b%v.%v", curIteration, beamSize, outConll))
 			conll.WriteFile(fmt.Sprintf("test.i%v.b%v.%v", curIteration, beamSize, outConll), graphs)
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"yap/alg/search"
+)
+
+// slowerFirst returns a parseFunc of instances' indices which parses
+// earlier instances slower, recording the order in which they finish
+func slowerFirst(n int, finished *[]int) parseFunc {
+	var lock sync.Mutex
+	return func(parser Parser, instance interface{}) interface{} {
+		i := instance.(int)
+		time.Sleep(time.Duration(n-i) * 10 * time.Millisecond)
+		lock.Lock()
+		*finished = append(*finished, i)
+		lock.Unlock()
+		return i
+	}
+}
+
+func outOfOrder(finished []int) bool {
+	for i := 1; i < len(finished); i++ {
+		if finished[i] < finished[i-1] {
+			return true
+		}
+	}
+	return false
+}
+
+func testInstances(n int) []interface{} {
+	instances := make([]interface{}, n)
+	for i := range instances {
+		instances[i] = i
+	}
+	return instances
+}
+
+func TestParseWorkersOrder(t *testing.T) {
+	defer func(workers int) { ParseWorkers = workers }(ParseWorkers)
+	ParseWorkers = 4
+	const n = 8
+	var finished []int
+	results := parse(testInstances(n), &search.Beam{}, slowerFirst(n, &finished))
+	if !outOfOrder(finished) {
+		t.Fatalf("Instances finished in order %v, expected concurrent workers to finish out of order", finished)
+	}
+	if len(results) != n {
+		t.Fatalf("Got %d results, expected %d", len(results), n)
+	}
+	for i, result := range results {
+		if result != i {
+			t.Errorf("Got results %v, expected them in input order", results)
+			break
+		}
+	}
+}
+
+func TestParseStreamWorkersOrder(t *testing.T) {
+	defer func(workers int) { ParseWorkers = workers }(ParseWorkers)
+	ParseWorkers = 4
+	const n = 8
+	instances := make(chan interface{})
+	go func() {
+		for _, instance := range testInstances(n) {
+			instances <- instance
+		}
+		close(instances)
+	}()
+	var finished []int
+	written := make(chan interface{}, n)
+	parseStream(instances, written, &search.Beam{}, slowerFirst(n, &finished))
+	var results []interface{}
+	for result := range written {
+		results = append(results, result)
+	}
+	if !outOfOrder(finished) {
+		t.Fatalf("Instances finished in order %v, expected concurrent workers to finish out of order", finished)
+	}
+	if len(results) != n {
+		t.Fatalf("Got %d results, expected %d", len(results), n)
+	}
+	for i, result := range results {
+		if result != i {
+			t.Errorf("Got results %v, expected them in input order", results)
+			break
+		}
+	}
+}
@@ -91,6 +91,9 @@ type Row struct {
 	// PHead int
 	// PDepRel string
 
+	// source text range of the row's token, written in the unused PDEPREL
+	// column as TokenRange=<start>:<end>
+	TokenRange nlp.TokenRange
 }
 
 func (r Row) String() string {
@@ -105,6 +108,10 @@ func (r Row) String() string {
 		r.DepRel,
 		"_",
 		"_"}
+	// the unused PDEPREL column carries the token's range
+	if r.TokenRange.Known() {
+		fields[9] = r.TokenRange.Attribute()
+	}
 	return strings.Join(fields, "\t")
 }
 
@@ -113,6 +120,47 @@ type Sentence map[int]Row
 
 type Sentences []Sentence
 
+type JSONRow struct {
+	ID      int    `json:"id"`
+	Form    string `json:"form"`
+	Lemma   string `json:"lemma,omitempty"`
+	CPosTag string `json:"cpostag"`
+	PosTag  string `json:"postag,omitempty"`
+	Feats   string `json:"feats,omitempty"`
+	Head    int    `json:"head"`
+	DepRel  string `json:"deprel"`
+	// source text range of the row's token, as start:end characters
+	TokenRange string `json:"token_range,omitempty"`
+}
+
+func Sentence2JSON(sent Sentence) []JSONRow {
+	rows := make([]JSONRow, len(sent))
+	for i := 1; i <= len(sent); i++ {
+		row := sent[i]
+		jsonRow := JSONRow{
+			ID:      row.ID,
+			Form:    row.Form,
+			CPosTag: row.CPosTag,
+			Head:    row.Head,
+			DepRel:  row.DepRel,
+		}
+		if row.Lemma != "_" {
+			jsonRow.Lemma = row.Lemma
+		}
+		if row.PosTag != "_" {
+			jsonRow.PosTag = row.PosTag
+		}
+		if row.FeatStr != "_" {
+			jsonRow.Feats = row.FeatStr
+		}
+		if row.TokenRange.Known() {
+			jsonRow.TokenRange = row.TokenRange.String()
+		}
+		rows[i-1] = jsonRow
+	}
+	return rows
+}
+
 func ParseInt(value string) (int, error) {
 	if value == "_" {
 		return 0, nil
@@ -221,6 +269,14 @@ func ParseRow(record []string) (Row, error) {
 	}
 	row.Feats = features
 	row.FeatStr = ParseString(record[5])
+
+	if len(record) > 9 {
+		tokenRange, _, err := nlp.ParseTokenRangeAttribute(record[9])
+		if err != nil {
+			return row, errors.New(fmt.Sprintf("Error parsing token range (%s): %s", record[9], err.Error()))
+		}
+		row.TokenRange = tokenRange
+	}
 	return row, nil
 }
 
@@ -402,6 +458,12 @@ func GetMorphProperties(node *transition.TaggedDepNode, eMHost, eMSuffix *util.E
 }
 
 func Graph2Conll(graph nlp.LabeledDependencyGraph, eMHost, eMSuffix *util.EnumSet) Sentence {
+	return Graph2ConllIgnoreLemma(graph, eMHost, eMSuffix, IGNORE_LEMMA)
+}
+
+// Graph2ConllIgnoreLemma converts a graph with an explicit lemma switch instead of
+// the package level IGNORE_LEMMA
+func Graph2ConllIgnoreLemma(graph nlp.LabeledDependencyGraph, eMHost, eMSuffix *util.EnumSet, ignoreLemma bool) Sentence {
 	sent := make(Sentence, graph.NumberOfNodes())
 	arcIndex := make(map[int]nlp.LabeledDepArc, graph.NumberOfNodes())
 	var (
@@ -433,7 +495,7 @@ func Graph2Conll(graph nlp.LabeledDependencyGraph, eMHost, eMSuffix *util.EnumSe
 			panic("Got node of type other than TaggedDepNode")
 		}
 		posTag = taggedToken.RawPOS
-		if !IGNORE_LEMMA {
+		if !ignoreLemma {
 			lemma = taggedToken.RawLemma
 			if lemma == "" {
 				lemma = "_"
@@ -466,6 +528,7 @@ func Graph2Conll(graph nlp.LabeledDependencyGraph, eMHost, eMSuffix *util.EnumSe
 			Head:    headID + 1,
 			DepRel:  depRel,
 		}
+		row.TokenRange = taggedToken.Range
 		sent[row.ID] = row
 	}
 	return sent
@@ -511,6 +574,7 @@ func Conll2Graph(sent Sentence, eWord, ePOS, eWPOS, eRel, eMHost, eMSuffix *util
 			Id:       i - 1,
 			RawToken: row.Form,
 			RawPOS:   row.CPosTag,
+			Range:    row.TokenRange,
 		}
 
 		switch WORD_TYPE {
@@ -598,6 +662,9 @@ func MorphGraph2Conll(graph nlp.MorphDependencyGraph) Sentence {
 			Head:    headID + 1,
 			DepRel:  depRel,
 		}
+		if mappings := graph.GetMappings(); node.TokenID > 0 && node.TokenID <= len(mappings) {
+			row.TokenRange = mappings[node.TokenID-1].Range
+		}
 		sent[row.ID] = row
 	}
 	return sent
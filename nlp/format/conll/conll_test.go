@@ -1,6 +1,8 @@
 package conll
 
 import (
+	nlp "yap/nlp/types"
+
 	"strings"
 	"testing"
 )
@@ -81,3 +83,22 @@ func TestParseRowWithRepeatingParams(t *testing.T) {
 		t.Error("Failure concatenating multiple features: should be F,M got " + parsed.Feats["suf_gen"])
 	}
 }
+
+func TestRowTokenRange(t *testing.T) {
+	row := Row{ID: 1, Form: "BIT", CPosTag: "NN", PosTag: "NN", Head: 0, DepRel: "ROOT", TokenRange: nlp.TokenRange{Start: 5, End: 9}}
+	str := row.String()
+	if !strings.HasSuffix(str, "\tROOT\t_\tTokenRange=5:9") {
+		t.Errorf("Expected the token range in the last column, got %s", str)
+	}
+	parsed, err := ParseRow(strings.Split(str, string(FIELD_SEPARATOR)))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if parsed.TokenRange != row.TokenRange {
+		t.Errorf("Expected token range %v, got %v", row.TokenRange, parsed.TokenRange)
+	}
+	row.TokenRange = nlp.TokenRange{}
+	if parsed, _ := ParseRow(strings.Split(row.String(), string(FIELD_SEPARATOR))); parsed.TokenRange.Known() {
+		t.Errorf("Expected no token range, got %v", parsed.TokenRange)
+	}
+}
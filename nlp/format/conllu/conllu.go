@@ -30,6 +30,9 @@ const (
 	FEATURES_SEPARATOR   = "|"
 	FEATURE_SEPARATOR    = "="
 	FEATURE_CONCAT_DELIM = ","
+	TOKEN_RANGE_MISC     = nlp.TOKEN_RANGE_ATTR
+	CONFIDENCE_MISC      = "Confidence"
+	FIXED_MISC           = "Fixed"
 )
 
 var (
@@ -38,6 +41,16 @@ var (
 	STRIP_VOICE  bool
 )
 
+// Options are the reading switches of a single reader, so that files may be
+// read regardless of the package level switches used by package level functions
+type Options struct {
+	IgnoreLemma bool
+}
+
+func GlobalOptions() Options {
+	return Options{IgnoreLemma: IGNORE_LEMMA}
+}
+
 type Features map[string]string
 
 func (f Features) String() string {
@@ -123,8 +136,11 @@ func (r Row) String() string {
 
 // A Sentence is a map of Rows using their ids and a set of tokens
 type Sentence struct {
-	Deps     map[int]Row
-	Tokens   []string
+	Deps   map[int]Row
+	Tokens []string
+	// source text range of each token, from the TokenRange attribute of
+	// its MISC field
+	Ranges   []nlp.TokenRange
 	Mappings nlp.Mappings
 	Comments []string
 }
@@ -133,6 +149,7 @@ func NewSentence() *Sentence {
 	return &Sentence{
 		Deps:     make(map[int]Row),
 		Tokens:   []string{},
+		Ranges:   []nlp.TokenRange{},
 		Mappings: nil,
 		Comments: make([]string, 0, 2),
 	}
@@ -185,6 +202,10 @@ func ParseFeatures(featuresStr string) (Features, error) {
 }
 
 func ParseRow(record []string) (Row, error) {
+	return GlobalOptions().ParseRow(record)
+}
+
+func (o Options) ParseRow(record []string) (Row, error) {
 	var row Row
 	id, err := ParseInt(record[0])
 	if err != nil {
@@ -215,7 +236,7 @@ func ParseRow(record []string) (Row, error) {
 	}
 	row.Form = form
 
-	if !IGNORE_LEMMA {
+	if !o.IgnoreLemma {
 		lemma := ParseString(record[2])
 		// if lemma == "" {
 		// 	return row, errors.New("Empty LEMMA field")
@@ -280,6 +301,26 @@ func ParseTokenRow(record []string) (string, int, error) {
 	return token, id2 - id1 + 1, nil
 }
 
+// ParseTokenRowRange returns the range of a multiword token row, from its
+// MISC field
+func ParseTokenRowRange(record []string) (nlp.TokenRange, error) {
+	if len(record) < 10 {
+		return nlp.TokenRange{}, nil
+	}
+	return MiscTokenRange(ParseString(record[9]))
+}
+
+// MiscTokenRange returns the range of the TokenRange attribute of a MISC
+// field, or the zero range if it has none
+func MiscTokenRange(misc string) (nlp.TokenRange, error) {
+	for _, attribute := range strings.Split(misc, FEATURES_SEPARATOR) {
+		if tokenRange, isRange, err := nlp.ParseTokenRangeAttribute(attribute); isRange {
+			return tokenRange, err
+		}
+	}
+	return nlp.TokenRange{}, nil
+}
+
 func ReadStream(reader *os.File, limit int) chan *Sentence {
 	sentences := make(chan *Sentence, 2)
 
@@ -334,7 +375,13 @@ func ReadStream(reader *os.File, limit int) chan *Sentence {
 					log.Println("Error at record", i, errors.New(fmt.Sprintf("Error processing record %d at statement %d: %s", line, numSentences, err.Error())))
 					return
 				}
+				tokenRange, err := ParseTokenRowRange(record)
+				if err != nil {
+					log.Println("Error at record", i, errors.New(fmt.Sprintf("Error processing record %d at statement %d: %s", line, numSentences, err.Error())))
+					return
+				}
 				currentSent.Tokens = append(currentSent.Tokens, token)
+				currentSent.Ranges = append(currentSent.Ranges, tokenRange)
 				numTokens++
 			} else {
 				numSyntacticWords++
@@ -346,7 +393,13 @@ func ReadStream(reader *os.File, limit int) chan *Sentence {
 				if numForms > 0 {
 					numForms--
 				} else {
+					tokenRange, err := MiscTokenRange(row.Misc)
+					if err != nil {
+						log.Println("Error at record", i, errors.New(fmt.Sprintf("Error processing record %d at statement %d: %s", line, numSentences, err.Error())))
+						return
+					}
 					currentSent.Tokens = append(currentSent.Tokens, row.Form)
+					currentSent.Ranges = append(currentSent.Ranges, tokenRange)
 					numTokens++
 				}
 				row.TokenID = len(currentSent.Tokens) - 1
@@ -362,6 +415,10 @@ func ReadStream(reader *os.File, limit int) chan *Sentence {
 }
 
 func Read(reader io.Reader, limit int) (Sentences, bool, error) {
+	return GlobalOptions().Read(reader, limit)
+}
+
+func (o Options) Read(reader io.Reader, limit int) (Sentences, bool, error) {
 	var sentences []*Sentence
 	bufReader := bufio.NewReaderSize(reader, 16384)
 
@@ -411,19 +468,29 @@ func Read(reader io.Reader, limit int) (Sentences, bool, error) {
 			if err != nil {
 				return nil, false, errors.New(fmt.Sprintf("Error processing record %d at statement %d: %s", line, len(sentences), err.Error()))
 			}
+			tokenRange, err := ParseTokenRowRange(record)
+			if err != nil {
+				return nil, false, errors.New(fmt.Sprintf("Error processing record %d at statement %d: %s", line, len(sentences), err.Error()))
+			}
 			hasSegmentation = true
 			currentSent.Tokens = append(currentSent.Tokens, token)
+			currentSent.Ranges = append(currentSent.Ranges, tokenRange)
 			numTokens++
 		} else {
 			numSyntacticWords++
-			row, err := ParseRow(record)
+			row, err := o.ParseRow(record)
 			if err != nil {
 				return nil, false, errors.New(fmt.Sprintf("Error processing record %d at statement %d: %s", line, len(sentences), err.Error()))
 			}
 			if numForms > 0 {
 				numForms--
 			} else {
+				tokenRange, err := MiscTokenRange(row.Misc)
+				if err != nil {
+					return nil, false, errors.New(fmt.Sprintf("Error processing record %d at statement %d: %s", line, len(sentences), err.Error()))
+				}
 				currentSent.Tokens = append(currentSent.Tokens, row.Form)
+				currentSent.Ranges = append(currentSent.Ranges, tokenRange)
 				numTokens++
 			}
 			row.TokenID = len(currentSent.Tokens) - 1
@@ -436,13 +503,17 @@ func Read(reader io.Reader, limit int) (Sentences, bool, error) {
 }
 
 func ReadFile(filename string, limit int) ([]*Sentence, bool, error) {
+	return GlobalOptions().ReadFile(filename, limit)
+}
+
+func (o Options) ReadFile(filename string, limit int) ([]*Sentence, bool, error) {
 	file, err := os.Open(filename)
 	defer file.Close()
 	if err != nil {
 		return nil, false, err
 	}
 
-	return Read(file, limit)
+	return o.Read(file, limit)
 }
 
 func ReadFileAsStream(filename string, limit int) (chan *Sentence, error) {
@@ -454,6 +525,59 @@ func ReadFileAsStream(filename string, limit int) (chan *Sentence, error) {
 	return ReadStream(file, limit), nil
 }
 
+// TokenRangeMisc returns the MISC attribute of a token's source text range,
+// or an empty string if the range is unknown
+func TokenRangeMisc(r nlp.TokenRange) string {
+	if !r.Known() {
+		return ""
+	}
+	return r.Attribute()
+}
+
+// ConfidenceMisc returns the MISC attribute of a disambiguation confidence
+func ConfidenceMisc(confidence float64) string {
+	return fmt.Sprintf("%s=%.4f", CONFIDENCE_MISC, confidence)
+}
+
+// FixedTokens returns the indices of the tokens of a sentence with a word
+// marked Fixed=Yes in its MISC field, whose analysis is fixed for
+// constrained morphological disambiguation
+func FixedTokens(sent *Sentence) map[int]bool {
+	fixed := make(map[int]bool)
+	for _, row := range sent.Deps {
+		for _, attribute := range strings.Split(row.Misc, FEATURES_SEPARATOR) {
+			if attribute == FIXED_MISC+FEATURE_SEPARATOR+"Yes" {
+				fixed[row.TokenID] = true
+			}
+		}
+	}
+	return fixed
+}
+
+// AddMisc adds an attribute to a MISC field
+func AddMisc(misc, attribute string) string {
+	if len(misc) == 0 || misc == "_" {
+		return attribute
+	}
+	return misc + FEATURES_SEPARATOR + attribute
+}
+
+func writeMultiWordToken(writer io.Writer, id int, mapping *nlp.Mapping) {
+	writer.Write([]byte(fmt.Sprintf("%d-%d\t%s", id, id+len(mapping.Spellout)-1, mapping.Token)))
+	for j := 0; j < 7; j++ {
+		writer.Write([]byte("\t_"))
+	}
+	misc := TokenRangeMisc(mapping.Range)
+	if mapping.MorphConfidence != nil {
+		misc = AddMisc(misc, ConfidenceMisc(mapping.Confidence))
+	}
+	if len(misc) > 0 {
+		writer.Write([]byte("\t" + misc + "\n"))
+	} else {
+		writer.Write([]byte("\t_\n"))
+	}
+}
+
 func Write(writer io.Writer, sents []interface{}) {
 	var lastToken int
 	for _, genericsent := range sents {
@@ -466,11 +590,7 @@ func Write(writer io.Writer, sents []interface{}) {
 			if row.TokenID > lastToken {
 				mapping := sent.Mappings[row.TokenID-1]
 				if len(mapping.Spellout) > 1 {
-					writer.Write([]byte(fmt.Sprintf("%d-%d\t%s", i, i+len(mapping.Spellout)-1, mapping.Token)))
-					for j := 0; j < 8; j++ {
-						writer.Write([]byte("\t_"))
-					}
-					writer.Write([]byte("\n"))
+					writeMultiWordToken(writer, i, mapping)
 				}
 			}
 			writer.Write(append([]byte(row.String()), '\n'))
@@ -492,11 +612,7 @@ func WriteStream(writer io.Writer, sents chan interface{}) {
 			if row.TokenID > lastToken {
 				mapping := sent.Mappings[row.TokenID-1]
 				if len(mapping.Spellout) > 1 {
-					writer.Write([]byte(fmt.Sprintf("%d-%d\t%s", i, i+len(mapping.Spellout)-1, mapping.Token)))
-					for j := 0; j < 8; j++ {
-						writer.Write([]byte("\t_"))
-					}
-					writer.Write([]byte("\n"))
+					writeMultiWordToken(writer, i, mapping)
 				}
 			}
 			writer.Write(append([]byte(row.String()), '\n'))
@@ -541,6 +657,12 @@ func GetMorphProperties(node *transition.TaggedDepNode, eMHost, eMSuffix *util.E
 	return "_"
 }
 func Graph2ConllU(graph nlp.LabeledDependencyGraph, eMHost, eMSuffix *util.EnumSet) Sentence {
+	return Graph2ConllUIgnoreLemma(graph, eMHost, eMSuffix, IGNORE_LEMMA)
+}
+
+// Graph2ConllUIgnoreLemma converts a graph with an explicit lemma switch instead of
+// the package level IGNORE_LEMMA
+func Graph2ConllUIgnoreLemma(graph nlp.LabeledDependencyGraph, eMHost, eMSuffix *util.EnumSet, ignoreLemma bool) Sentence {
 	sent := NewSentence()
 	arcIndex := make(map[int]nlp.LabeledDepArc, graph.NumberOfNodes())
 	var (
@@ -576,7 +698,7 @@ func Graph2ConllU(graph nlp.LabeledDependencyGraph, eMHost, eMSuffix *util.EnumS
 			panic("Got node of type other than TaggedDepNode")
 		}
 		posTag = taggedToken.RawPOS
-		if !IGNORE_LEMMA {
+		if !ignoreLemma {
 			lemma = taggedToken.RawLemma
 		} else {
 			lemma = ""
@@ -611,6 +733,7 @@ func Graph2ConllU(graph nlp.LabeledDependencyGraph, eMHost, eMSuffix *util.EnumS
 			FeatStr: GetMorphProperties(taggedToken, eMHost, eMSuffix),
 			Head:    headID + 1,
 			DepRel:  depRel,
+			Misc:    TokenRangeMisc(taggedToken.Range),
 		}
 		sent.Deps[row.ID] = row
 	}
@@ -649,6 +772,9 @@ func ConllU2MorphGraph(sent *Sentence, eWord, ePOS, eWPOS, eRel, eMFeat, eMHost,
 			Morphemes: nlp.Morphemes{},
 			Next:      make(map[int][]int),
 		}
+		if i < len(sent.Ranges) {
+			lattices[i].Range = sent.Ranges[i]
+		}
 	}
 
 	for i := 1; i <= len(sent.Deps); i++ {
@@ -659,6 +785,9 @@ func ConllU2MorphGraph(sent *Sentence, eWord, ePOS, eWPOS, eRel, eMFeat, eMHost,
 			RawToken: row.Form,
 			RawPOS:   row.UPosTag,
 		}
+		if row.TokenID >= 0 && row.TokenID < len(sent.Ranges) {
+			node.Range = sent.Ranges[row.TokenID]
+		}
 
 		switch WORD_TYPE {
 		case "form":
@@ -724,7 +853,7 @@ func ConllU2MorphGraph(sent *Sentence, eWord, ePOS, eWPOS, eRel, eMFeat, eMHost,
 
 	for i, lat := range lattices {
 		lat.GenSpellouts()
-		mappings[i] = &nlp.Mapping{lat.Token, lat.Spellouts[0]}
+		mappings[i] = &nlp.Mapping{Token: lat.Token, Spellout: lat.Spellouts[0], Range: lat.Range}
 	}
 
 	morphGraph := &morphtypes.BasicMorphGraph{
@@ -777,6 +906,9 @@ func ConllU2Graph(sent *Sentence, eWord, ePOS, eWPOS, eRel, eMHost, eMSuffix *ut
 			RawToken: row.Form,
 			RawPOS:   row.UPosTag,
 		}
+		if row.TokenID >= 0 && row.TokenID < len(sent.Ranges) {
+			node.Range = sent.Ranges[row.TokenID]
+		}
 
 		switch WORD_TYPE {
 		case "form":
@@ -878,6 +1010,9 @@ func MorphGraph2ConllU(graph nlp.MorphDependencyGraph) Sentence {
 			DepRel:  depRel,
 			TokenID: node.TokenID,
 		}
+		if node.TokenID > 0 && node.TokenID <= len(sent.Mappings) {
+			row.Misc = TokenRangeMisc(sent.Mappings[node.TokenID-1].Range)
+		}
 		sent.Deps[row.ID] = row
 	}
 	return *sent
@@ -892,14 +1027,22 @@ func MorphGraph2ConllCorpus(corpus []interface{}) []interface{} {
 }
 
 func MergeGraphAndMorph(dep Sentence, morph nlp.MorphDependencyGraph) interface{} {
+	return MergeGraphAndMappings(dep, morph.GetMappings())
+}
+
+func MergeGraphAndMappings(dep Sentence, mappings nlp.Mappings) Sentence {
 	sent := NewSentence()
-	sent.Mappings = morph.GetMappings()
+	sent.Mappings = mappings
 	sent.Deps = dep.Deps
 	curDepNode := 1
 	for tokenNum, mapping := range sent.Mappings {
-		for _, _ = range mapping.Spellout {
+		hasConfidence := len(mapping.MorphConfidence) == len(mapping.Spellout)
+		for j, _ := range mapping.Spellout {
 			curNode := sent.Deps[curDepNode]
 			curNode.TokenID = tokenNum + 1
+			if hasConfidence {
+				curNode.Misc = AddMisc(curNode.Misc, ConfidenceMisc(mapping.MorphConfidence[j]))
+			}
 			sent.Deps[curDepNode] = curNode
 			curDepNode += 1
 		}
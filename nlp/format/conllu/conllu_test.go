@@ -0,0 +1,68 @@
+package conllu
+
+import (
+	nlp "yap/nlp/types"
+	"yap/util"
+
+	"strings"
+	"testing"
+)
+
+const rangedSentence = "1-2\tהבית\t_\t_\t_\t_\t_\t_\t_\tTokenRange=0:4\n" +
+	"1\tה\tה\tDET\tDET\t_\t2\tdet\t_\t_\n" +
+	"2\tבית\tבית\tNOUN\tNOUN\t_\t0\troot\t_\t_\n" +
+	"3\tגדול\tגדול\tADJ\tADJ\t_\t2\tamod\t_\tTokenRange=5:9|Confidence=0.9000\n" +
+	"\n"
+
+func TestReadTokenRanges(t *testing.T) {
+	sents, _, err := Read(strings.NewReader(rangedSentence), 0)
+	if err != nil {
+		t.Fatalf("Failed reading CoNLL-U: %v", err)
+	}
+	if len(sents) != 1 {
+		t.Fatalf("Got %d sentences, expected 1", len(sents))
+	}
+	expected := []nlp.TokenRange{{Start: 0, End: 4}, {Start: 5, End: 9}}
+	if ranges := sents[0].Ranges; len(ranges) != 2 || ranges[0] != expected[0] || ranges[1] != expected[1] {
+		t.Errorf("Got token ranges %v, expected %v", ranges, expected)
+	}
+
+	e := func() *util.EnumSet { return util.NewEnumSet(10, "test") }
+	graph := ConllU2MorphGraph(sents[0], e(), e(), e(), e(), e(), e(), e())
+	for i, lat := range graph.Lattice {
+		if lat.Range != expected[i] {
+			t.Errorf("Lattice %d: got range %v, expected %v", i, lat.Range, expected[i])
+		}
+	}
+
+	var buf strings.Builder
+	writeMultiWordToken(&buf, 1, &nlp.Mapping{Token: "הבית", Spellout: nlp.Spellout{nil, nil}, Range: expected[0]})
+	if reread, err := ParseTokenRowRange(strings.Split(strings.TrimSpace(buf.String()), "\t")); err != nil || reread != expected[0] {
+		t.Errorf("Got range %v (%v) of a written token row, expected %v", reread, err, expected[0])
+	}
+
+	if _, _, err := Read(strings.NewReader(strings.Replace(rangedSentence, "5:9", "9:5", 1)), 0); err == nil {
+		t.Error("Expected an error reading an invalid token range")
+	}
+}
+
+func TestReadWithOptions(t *testing.T) {
+	ignoreLemma := IGNORE_LEMMA
+	IGNORE_LEMMA = true
+	defer func() { IGNORE_LEMMA = ignoreLemma }()
+
+	sents, _, err := Options{}.Read(strings.NewReader(rangedSentence), 0)
+	if err != nil {
+		t.Fatalf("Failed reading CoNLL-U: %v", err)
+	}
+	if lemma := sents[0].Deps[2].Lemma; lemma != "בית" {
+		t.Errorf("Got lemma %q, expected בית regardless of IGNORE_LEMMA", lemma)
+	}
+	sents, _, err = Read(strings.NewReader(rangedSentence), 0)
+	if err != nil {
+		t.Fatalf("Failed reading CoNLL-U: %v", err)
+	}
+	if lemma := sents[0].Deps[2].Lemma; lemma != "" {
+		t.Errorf("Got lemma %q, expected it to be ignored", lemma)
+	}
+}
@@ -40,6 +40,48 @@ var (
 	OVERRIDE_XPOS_WITH_UPOS bool
 )
 
+// Options are the lattice switches of a single model, so that models with
+// different settings may share a process. Package level functions use the
+// package level switches
+type Options struct {
+	IgnoreLemma    bool
+	IgnoreNNPFeats bool
+}
+
+func GlobalOptions() Options {
+	return Options{IgnoreLemma: IGNORE_LEMMA, IgnoreNNPFeats: IGNORE_NNP_FEATS}
+}
+
+// Apply returns a copy of an in-memory lattice as it would have been read
+// with the options, dropping edges that become duplicates
+func (o Options) Apply(lat Lattice) Lattice {
+	retLat := make(Lattice, len(lat))
+	for start, edges := range lat {
+		newEdges := make([]Edge, 0, len(edges))
+		for _, edge := range edges {
+			if o.IgnoreLemma {
+				edge.Lemma = ""
+			}
+			if o.IgnoreNNPFeats && edge.CPosTag == "NNP" {
+				edge.Feats, _ = ParseFeatures("_")
+				edge.FeatStr = ParseString("_")
+			}
+			dup := false
+			for _, otherEdge := range newEdges {
+				if edge.Equal(otherEdge) {
+					dup = true
+					break
+				}
+			}
+			if !dup {
+				newEdges = append(newEdges, edge)
+			}
+		}
+		retLat[start] = newEdges
+	}
+	return retLat
+}
+
 type Features map[string]string
 
 type JSONEdge struct {
@@ -51,6 +93,10 @@ type JSONEdge struct {
 	Feats   string `json:"feats,omitempty"`
 	Misc    string `json:"misc,omitempty"`
 	TokenID int    `json:"tokenid,omitempty"`
+	// source text range of the edge's token, as start:end characters
+	TokenRange string `json:"token_range,omitempty"`
+	// disambiguation confidence of the edge's morpheme
+	Confidence *float64 `json:"confidence,omitempty"`
 }
 
 type JSONLattice map[string][]JSONEdge
@@ -114,6 +160,11 @@ type Edge struct {
 	Token    int
 	Id       int
 	TokenStr string
+	// range of the token in the source text, an optional column of the
+	// lattice file format as TokenRange=<start>:<end>
+	TokenRange nlp.TokenRange
+	// disambiguation confidence of the morpheme, nil if unknown
+	Confidence *float64
 }
 
 type EdgeSlice []Edge
@@ -155,6 +206,9 @@ func (e Edge) String() string {
 	if len(e.Lemma) == 0 {
 		fields[3] = "_"
 	}
+	if e.TokenRange.Known() {
+		fields = append(fields, e.TokenRange.Attribute())
+	}
 	return strings.Join(fields, "\t")
 }
 
@@ -191,6 +245,31 @@ func (e *Edge) Copy() *Edge {
 
 type Lattice map[int][]Edge
 
+// SetTokenRanges sets the source text range of each edge by its token,
+// for lattices read from files which do not record ranges
+func (l Lattice) SetTokenRanges(ranges []nlp.TokenRange) {
+	for _, edges := range l {
+		for i, edge := range edges {
+			if edge.Token > 0 && edge.Token <= len(ranges) {
+				edges[i].TokenRange = ranges[edge.Token-1]
+			}
+		}
+	}
+}
+
+// SetConfidences sets the disambiguation confidence of each edge of a
+// disambiguated lattice by its start node, as numbered by the mapping format
+func (l Lattice) SetConfidences(confidences []float64) {
+	for _, edges := range l {
+		for i, edge := range edges {
+			if edge.Start >= 0 && edge.Start < len(confidences) {
+				confidence := confidences[edge.Start]
+				edges[i].Confidence = &confidence
+			}
+		}
+	}
+}
+
 func (l Lattice) MaxKey() (retval int) {
 	for k, _ := range l {
 		if k > retval {
@@ -272,6 +351,10 @@ func ParseFeatures(featuresStr string) (Features, error) {
 }
 
 func ParseULEdge(record []string) (*Edge, error) {
+	return GlobalOptions().ParseULEdge(record)
+}
+
+func (o Options) ParseULEdge(record []string) (*Edge, error) {
 	row := &Edge{}
 	start, err := ParseInt(record[0])
 	if err != nil {
@@ -291,7 +374,7 @@ func ParseULEdge(record []string) (*Edge, error) {
 	// }
 	row.Word = word
 
-	if !IGNORE_LEMMA {
+	if !o.IgnoreLemma {
 		lemma := ParseString(record[3])
 		row.Lemma = lemma
 	}
@@ -309,7 +392,7 @@ func ParseULEdge(record []string) (*Edge, error) {
 	// Note, xpostag may be empty in conllu
 	row.PosTag = xpostag
 
-	if IGNORE_NNP_FEATS && upostag == "NNP" {
+	if o.IgnoreNNPFeats && upostag == "NNP" {
 		record[6] = "_"
 	}
 	features, err := ParseFeatures(record[6])
@@ -378,6 +461,10 @@ func ParseUDEdge(record []string) (*Edge, error) {
 }
 
 func ParseEdge(record []string) (*Edge, error) {
+	return GlobalOptions().ParseEdge(record)
+}
+
+func (o Options) ParseEdge(record []string) (*Edge, error) {
 	row := &Edge{}
 	start, err := ParseInt(record[0])
 	if err != nil {
@@ -397,7 +484,7 @@ func ParseEdge(record []string) (*Edge, error) {
 	// }
 	row.Word = word
 
-	if !IGNORE_LEMMA {
+	if !o.IgnoreLemma {
 		lemma := ParseString(record[3])
 		row.Lemma = lemma
 	}
@@ -423,7 +510,7 @@ func ParseEdge(record []string) (*Edge, error) {
 	}
 	row.Token = token
 
-	if IGNORE_NNP_FEATS && cpostag == "NNP" {
+	if o.IgnoreNNPFeats && cpostag == "NNP" {
 		record[6] = "_"
 	}
 	features, err := ParseFeatures(record[6])
@@ -432,6 +519,16 @@ func ParseEdge(record []string) (*Edge, error) {
 	}
 	row.Feats = features
 	row.FeatStr = ParseString(record[6])
+
+	// optional columns following the token, such as the token's range
+	for _, field := range record[8:] {
+		if tokenRange, isRange, err := nlp.ParseTokenRangeAttribute(field); isRange {
+			if err != nil {
+				return row, errors.New(fmt.Sprintf("Error parsing token range (%s): %s", field, err.Error()))
+			}
+			row.TokenRange = tokenRange
+		}
+	}
 	return row, nil
 }
 
@@ -510,6 +607,10 @@ func ReadStream(in *os.File, limit int) chan Lattice {
 }
 
 func Read(r io.Reader, limit int) ([]Lattice, error) {
+	return GlobalOptions().Read(r, limit)
+}
+
+func (o Options) Read(r io.Reader, limit int) ([]Lattice, error) {
 	var sentences []Lattice
 	bufReader := bufio.NewReader(r)
 
@@ -542,7 +643,7 @@ func Read(r io.Reader, limit int) ([]Lattice, error) {
 		}
 		record := strings.Split(buf.String(), "\t")
 
-		edge, err := ParseEdge(record)
+		edge, err := o.ParseEdge(record)
 		if edge.Start == edge.End {
 			log.Println("At sent:", len(sentences), "Warning: found circular edge", edge, ", optimistically incrementing end")
 			edge.End += 1
@@ -686,6 +787,10 @@ func ULReadStream(in *os.File, limit int) chan Lattice {
 }
 
 func ULRead(r io.Reader, limit int) ([]Lattice, error) {
+	return GlobalOptions().ULRead(r, limit)
+}
+
+func (o Options) ULRead(r io.Reader, limit int) ([]Lattice, error) {
 	var sentences []Lattice
 	bufReader := bufio.NewReader(r)
 
@@ -741,7 +846,7 @@ func ULRead(r io.Reader, limit int) ([]Lattice, error) {
 			continue
 		}
 
-		edge, err := ParseULEdge(record)
+		edge, err := o.ParseULEdge(record)
 		// for non-multi-segment tokens, detect when a single-segment edge is
 		// a new token
 		if edge.Start >= tokTop && edge.End > tokBottom {
@@ -907,61 +1012,68 @@ func UDWrite(writer io.Writer, lattices []Lattice, comments [][]string, oovVecto
 	return nil
 }
 
-func UDWriteJSON(writer io.Writer, lattices []Lattice) error {
-	for _, lattice := range lattices {
-		var (
-			max, lastToken int
-			jsonEdge       *JSONEdge
-			jsonLat        JSONLattice
-		)
+func Lattice2JSON(lattice Lattice) []JSONLattice {
+	var (
+		max, lastToken int
+		jsonEdge       *JSONEdge
+		jsonLat        JSONLattice
+	)
+	retval := make([]JSONLattice, 0, len(lattice))
 
-		for k, _ := range lattice {
-			if k > max {
-				max = k
-			}
+	for k, _ := range lattice {
+		if k > max {
+			max = k
 		}
-		for i := 0; i <= max; i++ {
-			if row, exists := lattice[i]; exists {
-				if len(row) > 0 && row[0].Token > lastToken {
-					lastToken = row[0].Token
-					if jsonLat != nil {
-						marshalled, err := json.Marshal(jsonLat)
-						if err != nil {
-							panic(fmt.Sprintf("Failure marshalling %v", err))
-						}
-						writer.Write(marshalled)
-						writer.Write([]byte{'\n'})
-					}
-					jsonLat = make(JSONLattice)
+	}
+	for i := 0; i <= max; i++ {
+		if row, exists := lattice[i]; exists {
+			if len(row) > 0 && row[0].Token > lastToken {
+				lastToken = row[0].Token
+				if jsonLat != nil {
+					retval = append(retval, jsonLat)
 				}
-				for _, edge := range row {
-					jsonEdge = &JSONEdge{
-						Next:    fmt.Sprint(edge.End),
-						Form:    edge.Word,
-						UPOSTag: edge.CPosTag,
-					}
-					if edge.Lemma != "_" {
-						jsonEdge.Lemma = edge.Lemma
-					}
-					if edge.FeatStr != "_" {
-						jsonEdge.Feats = edge.FeatStr
-					}
-					if edge.PosTag != "_" {
-						jsonEdge.XPOSTag = edge.PosTag
-					}
-					startStr := fmt.Sprint(edge.Start)
-					if outEdges, edgesExist := jsonLat[startStr]; edgesExist {
-						outEdges = append(outEdges, *jsonEdge)
-						jsonLat[startStr] = outEdges
-					} else {
-						newList := make([]JSONEdge, 1, 2)
-						newList[0] = *jsonEdge
-						jsonLat[startStr] = newList
-					}
+				jsonLat = make(JSONLattice)
+			}
+			for _, edge := range row {
+				jsonEdge = &JSONEdge{
+					Next:    fmt.Sprint(edge.End),
+					Form:    edge.Word,
+					UPOSTag: edge.CPosTag,
+				}
+				if edge.Lemma != "_" {
+					jsonEdge.Lemma = edge.Lemma
+				}
+				if edge.FeatStr != "_" {
+					jsonEdge.Feats = edge.FeatStr
+				}
+				if edge.PosTag != "_" {
+					jsonEdge.XPOSTag = edge.PosTag
+				}
+				if edge.TokenRange.Known() {
+					jsonEdge.TokenRange = edge.TokenRange.String()
+				}
+				jsonEdge.Confidence = edge.Confidence
+				startStr := fmt.Sprint(edge.Start)
+				if outEdges, edgesExist := jsonLat[startStr]; edgesExist {
+					outEdges = append(outEdges, *jsonEdge)
+					jsonLat[startStr] = outEdges
+				} else {
+					newList := make([]JSONEdge, 1, 2)
+					newList[0] = *jsonEdge
+					jsonLat[startStr] = newList
 				}
 			}
 		}
-		if jsonLat != nil {
+	}
+	if jsonLat != nil {
+		retval = append(retval, jsonLat)
+	}
+	return retval
+}
+
+func UDWriteJSON(writer io.Writer, lattices []Lattice) error {
+	for _, lattice := range lattices {
+		for _, jsonLat := range Lattice2JSON(lattice) {
 			marshalled, err := json.Marshal(jsonLat)
 			if err != nil {
 				panic(fmt.Sprintf("Failure marshalling %v", err))
@@ -1015,13 +1127,17 @@ func Write(writer io.Writer, lattices []Lattice) error {
 }
 
 func ReadFile(filename string, limit int) ([]Lattice, error) {
+	return GlobalOptions().ReadFile(filename, limit)
+}
+
+func (o Options) ReadFile(filename string, limit int) ([]Lattice, error) {
 	file, err := os.Open(filename)
 	defer file.Close()
 	if err != nil {
 		return nil, err
 	}
 
-	return Read(file, limit)
+	return o.Read(file, limit)
 }
 
 func StreamFile(filename string, limit int) (chan Lattice, error) {
@@ -1034,13 +1150,17 @@ func StreamFile(filename string, limit int) (chan Lattice, error) {
 }
 
 func ReadULFile(filename string, limit int) ([]Lattice, error) {
+	return GlobalOptions().ReadULFile(filename, limit)
+}
+
+func (o Options) ReadULFile(filename string, limit int) ([]Lattice, error) {
 	file, err := os.Open(filename)
 	defer file.Close()
 	if err != nil {
 		return nil, err
 	}
 
-	return ULRead(file, limit)
+	return o.ULRead(file, limit)
 }
 func StreamULFile(filename string, limit int) (chan Lattice, error) {
 	file, err := os.Open(filename)
@@ -1139,6 +1259,9 @@ func Lattice2Sentence(lattice Lattice, eWord, ePOS, eWPOS, eMorphFeat, eMHost, e
 			skipEdge = false
 
 			lat := &sent[edge.Token-1]
+			if edge.TokenRange.Known() {
+				lat.Range = edge.TokenRange
+			}
 
 			// FIX Fusional 'H' in Modern Hebrew Corpus
 			if _FIX_FUSIONAL_H {
@@ -1321,12 +1444,16 @@ func Lattice2SentenceStream(corpus chan Lattice, eWord, ePOS, eWPOS, eMorphFeat,
 }
 
 func Sentence2Lattice(lattice nlp.LatticeSentence, xliter8or xliter8.Interface) Lattice {
+	return GlobalOptions().Sentence2Lattice(lattice, xliter8or)
+}
+
+func (o Options) Sentence2Lattice(lattice nlp.LatticeSentence, xliter8or xliter8.Interface) Lattice {
 	retLat := make(Lattice)
 	for _, sentlat := range lattice {
 		for _, m := range sentlat.Morphemes {
 			outForm := m.Form
 			outLemma := m.Lemma
-			if IGNORE_LEMMA {
+			if o.IgnoreLemma {
 				outLemma = ""
 			}
 			if xliter8or != nil {
@@ -1346,6 +1473,8 @@ func Sentence2Lattice(lattice nlp.LatticeSentence, xliter8or xliter8.Interface)
 				m.TokenID,
 				m.ID(),
 				string(sentlat.Token),
+				sentlat.Range,
+				nil,
 			}
 			if len(m.FeatureStr) == 0 {
 				e.FeatStr = "_"
@@ -1,6 +1,8 @@
 package lattice
 
 import (
+	nlp "yap/nlp/types"
+
 	"strings"
 	"testing"
 )
@@ -41,3 +43,127 @@ func TestParseEdgeWithRepeatingParams(t *testing.T) {
 		t.Error("Failure concatenating multiple features: should be F,M got " + parsed.Feats["suf_gen"])
 	}
 }
+
+func TestParseEdgeWithOptions(t *testing.T) {
+	row := strings.Split("0	1	EFRWT	EFR	CDT	CDT	gen=F|num=P	1",
+		string(FIELD_SEPARATOR))
+
+	parsed, err := Options{IgnoreLemma: true}.ParseEdge(row)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	if parsed.Lemma != "" {
+		t.Error("Lemma should be ignored, got " + parsed.Lemma)
+	}
+	parsed, err = Options{}.ParseEdge(row)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	if parsed.Lemma != "EFR" {
+		t.Error("Lemma should be EFR, got " + parsed.Lemma)
+	}
+}
+
+func TestULReadWithOptions(t *testing.T) {
+	ignoreLemma := IGNORE_LEMMA
+	IGNORE_LEMMA = true
+	defer func() { IGNORE_LEMMA = ignoreLemma }()
+	ul := "0\t1\tEFRWT\tEFR\tCDT\tCDT\tgen=F|num=P\t1\n\n"
+
+	lats, err := Options{}.ULRead(strings.NewReader(ul), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if lemma := lats[0][0][0].Lemma; lemma != "EFR" {
+		t.Error("Lemma should be EFR regardless of IGNORE_LEMMA, got " + lemma)
+	}
+	lats, err = ULRead(strings.NewReader(ul), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if lemma := lats[0][0][0].Lemma; lemma != "" {
+		t.Error("Lemma should be ignored, got " + lemma)
+	}
+}
+
+func TestOptionsApply(t *testing.T) {
+	lat := Lattice{
+		0: []Edge{
+			{Start: 0, End: 1, Word: "BIT", Lemma: "BIT", CPosTag: "NN", PosTag: "NN", Token: 1},
+			{Start: 0, End: 1, Word: "BIT", Lemma: "BIT2", CPosTag: "NN", PosTag: "NN", Token: 1},
+		},
+	}
+	applied := Options{IgnoreLemma: true}.Apply(lat)
+	if len(applied[0]) != 1 {
+		t.Errorf("Expected 1 edge without lemmas, got %d", len(applied[0]))
+	}
+	if len(lat[0]) != 2 || lat[0][0].Lemma != "BIT" {
+		t.Error("Apply should not modify the original lattice")
+	}
+}
+
+func TestTokenRanges(t *testing.T) {
+	lat := Lattice{
+		0: []Edge{{Start: 0, End: 1, Word: "B", CPosTag: "PREPOSITION", Token: 1, TokenStr: "BBIT"}},
+		1: []Edge{{Start: 1, End: 2, Word: "BIT", CPosTag: "NN", Token: 1, TokenStr: "BBIT"}},
+		2: []Edge{{Start: 2, End: 3, Word: "GDWL", CPosTag: "JJ", Token: 2, TokenStr: "GDWL"}},
+	}
+	lat.SetTokenRanges([]nlp.TokenRange{{Start: 0, End: 4}, {Start: 5, End: 9}})
+	if lat[1][0].TokenRange.String() != "0:4" || lat[2][0].TokenRange.String() != "5:9" {
+		t.Errorf("Expected token ranges 0:4 and 5:9, got %v and %v", lat[1][0].TokenRange, lat[2][0].TokenRange)
+	}
+	json := Lattice2JSON(lat)
+	if len(json) != 2 || json[0]["1"][0].TokenRange != "0:4" {
+		t.Errorf("Expected token range 0:4 in JSON lattice, got %v", json)
+	}
+}
+
+func TestTokenRangesFile(t *testing.T) {
+	lat := Lattice{
+		0: []Edge{{Start: 0, End: 1, Word: "B", Lemma: "B", CPosTag: "PREPOSITION", PosTag: "PREPOSITION", FeatStr: "_", Token: 1}},
+		1: []Edge{{Start: 1, End: 2, Word: "BIT", Lemma: "BIT", CPosTag: "NN", PosTag: "NN", FeatStr: "_", Token: 1}},
+		2: []Edge{{Start: 2, End: 3, Word: "GDWL", Lemma: "GDWL", CPosTag: "JJ", PosTag: "JJ", FeatStr: "_", Token: 2}},
+	}
+	lat.SetTokenRanges([]nlp.TokenRange{{Start: 0, End: 4}, {Start: 5, End: 9}})
+	var buf strings.Builder
+	Write(&buf, []Lattice{lat})
+	if !strings.Contains(buf.String(), "GDWL\tJJ\tJJ\t_\t2\tTokenRange=5:9\n") {
+		t.Errorf("Expected a token range column in\n%s", buf.String())
+	}
+	read, err := Options{}.Read(strings.NewReader(buf.String()), 0)
+	if err != nil {
+		t.Fatalf("Failed reading lattice: %v", err)
+	}
+	if len(read) != 1 {
+		t.Fatalf("Got %d lattices, expected 1", len(read))
+	}
+	for start, edges := range lat {
+		if got := read[0][start][0].TokenRange; got != edges[0].TokenRange {
+			t.Errorf("Edge %d: got token range %v, expected %v", start, got, edges[0].TokenRange)
+		}
+	}
+	// columns following the range, such as the confidence of mapping files
+	edge, err := ParseEdge(strings.Split("0\t1\tBIT\tBIT\tNN\tNN\t_\t1\tTokenRange=0:3\t0.9933", "\t"))
+	if err != nil || edge.TokenRange != (nlp.TokenRange{Start: 0, End: 3}) {
+		t.Errorf("Got token range %v (%v), expected 0:3", edge.TokenRange, err)
+	}
+	if _, err := ParseEdge(strings.Split("0\t1\tBIT\tBIT\tNN\tNN\t_\t1\tTokenRange=x", "\t")); err == nil {
+		t.Error("Expected an error parsing an invalid token range")
+	}
+}
+
+func TestConfidences(t *testing.T) {
+	lat := Lattice{
+		0: []Edge{{Start: 0, End: 1, Word: "B", CPosTag: "PREPOSITION", Token: 1, TokenStr: "BBIT"}},
+		1: []Edge{{Start: 1, End: 2, Word: "BIT", CPosTag: "NN", Token: 1, TokenStr: "BBIT"}},
+	}
+	json := Lattice2JSON(lat)
+	if json[0]["0"][0].Confidence != nil {
+		t.Errorf("Expected no confidence in JSON lattice, got %v", *json[0]["0"][0].Confidence)
+	}
+	lat.SetConfidences([]float64{0.5, 0.75})
+	json = Lattice2JSON(lat)
+	if confidence := json[0]["1"][0].Confidence; confidence == nil || *confidence != 0.75 {
+		t.Errorf("Expected confidence 0.75 in JSON lattice, got %v", confidence)
+	}
+}
@@ -0,0 +1,275 @@
+package lex
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"yap/util"
+)
+
+const (
+	// a line or MSR which the lexicon readers fail on or skip
+	ISSUE_MALFORMED = "malformed"
+	// a POS tag with no UD conversion
+	ISSUE_UNKNOWN_POS = "unknown_pos"
+	// a feature value with no lexicon or UD conversion
+	ISSUE_UNKNOWN_FEATURE = "unknown_feature"
+	// an analysis repeating a previous analysis of the token
+	ISSUE_DUPLICATE = "duplicate"
+	// a prefix whose morpheme forms do not spell it out
+	ISSUE_PREFIX_FORMS = "prefix_forms"
+	// a prefix longer than the analyzer ever tries
+	ISSUE_UNMATCHABLE_PREFIX = "unmatchable_prefix"
+)
+
+// Issue is a problem found in a lexicon or prefix file by a Checker
+type Issue struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Token  string `json:"token"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail"`
+}
+
+func (i Issue) String() string {
+	return strings.Join([]string{i.File, fmt.Sprintf("%d", i.Line), i.Token, i.Kind, i.Detail}, "\t")
+}
+
+// Checker collects issues of lexicon and prefix files, keeping the state
+// needed to find issues across lines (duplicates, prefix lengths)
+type Checker struct {
+	Issues []Issue
+
+	analyses map[string]map[string]int
+	prefixes []Issue
+	maxLen   int
+}
+
+func NewChecker() *Checker {
+	return &Checker{analyses: make(map[string]map[string]int)}
+}
+
+func (c *Checker) add(file string, line int, token, kind, detail string) {
+	c.Issues = append(c.Issues, Issue{file, line, token, kind, detail})
+}
+
+func (c *Checker) checkDuplicate(file string, line int, token, analysis string) {
+	seen, exists := c.analyses[file+"\t"+token]
+	if !exists {
+		seen = make(map[string]int, 2)
+		c.analyses[file+"\t"+token] = seen
+	}
+	if prevLine, exists := seen[analysis]; exists {
+		c.add(file, line, token, ISSUE_DUPLICATE, fmt.Sprintf("%s (line %d)", analysis, prevLine))
+		return
+	}
+	seen[analysis] = line
+}
+
+// udFeatureKnown returns whether a lexicon feature pair (as in
+// MSR_TYPE_FROM_VALUE) can be converted to UD by util.Heb2UDFeature
+func udFeatureKnown(CPOS, pair string) bool {
+	switch pair {
+	case "tense=BEINONI", "type=TOINFINITIVE", "tense=IMPERATIVE":
+		return true
+	}
+	split := strings.Split(pair, FEATURE_VALUE_SEPARATOR)
+	if len(split) != 2 {
+		return false
+	}
+	if split[0] == "binyan" {
+		return true
+	}
+	// handled by the UD reader as part of the POS
+	if CPOS == "CC" && split[0] == "type" {
+		return true
+	}
+	lookup, exists := util.HEB2UDFeatureNameLookup[split[0]]
+	if !exists {
+		return false
+	}
+	_, exists = lookup.ValueMap[split[1]]
+	return exists
+}
+
+// checkFeatures checks the feature values of a host or suffix MSR
+func (c *Checker) checkFeatures(file string, line int, token, CPOS string, values []string) {
+	for _, value := range values {
+		if len(value) == 0 {
+			continue
+		}
+		lkpStr, exists := MSR_TYPE_FROM_VALUE[value]
+		if !exists {
+			c.add(file, line, token, ISSUE_UNKNOWN_FEATURE, fmt.Sprintf("%s: unknown value %s", CPOS, value))
+			continue
+		}
+		for _, pair := range strings.Split(lkpStr, FEATURE_PAIR_SEPARATOR) {
+			if !udFeatureKnown(CPOS, pair) {
+				c.add(file, line, token, ISSUE_UNKNOWN_FEATURE, fmt.Sprintf("%s: %s (%s) has no UD conversion", CPOS, value, pair))
+			}
+		}
+	}
+}
+
+// CheckLexiconLine checks a line of a lexicon file, in the format read by
+// ProcessAnalyzedToken
+func (c *Checker) CheckLexiconLine(file string, line int, analysis string) {
+	split := strings.Split(analysis, SEPARATOR)
+	if len(split) < 3 || len(split)%2 != 1 {
+		c.add(file, line, split[0], ISSUE_MALFORMED, fmt.Sprintf("wrong number of fields (%d)", len(split)))
+		return
+	}
+	token := split[0]
+	for i := 1; i < len(split); i += 2 {
+		msr, lemma := split[i], split[i+1]
+		msrs := strings.Split(msr, MSR_SEPARATOR)
+		if len(msrs) != 3 {
+			c.add(file, line, token, ISSUE_MALFORMED, fmt.Sprintf("MSR %s should have prefix, host and suffix parts", msr))
+			continue
+		}
+		if len(msrs[0]) > 0 && msrs[0] != "DEF" {
+			c.add(file, line, token, ISSUE_MALFORMED, fmt.Sprintf("unknown prefix MSR %s in %s", msrs[0], msr))
+		}
+		if len(msrs[1]) == 0 {
+			c.add(file, line, token, ISSUE_MALFORMED, fmt.Sprintf("empty host MSR in %s", msr))
+			continue
+		}
+		host := strings.Split(msrs[1], FEATURE_SEPARATOR)
+		CPOS := host[0]
+		if _, exists := util.HEB2UDPOS[CPOS]; !exists && CPOS != "UNK" {
+			c.add(file, line, token, ISSUE_UNKNOWN_POS, fmt.Sprintf("unknown POS %s in %s", CPOS, msr))
+		}
+		c.checkFeatures(file, line, token, CPOS, host[1:])
+		if suffix := msrs[2]; len(suffix) > 0 {
+			if suffix[0] == 'S' && len(suffix) < 5 {
+				c.add(file, line, token, ISSUE_MALFORMED, fmt.Sprintf("malformed suffix MSR %s in %s", suffix, msr))
+			} else {
+				sufSplit := strings.Split(suffix, FEATURE_SEPARATOR)
+				c.checkFeatures(file, line, token, CPOS, sufSplit[1:])
+			}
+		}
+		c.checkDuplicate(file, line, token, msr+SEPARATOR+lemma)
+	}
+}
+
+// CheckPrefixLine checks a line of a prefix file, in the format read by
+// ProcessAnalyzedPrefix
+func (c *Checker) CheckPrefixLine(file string, line int, analysis string) {
+	split := strings.Split(analysis, SEPARATOR)
+	if len(split) < 3 || len(split)%2 != 1 {
+		c.add(file, line, split[0], ISSUE_MALFORMED, fmt.Sprintf("wrong number of fields (%d)", len(split)))
+		return
+	}
+	token := split[0]
+	// the analyzer tries prefixes up to the largest number of analyses of
+	// a prefix, see BGULex.LoadPrefixes
+	c.maxLen = util.Max(c.maxLen, (len(split)-1)/2)
+	c.prefixes = append(c.prefixes, Issue{File: file, Line: line, Token: token})
+	for i := 1; i < len(split); i += 2 {
+		forms := strings.Split(split[i], PREFIX_SEPARATOR)
+		msrs := strings.Split(split[i+1], PREFIX_MSR_SEPARATOR)
+		if len(forms) != len(msrs) {
+			c.add(file, line, token, ISSUE_MALFORMED, fmt.Sprintf("%s %s: %d forms and %d MSRs", split[i], split[i+1], len(forms), len(msrs)))
+			continue
+		}
+		var spelled, spelledNoDef string
+		for j, form := range forms {
+			POS := strings.Split(msrs[j], MSR_SEPARATOR)[0]
+			POS = strings.Replace(POS, "-SUBCONJ", "", -1)
+			if _, exists := util.HEB2UDPrefixPOS[POS]; !exists {
+				c.add(file, line, token, ISSUE_UNKNOWN_POS, fmt.Sprintf("unknown prefix POS %s in %s %s", POS, split[i], split[i+1]))
+			}
+			spelled += form
+			if POS != "DEF" {
+				spelledNoDef += form
+			}
+		}
+		// the definite article may be elided after a preposition (בבית)
+		if spelled != token && spelledNoDef != token {
+			c.add(file, line, token, ISSUE_PREFIX_FORMS, fmt.Sprintf("%s spells out %s", split[i], spelled))
+		}
+		c.checkDuplicate(file, line, token, split[i]+SEPARATOR+split[i+1])
+	}
+}
+
+func (c *Checker) check(input io.Reader, file string, checkLine func(string, int, string)) error {
+	scan := bufio.NewScanner(input)
+	var line int
+	for scan.Scan() {
+		line++
+		if len(scan.Text()) == 0 {
+			continue
+		}
+		checkLine(file, line, scan.Text())
+	}
+	return scan.Err()
+}
+
+func (c *Checker) CheckLexicon(input io.Reader, file string) error {
+	return c.check(input, file, c.CheckLexiconLine)
+}
+
+func (c *Checker) CheckPrefixes(input io.Reader, file string) error {
+	return c.check(input, file, c.CheckPrefixLine)
+}
+
+func (c *Checker) CheckFile(filename, format string) error {
+	file, err := os.Open(filename)
+	defer file.Close()
+	if err != nil {
+		return err
+	}
+	if format == "prefix" {
+		return c.CheckPrefixes(file, filename)
+	}
+	return c.CheckLexicon(file, filename)
+}
+
+// Report returns the issues found, including issues depending on all
+// checked files, sorted by file and line
+func (c *Checker) Report() []Issue {
+	issues := make([]Issue, len(c.Issues), len(c.Issues)+len(c.prefixes))
+	copy(issues, c.Issues)
+	for _, prefix := range c.prefixes {
+		if length := utf8.RuneCountInString(prefix.Token); length > c.maxLen {
+			prefix.Kind = ISSUE_UNMATCHABLE_PREFIX
+			prefix.Detail = fmt.Sprintf("length %d exceeds the longest prefix tried (%d)", length, c.maxLen)
+			issues = append(issues, prefix)
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		return issues[i].Line < issues[j].Line
+	})
+	return issues
+}
+
+// WriteIssues writes issues as tab separated values with a header line, or
+// as a JSON array
+func WriteIssues(writer io.Writer, issues []Issue, asJSON bool) error {
+	if asJSON {
+		if issues == nil {
+			issues = []Issue{}
+		}
+		enc := json.NewEncoder(writer)
+		enc.SetIndent("", "  ")
+		return enc.Encode(issues)
+	}
+	if _, err := writer.Write([]byte("file\tline\ttoken\tkind\tdetail\n")); err != nil {
+		return err
+	}
+	for _, issue := range issues {
+		if _, err := writer.Write([]byte(issue.String() + "\n")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package lex
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const (
+	testCheckPrefixes = `ב ב PREPOSITION
+ה ה DEF ה DEF
+בה ב^ה PREPOSITION+DEF בה PREPOSITION
+וכשב ו^כש^ב CONJ+TEMP-SUBCONJ+PREPOSITION
+ל ל XX
+מ ב PREPOSITION
+`
+	testCheckLexicon = `בית :NN-M-S: בית :NN-M-S: בית
+ספר :NN-M-S ספר
+דגן :NN-M-Q: דגן
+גנן :ZZ-M-S: גנן
+ילד :NN-M-S:S 
+`
+)
+
+func TestChecker(t *testing.T) {
+	c := NewChecker()
+	if err := c.CheckPrefixes(strings.NewReader(testCheckPrefixes), "prefixes"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CheckLexicon(strings.NewReader(testCheckLexicon), "lexicon"); err != nil {
+		t.Fatal(err)
+	}
+	expected := []Issue{
+		{"lexicon", 1, "בית", ISSUE_DUPLICATE, ":NN-M-S: בית (line 1)"},
+		{"lexicon", 2, "ספר", ISSUE_MALFORMED, "MSR :NN-M-S should have prefix, host and suffix parts"},
+		{"lexicon", 3, "דגן", ISSUE_UNKNOWN_FEATURE, "NN: unknown value Q"},
+		{"lexicon", 4, "גנן", ISSUE_UNKNOWN_POS, "unknown POS ZZ in :ZZ-M-S:"},
+		{"lexicon", 5, "ילד", ISSUE_MALFORMED, "malformed suffix MSR S in :NN-M-S:S"},
+		{"prefixes", 2, "ה", ISSUE_DUPLICATE, "ה DEF (line 2)"},
+		{"prefixes", 4, "וכשב", ISSUE_UNMATCHABLE_PREFIX, "length 4 exceeds the longest prefix tried (2)"},
+		{"prefixes", 5, "ל", ISSUE_UNKNOWN_POS, "unknown prefix POS XX in ל XX"},
+		{"prefixes", 6, "מ", ISSUE_PREFIX_FORMS, "ב spells out ב"},
+	}
+	issues := c.Report()
+	if len(issues) != len(expected) {
+		t.Fatalf("Expected %d issues, got %d: %v", len(expected), len(issues), issues)
+	}
+	for i, issue := range issues {
+		if issue != expected[i] {
+			t.Errorf("Issue %d: expected %v, got %v", i, expected[i], issue)
+		}
+	}
+	var buf bytes.Buffer
+	if err := WriteIssues(&buf, issues, true); err != nil {
+		t.Fatal(err)
+	}
+	var read []Issue
+	if err := json.Unmarshal(buf.Bytes(), &read); err != nil || len(read) != len(issues) {
+		t.Errorf("Failed reading JSON report: %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+package lex
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	PATCH_ADD    = "add"
+	PATCH_REMOVE = "remove"
+	PATCH_MODIFY = "modify"
+)
+
+// PatchOp is an edit of an analysis of a prefix or lexicon file
+//
+// Patch files have an edit per line, with tab separated fields:
+//
+//	add    token MSR lemma
+//	remove token MSR lemma
+//	modify token MSR lemma new-MSR new-lemma
+//
+// where MSR and lemma are a pair of fields of a lexicon line (e.g.
+// :NN-M-S: בית), or the forms and MSRs of a prefix line (e.g. ב^ה
+// PREPOSITION+DEF). Empty lines and lines starting with # are skipped
+type PatchOp struct {
+	Op, Token     string
+	Analysis, New [2]string
+	Line          int
+}
+
+func (p *PatchOp) String() string {
+	if p.Op == PATCH_MODIFY {
+		return fmt.Sprintf("%s %s %s %s -> %s %s", p.Op, p.Token, p.Analysis[0], p.Analysis[1], p.New[0], p.New[1])
+	}
+	return fmt.Sprintf("%s %s %s %s", p.Op, p.Token, p.Analysis[0], p.Analysis[1])
+}
+
+func ReadPatch(input io.Reader) ([]*PatchOp, error) {
+	ops := make([]*PatchOp, 0, 100)
+	scan := bufio.NewScanner(input)
+	var numLine int
+	for scan.Scan() {
+		numLine++
+		line := scan.Text()
+		if len(strings.TrimSpace(line)) == 0 || strings.HasPrefix(line, USER_LEX_COMMENT) {
+			continue
+		}
+		split := strings.Split(line, "\t")
+		op := &PatchOp{Op: split[0], Line: numLine}
+		expected := 4
+		if op.Op == PATCH_MODIFY {
+			expected = 6
+		} else if op.Op != PATCH_ADD && op.Op != PATCH_REMOVE {
+			return nil, errors.New(fmt.Sprintf("Line %d: unknown patch operation %s", numLine, op.Op))
+		}
+		if len(split) != expected {
+			return nil, errors.New(fmt.Sprintf("Line %d: %s expects %d fields, got %d", numLine, op.Op, expected, len(split)))
+		}
+		for i, field := range split {
+			if len(field) == 0 || strings.Contains(field, SEPARATOR) {
+				return nil, errors.New(fmt.Sprintf("Line %d: field %d is empty or contains a space", numLine, i+1))
+			}
+		}
+		op.Token = split[1]
+		op.Analysis = [2]string{split[2], split[3]}
+		if op.Op == PATCH_MODIFY {
+			op.New = [2]string{split[4], split[5]}
+		}
+		ops = append(ops, op)
+	}
+	return ops, scan.Err()
+}
+
+func ReadPatchFile(filename string) ([]*PatchOp, error) {
+	file, err := os.Open(filename)
+	defer file.Close()
+	if err != nil {
+		return nil, err
+	}
+	return ReadPatch(file)
+}
+
+type lexLine struct {
+	Token    string
+	Analyses [][2]string
+}
+
+// LexFile is a prefix or lexicon file as lines of analyses, kept in order
+// so that unpatched lines are written back unchanged
+type LexFile struct {
+	Format string
+	lines  []*lexLine
+	tokens map[string][]*lexLine
+}
+
+func ReadLexFile(input io.Reader, format string) (*LexFile, error) {
+	f := &LexFile{Format: format, tokens: make(map[string][]*lexLine, APPROX_LEX_SIZE)}
+	scan := bufio.NewScanner(input)
+	var numLine int
+	for scan.Scan() {
+		numLine++
+		split := strings.Split(scan.Text(), SEPARATOR)
+		if len(split) < 3 || len(split)%2 != 1 {
+			return nil, errors.New(fmt.Sprintf("Line %d: wrong number of fields (%d)", numLine, len(split)))
+		}
+		line := &lexLine{Token: split[0], Analyses: make([][2]string, 0, (len(split)-1)/2)}
+		for i := 1; i < len(split); i += 2 {
+			line.Analyses = append(line.Analyses, [2]string{split[i], split[i+1]})
+		}
+		f.lines = append(f.lines, line)
+		f.tokens[line.Token] = append(f.tokens[line.Token], line)
+	}
+	return f, scan.Err()
+}
+
+func ReadLexFileName(filename, format string) (*LexFile, error) {
+	file, err := os.Open(filename)
+	defer file.Close()
+	if err != nil {
+		return nil, err
+	}
+	return ReadLexFile(file, format)
+}
+
+func (f *LexFile) find(token string, analysis [2]string) (*lexLine, int) {
+	for _, line := range f.tokens[token] {
+		for i, cur := range line.Analyses {
+			if cur == analysis {
+				return line, i
+			}
+		}
+	}
+	return nil, -1
+}
+
+// validate verifies that a line is well formed and accepted by the lexicon
+// reader of the file's format
+func (f *LexFile) validate(line *lexLine) error {
+	if len(line.Analyses) == 0 {
+		return nil
+	}
+	var err error
+	checker := NewChecker()
+	if f.Format == "prefix" {
+		checker.CheckPrefixLine("", 0, line.String())
+	} else {
+		checker.CheckLexiconLine("", 0, line.String())
+	}
+	for _, issue := range checker.Issues {
+		if issue.Kind == ISSUE_MALFORMED {
+			return errors.New(issue.Detail)
+		}
+	}
+	if f.Format == "prefix" {
+		_, err = ProcessAnalyzedPrefix(line.String())
+	} else {
+		_, err = ProcessAnalyzedToken(line.String())
+	}
+	return err
+}
+
+// Apply applies patch operations in order, failing on the first which
+// does not apply (adding an existing analysis, removing or modifying a
+// missing one) or results in a line the lexicon reader rejects
+func (f *LexFile) Apply(ops []*PatchOp) error {
+	for _, op := range ops {
+		line, i := f.find(op.Token, op.Analysis)
+		switch op.Op {
+		case PATCH_ADD:
+			if line != nil {
+				return errors.New(fmt.Sprintf("Patch line %d: %v already exists", op.Line, op))
+			}
+			if lines := f.tokens[op.Token]; len(lines) > 0 {
+				line = lines[len(lines)-1]
+			} else {
+				line = &lexLine{Token: op.Token}
+				f.lines = append(f.lines, line)
+				f.tokens[op.Token] = []*lexLine{line}
+			}
+			line.Analyses = append(line.Analyses, op.Analysis)
+		case PATCH_REMOVE:
+			if line == nil {
+				return errors.New(fmt.Sprintf("Patch line %d: %v not found", op.Line, op))
+			}
+			line.Analyses = append(line.Analyses[:i], line.Analyses[i+1:]...)
+		case PATCH_MODIFY:
+			if line == nil {
+				return errors.New(fmt.Sprintf("Patch line %d: %v not found", op.Line, op))
+			}
+			if other, _ := f.find(op.Token, op.New); other != nil {
+				return errors.New(fmt.Sprintf("Patch line %d: %v, the new analysis already exists", op.Line, op))
+			}
+			line.Analyses[i] = op.New
+		}
+		if err := f.validate(line); err != nil {
+			return errors.New(fmt.Sprintf("Patch line %d: %v: %v", op.Line, op, err))
+		}
+	}
+	return nil
+}
+
+func (l *lexLine) String() string {
+	fields := make([]string, 1, 1+2*len(l.Analyses))
+	fields[0] = l.Token
+	for _, analysis := range l.Analyses {
+		fields = append(fields, analysis[0], analysis[1])
+	}
+	return strings.Join(fields, SEPARATOR)
+}
+
+// Write writes the file in the format of the lexicon readers, lines left
+// with no analyses are dropped
+func (f *LexFile) Write(writer io.Writer) error {
+	for _, line := range f.lines {
+		if len(line.Analyses) == 0 {
+			continue
+		}
+		if _, err := writer.Write([]byte(line.String() + "\n")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *LexFile) WriteFile(filename string) error {
+	file, err := os.Create(filename)
+	defer file.Close()
+	if err != nil {
+		return err
+	}
+	return f.Write(file)
+}
@@ -0,0 +1,82 @@
+package lex
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const (
+	testPatchLexicon = `בית :NN-M-S: בית :NN-M-S-CONST: בית
+ספר :NN-M-S: ספר
+ילד :NN-M-S: ילד
+`
+	testPatch = `# patch
+add	גנן	:NN-M-S:	גנן
+remove	ספר	:NN-M-S:	ספר
+modify	בית	:NN-M-S-CONST:	בית	:VB-M-S-3-PAST-PAAL:	ביית
+add	ילד	:JJ-M-S:	ילד
+`
+	testPatchedLexicon = `בית :NN-M-S: בית :VB-M-S-3-PAST-PAAL: ביית
+ילד :NN-M-S: ילד :JJ-M-S: ילד
+גנן :NN-M-S: גנן
+`
+)
+
+func TestPatch(t *testing.T) {
+	f, err := ReadLexFile(strings.NewReader(testPatchLexicon), "lexicon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops, err := ReadPatch(strings.NewReader(testPatch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 4 {
+		t.Fatalf("Expected 4 operations, got %d", len(ops))
+	}
+	if err := f.Apply(ops); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != testPatchedLexicon {
+		t.Errorf("Unexpected patched lexicon:\n%s", buf.String())
+	}
+	// the patched lexicon round-trips through the lexicon reader
+	analyses, err := Read(&buf, "lexicon", "spmrl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analyses) != 3 {
+		t.Errorf("Expected 3 tokens, got %d", len(analyses))
+	}
+}
+
+func TestPatchErrors(t *testing.T) {
+	for _, patch := range []string{
+		"remove\tספר\t:NN-F-S:\tספר",
+		"add\tבית\t:NN-M-S:\tבית",
+		"modify\tבית\t:NN-M-S:\tבית\t:NN-M-S-CONST:\tבית",
+		"add\tגנן\t:NN-M-S\tגנן",
+	} {
+		f, err := ReadLexFile(strings.NewReader(testPatchLexicon), "lexicon")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ops, err := ReadPatch(strings.NewReader(patch))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Apply(ops); err == nil {
+			t.Errorf("Expected error applying %s", patch)
+		}
+	}
+	for _, patch := range []string{"replace\tבית\t:NN-M-S:\tבית", "add\tבית\t:NN-M-S:"} {
+		if _, err := ReadPatch(strings.NewReader(patch)); err == nil {
+			t.Errorf("Expected error reading patch %s", patch)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package lex
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"yap/util"
+)
+
+const (
+	USER_LEX_SEPARATOR   = "\t"
+	USER_LEX_COMMENT     = "#"
+	USER_LEX_EMPTY       = "_"
+	USER_LEX_ANY_PREFIX  = "*"
+	USER_LEX_NO_PREFIX   = "-"
+	USER_LEX_PREFIX_LIST = ","
+)
+
+// UserEntry is a single analysis of a user lexicon file
+//
+// User lexicon files have a line per analysis, with tab separated fields:
+//
+//	form lemma POS features [prefixes]
+//
+// POS is a tag of the BGU lexicon (e.g. NN, NNP, VB, JJ), converted to UD
+// when the lexicon is read for UD output. Features are attribute=value pairs
+// in the lexicon's tag set separated by | (e.g. gen=F|num=S), or _ for none.
+// Prefixes is * (the default) if the form may follow any prefix, - if it
+// may not be prefixed, or a comma separated list of the prefixes it may
+// follow (e.g. ה,ו,וה). Empty lines and lines starting with # are skipped
+type UserEntry struct {
+	Form, Lemma, POS, Features string
+
+	// nil if any prefix is allowed
+	Prefixes []string
+	Line     int
+}
+
+// AnyPrefix returns whether the entry may follow any prefix
+func (e *UserEntry) AnyPrefix() bool {
+	return e.Prefixes == nil
+}
+
+func (e *UserEntry) String() string {
+	return fmt.Sprintf("%s/%s/%s", e.Lemma, e.POS, e.Features)
+}
+
+// ValidateFeatures verifies that features are attribute=value pairs known
+// to the UD conversion, returning them sorted
+func ValidateFeatures(features string) (string, error) {
+	if len(features) == 0 || features == USER_LEX_EMPTY {
+		return "", nil
+	}
+	pairs := strings.Split(features, FEATURE_PAIR_SEPARATOR)
+	for _, pair := range pairs {
+		split := strings.Split(pair, FEATURE_VALUE_SEPARATOR)
+		if len(split) != 2 || len(split[0]) == 0 || len(split[1]) == 0 {
+			return "", errors.New(fmt.Sprintf("Malformed feature %s", pair))
+		}
+		if split[0] == "binyan" {
+			continue
+		}
+		lookup, exists := util.HEB2UDFeatureNameLookup[split[0]]
+		if !exists {
+			return "", errors.New(fmt.Sprintf("Unknown feature %s", split[0]))
+		}
+		if _, exists := lookup.ValueMap[split[1]]; !exists {
+			return "", errors.New(fmt.Sprintf("Unknown value %s of feature %s", split[1], split[0]))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, FEATURE_PAIR_SEPARATOR), nil
+}
+
+func ProcessUserEntry(line string) (*UserEntry, error) {
+	split := strings.Split(line, USER_LEX_SEPARATOR)
+	if len(split) < 4 || len(split) > 5 {
+		return nil, errors.New("Wrong number of fields (" + line + ")")
+	}
+	for i, field := range split {
+		split[i] = strings.TrimSpace(field)
+	}
+	entry := &UserEntry{Form: split[0], Lemma: split[1], POS: split[2]}
+	if len(entry.Form) == 0 || len(entry.POS) == 0 {
+		return nil, errors.New("Empty form or POS (" + line + ")")
+	}
+	if len(entry.Lemma) == 0 || entry.Lemma == USER_LEX_EMPTY {
+		entry.Lemma = entry.Form
+	}
+	if _, exists := util.HEB2UDPOS[entry.POS]; !exists {
+		return nil, errors.New("Unknown POS " + entry.POS)
+	}
+	features, err := ValidateFeatures(split[3])
+	if err != nil {
+		return nil, err
+	}
+	entry.Features = features
+	if len(split) == 5 {
+		switch split[4] {
+		case "", USER_LEX_EMPTY, USER_LEX_ANY_PREFIX:
+		case USER_LEX_NO_PREFIX:
+			entry.Prefixes = []string{}
+		default:
+			entry.Prefixes = strings.Split(split[4], USER_LEX_PREFIX_LIST)
+		}
+	}
+	return entry, nil
+}
+
+func ReadUser(input io.Reader) ([]*UserEntry, error) {
+	entries := make([]*UserEntry, 0, 100)
+	scan := bufio.NewScanner(input)
+	var numLine int
+	for scan.Scan() {
+		numLine++
+		line := scan.Text()
+		if len(strings.TrimSpace(line)) == 0 || strings.HasPrefix(line, USER_LEX_COMMENT) {
+			continue
+		}
+		entry, err := ProcessUserEntry(line)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Line %d: %v", numLine, err))
+		}
+		entry.Line = numLine
+		entries = append(entries, entry)
+	}
+	return entries, scan.Err()
+}
+
+func ReadUserFile(filename string) ([]*UserEntry, error) {
+	file, err := os.Open(filename)
+	defer file.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadUser(file)
+}
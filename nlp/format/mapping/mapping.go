@@ -1,6 +1,7 @@
 package mapping
 
 import (
+	"yap/alg/search"
 	"yap/nlp/parser/disambig"
 	nlp "yap/nlp/types"
 
@@ -11,6 +12,13 @@ import (
 )
 
 func WriteMorph(writer io.Writer, morph *nlp.EMorpheme, curMorph, curToken int) {
+	writeMorphFields(writer, morph, curMorph, curToken, nlp.TokenRange{})
+	writer.Write([]byte{'\n'})
+}
+
+// writeMorphFields writes the lattice columns of a morpheme, followed by
+// the range of its token if known
+func writeMorphFields(writer io.Writer, morph *nlp.EMorpheme, curMorph, curToken int, tokenRange nlp.TokenRange) {
 	writer.Write([]byte(fmt.Sprintf("%d\t%d\t", curMorph, curMorph+1)))
 	writer.Write([]byte(morph.Form))
 	writer.Write([]byte{'\t'})
@@ -30,63 +38,70 @@ func WriteMorph(writer io.Writer, morph *nlp.EMorpheme, curMorph, curToken int)
 		writer.Write([]byte(morph.FeatureStr))
 	}
 	writer.Write([]byte{'\t'})
-	writer.Write([]byte(fmt.Sprintf("%d\n", curToken+1)))
+	writer.Write([]byte(fmt.Sprintf("%d", curToken+1)))
+	if tokenRange.Known() {
+		writer.Write([]byte("\t" + tokenRange.Attribute()))
+	}
 }
 
-func Write(writer io.Writer, mappedSents []interface{}) {
+func WriteSent(writer io.Writer, mappedSent *disambig.MDConfig) {
 	var curMorph int
-	for _, mappedSent := range mappedSents {
-		curMorph = 0
-		for i, mapping := range mappedSent.(*disambig.MDConfig).Mappings {
-			// log.Println("At token", i, mapping.Token)
-			if mapping.Token == nlp.ROOT_TOKEN {
+	for i, mapping := range mappedSent.Mappings {
+		// log.Println("At token", i, mapping.Token)
+		if mapping.Token == nlp.ROOT_TOKEN {
+			continue
+		}
+		// if mapping.Spellout != nil {
+		// 	log.Println("\t", mapping.Spellout.AsString())
+		// } else {
+		// 	log.Println("\t", "*No spellout")
+		// }
+		hasConfidence := len(mapping.MorphConfidence) == len(mapping.Spellout)
+		for j, morph := range mapping.Spellout {
+			if morph == nil {
+				// log.Println("\t", "Morph is nil, continuing")
 				continue
 			}
-			// if mapping.Spellout != nil {
-			// 	log.Println("\t", mapping.Spellout.AsString())
-			// } else {
-			// 	log.Println("\t", "*No spellout")
-			// }
-			for _, morph := range mapping.Spellout {
-				if morph == nil {
-					// log.Println("\t", "Morph is nil, continuing")
-					continue
-				}
-				WriteMorph(writer, morph, curMorph, i)
-				// log.Println("\t", "At morph", j, morph.Form)
-				curMorph++
+			writeMorphFields(writer, morph, curMorph, i, mapping.Range)
+			if hasConfidence {
+				writer.Write([]byte(fmt.Sprintf("\t%.4f", mapping.MorphConfidence[j])))
 			}
+			writer.Write([]byte{'\n'})
+			// log.Println("\t", "At morph", j, morph.Form)
+			curMorph++
 		}
-		writer.Write([]byte{'\n'})
+	}
+	writer.Write([]byte{'\n'})
+}
+
+// WriteKBest writes the k best results of a sentence as ranked mapping
+// blocks, each preceded by comment lines with the sentence, rank and score
+func WriteKBest(writer io.Writer, result *search.KBestResult, sent int) {
+	for i, conf := range result.Configurations {
+		writer.Write([]byte(fmt.Sprintf("# sent_id = %d\n# rank = %d\n# score = %v\n", sent+1, i+1, result.Scores[i])))
+		WriteSent(writer, conf.(*disambig.MDConfig))
+	}
+}
+
+func write(writer io.Writer, mappedSent interface{}, sent int) {
+	switch result := mappedSent.(type) {
+	case *search.KBestResult:
+		WriteKBest(writer, result, sent)
+	default:
+		WriteSent(writer, mappedSent.(*disambig.MDConfig))
+	}
+}
+
+func Write(writer io.Writer, mappedSents []interface{}) {
+	for i, mappedSent := range mappedSents {
+		write(writer, mappedSent, i)
 	}
 }
 
 func WriteStream(writer *os.File, mappedSents chan interface{}) {
-	var curMorph int
 	var i int
 	for mappedSent := range mappedSents {
-		curMorph = 0
-		for i, mapping := range mappedSent.(*disambig.MDConfig).Mappings {
-			// log.Println("At token", i, mapping.Token)
-			if mapping.Token == nlp.ROOT_TOKEN {
-				continue
-			}
-			// if mapping.Spellout != nil {
-			// 	log.Println("\t", mapping.Spellout.AsString())
-			// } else {
-			// 	log.Println("\t", "*No spellout")
-			// }
-			for _, morph := range mapping.Spellout {
-				if morph == nil {
-					// log.Println("\t", "Morph is nil, continuing")
-					continue
-				}
-				WriteMorph(writer, morph, curMorph, i)
-				// log.Println("\t", "At morph", j, morph.Form)
-				curMorph++
-			}
-		}
-		writer.Write([]byte{'\n'})
+		write(writer, mappedSent, i)
 		i++
 	}
 	writer.Close()
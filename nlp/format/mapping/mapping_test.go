@@ -0,0 +1,76 @@
+package mapping
+
+import (
+	"bytes"
+	"testing"
+
+	"yap/alg/graph"
+	"yap/alg/search"
+	"yap/alg/transition"
+	"yap/nlp/format/lattice"
+	"yap/nlp/parser/disambig"
+	nlp "yap/nlp/types"
+	"yap/util"
+)
+
+func testMorph(id, from, to int, form, CPOS string) *nlp.EMorpheme {
+	return &nlp.EMorpheme{Morpheme: nlp.Morpheme{
+		BasicDirectedEdge: graph.BasicDirectedEdge{id, from, to},
+		Form:              form,
+		Lemma:             form,
+		CPOS:              CPOS,
+		POS:               CPOS,
+		FeatureStr:        "_",
+	}}
+}
+
+func testMDConfig(spellout nlp.Spellout) *disambig.MDConfig {
+	return &disambig.MDConfig{Mappings: nlp.Mappings{{Token: "הבית", Spellout: spellout}}}
+}
+
+func TestWriteKBest(t *testing.T) {
+	result := &search.KBestResult{
+		Configurations: []transition.Configuration{
+			testMDConfig(nlp.Spellout{testMorph(0, 0, 1, "ה", "DEF"), testMorph(1, 1, 2, "בית", "NN")}),
+			testMDConfig(nlp.Spellout{testMorph(2, 0, 2, "הבית", "NNP")}),
+		},
+		Scores: []float64{12.5, 3},
+	}
+	var buf bytes.Buffer
+	WriteKBest(&buf, result, 1)
+	expected := "# sent_id = 2\n# rank = 1\n# score = 12.5\n" +
+		"0\t1\tה\tה\tDEF\tDEF\t_\t1\n" +
+		"1\t2\tבית\tבית\tNN\tNN\t_\t1\n" +
+		"\n" +
+		"# sent_id = 2\n# rank = 2\n# score = 3\n" +
+		"0\t1\tהבית\tהבית\tNNP\tNNP\t_\t1\n" +
+		"\n"
+	if buf.String() != expected {
+		t.Errorf("Got\n%s\nexpected\n%s", buf.String(), expected)
+	}
+}
+
+func TestWriteSentRanges(t *testing.T) {
+	conf := &disambig.MDConfig{Mappings: nlp.Mappings{
+		{Token: "הבית", Spellout: nlp.Spellout{testMorph(0, 0, 1, "ה", "DEF"), testMorph(1, 1, 2, "בית", "NN")}, Range: nlp.TokenRange{Start: 0, End: 4}, MorphConfidence: []float64{1, 0.5}},
+		{Token: "גדול", Spellout: nlp.Spellout{testMorph(2, 2, 3, "גדול", "JJ")}, Range: nlp.TokenRange{Start: 5, End: 9}},
+	}}
+	var buf bytes.Buffer
+	WriteSent(&buf, conf)
+	expected := "0\t1\tה\tה\tDEF\tDEF\t_\t1\tTokenRange=0:4\t1.0000\n" +
+		"1\t2\tבית\tבית\tNN\tNN\t_\t1\tTokenRange=0:4\t0.5000\n" +
+		"2\t3\tגדול\tגדול\tJJ\tJJ\t_\t2\tTokenRange=5:9\n" +
+		"\n"
+	if buf.String() != expected {
+		t.Fatalf("Got\n%s\nexpected\n%s", buf.String(), expected)
+	}
+	lats, err := lattice.Options{}.Read(&buf, 0)
+	if err != nil {
+		t.Fatalf("Failed reading mapping output as a lattice: %v", err)
+	}
+	e := func() *util.EnumSet { return util.NewEnumSet(10, "test") }
+	sent := lattice.Lattice2Sentence(lats[0], e(), e(), e(), e(), e(), e())
+	if len(sent) != 2 || sent[0].Range != conf.Mappings[0].Range || sent[1].Range != conf.Mappings[1].Range {
+		t.Errorf("Got lattices %v, expected the token ranges of the mappings", sent)
+	}
+}
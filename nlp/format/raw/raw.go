@@ -3,31 +3,49 @@ package raw
 // Package raw reads raw format files
 // raw files contain a token per line
 // sentences end with a new line
+// a token may be followed by a tab and its range in the source text, as
+// TokenRange=<start>:<end> (see tokenize -ranges)
 
 import (
 	nlp "yap/nlp/types"
 
 	"bufio"
-	"bytes"
+	"errors"
+	"fmt"
 	"io"
 	// "log"
 	"os"
+	"strings"
 )
 
-func ReadStream(reader io.Reader, limit int) chan nlp.BasicSentence {
-	sentences := make(chan nlp.BasicSentence, 2)
+// parseLine returns the token of a line, and its range if the line has one
+func parseLine(line string) (nlp.Token, nlp.TokenRange, error) {
+	fields := strings.Split(line, "\t")
+	for _, field := range fields[1:] {
+		if tokenRange, isRange, err := nlp.ParseTokenRangeAttribute(field); isRange {
+			return nlp.Token(fields[0]), tokenRange, err
+		}
+	}
+	return nlp.Token(fields[0]), nlp.TokenRange{}, nil
+}
+
+func newRangedSentence() nlp.RangedSentence {
+	return nlp.RangedSentence{BasicSentence: make(nlp.BasicSentence, 0, 10)}
+}
+
+func ReadStreamRanged(reader io.Reader, limit int) chan nlp.RangedSentence {
+	sentences := make(chan nlp.RangedSentence, 2)
 
 	go func() {
 		var (
 			i, numSentences int
 		)
 		bufReader := bufio.NewReader(reader)
-		currentSent := make(nlp.BasicSentence, 0, 10)
+		currentSent := newRangedSentence()
 		for curLine, isPrefix, err := bufReader.ReadLine(); err == nil; curLine, isPrefix, err = bufReader.ReadLine() {
 			if isPrefix {
 				panic("Buffer not large enough, fix me :(")
 			}
-			buf := bytes.NewBuffer(curLine)
 			// log.Println("At record", i)
 			// an empty line indicates a new record
 			if len(curLine) == 0 {
@@ -37,10 +55,15 @@ func ReadStream(reader io.Reader, limit int) chan nlp.BasicSentence {
 					close(sentences)
 					return
 				}
-				currentSent = make(nlp.BasicSentence, 0, 10)
+				currentSent = newRangedSentence()
 				continue
 			} else {
-				currentSent = append(currentSent, nlp.Token(buf.String()))
+				token, tokenRange, err := parseLine(string(curLine))
+				if err != nil {
+					panic(fmt.Sprintf("Error processing record %d at statement %d: %s", i, numSentences, err.Error()))
+				}
+				currentSent.BasicSentence = append(currentSent.BasicSentence, token)
+				currentSent.Ranges = append(currentSent.Ranges, tokenRange)
 			}
 
 			i++
@@ -50,19 +73,32 @@ func ReadStream(reader io.Reader, limit int) chan nlp.BasicSentence {
 	return sentences
 }
 
-func Read(reader io.Reader, limit int) ([]nlp.BasicSentence, error) {
-	var sentences []nlp.BasicSentence
+func ReadStream(reader io.Reader, limit int) chan nlp.BasicSentence {
+	sentences := make(chan nlp.BasicSentence, 2)
+
+	go func() {
+		for sent := range ReadStreamRanged(reader, limit) {
+			sentences <- sent.BasicSentence
+		}
+		close(sentences)
+	}()
+	return sentences
+}
+
+// ReadRanged reads sentences along with the range of each token, the zero
+// range for tokens without one
+func ReadRanged(reader io.Reader, limit int) ([]nlp.RangedSentence, error) {
+	var sentences []nlp.RangedSentence
 	bufReader := bufio.NewReader(reader)
 
 	var (
 		i int
 	)
-	currentSent := make(nlp.BasicSentence, 0, 10)
+	currentSent := newRangedSentence()
 	for curLine, isPrefix, err := bufReader.ReadLine(); err == nil; curLine, isPrefix, err = bufReader.ReadLine() {
 		if isPrefix {
 			panic("Buffer not large enough, fix me :(")
 		}
-		buf := bytes.NewBuffer(curLine)
 		// log.Println("At record", i)
 		// an empty line indicates a new record
 		if len(curLine) == 0 {
@@ -70,9 +106,14 @@ func Read(reader io.Reader, limit int) ([]nlp.BasicSentence, error) {
 			if limit > 0 && len(sentences) >= limit {
 				break
 			}
-			currentSent = make(nlp.BasicSentence, 0, 10)
+			currentSent = newRangedSentence()
 		} else {
-			currentSent = append(currentSent, nlp.Token(buf.String()))
+			token, tokenRange, err := parseLine(string(curLine))
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("Error processing record %d at statement %d: %s", i, len(sentences), err.Error()))
+			}
+			currentSent.BasicSentence = append(currentSent.BasicSentence, token)
+			currentSent.Ranges = append(currentSent.Ranges, tokenRange)
 		}
 
 		i++
@@ -80,6 +121,18 @@ func Read(reader io.Reader, limit int) ([]nlp.BasicSentence, error) {
 	return sentences, nil
 }
 
+func Read(reader io.Reader, limit int) ([]nlp.BasicSentence, error) {
+	ranged, err := ReadRanged(reader, limit)
+	if err != nil {
+		return nil, err
+	}
+	sentences := make([]nlp.BasicSentence, len(ranged))
+	for i, sent := range ranged {
+		sentences[i] = sent.BasicSentence
+	}
+	return sentences, nil
+}
+
 func ReadFile(filename string, limit int) ([]nlp.BasicSentence, error) {
 	file, err := os.Open(filename)
 	defer file.Close()
@@ -90,6 +143,16 @@ func ReadFile(filename string, limit int) ([]nlp.BasicSentence, error) {
 	return Read(file, limit)
 }
 
+func ReadFileRanged(filename string, limit int) ([]nlp.RangedSentence, error) {
+	file, err := os.Open(filename)
+	defer file.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadRanged(file, limit)
+}
+
 func Write(writer io.Writer, sents []interface{}) {
 	for _, sent := range sents {
 		for _, token := range sent.(nlp.BasicSentence) {
@@ -117,3 +180,12 @@ func ReadFileAsStream(filename string, limit int) (chan nlp.BasicSentence, error
 
 	return ReadStream(file, limit), nil
 }
+
+func ReadFileAsStreamRanged(filename string, limit int) (chan nlp.RangedSentence, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadStreamRanged(file, limit), nil
+}
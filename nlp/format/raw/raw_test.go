@@ -0,0 +1,48 @@
+package raw
+
+import (
+	nlp "yap/nlp/types"
+
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadRanged(t *testing.T) {
+	text := "הבית הגדול.\n"
+	var buf bytes.Buffer
+	WriteStreamRanged(&buf, testTokenizer.TokenizeStreamRanged(strings.NewReader(text), 0))
+	expected := "הבית\tTokenRange=0:4\nהגדול\tTokenRange=5:10\n.\tTokenRange=10:11\n\n"
+	if buf.String() != expected {
+		t.Fatalf("Got\n%s\nexpected\n%s", buf.String(), expected)
+	}
+
+	sents, err := ReadRanged(strings.NewReader(buf.String()), 0)
+	if err != nil {
+		t.Fatalf("Failed reading ranged raw file: %v", err)
+	}
+	if len(sents) != 1 || len(sents[0].Ranges) != 3 {
+		t.Fatalf("Got %v, expected a sentence of 3 ranged tokens", sents)
+	}
+	for i, expected := range []nlp.TokenRange{{0, 4}, {5, 10}, {10, 11}} {
+		if sents[0].Ranges[i] != expected {
+			t.Errorf("Token %d: got range %v, expected %v", i, sents[0].Ranges[i], expected)
+		}
+	}
+
+	plain, err := Read(strings.NewReader(buf.String()), 0)
+	if err != nil {
+		t.Fatalf("Failed reading ranged raw file: %v", err)
+	}
+	if len(plain) != 1 || plain[0].Joined(" ") != "הבית הגדול ." {
+		t.Errorf("Got %v, expected the tokens without their ranges", plain)
+	}
+
+	unranged, err := ReadRanged(strings.NewReader("הבית\nהגדול\n\n"), 0)
+	if err != nil || len(unranged) != 1 || unranged[0].Ranges[0].Known() {
+		t.Errorf("Got %v (%v), expected unknown ranges for tokens without one", unranged, err)
+	}
+	if _, err := ReadRanged(strings.NewReader("הבית\tTokenRange=4:0\n\n"), 0); err == nil {
+		t.Error("Expected an error reading an invalid range")
+	}
+}
@@ -0,0 +1,437 @@
+package raw
+
+import (
+	nlp "yap/nlp/types"
+
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	GERESH    = '׳'
+	GERSHAYIM = '״'
+	MAQAF     = '־'
+)
+
+var (
+	// tokens ending a sentence, along with any other token in the punctuation table
+	// consisting only of these characters (e.g. "...")
+	SENTENCE_END = map[string]bool{".": true, "!": true, "?": true, "…": true}
+
+	// punctuation which may close a sentence after its end, such as a closing quote
+	CLOSING_PUNCT = map[string]bool{")": true, "\"": true, "'": true, "”": true, "’": true, "»": true, "]": true}
+)
+
+// Tokenizer splits running Hebrew text into sentences of tokens, as read
+// from raw files
+//
+// Whitespace separated chunks are split further: punctuation is split off
+// the edges of words, tokens in the punctuation table are split within words,
+// and tokens matching one of the number expressions are kept whole. Gershayim
+// within a word (ח"כ) and geresh following a letter (ג'ירפה, ש') are kept as
+// part of the word, as are maqaf and hyphens between two words (ב-2010).
+// Sentences end at a blank line or after a sentence ending punctuation token,
+// and continue across single line breaks
+type Tokenizer struct {
+	Punct   map[string]string
+	Numbers []*regexp.Regexp
+}
+
+func NewTokenizer(punct map[string]string, numbers []*regexp.Regexp) *Tokenizer {
+	return &Tokenizer{Punct: punct, Numbers: numbers}
+}
+
+type chunkToken struct {
+	Token   string
+	Closing bool
+}
+
+func isHebrewLetter(r rune) bool {
+	return r >= 'א' && r <= 'ת'
+}
+
+func isEdgePunct(r rune) bool {
+	switch r {
+	case '@', '#', '_', GERESH, GERSHAYIM:
+		return false
+	}
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+func (t *Tokenizer) isNumber(token string) bool {
+	for _, re := range t.Numbers {
+		if re.MatchString(token) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Tokenizer) isSentenceEnd(token string) bool {
+	if SENTENCE_END[token] {
+		return true
+	}
+	if _, exists := t.Punct[token]; !exists {
+		return false
+	}
+	for _, r := range token {
+		if !SENTENCE_END[string(r)] {
+			return false
+		}
+	}
+	return true
+}
+
+// multiPunct returns the longest multi character punctuation token at the
+// start of s, if any
+func (t *Tokenizer) multiPunct(s string) string {
+	var longest string
+	for punct, _ := range t.Punct {
+		if utf8.RuneCountInString(punct) > 1 && len(punct) > len(longest) && strings.HasPrefix(s, punct) {
+			longest = punct
+		}
+	}
+	return longest
+}
+
+// multiPunctSuffix returns the longest multi character punctuation token at
+// the end of s, if any
+func (t *Tokenizer) multiPunctSuffix(s string) string {
+	var longest string
+	for punct, _ := range t.Punct {
+		if utf8.RuneCountInString(punct) > 1 && len(punct) > len(longest) && strings.HasSuffix(s, punct) {
+			longest = punct
+		}
+	}
+	return longest
+}
+
+func (t *Tokenizer) splitChunk(chunk string) []chunkToken {
+	if t.isNumber(chunk) {
+		return []chunkToken{{Token: chunk}}
+	}
+	var (
+		leading, trailing []chunkToken
+		openedSingleQuote bool
+	)
+	core := chunk
+	// split off leading punctuation
+	for len(core) > 0 {
+		if multi := t.multiPunct(core); len(multi) > 0 {
+			leading = append(leading, chunkToken{Token: multi})
+			core = core[len(multi):]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(core)
+		if !isEdgePunct(r) {
+			break
+		}
+		if r == '\'' {
+			openedSingleQuote = true
+		}
+		leading = append(leading, chunkToken{Token: core[:size]})
+		core = core[size:]
+	}
+	// split off trailing punctuation, from the end backwards
+	for len(core) > 0 {
+		if t.isNumber(core) {
+			break
+		}
+		if multi := t.multiPunctSuffix(core); len(multi) > 0 {
+			trailing = append(trailing, chunkToken{Token: multi, Closing: true})
+			core = core[:len(core)-len(multi)]
+			continue
+		}
+		r, size := utf8.DecodeLastRuneInString(core)
+		if !isEdgePunct(r) {
+			break
+		}
+		prefix := core[:len(core)-size]
+		if r == '\'' && !openedSingleQuote && len(prefix) > 0 {
+			// geresh as apostrophe, e.g. ש' or צ'
+			if prev, _ := utf8.DecodeLastRuneInString(prefix); isHebrewLetter(prev) {
+				break
+			}
+		}
+		trailing = append(trailing, chunkToken{Token: core[len(core)-size:], Closing: true})
+		core = prefix
+	}
+	tokens := leading
+	if len(core) > 0 {
+		for _, token := range t.splitWord(core) {
+			tokens = append(tokens, chunkToken{Token: token})
+		}
+	}
+	for i := len(trailing) - 1; i >= 0; i-- {
+		tokens = append(tokens, trailing[i])
+	}
+	return tokens
+}
+
+// splitWord splits a word with no punctuation at its edges on the single
+// character punctuation tokens it contains, keeping numbers, gershayim,
+// geresh, hyphens and dots (as in abbreviations and urls) within the word
+func (t *Tokenizer) splitWord(word string) []string {
+	if t.isNumber(word) {
+		return []string{word}
+	}
+	runes := []rune(word)
+	tokens := make([]string, 0, 1)
+	start := 0
+	for i, r := range runes {
+		switch r {
+		case '"', '\'', '-', '.', MAQAF:
+			continue
+		}
+		if _, exists := t.Punct[string(r)]; !exists {
+			continue
+		}
+		// keep separators within numbers, e.g. 10:30
+		if i > 0 && i < len(runes)-1 && unicode.IsDigit(runes[i-1]) && unicode.IsDigit(runes[i+1]) {
+			continue
+		}
+		if i > start {
+			tokens = append(tokens, string(runes[start:i]))
+		}
+		tokens = append(tokens, string(r))
+		start = i + 1
+	}
+	if start < len(runes) {
+		tokens = append(tokens, string(runes[start:]))
+	}
+	return tokens
+}
+
+// SplitFields splits text around whitespace as strings.Fields does, along
+// with the range of each field counted from offset, the character offset of
+// the text in its document
+func SplitFields(text string, offset int) nlp.RangedSentence {
+	var (
+		sent      nlp.RangedSentence
+		startByte = -1
+		start     int
+		pos       = offset
+	)
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if startByte >= 0 {
+				sent.BasicSentence = append(sent.BasicSentence, nlp.Token(text[startByte:i]))
+				sent.Ranges = append(sent.Ranges, nlp.TokenRange{Start: start, End: pos})
+				startByte = -1
+			}
+		} else if startByte < 0 {
+			startByte, start = i, pos
+		}
+		pos++
+	}
+	if startByte >= 0 {
+		sent.BasicSentence = append(sent.BasicSentence, nlp.Token(text[startByte:]))
+		sent.Ranges = append(sent.Ranges, nlp.TokenRange{Start: start, End: pos})
+	}
+	return sent
+}
+
+// sentenceSplitter collects the tokens of sentences, which may span lines
+type sentenceSplitter struct {
+	sentences []nlp.RangedSentence
+	current   nlp.RangedSentence
+	ended     bool
+}
+
+// flush ends the current sentence, if it has any tokens
+func (s *sentenceSplitter) flush() {
+	if len(s.current.BasicSentence) > 0 {
+		s.sentences = append(s.sentences, s.current)
+		s.current = nlp.RangedSentence{}
+	}
+	s.ended = false
+}
+
+// take returns the sentences ended so far
+func (s *sentenceSplitter) take() []nlp.RangedSentence {
+	sentences := s.sentences
+	s.sentences = nil
+	return sentences
+}
+
+// addLine adds the tokens of a line counted from offset, the character
+// offset of the line in its text, to the current sentence. A blank line
+// ends the sentence, a line break alone does not
+func (t *Tokenizer) addLine(s *sentenceSplitter, line string, offset int) {
+	chunks := SplitFields(line, offset)
+	if len(chunks.BasicSentence) == 0 {
+		s.flush()
+		return
+	}
+	for i, chunk := range chunks.BasicSentence {
+		start := chunks.Ranges[i].Start
+		for _, token := range t.splitChunk(string(chunk)) {
+			if s.ended && !(token.Closing && (CLOSING_PUNCT[token.Token] || t.isSentenceEnd(token.Token))) {
+				s.flush()
+			}
+			end := start + utf8.RuneCountInString(token.Token)
+			s.current.BasicSentence = append(s.current.BasicSentence, nlp.Token(token.Token))
+			s.current.Ranges = append(s.current.Ranges, nlp.TokenRange{Start: start, End: end})
+			start = end
+			if t.isSentenceEnd(token.Token) {
+				s.ended = true
+			}
+		}
+	}
+}
+
+// TokenizeLineRanged splits a single line of text into sentences, along with
+// the range of each token counted from offset, the character offset of the
+// line in its text
+func (t *Tokenizer) TokenizeLineRanged(line string, offset int) []nlp.RangedSentence {
+	splitter := new(sentenceSplitter)
+	t.addLine(splitter, line, offset)
+	splitter.flush()
+	return splitter.take()
+}
+
+// TokenizeLine splits a single line of text into sentences
+func (t *Tokenizer) TokenizeLine(line string) []nlp.BasicSentence {
+	return basicSentences(t.TokenizeLineRanged(line, 0))
+}
+
+// TokenizeRanged splits running text into sentences, along with the range
+// of each token in the text. Sentences continue across single line breaks
+func (t *Tokenizer) TokenizeRanged(text string) []nlp.RangedSentence {
+	var (
+		splitter = new(sentenceSplitter)
+		offset   int
+	)
+	for _, line := range strings.SplitAfter(text, "\n") {
+		t.addLine(splitter, line, offset)
+		offset += utf8.RuneCountInString(line)
+	}
+	splitter.flush()
+	return splitter.take()
+}
+
+// Tokenize splits running text into sentences
+func (t *Tokenizer) Tokenize(text string) []nlp.BasicSentence {
+	return basicSentences(t.TokenizeRanged(text))
+}
+
+func basicSentences(sents []nlp.RangedSentence) []nlp.BasicSentence {
+	basic := make([]nlp.BasicSentence, len(sents))
+	for i, sent := range sents {
+		basic[i] = sent.BasicSentence
+	}
+	return basic
+}
+
+func (t *Tokenizer) TokenizeStreamRanged(reader io.Reader, limit int) chan nlp.RangedSentence {
+	sentences := make(chan nlp.RangedSentence, 2)
+
+	go func() {
+		var (
+			numSentences, offset int
+			splitter             = new(sentenceSplitter)
+		)
+		bufReader := bufio.NewReader(reader)
+		for {
+			line, err := bufReader.ReadString('\n')
+			t.addLine(splitter, line, offset)
+			offset += utf8.RuneCountInString(line)
+			if err != nil {
+				splitter.flush()
+			}
+			for _, sent := range splitter.take() {
+				sentences <- sent
+				numSentences++
+				if limit > 0 && numSentences >= limit {
+					close(sentences)
+					return
+				}
+			}
+			if err != nil {
+				break
+			}
+		}
+		close(sentences)
+	}()
+	return sentences
+}
+
+func (t *Tokenizer) TokenizeStream(reader io.Reader, limit int) chan nlp.BasicSentence {
+	sentences := make(chan nlp.BasicSentence, 2)
+
+	go func() {
+		for sent := range t.TokenizeStreamRanged(reader, limit) {
+			sentences <- sent.BasicSentence
+		}
+		close(sentences)
+	}()
+	return sentences
+}
+
+func (t *Tokenizer) TokenizeFileAsStream(filename string, limit int) (chan nlp.BasicSentence, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.TokenizeStream(file, limit), nil
+}
+
+func (t *Tokenizer) TokenizeFileAsStreamRanged(filename string, limit int) (chan nlp.RangedSentence, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.TokenizeStreamRanged(file, limit), nil
+}
+
+func WriteStream(writer io.Writer, sents chan nlp.BasicSentence) {
+	for sent := range sents {
+		for _, token := range sent {
+			writer.Write([]byte(token))
+			writer.Write([]byte{'\n'})
+		}
+		writer.Write([]byte{'\n'})
+	}
+}
+
+// WriteStreamRanged writes sentences with the range of each known token
+// as a second column
+func WriteStreamRanged(writer io.Writer, sents chan nlp.RangedSentence) {
+	for sent := range sents {
+		for i, token := range sent.BasicSentence {
+			writer.Write([]byte(token))
+			if i < len(sent.Ranges) && sent.Ranges[i].Known() {
+				writer.Write([]byte("\t" + sent.Ranges[i].Attribute()))
+			}
+			writer.Write([]byte{'\n'})
+		}
+		writer.Write([]byte{'\n'})
+	}
+}
+
+func WriteStreamRangedToFile(filename string, sents chan nlp.RangedSentence) error {
+	file, err := os.Create(filename)
+	defer file.Close()
+	if err != nil {
+		return err
+	}
+	WriteStreamRanged(file, sents)
+	return nil
+}
+
+func WriteStreamToFile(filename string, sents chan nlp.BasicSentence) error {
+	file, err := os.Create(filename)
+	defer file.Close()
+	if err != nil {
+		return err
+	}
+	WriteStream(file, sents)
+	return nil
+}
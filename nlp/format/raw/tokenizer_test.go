@@ -0,0 +1,104 @@
+package raw
+
+import (
+	nlp "yap/nlp/types"
+
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var testTokenizer = NewTokenizer(
+	map[string]string{":": "yyCLN", ",": "yyCM", "-": "yyDASH", ".": "yyDOT", "...": "yyELPS", "!": "yyEXCL", "(": "yyLRB", "?": "yyQM", ")": "yyRRB", ";": "yySCLN", "\"": "yyQUOT"},
+	[]*regexp.Regexp{regexp.MustCompile("^\\d+(\\.\\d+)?$|^\\d{1,3}(,\\d{3})*(\\.\\d+)?$")},
+)
+
+func tokenizeToString(text string) string {
+	return sentencesToString(testTokenizer.Tokenize(text))
+}
+
+func sentencesToString(sents []nlp.BasicSentence) string {
+	strs := make([]string, len(sents))
+	for i, sent := range sents {
+		tokens := make([]string, len(sent))
+		for j, token := range sent {
+			tokens[j] = string(token)
+		}
+		strs[i] = strings.Join(tokens, " ")
+	}
+	return strings.Join(strs, "\n")
+}
+
+func TestTokenize(t *testing.T) {
+	cases := []struct {
+		Text, Expected string
+	}{
+		{`כך אמר ח"כ כהן.`, `כך אמר ח"כ כהן .`},
+		{`הוא נולד ב-2010, בתל-אביב.`, `הוא נולד ב-2010 , בתל-אביב .`},
+		{`המחיר 1,000.5 ש"ח (כולל מע"מ).`, `המחיר 1,000.5 ש"ח ( כולל מע"מ ) .`},
+		{`ג'ירפה ראתה את צ'כיה ואת רח' הרצל`, `ג'ירפה ראתה את צ'כיה ואת רח' הרצל`},
+		{`הוא אמר "שלום". ואז הלך...`, "הוא אמר \" שלום \" .\nואז הלך ..."},
+		{`באמת?! כן.`, "באמת ? !\nכן ."},
+		{`'מילה' בגרש`, `' מילה ' בגרש`},
+		{`ישיבה ב-10:30,מחר`, `ישיבה ב-10:30 , מחר`},
+	}
+	for _, c := range cases {
+		if result := tokenizeToString(c.Text); result != c.Expected {
+			t.Errorf("Tokenizing %s: expected\n%s\ngot\n%s", c.Text, c.Expected, result)
+		}
+	}
+}
+
+func TestTokenizeLines(t *testing.T) {
+	sents := testTokenizer.Tokenize("שורה ראשונה\n\nשורה שנייה")
+	if len(sents) != 2 {
+		t.Errorf("Expected 2 sentences, got %d", len(sents))
+	}
+}
+
+func TestTokenizeParagraphs(t *testing.T) {
+	text := "משפט ראשון\nשנמשך בשורה הבאה. משפט שני\nבלי נקודה\n\nשלישי"
+	expected := "משפט ראשון שנמשך בשורה הבאה .\nמשפט שני בלי נקודה\nשלישי"
+	if result := sentencesToString(testTokenizer.Tokenize(text)); result != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, result)
+	}
+	var streamed []nlp.BasicSentence
+	for sent := range testTokenizer.TokenizeStream(strings.NewReader(text), 0) {
+		streamed = append(streamed, sent)
+	}
+	if result := sentencesToString(streamed); result != expected {
+		t.Errorf("Streaming: expected\n%s\ngot\n%s", expected, result)
+	}
+}
+
+func TestTokenizeRanges(t *testing.T) {
+	text := "כך אמר ח\"כ כהן.\r\n  (שלום)"
+	runes := []rune(text)
+	expected := []string{"0:2", "3:6", "7:10", "11:14", "14:15", "19:20", "20:24", "24:25"}
+	var ranges []string
+	for _, sent := range testTokenizer.TokenizeRanged(text) {
+		if len(sent.Ranges) != len(sent.BasicSentence) {
+			t.Fatalf("Expected a range per token, got %d ranges for %d tokens", len(sent.Ranges), len(sent.BasicSentence))
+		}
+		for i, r := range sent.Ranges {
+			if source := string(runes[r.Start:r.End]); source != string(sent.BasicSentence[i]) {
+				t.Errorf("Range %v of token %s covers %s", r, sent.BasicSentence[i], source)
+			}
+			ranges = append(ranges, r.String())
+		}
+	}
+	if strings.Join(ranges, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected ranges\n%v\ngot\n%v", expected, ranges)
+	}
+}
+
+func TestTokenizeStreamRanges(t *testing.T) {
+	text := "שורה ראשונה.\nשורה שנייה"
+	var last string
+	for sent := range testTokenizer.TokenizeStreamRanged(strings.NewReader(text), 0) {
+		last = sent.Ranges[len(sent.Ranges)-1].String()
+	}
+	if last != "18:23" {
+		t.Errorf("Expected last token range 18:23, got %s", last)
+	}
+}
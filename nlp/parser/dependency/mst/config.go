@@ -0,0 +1,207 @@
+package mst
+
+// Package mst is a first-order graph based dependency parser (McDonald et
+// al. 2005), scoring each arc with the features of its head and modifier
+
+import (
+	. "yap/alg/transition"
+	dep "yap/nlp/parser/dependency/transition"
+	nlp "yap/nlp/types"
+	"yap/util"
+
+	"fmt"
+)
+
+// ROOT is the node ID of the artificial root, the head of the root
+const ROOT = -1
+
+// ArcConfig is a sentence with a candidate arc from Head to Modifier, whose
+// features are extracted as those of a configuration; a decoded ArcConfig
+// is a labeled dependency graph with the decoded arc of each modifier
+type ArcConfig struct {
+	dep.BasicDepGraph
+	Head, Modifier int
+
+	Last             Transition
+	InternalPrevious Configuration
+}
+
+var _ Configuration = &ArcConfig{}
+var _ nlp.LabeledDependencyGraph = &ArcConfig{}
+
+func (c *ArcConfig) Init(abstractSentence interface{}) {
+	sent := abstractSentence.(nlp.EnumTaggedSentence)
+	tokens := sent.EnumTaggedTokens()
+	c.Nodes = make([]nlp.DepNode, len(tokens))
+	for i, enumToken := range tokens {
+		c.Nodes[i] = &dep.TaggedDepNode{
+			Id:       i,
+			Token:    enumToken.EToken,
+			POS:      enumToken.EPOS,
+			TokenPOS: enumToken.ETPOS,
+			MHost:    enumToken.EMHost,
+			MSuffix:  enumToken.EMSuffix,
+			RawToken: enumToken.Token,
+			RawLemma: enumToken.Lemma,
+			RawPOS:   enumToken.POS,
+			Range:    enumToken.Range,
+		}
+	}
+	c.Arcs = nil
+	c.Head, c.Modifier = ROOT, 0
+	c.Last = ConstTransition(0)
+}
+
+// SetArc sets the candidate arc whose features are extracted
+func (c *ArcConfig) SetArc(head, modifier int) {
+	c.Head, c.Modifier = head, modifier
+}
+
+func (c *ArcConfig) Terminal() bool {
+	return len(c.Arcs) == len(c.Nodes)
+}
+
+func (c *ArcConfig) Copy() Configuration {
+	newConf := new(ArcConfig)
+	c.CopyTo(newConf)
+	return newConf
+}
+
+func (c *ArcConfig) CopyTo(target Configuration) {
+	newConf, ok := target.(*ArcConfig)
+	if !ok {
+		panic("Can't copy into non *mst.ArcConfig")
+	}
+	// nodes are read only, no need for copy
+	*newConf = *c
+	newConf.Arcs = make([]*dep.BasicDepArc, len(c.Arcs))
+	copy(newConf.Arcs, c.Arcs)
+	newConf.InternalPrevious = c
+}
+
+func (c *ArcConfig) Clear() {
+	c.InternalPrevious = nil
+}
+
+func (c *ArcConfig) Len() int {
+	if c.InternalPrevious != nil {
+		return 1 + c.InternalPrevious.Len()
+	}
+	return 1
+}
+
+func (c *ArcConfig) Previous() Configuration {
+	return c.InternalPrevious
+}
+
+func (c *ArcConfig) SetPrevious(prev Configuration) {
+	c.InternalPrevious = prev
+}
+
+func (c *ArcConfig) GetSequence() ConfigurationSequence {
+	retval := make(ConfigurationSequence, 0, 2)
+	for cur := Configuration(c); cur != nil; cur = cur.Previous() {
+		retval = append(retval, cur)
+	}
+	return retval
+}
+
+func (c *ArcConfig) SetLastTransition(t Transition) {
+	c.Last = t
+}
+
+func (c *ArcConfig) GetLastTransition() Transition {
+	return c.Last
+}
+
+func (c *ArcConfig) String() string {
+	return fmt.Sprintf("ARC\t=>(%d,%d)\t[%d]", c.Head, c.Modifier, len(c.Arcs))
+}
+
+// Equal compares the decoded arcs of two configurations
+func (c *ArcConfig) Equal(otherEq util.Equaler) bool {
+	other, ok := otherEq.(*ArcConfig)
+	if !ok || other == nil || len(c.Arcs) != len(other.Arcs) {
+		return false
+	}
+	for i, arc := range c.Arcs {
+		if arc.Head != other.Arcs[i].Head || arc.RawRelation != other.Arcs[i].RawRelation {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *ArcConfig) State() byte {
+	return 'M'
+}
+
+func (c *ArcConfig) Assignment() uint16 {
+	return uint16(c.Modifier)
+}
+
+// Address returns the node at an offset from the head (H) or modifier (M)
+// of the candidate arc, H0 of a root arc is the root
+func (c *ArcConfig) Address(location []byte, offset int) (int, bool, bool) {
+	var nodeID int
+	switch location[0] {
+	case 'H':
+		nodeID = c.Head + offset
+		if c.Head == ROOT && offset == 0 {
+			return ROOT, true, false
+		}
+	case 'M':
+		nodeID = c.Modifier + offset
+	default:
+		return 0, false, false
+	}
+	if nodeID < 0 || nodeID >= len(c.Nodes) {
+		return 0, false, false
+	}
+	return nodeID, true, false
+}
+
+func (c *ArcConfig) GenerateAddresses(nodeID int, location []byte) []int {
+	return nil
+}
+
+func (c *ArcConfig) Attribute(source byte, nodeID int, attribute []byte, transitions []int) (interface{}, bool, bool) {
+	if nodeID >= len(c.Nodes) {
+		return nil, false, false
+	}
+	switch attribute[0] {
+	case 'd':
+		return c.Distance(), true, false
+	case 'w':
+		if nodeID == ROOT {
+			return ROOT, true, false
+		}
+		node := c.Nodes[nodeID].(*dep.TaggedDepNode)
+		if len(attribute) > 1 && attribute[1] == 'p' {
+			return node.TokenPOS, true, false
+		}
+		return node.Token, true, false
+	case 'p':
+		if nodeID == ROOT {
+			return ROOT, true, false
+		}
+		return c.Nodes[nodeID].(*dep.TaggedDepNode).POS, true, false
+	}
+	return nil, false, false
+}
+
+// Distance is the signed distance from the head to the modifier,
+// "normalized" as the distance of the transition configurations
+func (c *ArcConfig) Distance() int {
+	dist, sign := c.Modifier-c.Head, 1
+	if dist < 0 {
+		dist, sign = -dist, -1
+	}
+	switch {
+	case dist > 10:
+		return sign * 6
+	case dist > 5:
+		return sign * 5
+	}
+	return sign * dist
+}
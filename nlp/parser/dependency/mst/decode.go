@@ -0,0 +1,269 @@
+package mst
+
+// Decoders of the highest scoring dependency tree of a sentence, given the
+// score of each arc as scores[head][modifier]. Node 0 is the artificial
+// root, the returned heads are of each node with -1 as the head of the root
+
+// Eisner returns the highest scoring projective tree (Eisner 1996)
+func Eisner(scores [][]int64) []int {
+	return eisner(scores, false)
+}
+
+// SingleRootEisner returns the highest scoring projective tree in which the
+// root heads a single node
+func SingleRootEisner(scores [][]int64) []int {
+	return eisner(scores, true)
+}
+
+func eisner(scores [][]int64, singleRoot bool) []int {
+	n := len(scores)
+	heads := make([]int, n)
+	heads[0] = -1
+	if n < 2 {
+		return heads
+	}
+	// complete and incomplete spans [s,t], headed by t (0) or s (1),
+	// with the split point of the best score of each
+	var complete, incomplete [2][][]int64
+	var completeSplit, incompleteSplit [2][][]int
+	for d := 0; d < 2; d++ {
+		complete[d], incomplete[d] = make([][]int64, n), make([][]int64, n)
+		completeSplit[d], incompleteSplit[d] = make([][]int, n), make([][]int, n)
+		for s := 0; s < n; s++ {
+			complete[d][s], incomplete[d][s] = make([]int64, n), make([]int64, n)
+			completeSplit[d][s], incompleteSplit[d][s] = make([]int, n), make([]int, n)
+		}
+	}
+	for k := 1; k < n; k++ {
+		for s := 0; s+k < n; s++ {
+			t := s + k
+			// the root is never a modifier, left spans starting at the root
+			// are never used
+			for r := s; r < t; r++ {
+				// with a single root, the root's arc spans all nodes
+				// before its modifier
+				if singleRoot && s == 0 && r > s {
+					break
+				}
+				val := complete[1][s][r] + complete[0][r+1][t]
+				if r == s || val > incomplete[1][s][t] {
+					incomplete[1][s][t], incompleteSplit[1][s][t] = val, r
+				}
+				if s > 0 && (r == s || val > incomplete[0][s][t]) {
+					incomplete[0][s][t], incompleteSplit[0][s][t] = val, r
+				}
+			}
+			incomplete[1][s][t] += scores[s][t]
+			if s > 0 {
+				incomplete[0][s][t] += scores[t][s]
+				for r := s; r < t; r++ {
+					val := complete[0][s][r] + incomplete[0][r][t]
+					if r == s || val > complete[0][s][t] {
+						complete[0][s][t], completeSplit[0][s][t] = val, r
+					}
+				}
+			}
+			for r := s + 1; r <= t; r++ {
+				val := incomplete[1][s][r] + complete[1][r][t]
+				if r == s+1 || val > complete[1][s][t] {
+					complete[1][s][t], completeSplit[1][s][t] = val, r
+				}
+			}
+		}
+	}
+	var backtrack func(s, t, d int, isComplete bool)
+	backtrack = func(s, t, d int, isComplete bool) {
+		if s == t {
+			return
+		}
+		if isComplete {
+			r := completeSplit[d][s][t]
+			if d == 0 {
+				backtrack(s, r, 0, true)
+				backtrack(r, t, 0, false)
+			} else {
+				backtrack(s, r, 1, false)
+				backtrack(r, t, 1, true)
+			}
+			return
+		}
+		r := incompleteSplit[d][s][t]
+		if d == 0 {
+			heads[s] = t
+		} else {
+			heads[t] = s
+		}
+		backtrack(s, r, 1, true)
+		backtrack(r+1, t, 0, true)
+	}
+	backtrack(0, n-1, 1, true)
+	return heads
+}
+
+// ChuLiuEdmonds returns the highest scoring tree, projective or not
+// (Chu & Liu 1965; Edmonds 1967)
+func ChuLiuEdmonds(scores [][]int64) []int {
+	n := len(scores)
+	heads := make([]int, n)
+	heads[0] = -1
+	for m := 1; m < n; m++ {
+		heads[m] = -1
+		for h := 0; h < n; h++ {
+			if h != m && (heads[m] < 0 || scores[h][m] > scores[heads[m]][m]) {
+				heads[m] = h
+			}
+		}
+	}
+	cycle := findCycle(heads)
+	if cycle == nil {
+		return heads
+	}
+	// contract the cycle to a single node, the last of the new nodes
+	inCycle := make([]bool, n)
+	for _, node := range cycle {
+		inCycle[node] = true
+	}
+	newIDs := make([]int, n)
+	oldIDs := make([]int, 0, n)
+	for node := 0; node < n; node++ {
+		if !inCycle[node] {
+			newIDs[node] = len(oldIDs)
+			oldIDs = append(oldIDs, node)
+		}
+	}
+	contracted := len(oldIDs)
+	for _, node := range cycle {
+		newIDs[node] = contracted
+	}
+	size := contracted + 1
+	// the cycle node entered by an arc from each node, and the cycle node
+	// heading each node
+	enters, exits := make([]int, size), make([]int, size)
+	newScores := make([][]int64, size)
+	for i := range newScores {
+		newScores[i] = make([]int64, size)
+	}
+	for _, h := range oldIDs {
+		for _, m := range oldIDs {
+			if h != m {
+				newScores[newIDs[h]][newIDs[m]] = scores[h][m]
+			}
+		}
+	}
+	for _, node := range oldIDs {
+		first := true
+		for _, cycleNode := range cycle {
+			// entering the cycle breaks the cycle node's arc
+			val := scores[node][cycleNode] - scores[heads[cycleNode]][cycleNode]
+			if first || val > newScores[newIDs[node]][contracted] {
+				newScores[newIDs[node]][contracted], enters[newIDs[node]] = val, cycleNode
+			}
+			if node > 0 && (first || scores[cycleNode][node] > newScores[contracted][newIDs[node]]) {
+				newScores[contracted][newIDs[node]], exits[newIDs[node]] = scores[cycleNode][node], cycleNode
+			}
+			first = false
+		}
+	}
+	newHeads := ChuLiuEdmonds(newScores)
+	for m := 1; m < n; m++ {
+		if inCycle[m] {
+			continue
+		}
+		if newHead := newHeads[newIDs[m]]; newHead == contracted {
+			heads[m] = exits[newIDs[m]]
+		} else {
+			heads[m] = oldIDs[newHead]
+		}
+	}
+	enteringHead := newHeads[contracted]
+	heads[enters[enteringHead]] = oldIDs[enteringHead]
+	return heads
+}
+
+// SingleRootChuLiuEdmonds returns the highest scoring tree in which the
+// root heads a single node. If the best tree has several children of the
+// root, decoding is retried with each node as the only one the root may head
+func SingleRootChuLiuEdmonds(scores [][]int64) []int {
+	heads := ChuLiuEdmonds(scores)
+	if rootChildren(heads) <= 1 {
+		return heads
+	}
+	n := len(scores)
+	// a forbidden root arc scores below any tree without it
+	var penalty int64 = 1
+	for h := range scores {
+		for m := 1; m < n; m++ {
+			if h != m {
+				if scores[h][m] < 0 {
+					penalty -= 2 * scores[h][m]
+				} else {
+					penalty += 2 * scores[h][m]
+				}
+			}
+		}
+	}
+	constrained := make([][]int64, n)
+	copy(constrained, scores)
+	constrained[0] = make([]int64, n)
+	var (
+		best      []int
+		bestScore int64
+	)
+	for child := 1; child < n; child++ {
+		for m := 1; m < n; m++ {
+			if m == child {
+				constrained[0][m] = scores[0][m]
+			} else {
+				constrained[0][m] = -penalty
+			}
+		}
+		candidate := ChuLiuEdmonds(constrained)
+		if score := treeScore(scores, candidate); best == nil || score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+	return best
+}
+
+// rootChildren returns the number of nodes headed by the root
+func rootChildren(heads []int) int {
+	var children int
+	for m := 1; m < len(heads); m++ {
+		if heads[m] == 0 {
+			children++
+		}
+	}
+	return children
+}
+
+// treeScore returns the score of the arcs of heads
+func treeScore(scores [][]int64, heads []int) int64 {
+	var score int64
+	for m := 1; m < len(heads); m++ {
+		score += scores[heads[m]][m]
+	}
+	return score
+}
+
+// findCycle returns the nodes of a cycle of heads, or nil if there is none
+func findCycle(heads []int) []int {
+	visited := make([]int, len(heads))
+	for start := range heads {
+		if visited[start] != 0 {
+			continue
+		}
+		node := start
+		for node >= 0 && visited[node] == 0 {
+			visited[node] = start + 1
+			node = heads[node]
+		}
+		if node >= 0 && visited[node] == start+1 {
+			cycle := []int{node}
+			for cur := heads[node]; cur != node; cur = heads[cur] {
+				cycle = append(cycle, cur)
+			}
+			return cycle
+		}
+	}
+	return nil
+}
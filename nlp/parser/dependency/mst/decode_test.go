@@ -0,0 +1,155 @@
+package mst
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// isTree verifies every node reaches the root
+func isTree(heads []int) bool {
+	for node := range heads {
+		cur := node
+		for steps := 0; cur > 0; steps++ {
+			if steps > len(heads) {
+				return false
+			}
+			cur = heads[cur]
+		}
+	}
+	return true
+}
+
+func isProjective(heads []int) bool {
+	for m := 1; m < len(heads); m++ {
+		h := heads[m]
+		low, high := h, m
+		if m < h {
+			low, high = m, h
+		}
+		for between := low + 1; between < high; between++ {
+			// every node between a head and a modifier descends from the head
+			cur := between
+			for cur > 0 && cur != h {
+				cur = heads[cur]
+			}
+			if cur != h {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// bestTree enumerates all head assignments for the best tree
+func bestTree(scores [][]int64, projective, singleRoot bool) int64 {
+	n := len(scores)
+	heads := make([]int, n)
+	heads[0] = -1
+	var (
+		best  int64
+		found bool
+		enum  func(m int)
+	)
+	enum = func(m int) {
+		if m == n {
+			if isTree(heads) && (!projective || isProjective(heads)) && (!singleRoot || rootChildren(heads) == 1) {
+				if score := treeScore(scores, heads); !found || score > best {
+					best, found = score, true
+				}
+			}
+			return
+		}
+		for h := 0; h < n; h++ {
+			if h != m {
+				heads[m] = h
+				enum(m + 1)
+			}
+		}
+	}
+	enum(1)
+	return best
+}
+
+func randomScores(r *rand.Rand, n int) [][]int64 {
+	scores := make([][]int64, n)
+	for h := range scores {
+		scores[h] = make([]int64, n)
+		for m := range scores[h] {
+			scores[h][m] = int64(r.Intn(40) - 20)
+		}
+	}
+	return scores
+}
+
+func TestEisner(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		scores := randomScores(r, 2+r.Intn(5))
+		heads := Eisner(scores)
+		if !isTree(heads) || !isProjective(heads) {
+			t.Fatalf("Eisner returned a non projective tree %v", heads)
+		}
+		if score, best := treeScore(scores, heads), bestTree(scores, true, false); score != best {
+			t.Fatalf("Eisner tree %v scored %d, expected %d", heads, score, best)
+		}
+	}
+}
+
+func TestChuLiuEdmonds(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		scores := randomScores(r, 2+r.Intn(5))
+		heads := ChuLiuEdmonds(scores)
+		if !isTree(heads) {
+			t.Fatalf("Chu-Liu-Edmonds returned a non tree %v", heads)
+		}
+		if score, best := treeScore(scores, heads), bestTree(scores, false, false); score != best {
+			t.Fatalf("Chu-Liu-Edmonds tree %v scored %d, expected %d", heads, score, best)
+		}
+	}
+}
+
+// rootHeavyScores favors arcs of the root, so that the best unconstrained
+// tree often has several children of the root
+func rootHeavyScores(r *rand.Rand, n int) [][]int64 {
+	scores := randomScores(r, n)
+	for m := 1; m < n; m++ {
+		scores[0][m] += 20
+	}
+	return scores
+}
+
+func TestSingleRootEisner(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		scores := rootHeavyScores(r, 2+r.Intn(5))
+		heads := SingleRootEisner(scores)
+		if !isTree(heads) || !isProjective(heads) || rootChildren(heads) != 1 {
+			t.Fatalf("Single root Eisner returned %v, expected a projective tree with a single child of the root", heads)
+		}
+		if score, best := treeScore(scores, heads), bestTree(scores, true, true); score != best {
+			t.Fatalf("Single root Eisner tree %v scored %d, expected %d", heads, score, best)
+		}
+	}
+}
+
+func TestSingleRootChuLiuEdmonds(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var retried int
+	for i := 0; i < 200; i++ {
+		scores := rootHeavyScores(r, 2+r.Intn(5))
+		if rootChildren(ChuLiuEdmonds(scores)) > 1 {
+			retried++
+		}
+		heads := SingleRootChuLiuEdmonds(scores)
+		if !isTree(heads) || rootChildren(heads) != 1 {
+			t.Fatalf("Single root Chu-Liu-Edmonds returned %v, expected a tree with a single child of the root", heads)
+		}
+		if score, best := treeScore(scores, heads), bestTree(scores, false, true); score != best {
+			t.Fatalf("Single root Chu-Liu-Edmonds tree %v scored %d, expected %d", heads, score, best)
+		}
+	}
+	if retried == 0 {
+		t.Error("Expected some best unconstrained trees with several children of the root")
+	}
+}
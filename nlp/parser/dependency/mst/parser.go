@@ -0,0 +1,180 @@
+package mst
+
+import (
+	"yap/alg/featurevector"
+	"yap/alg/perceptron"
+	"yap/alg/search"
+	. "yap/alg/transition"
+	TransitionModel "yap/alg/transition/model"
+	dep "yap/nlp/parser/dependency/transition"
+	nlp "yap/nlp/types"
+	"yap/util"
+
+	"fmt"
+	"log"
+)
+
+// Parser decodes the highest scoring tree of a sentence, each arc scored
+// with its best relation; the weights of the features of an arc are those
+// of the relation's index in the model. It is trained as a structured
+// perceptron, updating the arcs of the decoded tree which are not gold
+type Parser struct {
+	Model         *TransitionModel.AvgMatrixSparse
+	FeatExtractor perceptron.FeatureExtractor
+	Relations     *util.EnumSet
+	// TransType is the feature group of arcs
+	TransType byte
+	// NonProjective decodes with Chu-Liu-Edmonds instead of Eisner
+	NonProjective bool
+	NoRecover     bool
+}
+
+var _ perceptron.InstanceDecoder = &Parser{}
+var _ perceptron.EarlyUpdateInstanceDecoder = &Parser{}
+
+// decoded is a training instance decoded by the parser, it equals its gold
+// instance when no update is needed
+type decoded struct {
+	instance perceptron.Instance
+	decoded  interface{}
+	update   bool
+}
+
+func (d *decoded) Instance() perceptron.Instance {
+	return d.instance
+}
+
+func (d *decoded) Decoded() interface{} {
+	return d.decoded
+}
+
+func (d *decoded) Equal(otherEq util.Equaler) bool {
+	other, ok := otherEq.(*decoded)
+	return ok && !d.update && !other.update
+}
+
+func (p *Parser) Name() string {
+	if p.NonProjective {
+		return "First-order MST (Chu-Liu-Edmonds)"
+	}
+	return "First-order MST (Eisner)"
+}
+
+// decode returns the highest scoring tree of a sentence with its score,
+// and the features of each arc as features[head+1][modifier+1] if kept
+func (p *Parser) decode(sent interface{}, model *TransitionModel.AvgMatrixSparse, keepFeatures bool) (*ArcConfig, [][][]featurevector.Feature, int64) {
+	conf := new(ArcConfig)
+	conf.Init(sent)
+	numNodes := len(conf.Nodes) + 1
+	scores, labels := make([][]int64, numNodes), make([][]int, numNodes)
+	var features [][][]featurevector.Feature
+	if keepFeatures {
+		features = make([][][]featurevector.Feature, numNodes)
+	}
+	rootRel, rootRelExists := p.Relations.IndexOf(nlp.DepRel(nlp.ROOT_LABEL))
+	store := &featurevector.ArrayStore{}
+	store.Init()
+	store.SetTransitions(util.RangeInt(p.Relations.Len()))
+	for head := ROOT; head < len(conf.Nodes); head++ {
+		scores[head+1], labels[head+1] = make([]int64, numNodes), make([]int, numNodes)
+		if keepFeatures {
+			features[head+1] = make([][]featurevector.Feature, numNodes)
+		}
+		for modifier := range conf.Nodes {
+			if head == modifier {
+				continue
+			}
+			conf.SetArc(head, modifier)
+			feats := p.FeatExtractor.Features(conf, false, p.TransType, nil)
+			store.Clear()
+			model.SetTransitionScores(feats, store, false)
+			best := -1
+			for rel, score := range store.DataArray {
+				// only the root is attached with the root relation
+				if head != ROOT && rootRelExists && rel == rootRel {
+					continue
+				}
+				if best < 0 || score > scores[head+1][modifier+1] {
+					best, scores[head+1][modifier+1] = rel, score
+				}
+			}
+			labels[head+1][modifier+1] = best
+			if keepFeatures {
+				features[head+1][modifier+1] = feats
+			}
+		}
+	}
+	var heads []int
+	if p.NonProjective {
+		heads = SingleRootChuLiuEdmonds(scores)
+	} else {
+		heads = SingleRootEisner(scores)
+	}
+	var score int64
+	conf.Arcs = make([]*dep.BasicDepArc, len(conf.Nodes))
+	for modifier := range conf.Nodes {
+		head := heads[modifier+1]
+		rel := labels[head][modifier+1]
+		conf.Arcs[modifier] = &dep.BasicDepArc{Head: head - 1, Relation: rel, Modifier: modifier, RawRelation: p.Relations.ValueOf(rel).(nlp.DepRel)}
+		score += scores[head][modifier+1]
+	}
+	conf.SetArc(ROOT, 0)
+	return conf, features, score
+}
+
+func (p *Parser) Parse(problem search.Problem) (Configuration, interface{}) {
+	conf, _, score := p.decode(problem, p.Model, false)
+	return conf, score
+}
+
+func (p *Parser) Decode(instance perceptron.Instance, m perceptron.Model) (perceptron.DecodedInstance, interface{}) {
+	conf, _, score := p.decode(instance, m.(*TransitionModel.AvgMatrixSparse), false)
+	return &perceptron.Decoded{InstanceVal: instance, DecodedVal: conf}, score
+}
+
+// DecodeGold returns the gold instance as is, updates are of arcs rather
+// than of a gold sequence
+func (p *Parser) DecodeGold(goldInstance perceptron.DecodedInstance, m perceptron.Model) (perceptron.DecodedInstance, interface{}) {
+	return &decoded{instance: goldInstance.Instance(), decoded: goldInstance.Decoded()}, nil
+}
+
+func (p *Parser) DecodeEarlyUpdate(goldInstance perceptron.DecodedInstance, m perceptron.Model) (decodedInstance perceptron.DecodedInstance, predFeatures interface{}, goldFeatures interface{}, firstError int, size int, score float64) {
+	if !p.NoRecover {
+		defer func() {
+			if r := recover(); r != nil {
+				decodedInstance = nil
+				log.Println("Recovering parse error: ", r)
+			}
+		}()
+	}
+	gold := dep.NewGoldTree(goldInstance.Decoded().(nlp.LabeledDependencyGraph))
+	conf, features, treeScore := p.decode(goldInstance.Instance(), m.(*TransitionModel.AvgMatrixSparse), true)
+	var (
+		predFeats, goldFeats [][]featurevector.Feature
+		predRels, goldRels   []Transition
+	)
+	firstError = -1
+	for modifier, arc := range conf.Arcs {
+		goldHead := gold.Heads[modifier]
+		goldRel, exists := p.Relations.IndexOf(gold.Relations[modifier])
+		if !exists {
+			panic(fmt.Sprintf("Unknown gold relation %v", gold.Relations[modifier]))
+		}
+		if arc.Head == goldHead && arc.Relation == goldRel {
+			continue
+		}
+		if firstError < 0 {
+			firstError = modifier
+		}
+		predFeats = append(predFeats, features[arc.Head+1][modifier+1])
+		predRels = append(predRels, ConstTransition(arc.Relation))
+		goldFeats = append(goldFeats, features[goldHead+1][modifier+1])
+		goldRels = append(goldRels, ConstTransition(goldRel))
+	}
+	decodedInstance = &decoded{goldInstance.Instance(), conf, len(predFeats) > 0}
+	if len(predFeats) > 0 {
+		predFeatures = NewFeaturesList(predFeats, predRels)
+		goldFeatures = NewFeaturesList(goldFeats, goldRels)
+	}
+	return decodedInstance, predFeatures, goldFeatures, firstError, len(conf.Arcs), float64(treeScore)
+}
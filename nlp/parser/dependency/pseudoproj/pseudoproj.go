@@ -0,0 +1,232 @@
+// Package pseudoproj transforms non-projective trees to projective trees
+// and back (Nivre & Nilsson 2005): non-projective arcs are lifted to the
+// head of their head until the tree is projective, encoding the lifts in
+// the labels so that the parser's output can be de-projectivized
+package pseudoproj
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UP marks the label of a lifted arc, DOWN the labels of the arcs on the
+// path it was lifted over
+const (
+	UP   = "^"
+	DOWN = "~"
+)
+
+type Scheme int
+
+const (
+	// HEAD encodes the label of the original head in the lifted arc
+	// (d^h), restored by searching the head's descendants for h
+	HEAD Scheme = iota
+	// PATH marks the lifted arc (d^) and the arcs of the path (p~),
+	// restored by following the marked arcs
+	PATH
+	// HEADPATH encodes both (d^h, p~), restored by searching for h
+	// along the marked arcs
+	HEADPATH
+)
+
+var schemeNames = []string{"head", "path", "headpath"}
+
+func (s Scheme) String() string {
+	return schemeNames[s]
+}
+
+// ParseScheme returns the scheme named head, path or headpath
+func ParseScheme(name string) (Scheme, error) {
+	for i, schemeName := range schemeNames {
+		if name == schemeName {
+			return Scheme(i), nil
+		}
+	}
+	return HEAD, fmt.Errorf("Unknown pseudo-projective scheme %s, expected one of %v", name, schemeNames)
+}
+
+// IsEncoded returns whether a label encodes a lift or a lifting path
+func IsEncoded(label string) bool {
+	return strings.Contains(label, UP) || strings.HasSuffix(label, DOWN)
+}
+
+// The trees below are of nodes 1..n with node 0 as the root, heads[0] is
+// ignored
+
+func isDescendant(heads []int, node, ancestor int) bool {
+	for steps := 0; node > 0 && steps < len(heads); steps++ {
+		if node == ancestor {
+			return true
+		}
+		node = heads[node]
+	}
+	return node == ancestor
+}
+
+func isProjectiveArc(heads []int, modifier int) bool {
+	head := heads[modifier]
+	low, high := head, modifier
+	if modifier < head {
+		low, high = modifier, head
+	}
+	for between := low + 1; between < high; between++ {
+		if !isDescendant(heads, between, head) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsProjective returns whether all arcs of a tree are projective
+func IsProjective(heads []int) bool {
+	for modifier := 1; modifier < len(heads); modifier++ {
+		if !isProjectiveArc(heads, modifier) {
+			return false
+		}
+	}
+	return true
+}
+
+// smallestNonProjective returns the modifier of the shortest non-projective
+// arc (the leftmost of equal length), or 0 if the tree is projective
+func smallestNonProjective(heads []int) int {
+	var smallest, smallestLen int
+	for modifier := 1; modifier < len(heads); modifier++ {
+		if isProjectiveArc(heads, modifier) {
+			continue
+		}
+		length := heads[modifier] - modifier
+		if length < 0 {
+			length = -length
+		}
+		if smallest == 0 || length < smallestLen {
+			smallest, smallestLen = modifier, length
+		}
+	}
+	return smallest
+}
+
+// Projectivize lifts the non-projective arcs of a tree in place, shortest
+// first, and encodes the lifts in the labels; returns the number of lifts
+func Projectivize(heads []int, labels []string, scheme Scheme) int {
+	var (
+		lifts     int
+		lifted    = make([]bool, len(heads))
+		onPath    = make([]bool, len(heads))
+		headLabel = make([]string, len(heads))
+	)
+	for modifier := smallestNonProjective(heads); modifier > 0; modifier = smallestNonProjective(heads) {
+		head := heads[modifier]
+		if !lifted[modifier] {
+			lifted[modifier], headLabel[modifier] = true, labels[head]
+		}
+		onPath[head] = true
+		heads[modifier] = heads[head]
+		lifts++
+	}
+	for node := 1; node < len(heads); node++ {
+		if lifted[node] {
+			labels[node] += UP
+			if scheme != PATH {
+				labels[node] += headLabel[node]
+			}
+		}
+		if onPath[node] && scheme != HEAD {
+			labels[node] += DOWN
+		}
+	}
+	return lifts
+}
+
+// children returns the dependents of a node ordered by their distance
+// from target, excluding the subtree of target
+func children(heads []int, node, target int) []int {
+	retval := make([]int, 0, 2)
+	for distance := 1; distance < len(heads); distance++ {
+		for _, child := range []int{target - distance, target + distance} {
+			if child > 0 && child < len(heads) && child != target && heads[child] == node {
+				retval = append(retval, child)
+			}
+		}
+	}
+	return retval
+}
+
+// search returns the first descendant of node in breadth first order
+// (closest to target first) satisfying found, descending only through
+// nodes satisfying follow
+func search(heads []int, node, target int, follow, found func(int) bool) int {
+	queue := children(heads, node, target)
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if !follow(cur) {
+			continue
+		}
+		if found(cur) {
+			return cur
+		}
+		queue = append(queue, children(heads, cur, target)...)
+	}
+	return -1
+}
+
+// Deprojectivize restores the lifted arcs of a parsed tree in place, top
+// down, and removes the encoding from the labels; lifted arcs whose head
+// is not found are left in place
+func Deprojectivize(heads []int, labels []string, scheme Scheme) {
+	var (
+		base      = make([]string, len(heads))
+		lifted    = make([]bool, len(heads))
+		onPath    = make([]bool, len(heads))
+		headLabel = make([]string, len(heads))
+	)
+	for node := 1; node < len(heads); node++ {
+		label := labels[node]
+		if strings.HasSuffix(label, DOWN) {
+			label, onPath[node] = strings.TrimSuffix(label, DOWN), true
+		}
+		if i := strings.Index(label, UP); i >= 0 {
+			label, headLabel[node], lifted[node] = label[:i], label[i+len(UP):], true
+		}
+		base[node] = label
+	}
+	all := func(int) bool { return true }
+	marked := func(node int) bool { return onPath[node] }
+	// breadth first from the root, so that heads are restored before
+	// their dependents
+	visited := make([]bool, len(heads))
+	queue := children(heads, 0, 0)
+	for len(queue) > 0 {
+		modifier := queue[0]
+		queue = queue[1:]
+		if visited[modifier] {
+			continue
+		}
+		visited[modifier] = true
+		if lifted[modifier] {
+			newHead := -1
+			switch scheme {
+			case HEAD:
+				newHead = search(heads, heads[modifier], modifier, all, func(node int) bool {
+					return base[node] == headLabel[modifier]
+				})
+			case HEADPATH:
+				newHead = search(heads, heads[modifier], modifier, marked, func(node int) bool {
+					return base[node] == headLabel[modifier]
+				})
+			case PATH:
+				// the deepest node along the marked arcs
+				for node := search(heads, heads[modifier], modifier, marked, marked); node > 0; node = search(heads, node, modifier, marked, marked) {
+					newHead = node
+				}
+			}
+			if newHead > 0 {
+				heads[modifier] = newHead
+			}
+		}
+		queue = append(queue, children(heads, modifier, modifier)...)
+	}
+	copy(labels[1:], base[1:])
+}
@@ -0,0 +1,60 @@
+package pseudoproj
+
+import (
+	"reflect"
+	"testing"
+)
+
+func roundTrip(t *testing.T, heads []int, labels []string, scheme Scheme, expectedLifts int) {
+	projHeads, projLabels := append([]int{}, heads...), append([]string{}, labels...)
+	if lifts := Projectivize(projHeads, projLabels, scheme); lifts != expectedLifts {
+		t.Errorf("%v: expected %d lifts, got %d", scheme, expectedLifts, lifts)
+	}
+	if !IsProjective(projHeads) {
+		t.Errorf("%v: projectivized tree %v is not projective", scheme, projHeads)
+	}
+	Deprojectivize(projHeads, projLabels, scheme)
+	if !reflect.DeepEqual(projHeads, heads) || !reflect.DeepEqual(projLabels, labels) {
+		t.Errorf("%v: expected %v %v, got %v %v", scheme, heads, labels, projHeads, projLabels)
+	}
+}
+
+func TestSingleLift(t *testing.T) {
+	heads := []int{-1, 2, 0, 1, 2}
+	labels := []string{"", "a", "root", "b", "c"}
+	for _, scheme := range []Scheme{HEAD, PATH, HEADPATH} {
+		roundTrip(t, heads, labels, scheme, 1)
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	heads := []int{-1, 0, 1, 1, 2, 3}
+	labels := []string{"", "root", "a", "b", "c", "d"}
+	expected := map[Scheme][]string{
+		HEAD:     {"", "root", "a", "b", "c^a", "d^b"},
+		PATH:     {"", "root", "a~", "b~", "c^", "d^"},
+		HEADPATH: {"", "root", "a~", "b~", "c^a", "d^b"},
+	}
+	for scheme, expectedLabels := range expected {
+		projHeads, projLabels := append([]int{}, heads...), append([]string{}, labels...)
+		Projectivize(projHeads, projLabels, scheme)
+		if !reflect.DeepEqual(projHeads, []int{-1, 0, 1, 1, 1, 1}) || !reflect.DeepEqual(projLabels, expectedLabels) {
+			t.Errorf("%v: got %v %v", scheme, projHeads, projLabels)
+		}
+	}
+	// path alone is ambiguous for two lifts from the same head
+	for _, scheme := range []Scheme{HEAD, HEADPATH} {
+		roundTrip(t, heads, labels, scheme, 2)
+	}
+}
+
+func TestProjective(t *testing.T) {
+	heads := []int{-1, 2, 0, 2}
+	labels := []string{"", "a", "root", "b"}
+	if !IsProjective(heads) {
+		t.Errorf("expected %v to be projective", heads)
+	}
+	for _, scheme := range []Scheme{HEAD, PATH, HEADPATH} {
+		roundTrip(t, heads, labels, scheme, 0)
+	}
+}
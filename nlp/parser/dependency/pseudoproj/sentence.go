@@ -0,0 +1,94 @@
+package pseudoproj
+
+import (
+	"yap/nlp/format/conll"
+	"yap/nlp/format/conllu"
+
+	"fmt"
+)
+
+// tree returns the heads and labels of the rows of a sentence, numbered
+// 1..n, and sets them back
+type tree interface {
+	Tree() ([]int, []string)
+	SetTree([]int, []string)
+}
+
+type conllTree conll.Sentence
+
+func (t conllTree) Tree() ([]int, []string) {
+	heads, labels := make([]int, len(t)+1), make([]string, len(t)+1)
+	heads[0] = -1
+	for id, row := range t {
+		heads[id], labels[id] = row.Head, row.DepRel
+	}
+	return heads, labels
+}
+
+func (t conllTree) SetTree(heads []int, labels []string) {
+	for id, row := range t {
+		row.Head, row.DepRel = heads[id], labels[id]
+		t[id] = row
+	}
+}
+
+type conllUTree map[int]conllu.Row
+
+func (t conllUTree) Tree() ([]int, []string) {
+	heads, labels := make([]int, len(t)+1), make([]string, len(t)+1)
+	heads[0] = -1
+	for id, row := range t {
+		heads[id], labels[id] = row.Head, row.DepRel
+	}
+	return heads, labels
+}
+
+func (t conllUTree) SetTree(heads []int, labels []string) {
+	for id, row := range t {
+		row.Head, row.DepRel = heads[id], labels[id]
+		t[id] = row
+	}
+}
+
+func asTree(sent interface{}) tree {
+	switch s := sent.(type) {
+	case conll.Sentence:
+		return conllTree(s)
+	case conllu.Sentence:
+		return conllUTree(s.Deps)
+	case *conllu.Sentence:
+		return conllUTree(s.Deps)
+	}
+	panic(fmt.Sprintf("Can't transform sentence of type %T", sent))
+}
+
+// ProjectivizeSentence projectivizes a CoNLL or CoNLL-U sentence in place,
+// returns the number of lifts
+func ProjectivizeSentence(sent interface{}, scheme Scheme) int {
+	t := asTree(sent)
+	heads, labels := t.Tree()
+	lifts := Projectivize(heads, labels, scheme)
+	t.SetTree(heads, labels)
+	return lifts
+}
+
+// DeprojectivizeSentence deprojectivizes a parsed CoNLL or CoNLL-U
+// sentence in place
+func DeprojectivizeSentence(sent interface{}, scheme Scheme) {
+	t := asTree(sent)
+	heads, labels := t.Tree()
+	Deprojectivize(heads, labels, scheme)
+	t.SetTree(heads, labels)
+}
+
+// EncodedLabels returns the encoded labels of a projectivized sentence
+func EncodedLabels(sent interface{}) []string {
+	_, labels := asTree(sent).Tree()
+	retval := make([]string, 0, 1)
+	for _, label := range labels {
+		if IsEncoded(label) {
+			retval = append(retval, label)
+		}
+	}
+	return retval
+}
@@ -192,6 +192,9 @@ func (a *ArcEager) GetTransitions(from Configuration) (byte, []int) {
 	for transition := range transitions {
 		retval = append(retval, transition)
 	}
+	if conf := from.(*SimpleConfiguration); conf.Constraints != nil {
+		retval = a.constrainedTransitions(conf, retval)
+	}
 	return tType, retval
 }
 
@@ -205,6 +208,18 @@ func (a *ArcEager) AddDefaultOracle() {
 	a.oracle = Oracle(&ZparArcEagerOracle{Transitions: a.Transitions, LA: int(a.LEFT), RA: int(a.RIGHT)})
 }
 
+// AddDynamicOracle sets a dynamic oracle, defaulting to the zpar oracle for
+// gold sequences
+func (a *ArcEager) AddDynamicOracle() {
+	a.oracle = Oracle(&ArcEagerDynamicOracle{
+		ZparArcEagerOracle: ZparArcEagerOracle{Transitions: a.Transitions, LA: a.LEFT, RA: a.RIGHT},
+		Relations:          a.Relations,
+		SHIFT:              a.SHIFT,
+		REDUCE:             a.REDUCE,
+		POPROOT:            a.POPROOT,
+	})
+}
+
 // type NivreArcEagerOracle struct {
 // 	ArcStandardOracle
 // 	Transitions *util.EnumSet
@@ -32,26 +32,26 @@ var (
 )
 
 func SetupEagerTransEnum() {
-	TRANSITIONS_ENUM = util.NewEnumSet(len(TEST_RELATIONS)*2 + 2)
+	TRANSITIONS_ENUM = util.NewEnumSet(len(TEST_RELATIONS)*2+2, "Transitions")
 	_, _ = TRANSITIONS_ENUM.Add("NO")
 	iSH, _ := TRANSITIONS_ENUM.Add("SH")
 	iRE, _ := TRANSITIONS_ENUM.Add("RE")
 	iPR, _ := TRANSITIONS_ENUM.Add("PR")
-	SH = Transition(iSH)
-	RE = Transition(iRE)
-	PR = Transition(iPR)
-	LA = PR + 1
+	SH = ConstTransition(iSH)
+	RE = ConstTransition(iRE)
+	PR = ConstTransition(iPR)
+	LA = ConstTransition(iPR + 1)
 	for _, transition := range TEST_RELATIONS {
 		TRANSITIONS_ENUM.Add(string("LA-" + transition))
 	}
-	RA = Transition(TRANSITIONS_ENUM.Len())
+	RA = ConstTransition(TRANSITIONS_ENUM.Len())
 	for _, transition := range TEST_RELATIONS {
 		TRANSITIONS_ENUM.Add(string("RA-" + transition))
 	}
 	TEST_EAGER_ENUM_TRANSITIONS = make([]Transition, len(TEST_EAGER_TRANSITIONS))
 	for i, transition := range TEST_EAGER_TRANSITIONS {
 		index, _ := TRANSITIONS_ENUM.IndexOf(string(transition))
-		TEST_EAGER_ENUM_TRANSITIONS[i] = Transition(index)
+		TEST_EAGER_ENUM_TRANSITIONS[i] = ConstTransition(index)
 	}
 }
 
@@ -0,0 +1,149 @@
+package transition
+
+import (
+	"fmt"
+	. "yap/alg/transition"
+	. "yap/nlp/types"
+)
+
+// ArcHybrid is the arc hybrid transition system (Kuhlmann et al. 2011),
+// with the shift and left arc of arc standard and a right arc between the
+// two top elements of the stack
+type ArcHybrid struct {
+	ArcStandard
+}
+
+// Verify that ArcHybrid is a TransitionSystem
+var _ TransitionSystem = &ArcHybrid{}
+
+func (a *ArcHybrid) Transition(from Configuration, rawTransition Transition) Configuration {
+	transition := rawTransition.Value()
+	// Transition System:
+	// LA-r	(S|wi,		wj|B,	A) => (S   ,	wj|B,	A+{(wj,r,wi)})
+	// RA-r	(S|wi|wj,	   B,	A) => (S|wi,	   B,	A+{(wi,r,wj)})
+	// SH	(S   ,		wi|B, 	A) => (S|wi,	   B,	A)
+	if transition < a.RIGHT {
+		return a.ArcStandard.Transition(from, rawTransition)
+	}
+	conf, ok := from.Copy().(*SimpleConfiguration)
+	if !ok {
+		panic("Got wrong configuration type")
+	}
+	wj, wjExists := conf.Stack().Pop()
+	wi, wiExists := conf.Stack().Peek()
+	if !(wiExists && wjExists) {
+		panic(fmt.Sprintf("Can't RA, Stack has less than two elements: %v", conf))
+	}
+	rel := int(transition - a.RIGHT)
+	relValue := a.Relations.ValueOf(rel).(DepRel)
+	newArc := &BasicDepArc{wi, rel, wj, relValue}
+	conf.AddArc(newArc)
+	conf.Assign(uint16(conf.Nodes[newArc.Modifier].ID()))
+	// the dependent is popped off the stack
+	conf.NumHeadStack--
+	conf.SetLastTransition(rawTransition)
+	return conf
+}
+
+func (a *ArcHybrid) possibleTransitions(from Configuration, transitions chan int) {
+	conf, ok := from.(*SimpleConfiguration)
+	if !ok {
+		panic("Got wrong configuration type")
+	}
+	_, qExists := conf.Queue().Peek()
+	_, sExists := conf.Stack().Peek()
+	if qExists {
+		transitions <- a.SHIFT
+	}
+	if sExists && qExists {
+		for rel, _ := range a.Relations.Index {
+			transitions <- a.LEFT + rel
+		}
+	}
+	if conf.Stack().Size() > 1 {
+		for rel, _ := range a.Relations.Index {
+			transitions <- a.RIGHT + rel
+		}
+	}
+	close(transitions)
+}
+
+func (a *ArcHybrid) GetTransitions(from Configuration) (byte, []int) {
+	retval := make([]int, 0, 10)
+	tType, transitions := a.YieldTransitions(from)
+	for transition := range transitions {
+		retval = append(retval, transition)
+	}
+	return tType, retval
+}
+
+func (a *ArcHybrid) YieldTransitions(from Configuration) (byte, chan int) {
+	transitions := make(chan int)
+	go a.possibleTransitions(from, transitions)
+	return TransitionType, transitions
+}
+
+func (a *ArcHybrid) AddDefaultOracle() {
+	a.oracle = Oracle(&ArcHybridOracle{
+		ArcStandardOracle: ArcStandardOracle{Transitions: a.Transitions, LA: a.LEFT, RA: a.RIGHT},
+	})
+}
+
+// AddDynamicOracle sets a dynamic oracle, defaulting to the static oracle
+// for gold sequences
+func (a *ArcHybrid) AddDynamicOracle() {
+	a.oracle = Oracle(&ArcHybridDynamicOracle{
+		ArcHybridOracle: ArcHybridOracle{
+			ArcStandardOracle: ArcStandardOracle{Transitions: a.Transitions, LA: a.LEFT, RA: a.RIGHT},
+		},
+		Relations: a.Relations,
+		SHIFT:     a.SHIFT,
+	})
+}
+
+func (a *ArcHybrid) Name() string {
+	return "Arc Hybrid"
+}
+
+// ArcHybridOracle is the static oracle of arc hybrid
+type ArcHybridOracle struct {
+	ArcStandardOracle
+}
+
+var _ Decision = &ArcHybridOracle{}
+
+func (o *ArcHybridOracle) Transition(conf Configuration) Transition {
+	c := conf.(*SimpleConfiguration)
+
+	if o.gold == nil {
+		panic("Oracle needs gold reference, use SetGold")
+	}
+	// o(c = (S|wk|wi,wj|B,A)) =
+	// LA-r	if	(wj,r,wi) in Ad
+	// RA-r	if	(wk,r,wi) in Ad; and for all w,r', if (wi,r',w) in Ad then (wi,r',w) in A
+	// SH	otherwise
+	bTop, bExists := c.Queue().Peek()
+	sTop, sExists := c.Stack().Peek()
+	var index int
+	if !sExists && !bExists {
+		panic(fmt.Sprintf("Got empty configuration %v", c))
+	}
+	if sExists && bExists {
+		if arcs := o.arcSet.Get(&BasicDepArc{bTop, -1, sTop, DepRel("")}); len(arcs) > 0 {
+			index, _ = o.Transitions.IndexOf("LA-" + string(arcs[0].GetRelation()))
+			return &TypedTransition{TransitionType, index}
+		}
+	}
+	if sSecond, exists := c.Stack().Index(1); exists {
+		if arcs := o.arcSet.Get(&BasicDepArc{sSecond, -1, sTop, DepRel("")}); len(arcs) > 0 && o.complete(c, sTop) {
+			index, _ = o.Transitions.IndexOf("RA-" + string(arcs[0].GetRelation()))
+			return &TypedTransition{TransitionType, index}
+		}
+	}
+	index, _ = o.Transitions.IndexOf("SH")
+	return &TypedTransition{TransitionType, index}
+}
+
+func (o *ArcHybridOracle) Name() string {
+	return "Arc Hybrid Static Oracle"
+}
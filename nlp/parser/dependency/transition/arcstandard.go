@@ -123,6 +123,9 @@ func (a *ArcStandard) GetTransitions(from Configuration) (byte, []int) {
 	for transition := range transitions {
 		retval = append(retval, int(transition))
 	}
+	if conf := from.(*SimpleConfiguration); conf.Constraints != nil {
+		retval = a.constrainedTransitions(conf, retval)
+	}
 	return tType, retval
 }
 
@@ -227,6 +230,16 @@ func (o *ArcStandardOracle) Transition(conf Configuration) Transition {
 
 }
 
+// complete returns whether all gold dependents of a node are attached
+func (o *ArcStandardOracle) complete(c *SimpleConfiguration, node int) bool {
+	for _, arc := range o.arcSet.Get(&BasicDepArc{node, -1, -1, DepRel("")}) {
+		if !c.Arcs().HasHead(arc.GetModifier()) {
+			return false
+		}
+	}
+	return true
+}
+
 func (o *ArcStandardOracle) Name() string {
 	return "Arc Standard"
 }
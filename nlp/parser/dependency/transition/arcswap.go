@@ -0,0 +1,203 @@
+package transition
+
+import (
+	"fmt"
+	. "yap/alg/transition"
+	. "yap/nlp/types"
+
+	"sort"
+)
+
+// ArcSwap is arc standard with an additional swap transition (Nivre 2009),
+// reordering the input so that non-projective trees can be built
+type ArcSwap struct {
+	ArcStandard
+	SWAP int
+}
+
+// Verify that ArcSwap is a TransitionSystem
+var _ TransitionSystem = &ArcSwap{}
+
+func (a *ArcSwap) Transition(from Configuration, rawTransition Transition) Configuration {
+	if rawTransition.Value() != a.SWAP {
+		return a.ArcStandard.Transition(from, rawTransition)
+	}
+	conf, ok := from.Copy().(*SimpleConfiguration)
+	if !ok {
+		panic("Got wrong configuration type")
+	}
+	// Transition System (in addition to arc standard):
+	// SW	(S|wi,	wj|B,	A) => (S   ,	wj|wi|B,	A)	if: i < j
+	wi, wiExists := conf.Stack().Pop()
+	wj, wjExists := conf.Queue().Pop()
+	if !(wiExists && wjExists) {
+		panic(fmt.Sprintf("Can't swap, Stack and/or Queue are/is empty: %v", conf))
+	}
+	if wi > wj {
+		panic(fmt.Sprintf("Can't swap %d back after %d", wi, wj))
+	}
+	conf.Queue().Push(wi)
+	conf.Queue().Push(wj)
+	conf.Assign(uint16(conf.Nodes[wi].ID()))
+	// the swapped element is headless, and is back on the buffer
+	conf.NumHeadStack--
+	conf.SetLastTransition(rawTransition)
+	return conf
+}
+
+func (a *ArcSwap) possibleTransitions(from Configuration, transitions chan int) {
+	conf, ok := from.(*SimpleConfiguration)
+	if !ok {
+		panic("Got wrong configuration type")
+	}
+	standardTransitions := make(chan int)
+	go a.ArcStandard.possibleTransitions(from, standardTransitions)
+	for transition := range standardTransitions {
+		transitions <- transition
+	}
+	qPeek, qExists := conf.Queue().Peek()
+	sPeek, sExists := conf.Stack().Peek()
+	if qExists && sExists && sPeek < qPeek {
+		transitions <- a.SWAP
+	}
+	close(transitions)
+}
+
+func (a *ArcSwap) GetTransitions(from Configuration) (byte, []int) {
+	retval := make([]int, 0, 10)
+	tType, transitions := a.YieldTransitions(from)
+	for transition := range transitions {
+		retval = append(retval, transition)
+	}
+	return tType, retval
+}
+
+func (a *ArcSwap) YieldTransitions(from Configuration) (byte, chan int) {
+	transitions := make(chan int)
+	go a.possibleTransitions(from, transitions)
+	return TransitionType, transitions
+}
+
+func (a *ArcSwap) TransitionTypes() []string {
+	return append(a.ArcStandard.TransitionTypes(), "SW")
+}
+
+func (a *ArcSwap) Projective() bool {
+	return false
+}
+
+func (a *ArcSwap) AddDefaultOracle() {
+	a.oracle = Oracle(&ArcSwapOracle{
+		ArcStandardOracle: ArcStandardOracle{Transitions: a.Transitions, LA: a.LEFT, RA: a.RIGHT},
+	})
+}
+
+func (a *ArcSwap) Name() string {
+	return "Arc Standard with Swap (Nivre 2009)"
+}
+
+// ProjectiveOrder returns the position of each node of a graph in the
+// inorder traversal of its tree, an order in which the tree is projective
+func ProjectiveOrder(graph LabeledDependencyGraph) []int {
+	numNodes := graph.NumberOfNodes()
+	children := make([][]int, numNodes+1)
+	for _, edgeNum := range graph.GetEdges() {
+		arc := graph.GetLabeledArc(edgeNum)
+		head := arc.GetHead()
+		if head < 0 || head >= numNodes {
+			// children of the artificial root
+			head = numNodes
+		}
+		if modifier := arc.GetModifier(); modifier >= 0 && modifier < numNodes {
+			children[head] = append(children[head], modifier)
+		}
+	}
+	order := make([]int, numNodes)
+	for i := range order {
+		order[i] = -1
+	}
+	var (
+		position int
+		visit    func(node int)
+	)
+	visit = func(node int) {
+		sort.Ints(children[node])
+		for _, child := range children[node] {
+			if child < node {
+				visit(child)
+			}
+		}
+		if node < numNodes {
+			order[node] = position
+			position++
+		}
+		for _, child := range children[node] {
+			if child > node {
+				visit(child)
+			}
+		}
+	}
+	visit(numNodes)
+	// nodes unreachable from the root keep their relative order at the end
+	for i, pos := range order {
+		if pos < 0 {
+			order[i] = position
+			position++
+		}
+	}
+	return order
+}
+
+// ArcSwapOracle is the static oracle of arc standard with swap, swapping
+// whenever the top of the buffer precedes the top of the stack in the
+// projective order of the gold tree
+type ArcSwapOracle struct {
+	ArcStandardOracle
+	order []int
+}
+
+var _ Decision = &ArcSwapOracle{}
+
+func (o *ArcSwapOracle) SetGold(g interface{}) {
+	o.ArcStandardOracle.SetGold(g)
+	o.order = ProjectiveOrder(o.gold)
+}
+
+func (o *ArcSwapOracle) Transition(conf Configuration) Transition {
+	c := conf.(*SimpleConfiguration)
+
+	if o.gold == nil {
+		panic("Oracle needs gold reference, use SetGold")
+	}
+	// o(c = (S|wi,wj|B,A)) =
+	// LA-r	if	(wj,r,wi) in Ad and wi has all its dependents
+	// RA-r	if	(wi,r,wj) in Ad and wj has all its dependents
+	// SW	if	wj precedes wi in the projective order of Gd
+	// SH	otherwise
+	bTop, bExists := c.Queue().Peek()
+	sTop, sExists := c.Stack().Peek()
+	var index int
+	if !bExists {
+		panic(fmt.Sprintf("Got empty configuration %v", c))
+	}
+	if sExists {
+		if arcs := o.arcSet.Get(&BasicDepArc{bTop, -1, sTop, DepRel("")}); len(arcs) > 0 && o.complete(c, sTop) {
+			index, _ = o.Transitions.IndexOf("LA-" + string(arcs[0].GetRelation()))
+			return &TypedTransition{TransitionType, index}
+		}
+		if arcs := o.arcSet.Get(&BasicDepArc{sTop, -1, bTop, DepRel("")}); len(arcs) > 0 && o.complete(c, bTop) {
+			index, _ = o.Transitions.IndexOf("RA-" + string(arcs[0].GetRelation()))
+			return &TypedTransition{TransitionType, index}
+		}
+		if sTop < bTop && o.order[bTop] < o.order[sTop] {
+			index, _ = o.Transitions.IndexOf("SW")
+			return &TypedTransition{TransitionType, index}
+		}
+	}
+	index, _ = o.Transitions.IndexOf("SH")
+	return &TypedTransition{TransitionType, index}
+}
+
+func (o *ArcSwapOracle) Name() string {
+	return "Arc Standard with Swap Static Oracle"
+}
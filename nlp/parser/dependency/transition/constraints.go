@@ -0,0 +1,262 @@
+package transition
+
+import (
+	. "yap/nlp/types"
+	"yap/util"
+
+	"fmt"
+)
+
+// FREE is the head or relation of a node the parser attaches freely
+const FREE = -2
+
+// Constraints are fixed heads (-1 for the root) and relations of some of
+// the nodes of a sentence, the transitions of the eager and standard arc
+// systems are filtered to keep them
+type Constraints struct {
+	Heads     []int
+	Relations []int
+}
+
+func NewConstraints(numNodes int) *Constraints {
+	c := &Constraints{make([]int, numNodes), make([]int, numNodes)}
+	for i := range c.Heads {
+		c.Heads[i], c.Relations[i] = FREE, FREE
+	}
+	return c
+}
+
+// ConstrainedSentence is a sentence parsed with constraints
+type ConstrainedSentence struct {
+	EnumTaggedSentence
+	Constraints *Constraints
+}
+
+// Validate returns an error if the constraints can't be kept together:
+// heads out of range, more than one root, cycles, the root relation for
+// a non root, or crossing arcs when projective
+func (c *Constraints) Validate(rootRel int, projective bool) error {
+	var root = -1
+	for modifier, head := range c.Heads {
+		switch {
+		case head == FREE:
+		case head < -1 || head >= len(c.Heads) || head == modifier:
+			return fmt.Errorf("Node %d has an invalid head %d", modifier+1, head+1)
+		case head == -1 && root >= 0:
+			return fmt.Errorf("Nodes %d and %d are both fixed as the root", root+1, modifier+1)
+		case head == -1:
+			root = modifier
+		case c.Relations[modifier] == rootRel:
+			return fmt.Errorf("Node %d is fixed with the root relation to non root head %d", modifier+1, head+1)
+		}
+		for cur, steps := head, 0; cur >= 0; cur, steps = c.Heads[cur], steps+1 {
+			if cur == modifier || steps > len(c.Heads) {
+				return fmt.Errorf("Node %d is fixed in a cycle", modifier+1)
+			}
+		}
+	}
+	if !projective {
+		return nil
+	}
+	for modifier, head := range c.Heads {
+		if head < 0 {
+			continue
+		}
+		left, right := arcSpan(head, modifier)
+		if root > left && root < right {
+			return fmt.Errorf("Fixed arc %d -> %d crosses the root %d, can't be kept projective", head+1, modifier+1, root+1)
+		}
+		for other, otherHead := range c.Heads {
+			if otherHead < 0 {
+				continue
+			}
+			otherLeft, otherRight := arcSpan(otherHead, other)
+			if left < otherLeft && otherLeft < right && right < otherRight {
+				return fmt.Errorf("Fixed arcs %d -> %d and %d -> %d cross, can't be kept projective", head+1, modifier+1, otherHead+1, other+1)
+			}
+		}
+	}
+	return nil
+}
+
+func arcSpan(head, modifier int) (int, int) {
+	if head < modifier {
+		return head, modifier
+	}
+	return modifier, head
+}
+
+// tree returns the constraints as a partial tree, whose FREE heads and
+// empty relations are kept by any arc
+func (c *Constraints) tree(relations *util.EnumSet) *GoldTree {
+	tree := &GoldTree{c.Heads, make([]DepRel, len(c.Relations))}
+	for node, rel := range c.Relations {
+		if rel != FREE {
+			tree.Relations[node] = relations.ValueOf(rel).(DepRel)
+		}
+	}
+	return tree
+}
+
+// standardLoss returns the least number of the stack (bottom first) and
+// queue nodes not kept by a tree arc standard can reach from them: a
+// projective tree over these nodes in which a stack node returns to the
+// queue, taking a right dependent, before it is attached to or by a node on
+// its left, or becomes the root
+func (t *GoldTree) standardLoss(stack, queue []int) int {
+	nodes := append(append([]int(nil), stack...), queue...)
+	gain := maxProjectiveGain(len(nodes), func(head, modifier int) int {
+		if head >= 0 {
+			head = nodes[head]
+		}
+		if t.canKeep(head, nodes[modifier], true) {
+			return 1
+		}
+		return 0
+	}, func(node int) bool {
+		return node < len(stack)
+	})
+	if gain < 0 {
+		// a dead end
+		return len(nodes) + 1
+	}
+	return len(nodes) - gain
+}
+
+// pending returns whether a node with a fixed head or relation is not
+// attached yet
+func (c *Constraints) pending(conf *SimpleConfiguration) bool {
+	for node, head := range c.Heads {
+		if (head != FREE || c.Relations[node] != FREE) && !conf.Arcs().HasHead(node) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the transitions allowed by the constraints, or all
+// transitions if none is (the constraints can't be kept)
+func filter(transitions []int, allowed func(int) bool) []int {
+	retval := make([]int, 0, len(transitions))
+	for _, transition := range transitions {
+		if allowed(transition) {
+			retval = append(retval, transition)
+		}
+	}
+	if len(retval) == 0 {
+		return transitions
+	}
+	return retval
+}
+
+// constrainedTransitions filters arc standard transitions: an arc must keep
+// the constraints of its modifier, and no transition may lose a fixed arc
+// that is still reachable
+func (a *ArcStandard) constrainedTransitions(conf *SimpleConfiguration, transitions []int) []int {
+	if !conf.Constraints.pending(conf) {
+		// the last node is still shifted only onto an empty stack, the
+		// stack isn't reduced without a queue
+		return filter(transitions, func(transition int) bool {
+			return transition != a.SHIFT || conf.Queue().Size() > 1 || conf.Stack().Size() == 0
+		})
+	}
+	var (
+		tree      = conf.Constraints.tree(a.Relations)
+		s0, _     = conf.Stack().Peek()
+		b0, _     = conf.Queue().Peek()
+		stack     = stackNodes(conf)
+		queue     = queueNodes(conf)
+		numStack  = len(stack)
+		bottomUp  = make([]int, numStack)
+		losses    = make(map[int]int, 4)
+		lossAfter = func(op int) int {
+			if loss, exists := losses[op]; exists {
+				return loss
+			}
+			var nextStack, nextQueue []int
+			switch op {
+			case reachLeft:
+				nextStack, nextQueue = bottomUp[:numStack-1], queue
+			case reachRight:
+				nextStack, nextQueue = bottomUp[:numStack-1], append([]int{s0}, queue[1:]...)
+			case reachShift:
+				nextStack, nextQueue = append(bottomUp[:numStack:numStack], b0), queue[1:]
+			default:
+				nextStack, nextQueue = bottomUp, queue
+			}
+			losses[op] = tree.standardLoss(nextStack, nextQueue)
+			return losses[op]
+		}
+	)
+	for i, node := range stack {
+		bottomUp[numStack-1-i] = node
+	}
+	base := lossAfter(-1)
+	return filter(transitions, func(transition int) bool {
+		switch {
+		case transition >= a.LEFT && transition < a.RIGHT:
+			relation := a.Relations.ValueOf(transition - a.LEFT).(DepRel)
+			return tree.keeps(b0, s0, relation, true) && lossAfter(reachLeft) == base
+		case transition >= a.RIGHT:
+			relation := a.Relations.ValueOf(transition - a.RIGHT).(DepRel)
+			return tree.keeps(s0, b0, relation, true) && lossAfter(reachRight) == base
+		case transition == a.SHIFT:
+			return lossAfter(reachShift) == base
+		}
+		return true
+	})
+}
+
+// constrainedTransitions filters arc eager transitions by the costs of the
+// dynamic oracle for the constraints as a partial tree: an arc must keep
+// the constraints of its modifier, and no transition may lose a fixed arc
+// that is still reachable
+func (a *ArcEager) constrainedTransitions(conf *SimpleConfiguration, transitions []int) []int {
+	if !conf.Constraints.pending(conf) {
+		return transitions
+	}
+	var (
+		tree  = conf.Constraints.tree(a.Relations)
+		reach = &reachOracle{tree: tree, eager: true, reduce: a.REDUCE}
+		s0, _ = conf.Stack().Peek()
+		b0, _ = conf.Queue().Peek()
+	)
+	return filter(transitions, func(transition int) bool {
+		switch {
+		case transition >= a.LEFT && transition < a.RIGHT:
+			relation := a.Relations.ValueOf(transition - a.LEFT).(DepRel)
+			return tree.keeps(b0, s0, relation, true) && reach.cost(conf, reachLeft, true) == 0
+		case transition >= a.RIGHT:
+			relation := a.Relations.ValueOf(transition - a.RIGHT).(DepRel)
+			return tree.keeps(s0, b0, relation, true) && reach.cost(conf, reachRight, true) == 0
+		case transition == a.REDUCE:
+			return reach.cost(conf, reachReduce, false) == 0
+		case transition == a.SHIFT:
+			return reach.cost(conf, reachShift, false) == 0
+		case transition == a.POPROOT:
+			return tree.keeps(-1, s0, DepRel(ROOT_LABEL), true) && reach.cost(conf, reachPopRoot, true) == 0
+		}
+		return true
+	})
+}
+
+// Violated returns the nodes whose fixed head or relation is not kept by a
+// parsed graph, when the arc system couldn't complete a tree keeping them;
+// a node without a head or with the root relation is the root
+func (c *Constraints) Violated(graph LabeledDependencyGraph, relations *util.EnumSet) []int {
+	var (
+		tree     = c.tree(relations)
+		parsed   = NewGoldTree(graph)
+		violated []int
+	)
+	for node, head := range parsed.Heads {
+		relation := parsed.Relations[node]
+		if len(relation) == 0 {
+			relation = DepRel(ROOT_LABEL)
+		}
+		if !tree.keeps(head, node, relation, true) {
+			violated = append(violated, node)
+		}
+	}
+	return violated
+}
@@ -0,0 +1,156 @@
+package transition
+
+import (
+	"math/rand"
+	"testing"
+
+	. "yap/alg/transition"
+	nlp "yap/nlp/types"
+	"yap/util"
+)
+
+func newConstraintTestSystem(eager bool) (TransitionSystem, *util.EnumSet) {
+	system, transitions := newDynamicTestSystem(eager)
+	if eager {
+		return system, transitions
+	}
+	return &system.(*ArcHybrid).ArcStandard, transitions
+}
+
+func newConstraints(heads []int) *Constraints {
+	c := NewConstraints(len(heads))
+	copy(c.Heads, heads)
+	return c
+}
+
+func TestConstraintsValidate(t *testing.T) {
+	const rootRel = 0
+	tests := []struct {
+		heads, relations []int
+		valid            bool
+	}{
+		{[]int{1, -1, 1}, nil, true},
+		{[]int{FREE, 2, 3, FREE}, nil, true},
+		{[]int{1, 3, FREE, FREE}, nil, true},
+		{[]int{4, FREE}, nil, false},
+		{[]int{0, FREE}, nil, false},
+		{[]int{-1, -1}, nil, false},
+		{[]int{1, 0, FREE}, nil, false},
+		{[]int{1, -1}, []int{rootRel, FREE}, false},
+		{[]int{2, 3, FREE, FREE}, nil, false},
+		{[]int{2, FREE, -1, FREE, 1}, nil, false},
+	}
+	for _, test := range tests {
+		c := newConstraints(test.heads)
+		copy(c.Relations, test.relations)
+		if err := c.Validate(rootRel, true); (err == nil) != test.valid {
+			t.Errorf("Validate %v %v: got %v, expected valid %v", test.heads, c.Relations, err, test.valid)
+		}
+	}
+	if err := newConstraints([]int{2, 3, FREE, FREE}).Validate(rootRel, false); err != nil {
+		t.Errorf("Non projective validate of crossing arcs: got %v", err)
+	}
+}
+
+// constrainedConf returns the configuration of a constrained sentence
+// after the named transitions
+func constrainedConf(system TransitionSystem, transitions *util.EnumSet, eager bool, c *Constraints, names []string) Configuration {
+	terminalStack := 1
+	if eager {
+		terminalStack = 0
+	}
+	var conf Configuration = &SimpleConfiguration{TerminalStack: terminalStack}
+	conf.Init(&ConstrainedSentence{make(nlp.BasicETaggedSentence, len(c.Heads)), c})
+	for _, name := range names {
+		transition, _ := transitions.IndexOf(name)
+		conf = system.Transition(conf, &TypedTransition{TransitionType, transition})
+	}
+	return conf
+}
+
+func TestConstrainedDeadEnds(t *testing.T) {
+	tests := []struct {
+		eager     bool
+		heads     []int
+		prefix    []string
+		forbidden string
+	}{
+		// node 2 must be the right dependent of node 3 after taking node 1,
+		// it can't be shifted onto it
+		{false, []int{FREE, 2, 3, FREE}, []string{"SH", "SH"}, "SH"},
+		// node 2 is the head of node 5, the last node, which arc eager right
+		// attaches only after nodes 1 and 2 are both attached
+		{true, []int{FREE, FREE, 4, 2, 1}, []string{"SH"}, "SH"},
+		// a fixed non root head can't be kept with the root relation
+		{true, []int{1, FREE}, nil, "LA-" + nlp.ROOT_LABEL},
+		{false, []int{FREE, 0}, []string{"SH"}, "RA-" + nlp.ROOT_LABEL},
+	}
+	for _, test := range tests {
+		system, transitions := newConstraintTestSystem(test.eager)
+		conf := constrainedConf(system, transitions, test.eager, newConstraints(test.heads), test.prefix)
+		allowed := make(map[string]bool)
+		_, possible := system.GetTransitions(conf)
+		for _, transition := range possible {
+			allowed[transitions.ValueOf(transition).(string)] = true
+		}
+		if allowed[test.forbidden] || len(allowed) == 0 {
+			t.Errorf("Fixed heads %v after %v: allowed %v, expected some without %s", test.heads, test.prefix, allowed, test.forbidden)
+		}
+	}
+}
+
+// randomProjectiveTree returns the heads of a random projective tree
+func randomProjectiveTree(r *rand.Rand, size int) []int {
+	heads := make([]int, size)
+	var build func(left, right, head int)
+	build = func(left, right, head int) {
+		if left >= right {
+			return
+		}
+		node := left + r.Intn(right-left)
+		heads[node] = head
+		build(left, node, node)
+		build(node+1, right, node)
+	}
+	build(0, size, -1)
+	return heads
+}
+
+// TestConstrainedTransitions checks that random walks of the filtered
+// transitions keep random fixed arcs of projective trees
+func TestConstrainedTransitions(t *testing.T) {
+	relations := util.NewEnumSet(len(oracleTestRelations), "ERel")
+	for _, rel := range oracleTestRelations {
+		relations.Add(rel)
+	}
+	r := rand.New(rand.NewSource(1))
+	for _, eager := range []bool{false, true} {
+		system, transitions := newConstraintTestSystem(eager)
+		for i := 0; i < 500; i++ {
+			size := 2 + r.Intn(7)
+			c := NewConstraints(size)
+			for node, head := range randomProjectiveTree(r, size) {
+				if r.Intn(2) == 0 {
+					continue
+				}
+				c.Heads[node] = head
+				if head >= 0 && r.Intn(2) == 0 {
+					c.Relations[node] = 1 + r.Intn(len(oracleTestRelations)-1)
+				}
+			}
+			if err := c.Validate(0, true); err != nil {
+				t.Fatalf("Fixed heads %v of a projective tree: %v", c.Heads, err)
+			}
+			for j := 0; j < 5; j++ {
+				conf := constrainedConf(system, transitions, eager, c, nil)
+				for !conf.Terminal() {
+					_, possible := system.GetTransitions(conf)
+					conf = system.Transition(conf, &TypedTransition{TransitionType, possible[r.Intn(len(possible))]})
+				}
+				if violated := c.Violated(conf.(*SimpleConfiguration), relations); len(violated) > 0 {
+					t.Fatalf("Fixed heads %v relations %v violated for nodes %v", c.Heads, c.Relations, violated)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,465 @@
+package transition
+
+import (
+	. "yap/alg/transition"
+	. "yap/nlp/types"
+	"yap/util"
+)
+
+// GoldTree holds the gold head (-1 for the root) and relation of each node
+// of a gold graph, for computing transition costs
+type GoldTree struct {
+	Heads     []int
+	Relations []DepRel
+}
+
+func NewGoldTree(graph LabeledDependencyGraph) *GoldTree {
+	numNodes := graph.NumberOfNodes()
+	tree := &GoldTree{make([]int, numNodes), make([]DepRel, numNodes)}
+	for i := range tree.Heads {
+		tree.Heads[i] = -1
+	}
+	for _, edgeNum := range graph.GetEdges() {
+		arc := graph.GetLabeledArc(edgeNum)
+		if arc == nil {
+			continue
+		}
+		if modifier := arc.GetModifier(); modifier >= 0 && modifier < numNodes {
+			tree.Heads[modifier] = arc.GetHead()
+			tree.Relations[modifier] = arc.GetRelation()
+		}
+	}
+	return tree
+}
+
+// Equal compares the heads and relations of two trees
+func (t *GoldTree) Equal(otherEq util.Equaler) bool {
+	other, ok := otherEq.(*GoldTree)
+	if !ok || len(t.Heads) != len(other.Heads) {
+		return false
+	}
+	for i, head := range t.Heads {
+		if head != other.Heads[i] || t.Relations[i] != other.Relations[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func stackNodes(c *SimpleConfiguration) []int {
+	nodes := make([]int, c.Stack().Size())
+	for i := range nodes {
+		nodes[i], _ = c.Stack().Index(i)
+	}
+	return nodes
+}
+
+func queueNodes(c *SimpleConfiguration) []int {
+	nodes := make([]int, c.Queue().Size())
+	for i := range nodes {
+		nodes[i], _ = c.Queue().Index(i)
+	}
+	return nodes
+}
+
+// keeps returns whether attaching modifier to head with relation keeps its
+// gold arc; with rootLabel an arc labeled ROOT attaches to the root, as in
+// the output of arc eager. A FREE head or an empty relation (of a partial
+// tree of constraints) is kept by any
+func (t *GoldTree) keeps(head, modifier int, relation DepRel, rootLabel bool) bool {
+	if rootLabel && relation == DepRel(ROOT_LABEL) {
+		head = -1
+	}
+	switch goldHead := t.Heads[modifier]; {
+	case goldHead == -1:
+		return head == -1
+	case goldHead != FREE && goldHead != head:
+		return false
+	}
+	return len(t.Relations[modifier]) == 0 || t.Relations[modifier] == relation
+}
+
+// canKeep returns whether modifier can be attached to head (-1 for the
+// root) keeping its gold arc, with the right relation
+func (t *GoldTree) canKeep(head, modifier int, rootLabel bool) bool {
+	switch goldHead := t.Heads[modifier]; {
+	case goldHead == FREE:
+		relation := t.Relations[modifier]
+		return head != -1 || len(relation) == 0 || relation == DepRel(ROOT_LABEL)
+	case goldHead == -1 && rootLabel:
+		// with an arc labeled ROOT
+		return true
+	default:
+		return goldHead == head
+	}
+}
+
+// impossible is the gain of a tree that can't be reached
+const impossible = -1 << 20
+
+// maxProjectiveGain returns the highest gain of a projective tree over
+// numNodes nodes with a single root (Eisner 1996), given the gain of each
+// arc (head -1 for the root), impossible for an arc that can't be made.
+// With needsRight, a node for which it is true must have a right dependent
+// if it has a left dependent, a head to its left or is the root
+func maxProjectiveGain(numNodes int, gain func(head, modifier int) int, needsRight func(node int) bool) int {
+	if numNodes == 0 {
+		return 0
+	}
+	// complete and incomplete spans of s..t, headed by t (left) or s (right),
+	// incompRightL is incompL with a right dependent of s
+	var (
+		size                           = numNodes * numNodes
+		compL, compR, incompL, incompR = make([]int, size), make([]int, size), make([]int, size), make([]int, size)
+		incompRightL                   = make([]int, size)
+		valid                          = func(val int) int {
+			if val < 0 {
+				return impossible
+			}
+			return val
+		}
+		needs = func(node int) bool {
+			return needsRight != nil && needsRight(node)
+		}
+	)
+	for length := 1; length < numNodes; length++ {
+		for s := 0; s+length < numNodes; s++ {
+			t := s + length
+			best, bestRight := impossible, impossible
+			for q := s; q < t; q++ {
+				val := compR[s*numNodes+q] + compL[(q+1)*numNodes+t]
+				if val > best {
+					best = val
+				}
+				if q > s && val > bestRight {
+					bestRight = val
+				}
+			}
+			incompL[s*numNodes+t] = valid(best + gain(t, s))
+			incompRightL[s*numNodes+t] = valid(bestRight + gain(t, s))
+			incompR[s*numNodes+t] = valid(best + gain(s, t))
+			left, right := impossible, impossible
+			for q := s; q < t; q++ {
+				incomp := incompL[q*numNodes+t]
+				if q > s && needs(q) {
+					incomp = incompRightL[q*numNodes+t]
+				}
+				if val := compL[s*numNodes+q] + incomp; val > left {
+					left = val
+				}
+			}
+			for q := s + 1; q <= t; q++ {
+				if q == t && needs(q) {
+					continue
+				}
+				if val := incompR[s*numNodes+q] + compR[q*numNodes+t]; val > right {
+					right = val
+				}
+			}
+			compL[s*numNodes+t], compR[s*numNodes+t] = valid(left), valid(right)
+		}
+	}
+	best := impossible
+	for root := 0; root < numNodes; root++ {
+		if root == numNodes-1 && needs(root) {
+			continue
+		}
+		if val := compL[root] + compR[root*numNodes+numNodes-1] + gain(-1, root); val > best {
+			best = val
+		}
+	}
+	return valid(best)
+}
+
+// reachState is what decides the gold arcs that can still be reached from
+// a configuration: the stack (bottom first), whether each stack node has a
+// head, the queue, the number of nodes attached wrongly, and whether the
+// last transition was a reduce (which zpar doesn't shift after)
+type reachState struct {
+	stack       []int
+	headed      []bool
+	queue       []int
+	lost        int
+	afterReduce bool
+}
+
+func newReachState(c *SimpleConfiguration, tree *GoldTree, eager bool, reduce int) *reachState {
+	s := &reachState{queue: queueNodes(c)}
+	stack := stackNodes(c)
+	for i := len(stack) - 1; i >= 0; i-- {
+		s.stack = append(s.stack, stack[i])
+		s.headed = append(s.headed, c.Arcs().HasHead(stack[i]))
+	}
+	pending := make([]bool, len(tree.Heads))
+	for _, node := range s.queue {
+		pending[node] = true
+	}
+	for i, node := range s.stack {
+		pending[node] = !s.headed[i]
+	}
+	for node := range tree.Heads {
+		if pending[node] {
+			continue
+		}
+		if arc := c.GetLabeledArc(node); arc == nil || !tree.keeps(arc.GetHead(), node, arc.GetRelation(), eager) {
+			s.lost++
+		}
+	}
+	if last := c.GetLastTransition(); eager && last != nil {
+		s.afterReduce = len(s.queue) > 0 && last.Value() == reduce
+	}
+	return s
+}
+
+const (
+	reachLeft = iota
+	reachRight
+	reachReduce
+	reachShift
+	reachPopRoot
+)
+
+// apply returns the state after a transition, keeps is whether the arc
+// it makes is gold
+func (s *reachState) apply(op int, keeps, hybrid bool) *reachState {
+	next := &reachState{
+		stack:  append([]int(nil), s.stack...),
+		headed: append([]bool(nil), s.headed...),
+		queue:  s.queue,
+		lost:   s.lost,
+	}
+	top := len(next.stack) - 1
+	if !keeps && (op == reachLeft || op == reachRight || op == reachPopRoot) {
+		next.lost++
+	}
+	switch {
+	case op == reachReduce:
+		if !next.headed[top] {
+			// never attached
+			next.lost++
+		}
+		next.stack, next.headed = next.stack[:top], next.headed[:top]
+		next.afterReduce = len(next.queue) > 0
+	case op == reachShift || op == reachRight && !hybrid:
+		next.stack = append(next.stack, next.queue[0])
+		next.headed = append(next.headed, op == reachRight)
+		next.queue = next.queue[1:]
+	default:
+		next.stack, next.headed = next.stack[:top], next.headed[:top]
+	}
+	return next
+}
+
+func (s *reachState) headless() int {
+	var count int
+	for _, headed := range s.headed {
+		if !headed {
+			count++
+		}
+	}
+	return count
+}
+
+// reachOracle computes the cost of a transition as the difference between
+// the minimal losses of the trees reachable after and before it, exact for
+// non-projective gold trees as well. The trees reachable from a
+// configuration are the projective trees over its stack and queue in which
+// a stack node is attached only to the queue (arc hybrid: or to the node
+// below it), and only the bottom of the stack or a queue node is the root
+type reachOracle struct {
+	tree *GoldTree
+	// eager keeps the heads of stack nodes, attaches the arcs labeled ROOT
+	// to the root and doesn't shift after a reduce
+	eager  bool
+	reduce int
+
+	conf  *SimpleConfiguration
+	state *reachState
+	base  int
+	costs map[int]int
+}
+
+func (o *reachOracle) setGold(tree *GoldTree) {
+	o.tree, o.conf = tree, nil
+}
+
+func (o *reachOracle) canKeep(head, modifier int) bool {
+	return o.tree.canKeep(head, modifier, o.eager)
+}
+
+// loss returns the minimal number of gold arcs lost by a tree reachable
+// from a state
+func (o *reachOracle) loss(s *reachState) int {
+	if s.afterReduce {
+		// the front of the queue is attached to or by the stack, possibly
+		// after more reduces, before it is shifted
+		var (
+			top, front = len(s.stack) - 1, s.queue[0]
+			best       = -1
+		)
+		consider := func(loss int) {
+			if best < 0 || loss < best {
+				best = loss
+			}
+		}
+		if !s.headed[top] {
+			consider(o.loss(s.apply(reachLeft, o.canKeep(front, s.stack[top]), false)))
+		}
+		if len(s.queue) > 1 || s.headless() == 1 {
+			consider(o.loss(s.apply(reachRight, o.canKeep(s.stack[top], front), false)))
+		}
+		if s.headed[top] && len(s.stack) > 1 {
+			consider(o.loss(s.apply(reachReduce, false, false)))
+		}
+		return best
+	}
+	var (
+		nodes    = append(append([]int(nil), s.stack...), s.queue...)
+		numStack = len(s.stack)
+		pending  = len(s.queue)
+	)
+	for _, headed := range s.headed {
+		if !headed {
+			pending++
+		}
+	}
+	gain := o.maxGain(nodes, numStack, s.headed)
+	if gain < 0 {
+		return s.lost + pending
+	}
+	return s.lost + pending - gain
+}
+
+// maxGain returns the highest number of pending nodes attached to their
+// gold heads by a tree reachable from the stack and queue nodes
+func (o *reachOracle) maxGain(nodes []int, numStack int, headed []bool) int {
+	return maxProjectiveGain(len(nodes), func(head, modifier int) int {
+		if modifier < numStack {
+			switch {
+			case headed[modifier]:
+				// already attached, to the node below it
+				if head == modifier-1 {
+					return 0
+				}
+				return impossible
+			case head == -1 && modifier > 0:
+				return impossible
+			case head >= 0 && head < numStack && (o.eager || head != modifier-1):
+				return impossible
+			}
+		}
+		if head >= 0 {
+			head = nodes[head]
+		}
+		if o.canKeep(head, nodes[modifier]) {
+			return 1
+		}
+		return 0
+	}, nil)
+}
+
+func (o *reachOracle) cost(c *SimpleConfiguration, op int, keeps bool) int {
+	if o.tree == nil {
+		panic("Oracle needs gold reference, use SetGold")
+	}
+	if c != o.conf {
+		o.conf, o.state = c, newReachState(c, o.tree, o.eager, o.reduce)
+		o.base = o.loss(o.state)
+		o.costs = make(map[int]int, 8)
+	}
+	key := 2 * op
+	if keeps {
+		key++
+	}
+	if cost, cached := o.costs[key]; cached {
+		return cost
+	}
+	cost := o.loss(o.state.apply(op, keeps, !o.eager)) - o.base
+	o.costs[key] = cost
+	return cost
+}
+
+// ArcEagerDynamicOracle is the dynamic oracle of arc eager (Goldberg & Nivre
+// 2012), for the zpar variant: the root is the node popped by PR, the last
+// node is shifted only onto an empty stack and right attached only when the
+// rest of the stack has heads, and there is no shift after a reduce
+type ArcEagerDynamicOracle struct {
+	ZparArcEagerOracle
+	Relations              *util.EnumSet
+	SHIFT, REDUCE, POPROOT int
+	reach                  reachOracle
+}
+
+var _ DynamicOracle = &ArcEagerDynamicOracle{}
+
+func (o *ArcEagerDynamicOracle) SetGold(g interface{}) {
+	o.ZparArcEagerOracle.SetGold(g)
+	o.reach.eager, o.reach.reduce = true, o.REDUCE
+	o.reach.setGold(NewGoldTree(o.gold))
+}
+
+func (o *ArcEagerDynamicOracle) Cost(conf Configuration, rawTransition Transition) int {
+	c := conf.(*SimpleConfiguration)
+	transition := rawTransition.Value()
+	s0, _ := c.Stack().Peek()
+	b0, _ := c.Queue().Peek()
+	switch {
+	case transition >= o.LA && transition < o.RA:
+		relation := o.Relations.ValueOf(transition - o.LA).(DepRel)
+		return o.reach.cost(c, reachLeft, o.reach.tree.keeps(b0, s0, relation, true))
+	case transition >= o.RA:
+		relation := o.Relations.ValueOf(transition - o.RA).(DepRel)
+		return o.reach.cost(c, reachRight, o.reach.tree.keeps(s0, b0, relation, true))
+	case transition == o.REDUCE:
+		return o.reach.cost(c, reachReduce, false)
+	case transition == o.SHIFT:
+		return o.reach.cost(c, reachShift, false)
+	case transition == o.POPROOT:
+		return o.reach.cost(c, reachPopRoot, o.reach.tree.Heads[s0] == -1)
+	}
+	return 0
+}
+
+func (o *ArcEagerDynamicOracle) Name() string {
+	return "Arc Eager Dynamic Oracle (Goldberg & Nivre 2012)"
+}
+
+// ArcHybridDynamicOracle is the dynamic oracle of arc hybrid (Goldberg &
+// Nivre 2013), without an artificial root: the root is the node left on the
+// stack, so only the bottom of the stack or a queue node can be the root
+type ArcHybridDynamicOracle struct {
+	ArcHybridOracle
+	Relations *util.EnumSet
+	SHIFT     int
+	reach     reachOracle
+}
+
+var _ DynamicOracle = &ArcHybridDynamicOracle{}
+
+func (o *ArcHybridDynamicOracle) SetGold(g interface{}) {
+	o.ArcHybridOracle.SetGold(g)
+	o.reach.setGold(NewGoldTree(o.gold))
+}
+
+func (o *ArcHybridDynamicOracle) Cost(conf Configuration, rawTransition Transition) int {
+	c := conf.(*SimpleConfiguration)
+	transition := rawTransition.Value()
+	s0, _ := c.Stack().Peek()
+	b0, _ := c.Queue().Peek()
+	switch {
+	case transition >= o.LA && transition < o.RA:
+		relation := o.Relations.ValueOf(transition - o.LA).(DepRel)
+		return o.reach.cost(c, reachLeft, o.reach.tree.keeps(b0, s0, relation, false))
+	case transition >= o.RA:
+		relation := o.Relations.ValueOf(transition - o.RA).(DepRel)
+		s1, _ := c.Stack().Index(1)
+		return o.reach.cost(c, reachRight, o.reach.tree.keeps(s1, s0, relation, false))
+	case transition == o.SHIFT:
+		return o.reach.cost(c, reachShift, false)
+	}
+	return 0
+}
+
+func (o *ArcHybridDynamicOracle) Name() string {
+	return "Arc Hybrid Dynamic Oracle (Goldberg & Nivre 2013)"
+}
@@ -0,0 +1,222 @@
+package transition
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	. "yap/alg/transition"
+	nlp "yap/nlp/types"
+	"yap/util"
+)
+
+var oracleTestRelations = []nlp.DepRel{nlp.ROOT_LABEL, "a", "b"}
+
+type dynamicTestSystem interface {
+	TransitionSystem
+	AddDynamicOracle()
+}
+
+func newDynamicTestSystem(eager bool) (dynamicTestSystem, *util.EnumSet) {
+	relations := util.NewEnumSet(len(oracleTestRelations), "ERel")
+	transitions := util.NewEnumSet(len(oracleTestRelations)*2+3, "ETrans")
+	for _, rel := range oracleTestRelations {
+		relations.Add(rel)
+	}
+	iSH, _ := transitions.Add("SH")
+	iRE, _ := transitions.Add("RE")
+	iPR, _ := transitions.Add("PR")
+	for _, rel := range oracleTestRelations {
+		transitions.Add("LA-" + string(rel))
+	}
+	for _, rel := range oracleTestRelations {
+		transitions.Add("RA-" + string(rel))
+	}
+	standard := ArcStandard{
+		SHIFT:       iSH,
+		LEFT:        iPR + 1,
+		RIGHT:       iPR + 1 + len(oracleTestRelations),
+		Relations:   relations,
+		Transitions: transitions,
+	}
+	var system dynamicTestSystem
+	if eager {
+		system = &ArcEager{ArcStandard: standard, REDUCE: iRE, POPROOT: iPR}
+	} else {
+		system = &ArcHybrid{ArcStandard: standard}
+	}
+	system.AddDynamicOracle()
+	return system, transitions
+}
+
+// randomTree returns random heads (-1 for the root) and relations, trees
+// are not necessarily projective
+func randomTree(r *rand.Rand, size int) *BasicDepGraph {
+	order := r.Perm(size)
+	heads := make([]int, size)
+	heads[order[0]] = -1
+	for i := 1; i < size; i++ {
+		heads[order[i]] = order[r.Intn(i)]
+	}
+	graph := &BasicDepGraph{make([]nlp.DepNode, size), make([]*BasicDepArc, size)}
+	for modifier, head := range heads {
+		rel := 1 + r.Intn(len(oracleTestRelations)-1)
+		if head == -1 {
+			rel = 0
+		}
+		graph.Nodes[modifier] = &TaggedDepNode{Id: modifier}
+		graph.Arcs[modifier] = &BasicDepArc{head, rel, modifier, oracleTestRelations[rel]}
+	}
+	return graph
+}
+
+// treeLoss counts the nodes of a terminal configuration not attached to
+// their gold head with their gold relation; an arc eager node attached
+// with ROOT is attached to the root, an arc hybrid node without a head is
+// the root
+func treeLoss(c *SimpleConfiguration, gold *BasicDepGraph, eager bool) int {
+	var loss int
+	for modifier, goldArc := range gold.Arcs {
+		head, rel := -1, nlp.DepRel(nlp.ROOT_LABEL)
+		if arc := c.GetLabeledArc(modifier); arc != nil {
+			head, rel = arc.GetHead(), arc.GetRelation()
+			if eager && rel == nlp.ROOT_LABEL {
+				head = -1
+			}
+		} else if eager {
+			head = -2
+		}
+		if head != goldArc.GetHead() || rel != goldArc.GetRelation() {
+			loss++
+		}
+	}
+	return loss
+}
+
+type minLossSearch struct {
+	system TransitionSystem
+	gold   *BasicDepGraph
+	eager  bool
+	memo   map[string]int
+}
+
+// minLoss searches all the transition sequences from a configuration for
+// the lowest loss
+func (s *minLossSearch) minLoss(c Configuration) int {
+	conf := c.(*SimpleConfiguration)
+	if conf.Terminal() {
+		return treeLoss(conf, s.gold, s.eager)
+	}
+	arcs := make([]string, len(s.gold.Arcs))
+	for node := range s.gold.Arcs {
+		if arc := conf.GetLabeledArc(node); arc != nil {
+			arcs[node] = strconv.Itoa(arc.GetHead()) + string(arc.GetRelation())
+		}
+	}
+	// only a preceding reduce restricts the following transitions
+	afterReduce := s.eager && conf.GetLastTransition().Value() == s.system.(*ArcEager).REDUCE
+	key := fmt.Sprint(stackNodes(conf), queueNodes(conf), arcs, afterReduce)
+	if loss, exists := s.memo[key]; exists {
+		return loss
+	}
+	best := -1
+	_, transitions := s.system.GetTransitions(c)
+	for _, transition := range transitions {
+		next := s.system.Transition(c, &TypedTransition{TransitionType, transition})
+		if loss := s.minLoss(next); best < 0 || loss < best {
+			best = loss
+		}
+	}
+	s.memo[key] = best
+	return best
+}
+
+// testDynamicOracle compares the cost of each transition from random
+// configurations with the loss it adds to the best reachable tree
+func testDynamicOracle(t *testing.T, eager bool) {
+	system, eTrans := newDynamicTestSystem(eager)
+	var (
+		oracle  = system.Oracle().(DynamicOracle)
+		r       = rand.New(rand.NewSource(1))
+		checked int
+	)
+	terminalStack := 1
+	if eager {
+		terminalStack = 0
+	}
+	for i := 0; i < 300; i++ {
+		size := 2 + r.Intn(4)
+		gold := randomTree(r, size)
+		oracle.SetGold(gold)
+		sent := make(nlp.BasicETaggedSentence, size)
+		var c Configuration = &SimpleConfiguration{TerminalStack: terminalStack}
+		c.Init(sent)
+		for steps := r.Intn(2 * size); steps > 0 && !c.Terminal(); steps-- {
+			_, transitions := system.GetTransitions(c)
+			c = system.Transition(c, &TypedTransition{TransitionType, transitions[r.Intn(len(transitions))]})
+		}
+		if c.Terminal() {
+			continue
+		}
+		search := &minLossSearch{system, gold, eager, make(map[string]int)}
+		base := search.minLoss(c)
+		_, transitions := system.GetTransitions(c)
+		for _, transition := range transitions {
+			typed := &TypedTransition{TransitionType, transition}
+			expected := search.minLoss(system.Transition(c, typed)) - base
+			if cost := oracle.Cost(c, typed); cost != expected {
+				t.Fatalf("Gold %v at stack %v queue %v: %s cost %d, expected %d", gold.Arcs, stackNodes(c.(*SimpleConfiguration)), queueNodes(c.(*SimpleConfiguration)), eTrans.ValueOf(transition), cost, expected)
+			}
+			checked++
+		}
+	}
+	if checked == 0 {
+		t.Fatal("No transitions checked")
+	}
+}
+
+func TestArcEagerDynamicOracle(t *testing.T) {
+	testDynamicOracle(t, true)
+}
+
+func TestArcHybridDynamicOracle(t *testing.T) {
+	testDynamicOracle(t, false)
+}
+
+func TestGoldTreeEqual(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	graph := randomTree(r, 5)
+	tree := NewGoldTree(graph)
+	if !tree.Equal(NewGoldTree(graph)) {
+		t.Error("Expected the trees of the same graph to be equal")
+	}
+	other := NewGoldTree(graph)
+	other.Relations[0] = "other"
+	if tree.Equal(other) {
+		t.Error("Expected trees with different relations to differ")
+	}
+	other = NewGoldTree(graph)
+	other.Heads[0] = len(other.Heads)
+	if tree.Equal(other) {
+		t.Error("Expected trees with different heads to differ")
+	}
+	if tree.Equal(&GoldTree{tree.Heads[:4], tree.Relations[:4]}) {
+		t.Error("Expected trees of different lengths to differ")
+	}
+
+	// the results of configurations are compared for k-best distinctness
+	system, transitions := newDynamicTestSystem(true)
+	var (
+		result     = constrainedConf(system, transitions, true, NewConstraints(3), []string{"SH", "RA-a", "RA-b"}).(*SimpleConfiguration).Result()
+		same       = constrainedConf(system, transitions, true, NewConstraints(3), []string{"SH", "RA-a", "RA-b"}).(*SimpleConfiguration).Result()
+		otherLabel = constrainedConf(system, transitions, true, NewConstraints(3), []string{"SH", "RA-a", "RA-a"}).(*SimpleConfiguration).Result()
+		otherHead  = constrainedConf(system, transitions, true, NewConstraints(3), []string{"SH", "RA-a", "RE", "RA-b"}).(*SimpleConfiguration).Result()
+	)
+	if !result.Equal(same) {
+		t.Error("Expected the results of the same transitions to be equal")
+	}
+	if result.Equal(otherLabel) || result.Equal(otherHead) {
+		t.Error("Expected the results of different trees to differ")
+	}
+}
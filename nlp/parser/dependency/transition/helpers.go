@@ -169,7 +169,7 @@ func (s *ArcSetSimple) Last() LabeledDepArc {
 func (s *ArcSetSimple) String() string {
 	arcs := make([]string, s.Size())
 	for i, arc := range s.Arcs {
-		arcs[i] = fmt.Sprintf("%d %d %v", i, arc.ID, arc.String())
+		arcs[i] = fmt.Sprintf("%d %d %v", i, arc.ID(), arc.String())
 	}
 	return strings.Join(arcs, "\n")
 }
@@ -61,8 +61,9 @@ func CombineToGoldMorph(graph nlp.LabeledDependencyGraph, goldLat, ambLat nlp.La
 			continue
 		}
 		mapping := &nlp.Mapping{
-			lat.Token,
-			lat.Spellouts[0],
+			Token:    lat.Token,
+			Spellout: lat.Spellouts[0],
+			Range:    lat.Range,
 		}
 		// if the gold spellout doesn't exist in the lattice, add it
 		_, exists := ambLat[i].Spellouts.Find(mapping.Spellout)
@@ -0,0 +1,74 @@
+package transition
+
+import (
+	"math/rand"
+	"testing"
+
+	. "yap/alg/transition"
+	nlp "yap/nlp/types"
+	"yap/util"
+)
+
+// headsGraph returns a graph of heads (-1 for the root) with random
+// relations
+func headsGraph(r *rand.Rand, heads []int) *BasicDepGraph {
+	graph := &BasicDepGraph{make([]nlp.DepNode, len(heads)), make([]*BasicDepArc, len(heads))}
+	for modifier, head := range heads {
+		rel := 1 + r.Intn(len(oracleTestRelations)-1)
+		if head == -1 {
+			rel = 0
+		}
+		graph.Nodes[modifier] = &TaggedDepNode{Id: modifier}
+		graph.Arcs[modifier] = &BasicDepArc{head, rel, modifier, oracleTestRelations[rel]}
+	}
+	return graph
+}
+
+// testStaticOracle follows the static oracle of an arc system on random
+// gold trees, checking that each of its transitions is possible and that
+// it reaches the gold tree
+func testStaticOracle(t *testing.T, system TransitionSystem, transitions *util.EnumSet, randomGold func(r *rand.Rand, size int) *BasicDepGraph) {
+	system.AddDefaultOracle()
+	oracle := system.Oracle()
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		size := 1 + r.Intn(10)
+		gold := randomGold(r, size)
+		oracle.SetGold(gold)
+		var c Configuration = &SimpleConfiguration{TerminalStack: 1}
+		c.Init(make(nlp.BasicETaggedSentence, size))
+		for steps := 0; !c.Terminal(); steps++ {
+			if steps > size*size+2*size {
+				t.Fatalf("Gold %v: the oracle doesn't terminate", gold.Arcs)
+			}
+			transition := oracle.Transition(c)
+			_, possible := system.GetTransitions(c)
+			var isPossible bool
+			for _, other := range possible {
+				isPossible = isPossible || other == transition.Value()
+			}
+			if !isPossible {
+				t.Fatalf("Gold %v at stack %v queue %v: the oracle's %s is not possible", gold.Arcs, stackNodes(c.(*SimpleConfiguration)), queueNodes(c.(*SimpleConfiguration)), transitions.ValueOf(transition.Value()))
+			}
+			c = system.Transition(c, transition)
+		}
+		if loss := treeLoss(c.(*SimpleConfiguration), gold, false); loss > 0 {
+			t.Fatalf("Gold %v: the oracle's tree has %d wrong arcs", gold.Arcs, loss)
+		}
+	}
+}
+
+func TestArcSwapStaticOracle(t *testing.T) {
+	system, transitions := newDynamicTestSystem(false)
+	iSW, _ := transitions.Add("SW")
+	swap := &ArcSwap{ArcStandard: system.(*ArcHybrid).ArcStandard, SWAP: iSW}
+	// trees are not necessarily projective
+	testStaticOracle(t, swap, transitions, randomTree)
+}
+
+func TestArcHybridStaticOracle(t *testing.T) {
+	system, transitions := newDynamicTestSystem(false)
+	testStaticOracle(t, system, transitions, func(r *rand.Rand, size int) *BasicDepGraph {
+		return headsGraph(r, randomProjectiveTree(r, size))
+	})
+}
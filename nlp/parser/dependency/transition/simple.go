@@ -28,6 +28,8 @@ type SimpleConfiguration struct {
 	NumHeadStack  int
 	TerminalQueue int
 	TerminalStack int
+	// Constraints of a ConstrainedSentence
+	Constraints *Constraints
 }
 
 func (c *SimpleConfiguration) State() byte {
@@ -62,6 +64,7 @@ func (c *SimpleConfiguration) Init(abstractSentence interface{}) {
 			enumToken.Token,
 			enumToken.Lemma,
 			enumToken.POS,
+			enumToken.Range,
 		}
 		c.Nodes = append(c.Nodes, NewArcCachedDepNode(nlp.DepNode(node)))
 	}
@@ -87,6 +90,10 @@ func (c *SimpleConfiguration) Init(abstractSentence interface{}) {
 	c.Last = ConstTransition(0)
 	c.InternalPrevious = nil
 	c.NumHeadStack = 0
+	c.Constraints = nil
+	if constrained, ok := abstractSentence.(*ConstrainedSentence); ok {
+		c.Constraints = constrained.Constraints
+	}
 	// c.Pointers = 0
 }
 
@@ -151,12 +158,19 @@ func (c *SimpleConfiguration) CopyTo(target Configuration) {
 	newConf.NumHeadStack = c.NumHeadStack
 	newConf.TerminalQueue = c.TerminalQueue
 	newConf.TerminalStack = c.TerminalStack
+	newConf.Constraints = c.Constraints
 	// store a pointer to the previous configuration
 	newConf.InternalPrevious = c
 
 	newConf.EWord, newConf.EPOS, newConf.EWPOS, newConf.ERel, newConf.ETrans, newConf.EMHost, newConf.EMSuffix = c.EWord, c.EPOS, c.EWPOS, c.ERel, c.ETrans, c.EMHost, c.EMSuffix
 }
 
+// Result returns the tree of the configuration, configurations reaching
+// the same tree by different transitions are a single k-best result
+func (c *SimpleConfiguration) Result() util.Equaler {
+	return NewGoldTree(c)
+}
+
 func (c *SimpleConfiguration) AddArc(arc *BasicDepArc) {
 	c.Arcs().Add(arc)
 	if c.Nodes[arc.Modifier].ELabel >= 0 {
@@ -401,7 +415,7 @@ func (c *SimpleConfiguration) TaggedSentence() nlp.TaggedSentence {
 		sent[i] = nlp.EnumTaggedToken{
 			nlp.TaggedToken{taggedNode.RawToken, taggedNode.RawLemma, taggedNode.RawPOS},
 			// TODO: add lemma enum
-			taggedNode.Token, 0, taggedNode.POS, taggedNode.TokenPOS, taggedNode.MHost, taggedNode.MSuffix}
+			taggedNode.Token, 0, taggedNode.POS, taggedNode.TokenPOS, taggedNode.MHost, taggedNode.MSuffix, taggedNode.Range}
 	}
 	return sent
 }
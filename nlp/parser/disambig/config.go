@@ -22,6 +22,15 @@ var (
 	AFFIX_SIZE       int  = 10
 )
 
+// MDOptions scope the package level switches to a single model, so that
+// models with different settings may share a process. Configurations and
+// transition systems without options use the package level switches
+type MDOptions struct {
+	UsePOP          bool
+	SwitchFormLemma bool
+	Lemmas          bool
+}
+
 type MDConfig struct {
 	LatticeQueue Queue
 	Lattices     nlp.LatticeSentence
@@ -39,6 +48,7 @@ type MDConfig struct {
 	POP         Transition
 	Transitions *util.EnumSet
 	ParamFunc   nlp.MDParam
+	Options     *MDOptions
 	popped      int
 }
 
@@ -74,9 +84,23 @@ func (c *MDConfig) Init(abstractLattice interface{}) {
 	c.popped = 0
 }
 
+func (c *MDConfig) usePOP() bool {
+	if c.Options != nil {
+		return c.Options.UsePOP
+	}
+	return UsePOP
+}
+
+func (c *MDConfig) switchFormLemma() bool {
+	if c.Options != nil {
+		return c.Options.SwitchFormLemma
+	}
+	return SwitchFormLemma
+}
+
 func (c *MDConfig) Terminal() bool {
 	// return c.Last == Transition(0) && c.Alignment() == 1
-	if UsePOP {
+	if c.usePOP() {
 		return c.LatticeQueue.Size() == 0 && c.popped == len(c.Mappings)
 	} else {
 		return c.LatticeQueue.Size() == 0
@@ -108,6 +132,7 @@ func (c *MDConfig) CopyTo(target Configuration) {
 		lastMappingIdx := len(c.Mappings) - 1
 		newLastMapping := &nlp.Mapping{
 			Token: c.Mappings[lastMappingIdx].Token,
+			Range: c.Mappings[lastMappingIdx].Range,
 			Spellout: make(nlp.Spellout,
 				len(c.Mappings[lastMappingIdx].Spellout),
 				cap(c.Mappings[lastMappingIdx].Spellout))}
@@ -130,6 +155,7 @@ func (c *MDConfig) CopyTo(target Configuration) {
 	newConf.POP = c.POP
 	newConf.Transitions = c.Transitions
 	newConf.ParamFunc = c.ParamFunc
+	newConf.Options = c.Options
 }
 
 func (c *MDConfig) GetSequence() ConfigurationSequence {
@@ -163,7 +189,7 @@ func (c *MDConfig) State() byte {
 		return 'L'
 	}
 	qTop, qExists := c.LatticeQueue.Peek()
-	if UsePOP && ((!qExists && len(c.Mappings) != c.popped) ||
+	if c.usePOP() && ((!qExists && len(c.Mappings) != c.popped) ||
 		(qExists && qTop != c.popped)) {
 		// can pop
 		return 'P'
@@ -288,6 +314,12 @@ func (c *MDConfig) Equal(otherEq util.Equaler) bool {
 	}
 }
 
+// Result returns the mappings chosen, configurations reaching the same
+// mappings by different transitions are a single k-best result
+func (c *MDConfig) Result() util.Equaler {
+	return c.Mappings
+}
+
 func (c *MDConfig) Previous() Configuration {
 	return c.InternalPrevious
 }
@@ -304,7 +336,7 @@ func (c *MDConfig) AddSpellout(spellout string, paramFunc nlp.MDParam) bool {
 	// log.Println("\tAdding spellout")
 	if curLatticeId, exists := c.LatticeQueue.Pop(); exists {
 		curLattice := c.Lattices[curLatticeId]
-		if UsePOP && POP_ONLY_VAR_LEN {
+		if c.usePOP() && POP_ONLY_VAR_LEN {
 			poppedLat := c.Lattices[curLatticeId]
 			// only need to pop variable length
 			if !poppedLat.IsVarLen() {
@@ -315,7 +347,7 @@ func (c *MDConfig) AddSpellout(spellout string, paramFunc nlp.MDParam) bool {
 		for _, s := range curLattice.Spellouts {
 			if nlp.ProjectSpellout(s, paramFunc) == spellout {
 				c.CurrentLatNode = curLattice.Top()
-				c.Mappings = append(c.Mappings, &nlp.Mapping{Token: curLattice.Token, Spellout: s})
+				c.Mappings = append(c.Mappings, &nlp.Mapping{Token: curLattice.Token, Spellout: s, Range: curLattice.Range})
 				// log.Println("\tPost mappings:", c.Mappings)
 				return true
 			}
@@ -367,7 +399,7 @@ func (c *MDConfig) AddMapping(m *nlp.EMorpheme) {
 
 	if len(c.Mappings) == 0 || len(c.Mappings) < currentLatIdx {
 		// log.Println("\tAdding new mapping because", len(c.Mappings), currentLatIdx)
-		c.Mappings = append(c.Mappings, &nlp.Mapping{Token: c.Lattices[currentLatIdx].Token, Spellout: make(nlp.Spellout, 0, 1)})
+		c.Mappings = append(c.Mappings, &nlp.Mapping{Token: c.Lattices[currentLatIdx].Token, Spellout: make(nlp.Spellout, 0, 1), Range: c.Lattices[currentLatIdx].Range})
 	}
 
 	currentMap := c.Mappings[len(c.Mappings)-1]
@@ -381,7 +413,7 @@ func (c *MDConfig) AddMapping(m *nlp.EMorpheme) {
 	if currentLat := c.Lattices[currentLatIdx]; c.CurrentLatNode == currentLat.Top() {
 		// log.Println("\tPopping lattice queue")
 		poppedIndex, _ := c.LatticeQueue.Pop()
-		if UsePOP && POP_ONLY_VAR_LEN {
+		if c.usePOP() && POP_ONLY_VAR_LEN {
 			poppedLat := c.Lattices[poppedIndex]
 			// only need to pop variable length
 			if !poppedLat.IsVarLen() {
@@ -391,7 +423,7 @@ func (c *MDConfig) AddMapping(m *nlp.EMorpheme) {
 		val, exists := c.LatticeQueue.Peek()
 		// log.Println("\tNow at lattice (exists)", val, exists)
 		if exists {
-			c.Mappings = append(c.Mappings, &nlp.Mapping{Token: c.Lattices[val].Token, Spellout: make(nlp.Spellout, 0, 1)})
+			c.Mappings = append(c.Mappings, &nlp.Mapping{Token: c.Lattices[val].Token, Spellout: make(nlp.Spellout, 0, 1), Range: c.Lattices[val].Range})
 			// log.Println("\tSetting token to", c.Lattices[val].Token)
 		}
 	}
@@ -464,7 +496,7 @@ func (c *MDConfig) Attribute(source byte, nodeID int, attribute []byte, transiti
 				att = morpheme.EFCPOS
 				return
 			} else {
-				if SwitchFormLemma {
+				if c.switchFormLemma() {
 					att = morpheme.Lemma
 				} else {
 					att = morpheme.EForm
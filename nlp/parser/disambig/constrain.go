@@ -0,0 +1,52 @@
+package disambig
+
+import (
+	nlp "yap/nlp/types"
+)
+
+// fixedPosition returns the fixed spellout of a lattice and the position in
+// it of the next morpheme of the lattice's mapping
+func fixedPosition(conf *MDConfig, latIdx int) (nlp.Spellout, int) {
+	fixed := conf.Lattices[latIdx].Fixed
+	if len(fixed) == 0 {
+		return nil, 0
+	}
+	if latIdx < len(conf.Mappings) {
+		return fixed, len(conf.Mappings[latIdx].Spellout)
+	}
+	return fixed, 0
+}
+
+// FixedAllows returns whether a morpheme may be the next morpheme of its
+// lattice, given the spellout fixed for the lattice (if any)
+func FixedAllows(conf *MDConfig, latIdx int, morph *nlp.EMorpheme, paramFunc nlp.MDParam) bool {
+	fixed, pos := fixedPosition(conf, latIdx)
+	if fixed == nil {
+		return true
+	}
+	if pos >= len(fixed) || paramFunc(morph) != paramFunc(fixed[pos]) {
+		return false
+	}
+	// the last morpheme of the fixed spellout must end the lattice
+	return (morph.To() == conf.Lattices[latIdx].Top()) == (pos == len(fixed)-1)
+}
+
+// FixedAllowsLemma returns whether a lemma may be chosen for the next
+// morpheme of a lattice, fixed spellouts without lemmas allow any lemma
+func FixedAllowsLemma(conf *MDConfig, latIdx int, lemma string) bool {
+	fixed, pos := fixedPosition(conf, latIdx)
+	if pos >= len(fixed) {
+		return true
+	}
+	fixedLemma := fixed[pos].Lemma
+	return len(fixedLemma) == 0 || fixedLemma == "_" || fixedLemma == lemma
+}
+
+// FixedAllowsSpellout returns whether a whole spellout may be chosen for a
+// lattice, given the spellout fixed for the lattice (if any)
+func FixedAllowsSpellout(lat *nlp.Lattice, spellout nlp.Spellout, paramFunc nlp.MDParam) bool {
+	if len(lat.Fixed) == 0 {
+		return true
+	}
+	return nlp.ProjectSpellout(spellout, paramFunc) == nlp.ProjectSpellout(lat.Fixed, paramFunc)
+}
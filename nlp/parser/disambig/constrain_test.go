@@ -0,0 +1,47 @@
+package disambig
+
+import (
+	"testing"
+
+	. "yap/alg/transition"
+	nlp "yap/nlp/types"
+	"yap/util"
+)
+
+func TestTransitionFixed(t *testing.T) {
+	var (
+		prep    = testMorph(0, 0, 1, "ב", "PREPOSITION")
+		house   = testMorph(1, 1, 2, "בית", "NN")
+		inHouse = testMorph(2, 0, 2, "בבית", "PREPOSITION")
+	)
+	lat := nlp.Lattice{
+		Token:     "בבית",
+		Morphemes: nlp.Morphemes{prep, house, inHouse},
+		Next:      map[int][]int{0: {0, 2}, 1: {1}},
+		BottomId:  0,
+		TopId:     2,
+	}
+	trans := &MDTrans{ParamFunc: nlp.POS, Transitions: util.NewEnumSet(10, "test")}
+	trans.Transitions.Add("IDLE")
+	value, _ := trans.Transitions.Add(nlp.POS(prep))
+	transition := &TypedTransition{T: 'M', V: value}
+	for _, lemmas := range []bool{false, true} {
+		trans.Options = &MDOptions{Lemmas: lemmas}
+
+		conf := &MDConfig{Options: &MDOptions{Lemmas: lemmas}}
+		conf.Init(nlp.LatticeSentence{lat})
+		conf.Lattices[0].Fixed = nlp.Spellout{inHouse}
+		next := trans.Transition(conf, transition).(*MDConfig)
+		if len(next.Lemmas) > 0 || len(next.Mappings) == 0 || len(next.Mappings[0].Spellout) != 1 || next.Mappings[0].Spellout[0] != inHouse {
+			t.Errorf("Lemmas %v: got mappings %v with lemma ambiguity %v, expected the fixed arc %v", lemmas, next.Mappings, next.Lemmas, inHouse)
+		}
+
+		conf = &MDConfig{Options: &MDOptions{Lemmas: lemmas}}
+		conf.Init(nlp.LatticeSentence{lat})
+		conf.Lattices[0].Fixed = nlp.Spellout{prep, house}
+		next = trans.Transition(conf, transition).(*MDConfig)
+		if len(next.Lemmas) > 0 || next.CurrentLatNode != prep.To() {
+			t.Errorf("Lemmas %v: got node %d with lemma ambiguity %v, expected the fixed arc %v", lemmas, next.CurrentLatNode, next.Lemmas, prep)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package disambig
+
+import (
+	"yap/alg/search"
+	nlp "yap/nlp/types"
+
+	"math"
+)
+
+var _ search.Distinct = &MDConfig{}
+
+// KBestLattice returns the lattices of the sentence of k-best MD results,
+// pruned to the morphemes used by at least one of them
+func KBestLattice(result *search.KBestResult) nlp.LatticeSentence {
+	if len(result.Configurations) == 0 {
+		return nil
+	}
+	used := make(map[*nlp.EMorpheme]bool)
+	for _, conf := range result.Configurations {
+		for _, mapping := range conf.(*MDConfig).Mappings {
+			for _, morph := range mapping.Spellout {
+				used[morph] = true
+			}
+		}
+	}
+	lattices := result.Configurations[0].(*MDConfig).Lattices
+	pruned := make(nlp.LatticeSentence, len(lattices))
+	for i, lat := range lattices {
+		pruned[i] = nlp.Lattice{
+			Token:     lat.Token,
+			Morphemes: make(nlp.Morphemes, 0, len(lat.Morphemes)),
+			BottomId:  lat.BottomId,
+			TopId:     lat.TopId,
+			Range:     lat.Range,
+		}
+		for _, morph := range lat.Morphemes {
+			if used[morph] {
+				pruned[i].Morphemes = append(pruned[i].Morphemes, morph)
+			}
+		}
+	}
+	return pruned
+}
+
+// DefaultConfidenceTemperature is the softmax temperature of
+// ResultProbabilities for a temperature of 0, in score units per token.
+// Perceptron scores depend on the model, so -conftemp should be calibrated
+// on held out data (e.g. to the accuracy of tokens of a given confidence)
+const DefaultConfidenceTemperature = 1.0
+
+// ResultProbabilities normalizes the scores of k-best results of a sentence
+// of length tokens by a softmax with the given temperature. Scores grow
+// with the sentence length, so they are divided by it: results a margin of
+// temperature per token apart differ by a factor of e
+func ResultProbabilities(scores []float64, length int, temperature float64) []float64 {
+	probs := make([]float64, len(scores))
+	if len(scores) == 0 {
+		return probs
+	}
+	if temperature <= 0 {
+		temperature = DefaultConfidenceTemperature
+	}
+	if length < 1 {
+		length = 1
+	}
+	max := scores[0]
+	for _, score := range scores[1:] {
+		max = math.Max(max, score)
+	}
+	var sum float64
+	for i, score := range scores {
+		probs[i] = math.Exp((score - max) / float64(length) / temperature)
+		sum += probs[i]
+	}
+	for i := range probs {
+		probs[i] /= sum
+	}
+	return probs
+}
+
+func containsMorph(spellout nlp.Spellout, morph *nlp.EMorpheme) bool {
+	for _, other := range spellout {
+		if other != nil && other.Equal(morph) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetConfidence sets the confidence of each mapping of the best of k-best
+// MD results, and of each of its morphemes, as the probability of the
+// results agreeing with it at that token (see ResultProbabilities).
+// Returns the best result
+func SetConfidence(result *search.KBestResult, temperature float64) *MDConfig {
+	if len(result.Configurations) == 0 {
+		return nil
+	}
+	best := result.Configurations[0].(*MDConfig)
+	probs := ResultProbabilities(result.Scores, len(best.Mappings), temperature)
+	for i, mapping := range best.Mappings {
+		// mappings may be shared with other configurations of the beam
+		confMapping := &nlp.Mapping{
+			Token:           mapping.Token,
+			Spellout:        mapping.Spellout,
+			Range:           mapping.Range,
+			MorphConfidence: make([]float64, len(mapping.Spellout)),
+		}
+		for j, conf := range result.Configurations {
+			other := conf.(*MDConfig)
+			if i >= len(other.Mappings) {
+				continue
+			}
+			otherSpellout := other.Mappings[i].Spellout
+			if mapping.Spellout.Equal(otherSpellout) {
+				confMapping.Confidence += probs[j]
+			}
+			for k, morph := range mapping.Spellout {
+				if morph != nil && containsMorph(otherSpellout, morph) {
+					confMapping.MorphConfidence[k] += probs[j]
+				}
+			}
+		}
+		best.Mappings[i] = confMapping
+	}
+	return best
+}
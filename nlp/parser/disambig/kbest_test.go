@@ -0,0 +1,215 @@
+package disambig
+
+import (
+	"math"
+	"testing"
+
+	. "yap/alg"
+	"yap/alg/graph"
+	"yap/alg/search"
+	. "yap/alg/transition"
+	nlp "yap/nlp/types"
+)
+
+func testMorph(id, from, to int, form, CPOS string) *nlp.EMorpheme {
+	return &nlp.EMorpheme{Morpheme: nlp.Morpheme{
+		BasicDirectedEdge: graph.BasicDirectedEdge{id, from, to},
+		Form:              form,
+		Lemma:             form,
+		CPOS:              CPOS,
+		POS:               CPOS,
+		Features:          make(map[string]string),
+		FeatureStr:        "_",
+	}}
+}
+
+func TestResultProbabilities(t *testing.T) {
+	probs := ResultProbabilities([]float64{30, 20, 10}, 1, 10)
+	var sum float64
+	for _, prob := range probs {
+		sum += prob
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("Got probabilities %v summing to %v, expected 1", probs, sum)
+	}
+	if !(probs[0] > probs[1] && probs[1] > probs[2]) {
+		t.Errorf("Got probabilities %v, expected them ordered by score", probs)
+	}
+	if ratio := probs[1] / probs[0]; math.Abs(ratio-math.Exp(-1)) > 1e-9 {
+		t.Errorf("Got ratio %v of results a temperature apart, expected e^-1", ratio)
+	}
+
+	// scores are normalized by the sentence length, not by their offset
+	long := ResultProbabilities([]float64{3000150, 3000100, 3000050}, 5, 10)
+	for i := range probs {
+		if math.Abs(long[i]-probs[i]) > 1e-9 {
+			t.Errorf("Got probabilities %v for a sentence of 5 tokens, expected %v of the same margin per token", long, probs)
+			break
+		}
+	}
+
+	if def, zero := ResultProbabilities([]float64{2, 1}, 1, DefaultConfidenceTemperature), ResultProbabilities([]float64{2, 1}, 1, 0); def[0] != zero[0] {
+		t.Errorf("Got %v for temperature 0, expected the default temperature %v", zero, def)
+	}
+	if cold, hot := ResultProbabilities([]float64{2, 1}, 1, 0.1), ResultProbabilities([]float64{2, 1}, 1, 10); cold[0] <= hot[0] {
+		t.Errorf("Got best %v at a low temperature and %v at a high one, expected it sharper when colder", cold[0], hot[0])
+	}
+	for _, prob := range ResultProbabilities([]float64{5, 5, 5, 5}, 1, 1) {
+		if math.Abs(prob-0.25) > 1e-9 {
+			t.Errorf("Got probability %v for equal scores, expected 0.25", prob)
+		}
+	}
+	if probs := ResultProbabilities(nil, 1, 1); len(probs) != 0 {
+		t.Errorf("Got %v for no scores, expected none", probs)
+	}
+}
+
+func TestResultProbabilitiesMargin(t *testing.T) {
+	narrow := ResultProbabilities([]float64{10, 9}, 2, 1)
+	wide := ResultProbabilities([]float64{10, 0}, 2, 1)
+	if wide[0] <= narrow[0] {
+		t.Errorf("Got best %v for a wide margin and %v for a narrow one, expected higher confidence with a wider margin", wide[0], narrow[0])
+	}
+	if math.Abs(narrow[0]-1/(1+math.Exp(-0.5))) > 1e-9 {
+		t.Errorf("Got best %v for a margin of 0.5 per token, expected %v", narrow[0], 1/(1+math.Exp(-0.5)))
+	}
+}
+
+func testMDConfig(mappings ...nlp.Spellout) *MDConfig {
+	conf := &MDConfig{Mappings: make(nlp.Mappings, len(mappings))}
+	for i, spellout := range mappings {
+		conf.Mappings[i] = &nlp.Mapping{Token: nlp.Token("token"), Spellout: spellout}
+	}
+	return conf
+}
+
+func TestSetConfidence(t *testing.T) {
+	var (
+		h     = testMorph(0, 0, 1, "ה", "DEF")
+		bait  = testMorph(1, 1, 2, "בית", "NN")
+		habit = testMorph(2, 0, 2, "הבית", "NNP")
+		big   = testMorph(0, 2, 3, "גדול", "JJ")
+	)
+	result := &search.KBestResult{
+		Configurations: []Configuration{
+			testMDConfig(nlp.Spellout{h, bait}, nlp.Spellout{big}),
+			testMDConfig(nlp.Spellout{habit}, nlp.Spellout{big}),
+			testMDConfig(nlp.Spellout{h, bait.Copy()}, nlp.Spellout{big}),
+		},
+		Scores: []float64{3, 2, 1},
+	}
+	shared := result.Configurations[0].(*MDConfig).Mappings[0]
+	probs := ResultProbabilities(result.Scores, 2, 1)
+	best := SetConfidence(result, 1)
+	if best != result.Configurations[0] {
+		t.Fatal("Expected the best result to be returned")
+	}
+	if shared.MorphConfidence != nil {
+		t.Error("Expected the mappings of the beam to be left as they are")
+	}
+	first, second := best.Mappings[0], best.Mappings[1]
+	if expected := probs[0] + probs[2]; math.Abs(first.Confidence-expected) > 1e-9 {
+		t.Errorf("Got confidence %v for the first token, expected %v of the results agreeing with it", first.Confidence, expected)
+	}
+	for i, conf := range first.MorphConfidence {
+		if expected := probs[0] + probs[2]; math.Abs(conf-expected) > 1e-9 {
+			t.Errorf("Got confidence %v for morpheme %d of the first token, expected %v", conf, i, expected)
+		}
+	}
+	if math.Abs(second.Confidence-1) > 1e-9 || len(second.MorphConfidence) != 1 || math.Abs(second.MorphConfidence[0]-1) > 1e-9 {
+		t.Errorf("Got confidence %v %v for the second token, expected 1 for all agreeing results", second.Confidence, second.MorphConfidence)
+	}
+
+	if SetConfidence(&search.KBestResult{}, 1) != nil {
+		t.Error("Expected no best result without results")
+	}
+}
+
+func testScored(conf *MDConfig, score int64, terminal bool) *search.ScoredConfiguration {
+	conf.LatticeQueue = NewQueueSlice(1)
+	if !terminal {
+		conf.LatticeQueue.Enqueue(0)
+	}
+	return &search.ScoredConfiguration{
+		C:              conf,
+		InternalScores: search.ScoreState{{Total: score, Number: 1}},
+		Expanded:       true,
+	}
+}
+
+func TestBestK(t *testing.T) {
+	var (
+		h     = testMorph(0, 0, 1, "ה", "DEF")
+		bait  = testMorph(1, 1, 2, "בית", "NN")
+		habit = testMorph(2, 0, 2, "הבית", "NNP")
+		verb  = testMorph(3, 1, 2, "בית", "VB")
+	)
+	agenda := &search.BaseAgenda{Confs: []*search.ScoredConfiguration{
+		testScored(testMDConfig(nlp.Spellout{habit}), 2, true),
+		testScored(testMDConfig(nlp.Spellout{h, verb}), 5, false),
+		testScored(testMDConfig(nlp.Spellout{h, bait}), 3, true),
+		// the same result by other transitions
+		testScored(testMDConfig(nlp.Spellout{h, bait.Copy()}), 2, true),
+		testScored(testMDConfig(nlp.Spellout{h, verb}), 1, true),
+	}}
+	beam := &search.Beam{}
+	best := beam.BestK(agenda, 3)
+	expected := []nlp.Spellout{{h, bait}, {habit}, {h, verb}}
+	if len(best) != len(expected) {
+		t.Fatalf("Got %d results, expected %d distinct terminal results", len(best), len(expected))
+	}
+	for i, candidate := range best {
+		scored := candidate.(*search.ScoredConfiguration)
+		if spellout := scored.C.(*MDConfig).Mappings[0].Spellout; !spellout.Equal(expected[i]) {
+			t.Errorf("Rank %d: got %v, expected %v", i+1, spellout, expected[i])
+		}
+		if i > 0 && scored.Score() > best[i-1].Score() {
+			t.Errorf("Rank %d: got score %v above the previous %v", i+1, scored.Score(), best[i-1].Score())
+		}
+	}
+	if best := beam.BestK(agenda, 1); len(best) != 1 || best[0].Score() != 3 {
+		t.Errorf("Got %v, expected only the best terminal result", best)
+	}
+}
+
+func TestKBestLattice(t *testing.T) {
+	var (
+		h     = testMorph(0, 0, 1, "ה", "DEF")
+		bait  = testMorph(1, 1, 2, "בית", "NN")
+		habit = testMorph(2, 0, 2, "הבית", "NNP")
+		verb  = testMorph(3, 1, 2, "בית", "VB")
+		big   = testMorph(0, 2, 3, "גדול", "JJ")
+	)
+	lattices := nlp.LatticeSentence{
+		{Token: "הבית", Morphemes: nlp.Morphemes{h, bait, habit, verb}, BottomId: 0, TopId: 2},
+		{Token: "גדול", Morphemes: nlp.Morphemes{big}, BottomId: 2, TopId: 3},
+	}
+	first, second := testMDConfig(nlp.Spellout{h, bait}, nlp.Spellout{big}), testMDConfig(nlp.Spellout{habit}, nlp.Spellout{big})
+	first.Lattices, second.Lattices = lattices, lattices
+	pruned := KBestLattice(&search.KBestResult{Configurations: []Configuration{first, second}, Scores: []float64{2, 1}})
+	if len(pruned) != len(lattices) {
+		t.Fatalf("Got %d lattices, expected %d", len(pruned), len(lattices))
+	}
+	expected := []nlp.Morphemes{{h, bait, habit}, {big}}
+	for i, lat := range pruned {
+		if lat.Token != lattices[i].Token || lat.BottomId != lattices[i].BottomId || lat.TopId != lattices[i].TopId {
+			t.Errorf("Lattice %d: got %v %d-%d, expected the original token and nodes", i, lat.Token, lat.BottomId, lat.TopId)
+		}
+		if len(lat.Morphemes) != len(expected[i]) {
+			t.Errorf("Lattice %d: got morphemes %v, expected %v", i, lat.Morphemes, expected[i])
+			continue
+		}
+		for j, morph := range lat.Morphemes {
+			if morph != expected[i][j] {
+				t.Errorf("Lattice %d: got morphemes %v, expected %v", i, lat.Morphemes, expected[i])
+				break
+			}
+		}
+	}
+	if len(lattices[0].Morphemes) != 4 {
+		t.Error("Expected the original lattices to be left as they are")
+	}
+	if KBestLattice(&search.KBestResult{}) != nil {
+		t.Error("Expected no lattices without results")
+	}
+}
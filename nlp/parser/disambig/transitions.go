@@ -21,12 +21,20 @@ type MDTrans struct {
 	Transitions *util.EnumSet
 	oracle      Oracle
 
-	Log    bool
-	UsePOP bool
+	Log     bool
+	UsePOP  bool
+	Options *MDOptions
 }
 
 var _ TransitionSystem = &MDTrans{}
 
+func (t *MDTrans) lemmas() bool {
+	if t.Options != nil {
+		return t.Options.Lemmas
+	}
+	return LEMMAS
+}
+
 func (t *MDTrans) Transition(from Configuration, transition Transition) Configuration {
 	c := from.Copy().(*MDConfig)
 
@@ -85,7 +93,9 @@ func (t *MDTrans) Transition(from Configuration, transition Transition) Configur
 		if TSAllOut || t.Log {
 			log.Println("\tComparing morpheme param val", t.ParamFunc(morph), "to", paramStr, t.ParamFunc(morph) == paramStr)
 		}
-		if t.ParamFunc(morph) == paramStr {
+		// arcs of the same param value may end at different nodes, follow
+		// only those allowed by the fixed spellout (if any)
+		if t.ParamFunc(morph) == paramStr && FixedAllows(c, qTop, morph, t.ParamFunc) {
 			if foundMorph == nil {
 				// log.Println("\t\tSetting morph", morph)
 				c.SetLastTransition(transition)
@@ -102,7 +112,7 @@ func (t *MDTrans) Transition(from Configuration, transition Transition) Configur
 		}
 	}
 	if foundMorph != nil {
-		if LEMMAS && ambLemmas != nil && len(ambLemmas) > 1 {
+		if t.lemmas() && ambLemmas != nil && len(ambLemmas) > 1 {
 			if TSAllOut || t.Log {
 				log.Println("Add lemma ambiguity", ambLemmas)
 			}
@@ -137,8 +147,19 @@ func (t *MDTrans) possibleTransitions(conf *MDConfig, transitions chan int) {
 			panic("Can't choose lemma if no lattices are in the queue")
 		}
 		latticeMorphemes := conf.Lattices[currentLat].Morphemes
+		// a fixed lemma missing from the ambiguous lemmas constrains nothing
+		allowAll := true
+		for _, m := range conf.Lemmas {
+			if FixedAllowsLemma(conf, currentLat, latticeMorphemes[m].Lemma) {
+				allowAll = false
+				break
+			}
+		}
 		for _, m := range conf.Lemmas {
 			morph = latticeMorphemes[m]
+			if !allowAll && !FixedAllowsLemma(conf, currentLat, morph.Lemma) {
+				continue
+			}
 			transition, _ = t.Transitions.Add(morph.Lemma)
 			transitions <- transition
 		}
@@ -156,6 +177,9 @@ func (t *MDTrans) possibleTransitions(conf *MDConfig, transitions chan int) {
 					log.Println("\t\tpossible transitions", nextList)
 				}
 				for _, next := range nextList {
+					if !FixedAllows(conf, qTop, lat.Morphemes[next], t.ParamFunc) {
+						continue
+					}
 					transition, _ = t.Transitions.Add(t.ParamFunc(lat.Morphemes[next]))
 					transitions <- transition
 				}
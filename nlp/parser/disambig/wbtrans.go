@@ -90,6 +90,9 @@ func (t *MDWBTrans) possibleTransitions(from Configuration, transitions chan int
 		if qExists {
 			lat := conf.Lattices[qTop]
 			for _, s := range lat.Spellouts {
+				if !FixedAllowsSpellout(&lat, s, t.ParamFunc) {
+					continue
+				}
 				transition, _ = t.Transitions.Add(ProjectSpellout(s, t.ParamFunc))
 				transitions <- transition
 			}
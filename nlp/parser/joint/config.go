@@ -29,6 +29,7 @@ var (
 	_ search.Aligned              = &JointConfig{}
 	_ dep.DependencyConfiguration = &JointConfig{}
 	_ nlp.DependencyGraph         = &JointConfig{}
+	_ search.Distinct             = &JointConfig{}
 	_ nlp.MorphDependencyGraph    = &JointConfig{}
 )
 
@@ -148,6 +149,22 @@ func (c *JointConfig) Equal(otherEq util.Equaler) bool {
 	panic("Can't equal to non-Joint config")
 }
 
+// jointResult is the mappings and the tree of a joint configuration
+type jointResult struct {
+	mappings, tree util.Equaler
+}
+
+func (r *jointResult) Equal(otherEq util.Equaler) bool {
+	other, ok := otherEq.(*jointResult)
+	return ok && r.mappings.Equal(other.mappings) && r.tree.Equal(other.tree)
+}
+
+// Result returns the mappings and tree of the configuration, for distinct
+// k-best results
+func (c *JointConfig) Result() util.Equaler {
+	return &jointResult{c.MDConfig.Result(), c.SimpleConfiguration.Result()}
+}
+
 func (c *JointConfig) Address(location []byte, offset int) (nodeID int, exists bool, isGenerator bool) {
 	if location[0] == 'M' || location[0] == 'L' {
 		return c.MDConfig.Address(location, offset)
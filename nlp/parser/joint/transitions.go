@@ -72,6 +72,10 @@ func (t *JointTrans) Transition(from Configuration, transition Transition) Confi
 				curMorpheme.Form,
 				curMorpheme.Lemma,
 				curMorpheme.POS,
+				TokenRange{},
+			}
+			if latIdx := curMorpheme.TokenID - 1; latIdx >= 0 && latIdx < len(c.MDConfig.Lattices) {
+				newNode.Range = c.MDConfig.Lattices[latIdx].Range
 			}
 
 			c.SimpleConfiguration.Nodes = append(c.SimpleConfiguration.Nodes,
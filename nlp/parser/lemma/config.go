@@ -0,0 +1,319 @@
+// Package lemma chooses the lemmas of disambiguated morphemes, as a
+// transition system deciding the lemma of each morpheme in turn
+package lemma
+
+import (
+	. "yap/alg/transition"
+	"yap/nlp/parser/ma"
+	nlp "yap/nlp/types"
+	"yap/util"
+
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const SUFFIX_SIZE = 2
+
+// Instance is a disambiguated sentence whose lemmas are chosen among the
+// lemmas of the morphemes of its lattices sharing the form, POS and
+// features of the disambiguated morphemes
+type Instance struct {
+	Mappings nlp.Mappings
+	Lattices nlp.LatticeSentence
+}
+
+func (i *Instance) Equal(otherEq util.Equaler) bool {
+	other, ok := otherEq.(*Instance)
+	return ok && i.Mappings.Equal(other.Mappings)
+}
+
+// Lemmas are the lemmas of the morphemes of a disambiguation, in order
+type Lemmas []string
+
+func (l Lemmas) Equal(otherEq util.Equaler) bool {
+	other, ok := otherEq.(Lemmas)
+	if !ok || len(l) != len(other) {
+		return false
+	}
+	for i, lemma := range l {
+		if lemma != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// GoldLemmas returns the lemmas of the morphemes of gold mappings
+func GoldLemmas(mappings nlp.Mappings) Lemmas {
+	lemmas := make(Lemmas, 0, len(mappings)*2)
+	for _, mapping := range mappings {
+		if mapping == nil {
+			continue
+		}
+		for _, morph := range mapping.Spellout {
+			if morph == nil {
+				continue
+			}
+			lemmas = append(lemmas, morph.Lemma)
+		}
+	}
+	return lemmas
+}
+
+func sameAnalysis(m, other *nlp.EMorpheme) bool {
+	return m.Form == other.Form && m.CPOS == other.CPOS && m.POS == other.POS && m.FeatureStr == other.FeatureStr
+}
+
+// Candidates returns the distinct lemmas of the morphemes of a lattice
+// with the form, POS and features of a morpheme (including the morpheme's
+// own lemma), sorted; a morpheme without any lemma is its own lemma
+func Candidates(lat *nlp.Lattice, morph *nlp.EMorpheme) []string {
+	seen := make(map[string]bool, 2)
+	candidates := make([]string, 0, 2)
+	add := func(lemma string) {
+		if len(lemma) > 0 && !seen[lemma] {
+			seen[lemma] = true
+			candidates = append(candidates, lemma)
+		}
+	}
+	add(morph.Lemma)
+	if lat != nil {
+		for _, other := range lat.Morphemes {
+			if sameAnalysis(morph, other) {
+				add(other.Lemma)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return []string{morph.Form}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// Config is the lemmas chosen for the first morphemes of a disambiguation,
+// each lemma chosen by the rule deriving it from the form of its morpheme
+// (see ma.LemmaRule), so that choices generalize across forms
+type Config struct {
+	Mappings   nlp.Mappings
+	Morphemes  nlp.Morphemes
+	TokenIDs   []int
+	Candidates [][]string
+	Rules      [][]string
+	Lemmas     []string
+
+	Last             Transition
+	InternalPrevious Configuration
+	Transitions      *util.EnumSet
+}
+
+var _ Configuration = &Config{}
+
+func (c *Config) Init(abstractInstance interface{}) {
+	instance := abstractInstance.(*Instance)
+	c.Mappings = instance.Mappings
+	c.Morphemes = make(nlp.Morphemes, 0, len(instance.Mappings)*2)
+	c.TokenIDs = make([]int, 0, len(instance.Mappings)*2)
+	for i, mapping := range instance.Mappings {
+		if mapping == nil {
+			continue
+		}
+		for _, morph := range mapping.Spellout {
+			if morph == nil {
+				continue
+			}
+			c.Morphemes = append(c.Morphemes, morph)
+			c.TokenIDs = append(c.TokenIDs, i)
+		}
+	}
+	c.Candidates = make([][]string, len(c.Morphemes))
+	c.Rules = make([][]string, len(c.Morphemes))
+	for i, morph := range c.Morphemes {
+		var lat *nlp.Lattice
+		if c.TokenIDs[i] < len(instance.Lattices) {
+			lat = &instance.Lattices[c.TokenIDs[i]]
+		}
+		c.Candidates[i] = Candidates(lat, morph)
+		c.Rules[i] = make([]string, len(c.Candidates[i]))
+		for j, candidate := range c.Candidates[i] {
+			c.Rules[i][j] = ma.LemmaRule(morph.Form, candidate)
+		}
+	}
+	c.Lemmas = make([]string, 0, len(c.Morphemes))
+	c.Last = ConstTransition(0)
+}
+
+func (c *Config) Terminal() bool {
+	return len(c.Lemmas) == len(c.Morphemes)
+}
+
+func (c *Config) Copy() Configuration {
+	newConf := new(Config)
+	c.CopyTo(newConf)
+	return newConf
+}
+
+func (c *Config) CopyTo(target Configuration) {
+	newConf, ok := target.(*Config)
+	if !ok {
+		panic("Can't copy into non *lemma.Config")
+	}
+	// morphemes and candidates are read only, no need for copy
+	*newConf = *c
+	newConf.Lemmas = make([]string, len(c.Lemmas), util.Max(cap(c.Lemmas), len(c.Morphemes)))
+	copy(newConf.Lemmas, c.Lemmas)
+	newConf.InternalPrevious = c
+}
+
+func (c *Config) Clear() {
+	c.InternalPrevious = nil
+}
+
+func (c *Config) Len() int {
+	if c.InternalPrevious != nil {
+		return 1 + c.InternalPrevious.Len()
+	}
+	return 1
+}
+
+func (c *Config) Previous() Configuration {
+	return c.InternalPrevious
+}
+
+func (c *Config) SetPrevious(prev Configuration) {
+	c.InternalPrevious = prev
+}
+
+func (c *Config) GetSequence() ConfigurationSequence {
+	retval := make(ConfigurationSequence, 0, len(c.Morphemes)+1)
+	for cur := Configuration(c); cur != nil; cur = cur.Previous() {
+		retval = append(retval, cur)
+	}
+	return retval
+}
+
+func (c *Config) SetLastTransition(t Transition) {
+	c.Last = t
+}
+
+func (c *Config) GetLastTransition() Transition {
+	return c.Last
+}
+
+func (c *Config) String() string {
+	if len(c.Lemmas) == 0 {
+		return fmt.Sprintf("\t=>([],\t[%d])", len(c.Morphemes))
+	}
+	last := len(c.Lemmas) - 1
+	return fmt.Sprintf("LEMMA\t=>([%s/%s],\t[%d])", c.Morphemes[last].Form, c.Lemmas[last], len(c.Morphemes)-len(c.Lemmas))
+}
+
+func (c *Config) Equal(otherEq util.Equaler) bool {
+	other, ok := otherEq.(*Config)
+	if !ok || other == nil {
+		return false
+	}
+	if !c.Last.Equal(other.Last) {
+		return false
+	}
+	if c.InternalPrevious == nil || other.InternalPrevious == nil {
+		return c.InternalPrevious == nil && other.InternalPrevious == nil
+	}
+	return c.InternalPrevious.Equal(other.InternalPrevious)
+}
+
+func (c *Config) State() byte {
+	return 'L'
+}
+
+func (c *Config) Assignment() uint16 {
+	return uint16(len(c.Lemmas))
+}
+
+// Address returns the morpheme at an offset from the next morpheme to be
+// lemmatized (M0), negative offsets are of lemmatized morphemes
+func (c *Config) Address(location []byte, offset int) (int, bool, bool) {
+	if location[0] != 'M' {
+		return 0, false, false
+	}
+	nodeID := len(c.Lemmas) + offset
+	if nodeID < 0 || nodeID >= len(c.Morphemes) {
+		return 0, false, false
+	}
+	return nodeID, true, false
+}
+
+func (c *Config) GenerateAddresses(nodeID int, location []byte) []int {
+	return nil
+}
+
+func (c *Config) Attribute(source byte, nodeID int, attribute []byte, transitions []int) (interface{}, bool, bool) {
+	if source != 'M' || nodeID >= len(c.Morphemes) {
+		return nil, false, false
+	}
+	morph := c.Morphemes[nodeID]
+	switch attribute[0] {
+	case 'm': // form
+		return morph.Form, true, false
+	case 'p': // POS
+		return morph.CPOS, true, false
+	case 'f': // features
+		return morph.FeatureStr, true, false
+	case 't': // token
+		return string(c.Mappings[c.TokenIDs[nodeID]].Token), true, false
+	case 's': // suffix of the form
+		runes := []rune(morph.Form)
+		return string(runes[len(runes)-util.Min(SUFFIX_SIZE, len(runes)):]), true, false
+	case 'c': // rules of the candidate lemmas
+		return strings.Join(c.Rules[nodeID], " "), true, false
+	case 'l': // chosen lemma
+		if nodeID < len(c.Lemmas) {
+			return c.Lemmas[nodeID], true, false
+		}
+	case 'r': // rule of the chosen lemma
+		if nodeID < len(c.Lemmas) {
+			return ma.LemmaRule(morph.Form, c.Lemmas[nodeID]), true, false
+		}
+	}
+	return nil, false, false
+}
+
+// Choose chooses the lemma of the next morpheme by its rule
+func (c *Config) Choose(rule string) {
+	i := len(c.Lemmas)
+	for j, candidate := range c.Rules[i] {
+		if candidate == rule {
+			c.Lemmas = append(c.Lemmas, c.Candidates[i][j])
+			return
+		}
+	}
+	panic(fmt.Sprintf("Lemma rule %s is not of a candidate of %s (%v)", rule, c.Morphemes[i].Form, c.Candidates[i]))
+}
+
+// Result returns the mappings with the chosen lemmas, as copies of the
+// disambiguated morphemes
+func (c *Config) Result() nlp.Mappings {
+	result := make(nlp.Mappings, len(c.Mappings))
+	var i int
+	for j, mapping := range c.Mappings {
+		if mapping == nil {
+			continue
+		}
+		newMapping := *mapping
+		newMapping.Spellout = make(nlp.Spellout, len(mapping.Spellout))
+		for k, morph := range mapping.Spellout {
+			if morph == nil {
+				continue
+			}
+			newMorph := morph.Copy()
+			if i < len(c.Lemmas) {
+				newMorph.Lemma = c.Lemmas[i]
+			}
+			newMapping.Spellout[k] = newMorph
+			i++
+		}
+		result[j] = &newMapping
+	}
+	return result
+}
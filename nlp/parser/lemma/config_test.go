@@ -0,0 +1,100 @@
+package lemma
+
+import (
+	"yap/alg/transition"
+	nlp "yap/nlp/types"
+	"yap/util"
+
+	"reflect"
+	"testing"
+)
+
+func testMorph(form, lemma, CPOS string) *nlp.EMorpheme {
+	return &nlp.EMorpheme{Morpheme: nlp.Morpheme{Form: form, Lemma: lemma, CPOS: CPOS, POS: CPOS, FeatureStr: "_"}}
+}
+
+func testInstance() *Instance {
+	lats := nlp.LatticeSentence{
+		nlp.Lattice{Token: "ילדים", Morphemes: nlp.Morphemes{
+			testMorph("ילדים", "ילד", "NN"),
+			testMorph("ילדים", "ילדה", "NN"),
+			testMorph("ילדים", "ילד", "VB"),
+		}},
+		nlp.Lattice{Token: "רצו", Morphemes: nlp.Morphemes{
+			testMorph("רצו", "רץ", "VB"),
+		}},
+	}
+	mappings := nlp.Mappings{
+		&nlp.Mapping{Token: "ילדים", Spellout: nlp.Spellout{testMorph("ילדים", "ילד", "NN")}},
+		&nlp.Mapping{Token: "רצו", Spellout: nlp.Spellout{testMorph("רצו", "", "VB")}},
+	}
+	return &Instance{Mappings: mappings, Lattices: lats}
+}
+
+func TestCandidates(t *testing.T) {
+	instance := testInstance()
+	if candidates := Candidates(&instance.Lattices[0], instance.Mappings[0].Spellout[0]); !reflect.DeepEqual(candidates, []string{"ילד", "ילדה"}) {
+		t.Errorf("Got candidates %v, expected the lemmas of the NN analyses", candidates)
+	}
+	if candidates := Candidates(nil, testMorph("רצו", "", "VB")); !reflect.DeepEqual(candidates, []string{"רצו"}) {
+		t.Errorf("Got candidates %v, expected the form", candidates)
+	}
+}
+
+func TestOracleSequence(t *testing.T) {
+	instance := testInstance()
+	enum := util.NewEnumSet(10, "ELemma")
+	trans := &LemmaTrans{Transitions: enum}
+	trans.AddDefaultOracle()
+	gold := Lemmas{"ילדה", "רץ"}
+	trans.Oracle().SetGold(gold)
+
+	var c transition.Configuration = &Config{Transitions: enum}
+	c.Init(instance)
+	for !c.Terminal() {
+		_, possible := trans.GetTransitions(c)
+		oracleTrans := trans.Oracle().Transition(c)
+		var found bool
+		for _, val := range possible {
+			found = found || val == oracleTrans.Value()
+		}
+		if !found {
+			t.Fatalf("Oracle transition %v is not one of %v", oracleTrans, possible)
+		}
+		c = trans.Transition(c, oracleTrans)
+	}
+	conf := c.(*Config)
+	if !gold.Equal(Lemmas(conf.Lemmas)) {
+		t.Errorf("Got lemmas %v, expected %v", conf.Lemmas, gold)
+	}
+	result := conf.Result()
+	if result[0].Spellout[0].Lemma != "ילדה" || result[1].Spellout[0].Lemma != "רץ" {
+		t.Errorf("Got result %v, expected the chosen lemmas", result)
+	}
+	if instance.Mappings[0].Spellout[0].Lemma != "ילד" {
+		t.Errorf("Result changed the disambiguated morphemes")
+	}
+
+	evaluation := Evaluate(conf, nlp.Mappings{
+		&nlp.Mapping{Spellout: nlp.Spellout{testMorph("ילדים", "ילד", "NN")}},
+		&nlp.Mapping{Spellout: nlp.Spellout{testMorph("רצו", "רץ", "VB")}},
+	})
+	if evaluation.TP != 1 || evaluation.FP != 1 {
+		t.Errorf("Got TP %d FP %d, expected 1 and 1", evaluation.TP, evaluation.FP)
+	}
+}
+
+func TestGoldLemmas(t *testing.T) {
+	instance := testInstance()
+	instance.Mappings[0].Spellout = append(instance.Mappings[0].Spellout, nil)
+	instance.Mappings = append(instance.Mappings, nil)
+	lemmas := GoldLemmas(instance.Mappings)
+	if !lemmas.Equal(Lemmas{"ילד", ""}) {
+		t.Errorf("Got gold lemmas %v, expected the lemmas of the morphemes", lemmas)
+	}
+	conf := &Config{Transitions: util.NewEnumSet(10, "ELemma")}
+	conf.Init(instance)
+	if len(lemmas) != len(conf.Morphemes) {
+		t.Errorf("Got %d gold lemmas, expected one for each of the %d morphemes of the configuration", len(lemmas), len(conf.Morphemes))
+	}
+}
@@ -0,0 +1,53 @@
+package lemma
+
+import (
+	"yap/eval"
+	nlp "yap/nlp/types"
+)
+
+// Evaluate compares the chosen lemmas to the gold lemmas of the morphemes
+// matching the gold morphemes by form and POS; chosen lemmas are counted as
+// TP when correct and FP otherwise, Other holds the same counts for morphemes
+// with more than one candidate lemma
+func Evaluate(test *Config, gold nlp.Mappings) *eval.Result {
+	ambiguous := &eval.Result{}
+	retval := &eval.Result{Other: ambiguous}
+	var i int
+	for j, mapping := range test.Mappings {
+		if mapping == nil {
+			continue
+		}
+		var goldSpellout nlp.Spellout
+		if j < len(gold) && gold[j] != nil {
+			goldSpellout = gold[j].Spellout
+		}
+		for k, morph := range mapping.Spellout {
+			if morph == nil {
+				continue
+			}
+			cur := i
+			i++
+			if cur >= len(test.Lemmas) || k >= len(goldSpellout) {
+				continue
+			}
+			goldMorph := goldSpellout[k]
+			if goldMorph.Form != morph.Form || goldMorph.CPOS != morph.CPOS || len(goldMorph.Lemma) == 0 {
+				continue
+			}
+			correct := test.Lemmas[cur] == goldMorph.Lemma
+			if correct {
+				retval.TP++
+			} else {
+				retval.FP++
+			}
+			if len(test.Candidates[cur]) > 1 {
+				if correct {
+					ambiguous.TP++
+				} else {
+					ambiguous.FP++
+				}
+			}
+		}
+	}
+	return retval
+}
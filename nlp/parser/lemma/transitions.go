@@ -0,0 +1,117 @@
+package lemma
+
+import (
+	. "yap/alg/transition"
+	"yap/util"
+
+	"fmt"
+	"log"
+)
+
+// LemmaTrans chooses the lemma of the next morpheme, transitions are the
+// rules deriving the candidate lemmas from the morpheme's form
+type LemmaTrans struct {
+	Transitions *util.EnumSet
+
+	oracle Oracle
+	Log    bool
+}
+
+var _ TransitionSystem = &LemmaTrans{}
+
+func (t *LemmaTrans) Transition(from Configuration, transition Transition) Configuration {
+	c := from.Copy().(*Config)
+	rule := t.Transitions.ValueOf(transition.Value()).(string)
+	if t.Log {
+		log.Println("Choosing lemma rule", rule)
+	}
+	c.SetLastTransition(transition)
+	c.Choose(rule)
+	return c
+}
+
+func (t *LemmaTrans) TransitionTypes() []string {
+	return []string{"LEMMA:L-*"}
+}
+
+func (t *LemmaTrans) possibleTransitions(conf *Config, transitions chan int) {
+	if !conf.Terminal() {
+		for _, rule := range conf.Rules[len(conf.Lemmas)] {
+			transition, _ := t.Transitions.Add(rule)
+			transitions <- transition
+		}
+	}
+	close(transitions)
+}
+
+func (t *LemmaTrans) GetTransitions(from Configuration) (byte, []int) {
+	retval := make([]int, 0, 4)
+	tType, transitions := t.YieldTransitions(from)
+	for transition := range transitions {
+		retval = append(retval, transition)
+	}
+	return tType, retval
+}
+
+func (t *LemmaTrans) YieldTransitions(c Configuration) (byte, chan int) {
+	conf, ok := c.(*Config)
+	if !ok {
+		panic("Got wrong configuration type")
+	}
+	transitions := make(chan int)
+	go t.possibleTransitions(conf, transitions)
+	return conf.State(), transitions
+}
+
+func (t *LemmaTrans) Oracle() Oracle {
+	return t.oracle
+}
+
+func (t *LemmaTrans) AddDefaultOracle() {
+	t.oracle = &LemmaOracle{Transitions: t.Transitions}
+}
+
+func (t *LemmaTrans) Name() string {
+	return "Lemma Disambiguator"
+}
+
+// LemmaOracle chooses the gold lemma of each morpheme, or the first
+// candidate for morphemes without a gold lemma
+type LemmaOracle struct {
+	Transitions *util.EnumSet
+	gold        Lemmas
+}
+
+var _ Decision = &LemmaOracle{}
+
+func (o *LemmaOracle) SetGold(g interface{}) {
+	lemmas, ok := g.(Lemmas)
+	if !ok {
+		panic("Gold is not an array of lemmas")
+	}
+	o.gold = lemmas
+}
+
+func (o *LemmaOracle) Transition(conf Configuration) Transition {
+	c := conf.(*Config)
+	if o.gold == nil {
+		panic("Oracle needs gold reference, use SetGold")
+	}
+	i := len(c.Lemmas)
+	if i >= len(o.gold) {
+		panic(fmt.Sprintf("Gold has %d lemmas, configuration has more morphemes", len(o.gold)))
+	}
+	rule := c.Rules[i][0]
+	for j, candidate := range c.Candidates[i] {
+		if candidate == o.gold[i] {
+			rule = c.Rules[i][j]
+			break
+		}
+	}
+	transition, _ := o.Transitions.Add(rule)
+	return &TypedTransition{T: 'L', V: transition}
+}
+
+func (o *LemmaOracle) Name() string {
+	return "Lemma Oracle"
+}
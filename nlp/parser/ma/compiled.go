@@ -0,0 +1,165 @@
+package ma
+
+import (
+	. "yap/nlp/types"
+
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// CompiledLex is a binary form of the BGU prefix and lexicon files, as
+// written by lexcompile. Morphemes are interned, analyses refer to them by
+// index. Since the lexicon reader's settings (MA type, NNP features) are
+// applied when compiling, they are recorded to verify them when loading
+type CompiledLex struct {
+	MAType     string
+	NNPNoFeats bool
+	Morphemes  []Morpheme
+	Prefixes   map[string][][]int
+	Lex        map[string][][]int
+}
+
+type morphemeInterner struct {
+	morphemes []Morpheme
+	index     map[string]int
+}
+
+func morphemeKey(m *Morpheme) string {
+	feats := make([]string, 0, len(m.Features))
+	for k, v := range m.Features {
+		feats = append(feats, k+"="+v)
+	}
+	sort.Strings(feats)
+	return fmt.Sprintf("%v\t%s\t%s\t%s\t%s\t%d\t%s\t%s", m.BasicDirectedEdge, m.Form, m.Lemma, m.CPOS, m.POS, m.TokenID, m.FeatureStr, strings.Join(feats, "|"))
+}
+
+func (i *morphemeInterner) analyses(tokens map[string][]BasicMorphemes) map[string][][]int {
+	compiled := make(map[string][][]int, len(tokens))
+	for token, analyses := range tokens {
+		indices := make([][]int, len(analyses))
+		for j, analysis := range analyses {
+			indices[j] = make([]int, len(analysis))
+			for k, morph := range analysis {
+				key := morphemeKey(morph)
+				id, exists := i.index[key]
+				if !exists {
+					id = len(i.morphemes)
+					i.morphemes = append(i.morphemes, *morph)
+					i.index[key] = id
+				}
+				indices[j][k] = id
+			}
+		}
+		compiled[token] = indices
+	}
+	return compiled
+}
+
+// Compile returns the binary form of the loaded prefixes and lexicon
+func (l *BGULex) Compile(nnpnofeats bool) *CompiledLex {
+	interner := &morphemeInterner{index: make(map[string]int)}
+	compiled := &CompiledLex{
+		MAType:     l.MAType,
+		NNPNoFeats: nnpnofeats,
+		Prefixes:   interner.analyses(l.Prefixes),
+		Lex:        interner.analyses(l.Lex),
+	}
+	compiled.Morphemes = interner.morphemes
+	return compiled
+}
+
+// tokens expands compiled analyses, sharing a single instance of each
+// interned morpheme
+func (c *CompiledLex) tokens(compiled map[string][][]int, morphemes BasicMorphemes) (map[string][]BasicMorphemes, error) {
+	tokens := make(map[string][]BasicMorphemes, len(compiled))
+	for token, indices := range compiled {
+		analyses := make([]BasicMorphemes, len(indices))
+		for j, analysis := range indices {
+			analyses[j] = make(BasicMorphemes, len(analysis))
+			for k, id := range analysis {
+				if id < 0 || id >= len(morphemes) {
+					return nil, errors.New(fmt.Sprintf("Token %s refers to unknown morpheme %d", token, id))
+				}
+				analyses[j][k] = morphemes[id]
+			}
+		}
+		tokens[token] = analyses
+	}
+	return tokens, nil
+}
+
+func (c *CompiledLex) Write(writer io.Writer) error {
+	enc := gob.NewEncoder(writer)
+	return enc.Encode(c)
+}
+
+func (c *CompiledLex) Read(r io.Reader) error {
+	dec := gob.NewDecoder(r)
+	return dec.Decode(c)
+}
+
+func (c *CompiledLex) WriteFile(filename string) error {
+	file, err := os.Create(filename)
+	defer file.Close()
+
+	if err != nil {
+		return err
+	}
+
+	return c.Write(file)
+}
+
+func (c *CompiledLex) ReadFile(filename string) error {
+	file, err := os.Open(filename)
+	defer file.Close()
+
+	if err != nil {
+		return err
+	}
+
+	return c.Read(file)
+}
+
+// LoadCompiled loads prefixes and lexicon from a file written by lexcompile,
+// in place of LoadPrefixes and LoadLex
+func (l *BGULex) LoadCompiled(file string, nnpnofeats bool) {
+	compiled := new(CompiledLex)
+	if err := compiled.ReadFile(file); err != nil {
+		panic(fmt.Sprintf("Failed to load %v: %v", file, err))
+	}
+	if len(l.MAType) > 0 && compiled.MAType != l.MAType {
+		log.Println("Warning: compiled lexicon", file, "is of type", compiled.MAType, "expected", l.MAType)
+	}
+	if compiled.NNPNoFeats != nnpnofeats {
+		log.Println("Warning: compiled lexicon", file, "was compiled with addnnpnofeats", compiled.NNPNoFeats)
+	}
+	if err := l.SetCompiled(compiled); err != nil {
+		panic(fmt.Sprintf("Failed to load %v: %v", file, err))
+	}
+	log.Println("Loaded", len(l.Prefixes), "prefixes and", len(l.Lex), "tokens from compiled lexicon", file, "with", len(compiled.Morphemes), "distinct morphemes")
+}
+
+// SetCompiled replaces the prefixes and lexicon with those of compiled
+func (l *BGULex) SetCompiled(compiled *CompiledLex) error {
+	morphemes := make(BasicMorphemes, len(compiled.Morphemes))
+	for i := range compiled.Morphemes {
+		morphemes[i] = &compiled.Morphemes[i]
+	}
+	prefixes, err := compiled.tokens(compiled.Prefixes, morphemes)
+	if err != nil {
+		return err
+	}
+	tokens, err := compiled.tokens(compiled.Lex, morphemes)
+	if err != nil {
+		return err
+	}
+	l.Prefixes, l.Lex = prefixes, tokens
+	l.setMaxPrefixLen()
+	return nil
+}
@@ -0,0 +1,81 @@
+package ma
+
+import (
+	"yap/alg/graph"
+	. "yap/nlp/types"
+
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestCompiledLexRoundTrip(t *testing.T) {
+	shared := makeMorphWithPOS("בית", "בית", "NN")
+	l := &BGULex{
+		Prefixes: map[string][]BasicMorphemes{
+			"ב":  testPrefix("ב"),
+			"ל":  testPrefix("ל"),
+			"וב": append(testPrefix("ו", "ב"), testPrefix("וב")...),
+		},
+		Lex: map[string][]BasicMorphemes{
+			"בית": shared,
+			// plene spelling, sharing the analysis
+			"ביית": shared,
+			"ביתו": []BasicMorphemes{BasicMorphemes{
+				&Morpheme{
+					BasicDirectedEdge: graph.BasicDirectedEdge{0, 0, 1},
+					Form:              "בית",
+					Lemma:             "בית",
+					CPOS:              "NN",
+					POS:               "NN",
+					FeatureStr:        "gen=M|num=S",
+					Features:          map[string]string{"gen": "M", "num": "S"},
+				},
+				&Morpheme{
+					BasicDirectedEdge: graph.BasicDirectedEdge{0, 1, 2},
+					Form:              "הוא",
+					Lemma:             "הוא",
+					CPOS:              "S_PRN",
+					POS:               "S_PRN",
+					FeatureStr:        "gen=M|num=S|per=3",
+					Features:          map[string]string{"gen": "M", "num": "S", "per": "3"},
+				},
+			}},
+		},
+		MAType: "spmrl",
+	}
+	l.setMaxPrefixLen()
+	compiled := l.Compile(false)
+	// the בית/NN morpheme of בית and ביית is stored once
+	if len(compiled.Morphemes) != 8 {
+		t.Errorf("Expected 8 distinct morphemes, got %d", len(compiled.Morphemes))
+	}
+	var buf bytes.Buffer
+	if err := compiled.Write(&buf); err != nil {
+		t.Fatalf("Failed writing compiled lexicon: %v", err)
+	}
+	read := new(CompiledLex)
+	if err := read.Read(&buf); err != nil {
+		t.Fatalf("Failed reading compiled lexicon: %v", err)
+	}
+	loaded := &BGULex{MAType: "spmrl"}
+	if err := loaded.SetCompiled(read); err != nil {
+		t.Fatalf("Failed loading compiled lexicon: %v", err)
+	}
+	if loaded.MaxPrefixLen != l.MaxPrefixLen {
+		t.Errorf("Expected max prefix length %d, got %d", l.MaxPrefixLen, loaded.MaxPrefixLen)
+	}
+	for _, token := range []string{"בית", "ביתו", "לבית", "וביתו", "ב-2010", "מחשב"} {
+		expected, expectedOOV := l.AnalyzeToken(token, 0, 0)
+		got, gotOOV := loaded.AnalyzeToken(token, 0, 0)
+		if expectedOOV.(bool) != gotOOV.(bool) {
+			t.Errorf("Token %s: expected OOV %v, got %v", token, expectedOOV, gotOOV)
+		}
+		if !reflect.DeepEqual(spellouts(expected), spellouts(got)) {
+			t.Errorf("Token %s: expected analyses %v, got %v", token, spellouts(expected), spellouts(got))
+		}
+		if !reflect.DeepEqual(expected.Morphemes, got.Morphemes) {
+			t.Errorf("Token %s: morphemes differ from the text lexicon", token)
+		}
+	}
+}
@@ -365,11 +365,25 @@ func (m *MADict) ReadFile(filename string) error {
 type AnalyzeStats struct {
 	TotalTokens, OOVTokens    int
 	UniqTokens, UniqOOVTokens map[string]int
+
+	// number of tokens changed by each normalization
+	NormalizedTokens int
+	Normalizations   map[Normalization]int
 }
 
 func (a *AnalyzeStats) Init() {
 	a.UniqTokens = make(map[string]int, 10000)
 	a.UniqOOVTokens = make(map[string]int, 1000)
+	a.Normalizations = make(map[Normalization]int, len(NORMALIZATION_NAMES))
+}
+
+func (a *AnalyzeStats) AddNormalization(fired Normalization) {
+	a.NormalizedTokens++
+	for _, norm := range NORMALIZATION_NAMES {
+		if fired&norm.Norm != 0 {
+			a.Normalizations[norm.Norm]++
+		}
+	}
 }
 
 func (a *AnalyzeStats) AddToken(token string) {
@@ -0,0 +1,181 @@
+package ma
+
+import (
+	. "yap/nlp/types"
+	"yap/util"
+
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+var (
+	UD_UPOS = map[string]bool{
+		"ADJ": true, "ADP": true, "ADV": true, "AUX": true, "CCONJ": true,
+		"DET": true, "INTJ": true, "NOUN": true, "NUM": true, "PART": true,
+		"PRON": true, "PROPN": true, "PUNCT": true, "SCONJ": true, "SYM": true,
+		"VERB": true, "X": true,
+	}
+	// tags of morphemes added by the lexicon reader rather than the lexicon
+	HEB2UD_EXTRA_POS = map[string]string{
+		"S_PRN": "PRON",
+	}
+)
+
+// ExportedMorpheme is a morpheme of a lexicon analysis, along with its POS
+// and features mapped to UD
+type ExportedMorpheme struct {
+	Form    string `json:"form"`
+	Lemma   string `json:"lemma"`
+	CPOS    string `json:"cpos"`
+	POS     string `json:"pos"`
+	Feats   string `json:"feats"`
+	UDPOS   string `json:"upos"`
+	UDFeats string `json:"ufeats"`
+}
+
+// ExportedToken is a token of a lexicon, prefix table or MADict with its
+// analyses, as written by lexexport
+type ExportedToken struct {
+	Source   string               `json:"source"`
+	Token    string               `json:"token"`
+	Analyses [][]ExportedMorpheme `json:"analyses"`
+}
+
+// UDTags maps the POS and features of a morpheme to UD, morphemes already
+// tagged with UD are kept as is, unknown tags are mapped to X
+func UDTags(morph *Morpheme) (string, string) {
+	if UD_UPOS[morph.CPOS] {
+		return morph.CPOS, morph.FeatureStr
+	}
+	var (
+		UDPOS, UDFeats string
+		feats          []string
+	)
+	if strings.HasPrefix(morph.CPOS, "yy") {
+		return "PUNCT", ""
+	} else if UDMSR, exists := util.HEB2UDPOS[morph.CPOS]; exists {
+		split := strings.SplitN(UDMSR, "-", 2)
+		UDPOS = split[0]
+		if len(split) > 1 {
+			UDFeats = split[1]
+		}
+	} else if prefixPOS, exists := util.HEB2UDPrefixPOS[morph.CPOS]; exists {
+		UDPOS = prefixPOS
+	} else if extraPOS, exists := HEB2UD_EXTRA_POS[morph.CPOS]; exists {
+		UDPOS = extraPOS
+	} else {
+		UDPOS = "X"
+	}
+	if len(morph.FeatureStr) > 0 && morph.FeatureStr != "_" {
+		for _, pair := range strings.Split(morph.FeatureStr, "|") {
+			if strings.HasPrefix(pair, "suf_") {
+				// features of suffixes folded into the host are kept
+				feats = append(feats, pair)
+			} else if udPair, known := udFeature(pair); known && len(udPair) > 0 {
+				feats = append(feats, udPair)
+			}
+		}
+	}
+	featureStr, _ := util.MergeFeatureStrs(strings.Join(feats, "|"), UDFeats)
+	return UDPOS, featureStr
+}
+
+// udFeature converts a feature to UD as util.Heb2UDFeature, without
+// panicking on unknown features
+func udFeature(pair string) (string, bool) {
+	split := strings.Split(pair, "=")
+	if len(split) != 2 {
+		return "", false
+	}
+	switch pair {
+	case "tense=BEINONI", "type=TOINFINITIVE", "tense=IMPERATIVE":
+		return util.Heb2UDFeature(pair), true
+	}
+	if split[0] == "binyan" {
+		return util.Heb2UDFeature(pair), true
+	}
+	lookup, exists := util.HEB2UDFeatureNameLookup[split[0]]
+	if !exists {
+		return "", false
+	}
+	if _, exists := lookup.ValueMap[split[1]]; !exists {
+		return "", false
+	}
+	return util.Heb2UDFeature(pair), true
+}
+
+// ExportTokens returns the tokens of a lexicon, prefix table or MADict
+// data, sorted by token
+func ExportTokens(source string, tokens map[string][]BasicMorphemes) []ExportedToken {
+	keys := make([]string, 0, len(tokens))
+	for token := range tokens {
+		keys = append(keys, token)
+	}
+	sort.Strings(keys)
+	exported := make([]ExportedToken, len(keys))
+	for i, token := range keys {
+		analyses := tokens[token]
+		exported[i] = ExportedToken{Source: source, Token: token, Analyses: make([][]ExportedMorpheme, len(analyses))}
+		for j, analysis := range analyses {
+			morphs := make([]ExportedMorpheme, len(analysis))
+			for k, morph := range analysis {
+				UDPOS, UDFeats := UDTags(morph)
+				morphs[k] = ExportedMorpheme{
+					Form:    morph.Form,
+					Lemma:   morph.Lemma,
+					CPOS:    morph.CPOS,
+					POS:     morph.POS,
+					Feats:   morph.FeatureStr,
+					UDPOS:   UDPOS,
+					UDFeats: UDFeats,
+				}
+			}
+			exported[i].Analyses[j] = morphs
+		}
+	}
+	return exported
+}
+
+func tsvField(value string) string {
+	if len(value) == 0 {
+		return "_"
+	}
+	return value
+}
+
+// WriteExportedTSV writes exported tokens with a line per morpheme of each
+// analysis, with a header line
+func WriteExportedTSV(writer io.Writer, tokens []ExportedToken, header bool) error {
+	if header {
+		if _, err := writer.Write([]byte("source\ttoken\tanalysis\tmorpheme\tform\tlemma\tcpos\tpos\tfeats\tupos\tufeats\n")); err != nil {
+			return err
+		}
+	}
+	for _, token := range tokens {
+		for i, analysis := range token.Analyses {
+			for j, morph := range analysis {
+				line := strings.Join([]string{
+					token.Source, token.Token, fmt.Sprintf("%d", i+1), fmt.Sprintf("%d", j+1),
+					tsvField(morph.Form), tsvField(morph.Lemma), tsvField(morph.CPOS), tsvField(morph.POS),
+					tsvField(morph.Feats), tsvField(morph.UDPOS), tsvField(morph.UDFeats),
+				}, "\t")
+				if _, err := writer.Write([]byte(line + "\n")); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// WriteExportedJSON writes exported tokens as a JSON array
+func WriteExportedJSON(writer io.Writer, tokens []ExportedToken) error {
+	if tokens == nil {
+		tokens = []ExportedToken{}
+	}
+	enc := json.NewEncoder(writer)
+	return enc.Encode(tokens)
+}
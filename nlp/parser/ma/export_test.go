@@ -0,0 +1,46 @@
+package ma
+
+import (
+	. "yap/nlp/types"
+
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestUDTags(t *testing.T) {
+	cases := []struct {
+		Morph          *Morpheme
+		UDPOS, UDFeats string
+	}{
+		{&Morpheme{CPOS: "NN", FeatureStr: "gen=M|num=S"}, "NOUN", "Gender=Masc|Number=Sing"},
+		{&Morpheme{CPOS: "NOUN", FeatureStr: "Gender=Masc"}, "NOUN", "Gender=Masc"},
+		{&Morpheme{CPOS: "yyDOT", FeatureStr: "_"}, "PUNCT", ""},
+		{&Morpheme{CPOS: "ZZ", FeatureStr: "gen=Q"}, "X", ""},
+	}
+	for _, c := range cases {
+		UDPOS, UDFeats := UDTags(c.Morph)
+		if UDPOS != c.UDPOS || UDFeats != c.UDFeats {
+			t.Errorf("%s %s: expected %s %s, got %s %s", c.Morph.CPOS, c.Morph.FeatureStr, c.UDPOS, c.UDFeats, UDPOS, UDFeats)
+		}
+	}
+}
+
+func TestExportTokens(t *testing.T) {
+	tokens := map[string][]BasicMorphemes{
+		"ספר": makeMorphWithPOS("ספר", "ספר", "NN"),
+		"בית": makeMorphWithPOS("בית", "בית", "NN"),
+	}
+	exported := ExportTokens("lexicon", tokens)
+	if len(exported) != 2 || exported[0].Token != "בית" || exported[1].Token != "ספר" {
+		t.Fatalf("Expected tokens sorted by token, got %v", exported)
+	}
+	var buf bytes.Buffer
+	if err := WriteExportedTSV(&buf, exported, true); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "lexicon\tבית\t1\t1\tבית\tבית\tNN\tNN\t") {
+		t.Errorf("Unexpected TSV export:\n%s", buf.String())
+	}
+}
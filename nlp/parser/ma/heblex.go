@@ -10,6 +10,7 @@ import (
 	"log"
 	"regexp"
 	"strings"
+	"unicode/utf8"
 )
 
 const ESTIMATED_MORPHS_PER_TOKEN = 5
@@ -26,6 +27,19 @@ type BGULex struct {
 	AlwaysNNP bool
 	LogOOV    bool
 	MAType    string
+
+	// if set, analyses of OOV hosts are guessed rather than OOVMSRS
+	OOVGuesser *OOVGuesser
+
+	// normalizations applied to tokens before lookup, the lattice
+	// keeps the token's original surface form
+	Normalize        Normalization
+	LogNormalization bool
+
+	// forms of merged user lexicons, mapped to whether they were
+	// in the lexicon, and the prefixes allowed before user analyses
+	userForms          map[string]bool
+	prefixRestrictions map[*Morpheme]map[string]bool
 }
 
 var (
@@ -60,6 +74,12 @@ var (
 		{regexp.MustCompile("^\\d+(\\.\\d+)?$|^\\d{1,3}(,\\d{3})*(\\.\\d+)?$"), "CD"},
 		{regexp.MustCompile("\\d"), "NCD"},
 	}
+	// hosts in Latin script, analyzed when following a prefix (בGoogle, ל-CNN)
+	LATIN_HOST       = regexp.MustCompile("^[A-Za-z][A-Za-z0-9&'.]*$")
+	FOREIGN_HOST_POS = "NNP"
+	// separators allowed between a prefix and its host, hyphen and maqaf
+	PREFIX_SEPARATORS = map[rune]bool{'-': true, '\u05BE': true}
+
 	_ MorphologicalAnalyzer = &BGULex{}
 )
 
@@ -88,13 +108,17 @@ func (l *BGULex) loadTokens(file, format string) {
 
 func (l *BGULex) LoadPrefixes(file string) {
 	l.loadTokens(file, "prefix")
+	l.setMaxPrefixLen()
+	log.Println("Loaded", len(l.Prefixes), "prefixes from lexicon")
+}
+
+func (l *BGULex) setMaxPrefixLen() {
 	l.MaxPrefixLen = 0
 	for _, morphs := range l.Prefixes {
 		if l.MaxPrefixLen < len(morphs) {
 			l.MaxPrefixLen = len(morphs)
 		}
 	}
-	log.Println("Loaded", len(l.Prefixes), "prefixes from lexicon")
 }
 
 func (l *BGULex) LoadLex(file string, nnpnofeats bool) {
@@ -116,7 +140,19 @@ func makeMorphWithPOS(input, lemma, POS string) []BasicMorphemes {
 	})}
 }
 
+// AddOOVAnalysis adds the analyses of a host missing from the lexicon,
+// guessed by the OOV guesser if there is one
 func (l *BGULex) AddOOVAnalysis(lat *Lattice, prefix BasicMorphemes, hostStr string, numToken int) {
+	if l.OOVGuesser != nil {
+		lat.AddAnalysis(prefix, l.OOVGuesser.Analyses(hostStr), numToken)
+		return
+	}
+	l.AddNNPAnalysis(lat, prefix, hostStr, numToken)
+}
+
+// AddNNPAnalysis adds the constant OOVMSRS analyses of a host, as added to
+// every host with AlwaysNNP
+func (l *BGULex) AddNNPAnalysis(lat *Lattice, prefix BasicMorphemes, hostStr string, numToken int) {
 	var OOVPOS, featuresStr string
 	for _, msr := range OOVMSRS {
 		// if logAnalyze {
@@ -153,6 +189,29 @@ func checkRegexes(input string) ([]BasicMorphemes, bool) {
 	return nil, false
 }
 
+// foreignHost returns an analysis of a host which is not in the lexicon,
+// but is written in Latin script (e.g. Google, CNN) and may follow a prefix
+func (l *BGULex) foreignHost(input string) ([]BasicMorphemes, bool) {
+	if !LATIN_HOST.MatchString(input) {
+		return nil, false
+	}
+	POS := FOREIGN_HOST_POS
+	if l.MAType == "ud" {
+		POS = util.HEB2UDPOS[POS]
+	}
+	return makeMorphWithPOS(input, input, POS), true
+}
+
+// splitPrefix splits the first prefixLen characters of input off as a
+// prefix, along with a maqaf or hyphen separating it from its host (ב-2010)
+func splitPrefix(input []rune, prefixLen int) (string, string) {
+	prefixStr, host := string(input[:prefixLen]), input[prefixLen:]
+	if len(host) > 1 && PREFIX_SEPARATORS[host[0]] {
+		host = host[1:]
+	}
+	return prefixStr, string(host)
+}
+
 var logAnalyze bool = false
 
 func (l *BGULex) OOVForLen(lat *Lattice, input string, startingNode, numToken, prefixLen int) bool {
@@ -160,15 +219,16 @@ func (l *BGULex) OOVForLen(lat *Lattice, input string, startingNode, numToken, p
 		found   bool
 		hostStr string
 	)
-	if len(input) < prefixLen*2 {
+	runes := []rune(input)
+	if len(runes) < prefixLen {
 		return found
 	}
-	prefixLat, prefixExists := l.Prefixes[input[0:prefixLen*2]]
-	// log.Println("\tPrefixes", input[0:prefixLen*2], prefixExists)
+	prefixStr, hostStr := splitPrefix(runes, prefixLen)
+	prefixLat, prefixExists := l.Prefixes[prefixStr]
+	// log.Println("\tPrefixes", prefixStr, prefixExists)
 	if prefixExists {
-		hostStr = input[2*prefixLen:]
-		if len(hostStr) > 2 {
-			// Always add NNP hosts for len(hosts)>1 (unicode = 2 runes)
+		if utf8.RuneCountInString(hostStr) > 1 {
+			// Always add NNP hosts for len(hosts)>1
 			for _, prefix := range prefixLat {
 				l.AddOOVAnalysis(lat, prefix, hostStr, numToken)
 				// lat.AddAnalysis(prefix, l.OOVAnalysis(hostStr), numToken)
@@ -184,27 +244,35 @@ func (l *BGULex) analyzeTokenForLen(lat *Lattice, input string, startingNode, nu
 		hostLat           []BasicMorphemes
 		hostStr           string
 	)
-	if len(input) < prefixLen*2 {
+	runes := []rune(input)
+	if len(runes) < prefixLen {
 		return found
 	}
-	prefixLat, prefixExists := l.Prefixes[input[0:prefixLen*2]]
-	// log.Println("\tPrefixes", input[0:prefixLen*2], prefixExists)
+	prefixStr, hostStr := splitPrefix(runes, prefixLen)
+	prefixLat, prefixExists := l.Prefixes[prefixStr]
+	// log.Println("\tPrefixes", prefixStr, prefixExists)
 	if prefixExists {
-		hostStr = input[2*prefixLen:]
 		if l.AlwaysNNP {
-			if len(hostStr) > 2 {
-				// Always add NNP hosts for len(hosts)>1 (unicode = 2 runes)
+			if utf8.RuneCountInString(hostStr) > 1 {
+				// Always add NNP hosts for len(hosts)>1
 				for _, prefix := range prefixLat {
-					l.AddOOVAnalysis(lat, prefix, hostStr, numToken)
+					l.AddNNPAnalysis(lat, prefix, hostStr, numToken)
 					// lat.AddAnalysis(prefix, l.OOVAnalysis(hostStr), numToken)
 				}
 			}
 		}
 		hostLat, hostExists = l.Lex[hostStr]
+		if hostExists && len(l.prefixRestrictions) > 0 {
+			hostLat = l.allowedAfterPrefix(hostLat, prefixStr)
+			hostExists = len(hostLat) > 0
+		}
 		if !hostExists {
 			hostLat, hostExists = checkRegexes(hostStr)
 		}
-		// log.Println("\tHosts", input[2*prefixLen:], hostExists)
+		if !hostExists {
+			hostLat, hostExists = l.foreignHost(hostStr)
+		}
+		// log.Println("\tHosts", hostStr, hostExists)
 		if hostExists {
 			for _, prefix := range prefixLat {
 				// log.Println("\t\tAdding", prefix, hostLat)
@@ -221,8 +289,18 @@ func (l *BGULex) AnalyzeToken(input string, startingNode, indexToken int) (*Latt
 	if logAnalyze {
 		log.Println("Analyzing token", numToken, "starting at", startingNode)
 	}
+	surface := input
+	input, normalized := l.Normalize.Normalize(input)
+	if normalized != NORMALIZE_NONE {
+		if l.LogNormalization {
+			log.Println("Token", numToken, "normalized", fmt.Sprintf("(%v):", normalized), surface, "->", input)
+		}
+		if l.Stats != nil {
+			l.Stats.AddNormalization(normalized)
+		}
+	}
 	lat := &Lattice{
-		Token:     Token(input),
+		Token:     Token(surface),
 		Morphemes: make(Morphemes, 0, ESTIMATED_MORPHS_PER_TOKEN),
 		Next:      make(map[int][]int, ESTIMATED_MORPHS_PER_TOKEN),
 		BottomId:  startingNode,
@@ -250,7 +328,7 @@ func (l *BGULex) AnalyzeToken(input string, startingNode, indexToken int) (*Latt
 		return lat, false
 	}
 	if l.AlwaysNNP {
-		l.AddOOVAnalysis(lat, nil, input, numToken)
+		l.AddNNPAnalysis(lat, nil, input, numToken)
 		// oovLat := l.OOVAnalysis(input)
 		// lat.AddAnalysis(nil, oovLat, numToken)
 	}
@@ -265,13 +343,16 @@ func (l *BGULex) AnalyzeToken(input string, startingNode, indexToken int) (*Latt
 		lat.AddAnalysis(nil, hostLat, numToken)
 		anyExists = true
 	} else {
-		if !l.AlwaysNNP {
+		// the NNP analyses were already added with AlwaysNNP, guesses
+		// are only of hosts missing from the lexicon
+		if !l.AlwaysNNP || l.OOVGuesser != nil {
 			l.AddOOVAnalysis(lat, nil, input, numToken)
 			// oovLat := l.OOVAnalysis(input)
 			// lat.AddAnalysis(nil, oovLat, numToken)
 		}
 	}
-	for i := 1; i <= util.Min(l.MaxPrefixLen, len(input)); i++ {
+	inputLen := utf8.RuneCountInString(input)
+	for i := 1; i <= util.Min(l.MaxPrefixLen, inputLen); i++ {
 		if logAnalyze {
 			log.Println("\ti is", i)
 		}
@@ -281,9 +362,9 @@ func (l *BGULex) AnalyzeToken(input string, startingNode, indexToken int) (*Latt
 	if !anyExists {
 		// if logAnalyze {
 		if l.LogOOV {
-			log.Println("Token", numToken, "is OOV:", input)
+			log.Println("Token", numToken, "is OOV:", surface)
 		}
-		for i := 1; i < util.Min(l.MaxPrefixLen, len(input)); i++ {
+		for i := 1; i < util.Min(l.MaxPrefixLen, inputLen); i++ {
 			if logAnalyze {
 				log.Println("\ti is", i)
 			}
@@ -292,7 +373,7 @@ func (l *BGULex) AnalyzeToken(input string, startingNode, indexToken int) (*Latt
 		// }
 		if l.Stats != nil {
 			l.Stats.OOVTokens++
-			l.Stats.AddOOVToken(input)
+			l.Stats.AddOOVToken(surface)
 		}
 	}
 	lat.Optimize()
@@ -0,0 +1,77 @@
+package ma
+
+import (
+	"yap/alg/graph"
+	. "yap/nlp/types"
+
+	"strings"
+	"testing"
+)
+
+func testPrefix(forms ...string) []BasicMorphemes {
+	morphs := make(BasicMorphemes, len(forms))
+	for i, form := range forms {
+		morphs[i] = &Morpheme{
+			BasicDirectedEdge: graph.BasicDirectedEdge{0, i, i + 1},
+			Form:              form,
+			CPOS:              "PREPOSITION",
+			POS:               "PREPOSITION",
+		}
+	}
+	return []BasicMorphemes{morphs}
+}
+
+func spellouts(lat *Lattice) []string {
+	lat.GenSpellouts()
+	strs := make([]string, len(lat.Spellouts))
+	for i, spellout := range lat.Spellouts {
+		forms := make([]string, len(spellout))
+		for j, morph := range spellout {
+			forms[j] = morph.Form + "/" + morph.CPOS
+		}
+		strs[i] = strings.Join(forms, "+")
+	}
+	return strs
+}
+
+func TestAnalyzePrefixedForeignHosts(t *testing.T) {
+	l := &BGULex{
+		MaxPrefixLen: 2,
+		Prefixes: map[string][]BasicMorphemes{
+			"ב":  testPrefix("ב"),
+			"ל":  testPrefix("ל"),
+			"וב": testPrefix("ו", "ב"),
+		},
+		Lex:    map[string][]BasicMorphemes{"בית": makeMorphWithPOS("בית", "בית", "NN")},
+		MAType: "spmrl",
+	}
+	cases := []struct {
+		Token, Expected string
+	}{
+		{"ב-2010", "ב/PREPOSITION+2010/CD"},
+		{"ב־2010", "ב/PREPOSITION+2010/CD"},
+		{"וב-2010", "ו/PREPOSITION+ב/PREPOSITION+2010/CD"},
+		{"בGoogle", "ב/PREPOSITION+Google/NNP"},
+		{"ל-CNN", "ל/PREPOSITION+CNN/NNP"},
+		{"לבית", "ל/PREPOSITION+בית/NN"},
+	}
+	for _, c := range cases {
+		lat, oov := l.AnalyzeToken(c.Token, 0, 0)
+		if oov.(bool) {
+			t.Errorf("Token %s should not be OOV", c.Token)
+		}
+		found := false
+		analyses := spellouts(lat)
+		for _, analysis := range analyses {
+			if analysis == c.Expected {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Token %s: expected analysis %s in %v", c.Token, c.Expected, analyses)
+		}
+		if string(lat.Token) != c.Token {
+			t.Errorf("Token %s: lattice token is %s", c.Token, lat.Token)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package ma
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Normalization is a set of rewrites applied to tokens before lexicon
+// lookup, so that tokens differing from the lexicon only in their encoding
+// are not analyzed as OOV
+type Normalization int
+
+const (
+	// strip niqqud (vowel points, dagesh, shin/sin dots, rafe and meteg)
+	NORMALIZE_NIQQUD Normalization = 1 << iota
+	// strip cantillation marks (teamim)
+	NORMALIZE_CANTILLATION
+	// replace Hebrew punctuation geresh (U+05F3) and gershayim (U+05F4) with ' and "
+	NORMALIZE_GERESH
+	// remove bidirectional control marks (RLM, LRM, embeddings and isolates)
+	NORMALIZE_BIDI
+	// replace alphabetic presentation forms (U+FB1D-U+FB4F) with their letters
+	NORMALIZE_PRESENTATION
+
+	NORMALIZE_NONE Normalization = 0
+	NORMALIZE_ALL                = NORMALIZE_NIQQUD | NORMALIZE_CANTILLATION | NORMALIZE_GERESH | NORMALIZE_BIDI | NORMALIZE_PRESENTATION
+)
+
+var (
+	NORMALIZATION_NAMES = []struct {
+		Norm Normalization
+		Name string
+	}{
+		{NORMALIZE_NIQQUD, "niqqud"},
+		{NORMALIZE_CANTILLATION, "cantillation"},
+		{NORMALIZE_GERESH, "geresh"},
+		{NORMALIZE_BIDI, "bidi"},
+		{NORMALIZE_PRESENTATION, "presentation"},
+	}
+
+	// decompositions of the Hebrew presentation forms, the points are
+	// stripped separately if niqqud is normalized
+	PRESENTATION_FORMS = map[rune]string{
+		'\uFB1D': "\u05D9\u05B4", '\uFB1F': "\u05F2\u05B7", '\uFB20': "\u05E2", '\uFB21': "\u05D0",
+		'\uFB22': "\u05D3", '\uFB23': "\u05D4", '\uFB24': "\u05DB", '\uFB25': "\u05DC",
+		'\uFB26': "\u05DD", '\uFB27': "\u05E8", '\uFB28': "\u05EA", '\uFB29': "+",
+		'\uFB2A': "\u05E9\u05C1", '\uFB2B': "\u05E9\u05C2", '\uFB2C': "\u05E9\u05BC\u05C1", '\uFB2D': "\u05E9\u05BC\u05C2",
+		'\uFB2E': "\u05D0\u05B7", '\uFB2F': "\u05D0\u05B8", '\uFB30': "\u05D0\u05BC", '\uFB31': "\u05D1\u05BC",
+		'\uFB32': "\u05D2\u05BC", '\uFB33': "\u05D3\u05BC", '\uFB34': "\u05D4\u05BC", '\uFB35': "\u05D5\u05BC",
+		'\uFB36': "\u05D6\u05BC", '\uFB38': "\u05D8\u05BC", '\uFB39': "\u05D9\u05BC", '\uFB3A': "\u05DA\u05BC",
+		'\uFB3B': "\u05DB\u05BC", '\uFB3C': "\u05DC\u05BC", '\uFB3E': "\u05DE\u05BC", '\uFB40': "\u05E0\u05BC",
+		'\uFB41': "\u05E1\u05BC", '\uFB43': "\u05E3\u05BC", '\uFB44': "\u05E4\u05BC", '\uFB46': "\u05E6\u05BC",
+		'\uFB47': "\u05E7\u05BC", '\uFB48': "\u05E8\u05BC", '\uFB49': "\u05E9\u05BC", '\uFB4A': "\u05EA\u05BC",
+		'\uFB4B': "\u05D5\u05B9", '\uFB4C': "\u05D1\u05BF", '\uFB4D': "\u05DB\u05BF", '\uFB4E': "\u05E4\u05BF",
+		'\uFB4F': "\u05D0\u05DC",
+	}
+)
+
+func (n Normalization) String() string {
+	if n == NORMALIZE_NONE {
+		return "none"
+	}
+	names := make([]string, 0, len(NORMALIZATION_NAMES))
+	for _, norm := range NORMALIZATION_NAMES {
+		if n&norm.Norm != 0 {
+			names = append(names, norm.Name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// ParseNormalization parses a comma separated list of normalization names,
+// or one of all or none
+func ParseNormalization(value string) (Normalization, error) {
+	var n Normalization
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		switch name {
+		case "", "none":
+			continue
+		case "all":
+			n |= NORMALIZE_ALL
+			continue
+		}
+		found := false
+		for _, norm := range NORMALIZATION_NAMES {
+			if norm.Name == name {
+				n |= norm.Norm
+				found = true
+				break
+			}
+		}
+		if !found {
+			return NORMALIZE_NONE, errors.New(fmt.Sprintf("Unknown normalization %s", name))
+		}
+	}
+	return n, nil
+}
+
+func isNiqqud(r rune) bool {
+	return (r >= '\u05B0' && r <= '\u05BD') || r == '\u05BF' || r == '\u05C1' || r == '\u05C2' ||
+		r == '\u05C4' || r == '\u05C5' || r == '\u05C7' || r == '\uFB1E'
+}
+
+func isCantillation(r rune) bool {
+	return r >= '\u0591' && r <= '\u05AF'
+}
+
+func isBidiMark(r rune) bool {
+	return r == '\u200E' || r == '\u200F' || r == '\u061C' ||
+		(r >= '\u202A' && r <= '\u202E') || (r >= '\u2066' && r <= '\u2069')
+}
+
+// Normalize returns the token rewritten by the normalizations in n, along
+// with the normalizations which changed it
+func (n Normalization) Normalize(token string) (string, Normalization) {
+	if n == NORMALIZE_NONE {
+		return token, NORMALIZE_NONE
+	}
+	var fired Normalization
+	if n&NORMALIZE_PRESENTATION != 0 {
+		var decomposed []rune
+		for _, r := range token {
+			if forms, exists := PRESENTATION_FORMS[r]; exists {
+				decomposed = append(decomposed, []rune(forms)...)
+				fired |= NORMALIZE_PRESENTATION
+			} else {
+				decomposed = append(decomposed, r)
+			}
+		}
+		token = string(decomposed)
+	}
+	normalized := make([]rune, 0, len(token))
+	for _, r := range token {
+		switch {
+		case n&NORMALIZE_NIQQUD != 0 && isNiqqud(r):
+			fired |= NORMALIZE_NIQQUD
+		case n&NORMALIZE_CANTILLATION != 0 && isCantillation(r):
+			fired |= NORMALIZE_CANTILLATION
+		case n&NORMALIZE_BIDI != 0 && isBidiMark(r):
+			fired |= NORMALIZE_BIDI
+		case n&NORMALIZE_GERESH != 0 && r == '\u05F3':
+			normalized = append(normalized, '\'')
+			fired |= NORMALIZE_GERESH
+		case n&NORMALIZE_GERESH != 0 && r == '\u05F4':
+			normalized = append(normalized, '"')
+			fired |= NORMALIZE_GERESH
+		default:
+			normalized = append(normalized, r)
+		}
+	}
+	if fired == NORMALIZE_NONE {
+		return token, fired
+	}
+	return string(normalized), fired
+}
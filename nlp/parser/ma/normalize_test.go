@@ -0,0 +1,47 @@
+package ma
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		Norm             Normalization
+		Token, Expected  string
+		ExpectedNormsStr string
+	}{
+		{NORMALIZE_ALL, "שָׁלוֹם", "שלום", "niqqud"},
+		{NORMALIZE_ALL, "בְּרֵאשִׁ֖ית", "בראשית", "niqqud,cantillation"},
+		{NORMALIZE_ALL, "צה״ל", "צה\"ל", "geresh"},
+		{NORMALIZE_ALL, "ג׳ירפה", "ג'ירפה", "geresh"},
+		{NORMALIZE_ALL, "\u200Fשלום\u200E", "שלום", "bidi"},
+		{NORMALIZE_ALL, "\uFB2Aלום", "שלום", "niqqud,presentation"},
+		{NORMALIZE_ALL, "\uFB4F", "אל", "presentation"},
+		{NORMALIZE_PRESENTATION, "\uFB2Aלום", "שׁלום", "presentation"},
+		{NORMALIZE_GERESH, "שָׁלוֹם", "שָׁלוֹם", "none"},
+		{NORMALIZE_ALL, "שלום", "שלום", "none"},
+	}
+	for _, c := range cases {
+		normalized, fired := c.Norm.Normalize(c.Token)
+		if normalized != c.Expected {
+			t.Errorf("Normalizing %q with %v: expected %q got %q", c.Token, c.Norm, c.Expected, normalized)
+		}
+		if fired.String() != c.ExpectedNormsStr {
+			t.Errorf("Normalizing %q with %v: expected normalizations %s got %v", c.Token, c.Norm, c.ExpectedNormsStr, fired)
+		}
+	}
+}
+
+func TestParseNormalization(t *testing.T) {
+	norm, err := ParseNormalization("niqqud, bidi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if norm != NORMALIZE_NIQQUD|NORMALIZE_BIDI {
+		t.Errorf("Expected niqqud,bidi got %v", norm)
+	}
+	if norm, _ := ParseNormalization("all"); norm != NORMALIZE_ALL {
+		t.Errorf("Expected all normalizations got %v", norm)
+	}
+	if _, err := ParseNormalization("nikud"); err == nil {
+		t.Error("Expected error for unknown normalization")
+	}
+}
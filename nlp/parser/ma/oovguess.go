@@ -0,0 +1,396 @@
+package ma
+
+import (
+	"yap/alg/graph"
+	"yap/nlp/format/conllu"
+	"yap/nlp/format/lattice"
+	. "yap/nlp/types"
+	"yap/util"
+
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	LEMMA_RULE_SEPARATOR = ":"
+	SUFFIX_MSR_SEPARATOR = "\t"
+)
+
+// OOVGuesser proposes analyses of hosts missing from the lexicon, learned
+// from the hosts of a treebank by their suffixes, as in the unknown word
+// model of TnT (Brants, 2000)
+//
+// The probability of an MSR (CPOS|POS|features) given the last n characters
+// of a host is interpolated with its probability given the last n-1, by
+// successive abstraction. Lemmas are derived from the host by the most
+// frequent rule (strip characters from the end, append others) of the MSR
+// with the longest matching suffix
+type OOVGuesser struct {
+	// longest suffix considered, hosts more frequent than MaxFreq in
+	// training are ignored, as unlike OOV hosts
+	MaxSuffix, MaxFreq int
+	// number of analyses proposed per host
+	MaxGuesses int
+
+	Files []TrainingFile
+	// tag set of the training treebank, ud from CoNLL-U or spmrl from
+	// lattices, which must be that of the analyzer's output
+	MAType string
+
+	// MSR counts by host suffix, the empty suffix counting all hosts
+	Suffixes map[string]MSRFreq
+	// lemma rule counts by suffix and MSR
+	LemmaRules map[string]MSRFreq
+	Theta      float64
+
+	hosts BasicMorphemes
+}
+
+// OOVGuess is a proposed analysis of an OOV host
+type OOVGuess struct {
+	CPOS, POS, FeatureStr, Lemma string
+	Prob                         float64
+}
+
+func (g *OOVGuesser) init() {
+	if g.MaxSuffix == 0 {
+		g.MaxSuffix = 4
+	}
+	if g.MaxFreq == 0 {
+		g.MaxFreq = 10
+	}
+	if g.MaxGuesses == 0 {
+		g.MaxGuesses = 10
+	}
+	if g.Files == nil {
+		g.Files = make([]TrainingFile, 0, 1)
+	}
+}
+
+// setMAType sets the tag set of a training file, guessers are trained on a
+// single tag set
+func (g *OOVGuesser) setMAType(maType string) error {
+	if len(g.MAType) > 0 && g.MAType != maType {
+		return errors.New(fmt.Sprintf("Can't train an OOV guesser of type %s on %s training data", g.MAType, maType))
+	}
+	g.MAType = maType
+	return nil
+}
+
+func isPunctMorpheme(morph *Morpheme) bool {
+	if len(morph.CPOS) == 1 && strings.Contains(PUNCTUATION, morph.CPOS) {
+		return true
+	}
+	return strings.HasPrefix(morph.CPOS, "yy") || morph.CPOS == "PUNCT"
+}
+
+// AddHosts adds the morphemes of an analysis as training hosts, the model
+// is estimated by Train
+func (g *OOVGuesser) AddHosts(morphs BasicMorphemes) {
+	for _, morph := range morphs {
+		if len(morph.Form) == 0 || isPunctMorpheme(morph) {
+			continue
+		}
+		if _, isNumber := checkRegexes(morph.Form); isNumber {
+			continue
+		}
+		g.hosts = append(g.hosts, morph)
+	}
+}
+
+func (g *OOVGuesser) LearnFromConllU(conlluFile string, limit int) (int, error) {
+	g.init()
+	if err := g.setMAType("ud"); err != nil {
+		return 0, err
+	}
+	md5, err := util.MD5File(conlluFile)
+	if err != nil {
+		return 0, err
+	}
+	conllus, _, err := conllu.ReadFile(conlluFile, limit)
+	if err != nil {
+		log.Println("Error reading conllu file")
+		return 0, err
+	}
+	eWord := util.NewEnumSet(100, "eWord")
+	ePOS := util.NewEnumSet(100, "ePOS")
+	eWPOS := util.NewEnumSet(100, "eWPOS")
+	eMorphFeat := util.NewEnumSet(100, "eMorphFeat")
+	eMHost := util.NewEnumSet(100, "eMHost")
+	eMSuffix := util.NewEnumSet(100, "eMSuffix")
+	eRel := util.NewEnumSet(100, "eRel")
+
+	corpus := conllu.ConllU2MorphGraphCorpus(conllus, eWord, ePOS, eWPOS, eRel, eMorphFeat, eMHost, eMSuffix)
+	numHosts := len(g.hosts)
+	for _, sent := range corpus {
+		for _, mapping := range sent.(MorphDependencyGraph).GetMappings() {
+			g.AddHosts(Morphemes(mapping.Spellout).Standalone())
+		}
+	}
+	g.Files = append(g.Files, TrainingFile{conlluFile, "", md5, ""})
+	return len(g.hosts) - numHosts, nil
+}
+
+func (g *OOVGuesser) LearnFromLat(latticeFile string, limit int) (int, error) {
+	g.init()
+	if err := g.setMAType("spmrl"); err != nil {
+		return 0, err
+	}
+	md5, err := util.MD5File(latticeFile)
+	if err != nil {
+		return 0, err
+	}
+	lattices, err := lattice.ReadFile(latticeFile, limit)
+	if err != nil {
+		log.Println("Error reading lattice file")
+		return 0, err
+	}
+	eWord := util.NewEnumSet(100, "eWord")
+	ePOS := util.NewEnumSet(100, "ePOS")
+	eWPOS := util.NewEnumSet(100, "eWPOS")
+	eMorphFeat := util.NewEnumSet(100, "eMorphFeat")
+	eMHost := util.NewEnumSet(100, "eMHost")
+	eMSuffix := util.NewEnumSet(100, "eMSuffix")
+
+	corpus := lattice.Lattice2SentenceCorpus(lattices, eWord, ePOS, eWPOS, eMorphFeat, eMHost, eMSuffix)
+	numHosts := len(g.hosts)
+	for _, sent := range corpus {
+		for _, lat := range sent.(LatticeSentence) {
+			g.AddHosts(lat.Morphemes.Standalone())
+		}
+	}
+	g.Files = append(g.Files, TrainingFile{latticeFile, "", md5, ""})
+	return len(g.hosts) - numHosts, nil
+}
+
+func suffix(runes []rune, n int) string {
+	return string(runes[len(runes)-n:])
+}
+
+func oovMSR(morph *Morpheme) string {
+	return strings.Join([]string{morph.CPOS, morph.POS, morph.FeatureStr}, MSR_SEPARATOR)
+}
+
+// LemmaRule returns the rule deriving lemma from form, as the number of
+// characters stripped from the end of form and the string appended
+func LemmaRule(form, lemma string) string {
+	formRunes, lemmaRunes := []rune(form), []rune(lemma)
+	var common int
+	for common < len(formRunes) && common < len(lemmaRunes) && formRunes[common] == lemmaRunes[common] {
+		common++
+	}
+	return fmt.Sprintf("%d%s%s", len(formRunes)-common, LEMMA_RULE_SEPARATOR, string(lemmaRunes[common:]))
+}
+
+// ApplyLemmaRule derives a lemma from form by a rule of LemmaRule
+func ApplyLemmaRule(form, rule string) string {
+	split := strings.SplitN(rule, LEMMA_RULE_SEPARATOR, 2)
+	strip, err := strconv.Atoi(split[0])
+	runes := []rune(form)
+	if err != nil || len(split) != 2 || strip > len(runes) {
+		return form
+	}
+	return string(runes[:len(runes)-strip]) + split[1]
+}
+
+func addCount(m map[string]MSRFreq, key, value string) {
+	freq, exists := m[key]
+	if !exists {
+		freq = make(MSRFreq)
+		m[key] = freq
+	}
+	freq[value]++
+}
+
+// Train estimates the model from the hosts added since the last Train
+func (g *OOVGuesser) Train() {
+	g.init()
+	formFreq := make(map[string]int, len(g.hosts))
+	for _, host := range g.hosts {
+		formFreq[host.Form]++
+	}
+	g.Suffixes = make(map[string]MSRFreq, len(g.hosts))
+	g.LemmaRules = make(map[string]MSRFreq, len(g.hosts))
+	for _, host := range g.hosts {
+		if formFreq[host.Form] > g.MaxFreq {
+			continue
+		}
+		msr := oovMSR(host)
+		runes := []rune(host.Form)
+		hasLemma := len(host.Lemma) > 0 && host.Lemma != "_"
+		var rule string
+		if hasLemma {
+			rule = LemmaRule(host.Form, host.Lemma)
+		}
+		for n := 0; n <= util.Min(g.MaxSuffix, len(runes)); n++ {
+			suf := suffix(runes, n)
+			addCount(g.Suffixes, suf, msr)
+			if hasLemma {
+				addCount(g.LemmaRules, suf+SUFFIX_MSR_SEPARATOR+msr, rule)
+			}
+		}
+	}
+	// theta is the standard deviation of the unconditioned MSR probabilities
+	all := g.Suffixes[""]
+	total := all.total()
+	g.Theta = 0
+	if len(all) > 1 {
+		mean := 1.0 / float64(len(all))
+		var sumSq float64
+		for _, count := range all {
+			diff := float64(count)/float64(total) - mean
+			sumSq += diff * diff
+		}
+		g.Theta = math.Sqrt(sumSq / float64(len(all)-1))
+	}
+	log.Println("Trained OOV guesser on", total, "host occurences of", len(all), "MSRs, theta", g.Theta)
+	g.hosts = nil
+}
+
+func (f MSRFreq) total() (total int) {
+	for _, count := range f {
+		total += count
+	}
+	return
+}
+
+func (g *OOVGuesser) lemma(host string, runes []rune, msr string) string {
+	for n := util.Min(g.MaxSuffix, len(runes)); n >= 0; n-- {
+		rules, exists := g.LemmaRules[suffix(runes, n)+SUFFIX_MSR_SEPARATOR+msr]
+		if !exists {
+			continue
+		}
+		var (
+			best      string
+			bestCount int
+		)
+		for rule, count := range rules {
+			if count > bestCount || (count == bestCount && rule < best) {
+				best, bestCount = rule, count
+			}
+		}
+		return ApplyLemmaRule(host, best)
+	}
+	return host
+}
+
+// Guess returns up to MaxGuesses analyses of host, ranked by probability
+func (g *OOVGuesser) Guess(host string) []OOVGuess {
+	all, exists := g.Suffixes[""]
+	if !exists {
+		return nil
+	}
+	total := float64(all.total())
+	probs := make(map[string]float64, len(all))
+	for msr, count := range all {
+		probs[msr] = float64(count) / total
+	}
+	runes := []rune(host)
+	for n := 1; n <= util.Min(g.MaxSuffix, len(runes)); n++ {
+		freq, exists := g.Suffixes[suffix(runes, n)]
+		if !exists {
+			break
+		}
+		sufTotal := float64(freq.total())
+		for msr, prob := range probs {
+			probs[msr] = (float64(freq[msr])/sufTotal + g.Theta*prob) / (1 + g.Theta)
+		}
+	}
+	guesses := make([]OOVGuess, 0, len(probs))
+	for msr, prob := range probs {
+		split := strings.SplitN(msr, MSR_SEPARATOR, 3)
+		guesses = append(guesses, OOVGuess{
+			CPOS:       split[0],
+			POS:        split[1],
+			FeatureStr: split[2],
+			Lemma:      g.lemma(host, runes, msr),
+			Prob:       prob,
+		})
+	}
+	sort.Slice(guesses, func(i, j int) bool {
+		if guesses[i].Prob != guesses[j].Prob {
+			return guesses[i].Prob > guesses[j].Prob
+		}
+		return oovGuessKey(guesses[i]) < oovGuessKey(guesses[j])
+	})
+	if len(guesses) > g.MaxGuesses {
+		guesses = guesses[:g.MaxGuesses]
+	}
+	return guesses
+}
+
+func oovGuessKey(guess OOVGuess) string {
+	return strings.Join([]string{guess.CPOS, guess.POS, guess.FeatureStr}, MSR_SEPARATOR)
+}
+
+// Analyses returns the guesses for host as single morpheme analyses
+func (g *OOVGuesser) Analyses(host string) []BasicMorphemes {
+	guesses := g.Guess(host)
+	analyses := make([]BasicMorphemes, len(guesses))
+	for i, guess := range guesses {
+		var features map[string]string
+		if len(guess.FeatureStr) > 0 && guess.FeatureStr != "_" {
+			_, features = util.MergeFeatureStrs(guess.FeatureStr, "")
+		}
+		analyses[i] = BasicMorphemes{&Morpheme{
+			BasicDirectedEdge: graph.BasicDirectedEdge{0, 0, 1},
+			Form:              host,
+			Lemma:             guess.Lemma,
+			CPOS:              guess.CPOS,
+			POS:               guess.POS,
+			Features:          features,
+			FeatureStr:        guess.FeatureStr,
+		}}
+	}
+	return analyses
+}
+
+func (g *OOVGuesser) Write(writer io.Writer) error {
+	enc := json.NewEncoder(writer)
+	return enc.Encode(g)
+}
+
+func (g *OOVGuesser) Read(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	return dec.Decode(g)
+}
+
+func (g *OOVGuesser) WriteFile(filename string) error {
+	file, err := os.Create(filename)
+	defer file.Close()
+
+	if err != nil {
+		return err
+	}
+
+	return g.Write(file)
+}
+
+func (g *OOVGuesser) ReadFile(filename string) error {
+	file, err := os.Open(filename)
+	defer file.Close()
+
+	if err != nil {
+		return err
+	}
+
+	return g.Read(file)
+}
+
+// SetOOVGuesser sets the guesser of hosts missing from the lexicon, which
+// must be trained on the tag set of the lexicon's analyses
+func (l *BGULex) SetOOVGuesser(g *OOVGuesser) error {
+	if len(g.MAType) > 0 && len(l.MAType) > 0 && g.MAType != l.MAType {
+		return errors.New(fmt.Sprintf("OOV guesser is of type %s, expected %s", g.MAType, l.MAType))
+	}
+	l.OOVGuesser = g
+	return nil
+}
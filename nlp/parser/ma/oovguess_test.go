@@ -0,0 +1,173 @@
+package ma
+
+import (
+	"yap/alg/graph"
+	. "yap/nlp/types"
+
+	"bytes"
+	"testing"
+)
+
+func testHost(form, lemma, POS, feats string) BasicMorphemes {
+	return BasicMorphemes{&Morpheme{
+		BasicDirectedEdge: graph.BasicDirectedEdge{0, 0, 1},
+		Form:              form,
+		Lemma:             lemma,
+		CPOS:              POS,
+		POS:               POS,
+		FeatureStr:        feats,
+	}}
+}
+
+func testGuesser() *OOVGuesser {
+	g := &OOVGuesser{MaxSuffix: 3, MaxFreq: 2, MaxGuesses: 3}
+	for _, host := range []BasicMorphemes{
+		testHost("מחשבות", "מחשבה", "NN", "gen=F|num=P"),
+		testHost("תכניות", "תכנית", "NN", "gen=F|num=P"),
+		testHost("עגלות", "עגלה", "NN", "gen=F|num=P"),
+		testHost("שמלות", "שמלה", "NN", "gen=F|num=P"),
+		testHost("ספרים", "ספר", "NN", "gen=M|num=P"),
+		testHost("כלבים", "כלב", "NN", "gen=M|num=P"),
+		testHost("גדולים", "גדול", "JJ", "gen=M|num=P"),
+		testHost("ירושלים", "ירושלים", "NNP", ""),
+		testHost("חיפה", "חיפה", "NNP", ""),
+		// too frequent to be like an OOV host
+		testHost("של", "של", "POS", ""),
+		testHost("של", "של", "POS", ""),
+		testHost("של", "של", "POS", ""),
+		testHost(",", ",", "yyCM", ""),
+		testHost("2010", "2010", "CD", ""),
+	} {
+		g.AddHosts(host)
+	}
+	g.Train()
+	return g
+}
+
+func TestOOVGuesser(t *testing.T) {
+	g := testGuesser()
+	if _, exists := g.Suffixes[""]["POS|POS|"]; exists {
+		t.Errorf("Frequent host should not be counted")
+	}
+	if total := g.Suffixes[""].total(); total != 9 {
+		t.Errorf("Expected 9 training hosts, got %d", total)
+	}
+	cases := []struct {
+		Host, CPOS, Feats, Lemma string
+	}{
+		{"מכוניות", "NN", "gen=F|num=P", "מכונית"},
+		{"סוסים", "NN", "gen=M|num=P", "סוס"},
+	}
+	for _, c := range cases {
+		guesses := g.Guess(c.Host)
+		if len(guesses) != 3 {
+			t.Fatalf("Host %s: expected 3 guesses, got %v", c.Host, guesses)
+		}
+		best := guesses[0]
+		if best.CPOS != c.CPOS || best.FeatureStr != c.Feats || best.Lemma != c.Lemma {
+			t.Errorf("Host %s: expected %s/%s/%s, got %v", c.Host, c.Lemma, c.CPOS, c.Feats, best)
+		}
+		for i := 1; i < len(guesses); i++ {
+			if guesses[i].Prob > guesses[i-1].Prob {
+				t.Errorf("Host %s: guesses not ranked %v", c.Host, guesses)
+			}
+		}
+	}
+}
+
+func TestOOVGuesserLexicon(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testGuesser().Write(&buf); err != nil {
+		t.Fatalf("Failed writing guesser: %v", err)
+	}
+	g := new(OOVGuesser)
+	if err := g.Read(&buf); err != nil {
+		t.Fatalf("Failed reading guesser: %v", err)
+	}
+	l := &BGULex{
+		MaxPrefixLen: 2,
+		Prefixes:     map[string][]BasicMorphemes{"ה": testPrefix("ה")},
+		Lex:          map[string][]BasicMorphemes{},
+		MAType:       "spmrl",
+		OOVGuesser:   g,
+	}
+	lat, oov := l.AnalyzeToken("המכוניות", 0, 0)
+	lat.GenSpellouts()
+	for _, spellout := range lat.Spellouts {
+		host := spellout[len(spellout)-1]
+		if host.Form == "מכוניות" && host.FeatureStr == "gen=F|num=P" && host.Lemma != "מכונית" {
+			t.Errorf("Expected guessed lemma מכונית, got %s", host.Lemma)
+		}
+	}
+	if !oov.(bool) {
+		t.Errorf("Token should be OOV")
+	}
+	found := false
+	for _, analysis := range spellouts(lat) {
+		if analysis == "ה/PREPOSITION+מכוניות/NN" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected guessed analysis of prefixed host in %v", spellouts(lat))
+	}
+}
+
+func TestOOVGuesserAlwaysNNP(t *testing.T) {
+	l := &BGULex{
+		MaxPrefixLen: 2,
+		Prefixes:     map[string][]BasicMorphemes{"ה": testPrefix("ה")},
+		Lex:          map[string][]BasicMorphemes{"ספרים": {testHost("ספרים", "ספר", "NN", "gen=M|num=P")}},
+		MAType:       "spmrl",
+		AlwaysNNP:    true,
+		OOVGuesser:   testGuesser(),
+	}
+	lat, _ := l.AnalyzeToken("ספרים", 0, 0)
+	for _, analysis := range spellouts(lat) {
+		if analysis != "ספרים/NN" && analysis != "ספרים/NNP" && analysis != "ה/PREPOSITION+פרים/NNP" && analysis != "ה/PREPOSITION+פרים/NN" {
+			t.Errorf("Got analysis %s of a known token, expected those of the lexicon and NNP", analysis)
+		}
+	}
+	for _, morph := range lat.Morphemes {
+		if morph.CPOS == "JJ" {
+			t.Errorf("Got guessed analysis %v of a known token", morph)
+		}
+	}
+
+	lat, _ = l.AnalyzeToken("מכוניות", 0, 0)
+	var guessed bool
+	for _, morph := range lat.Morphemes {
+		guessed = guessed || morph.Lemma == "מכונית"
+	}
+	if !guessed {
+		t.Errorf("Expected guessed analyses of an unknown token with AlwaysNNP, got %v", spellouts(lat))
+	}
+}
+
+func TestSetOOVGuesser(t *testing.T) {
+	g := testGuesser()
+	if err := g.setMAType("spmrl"); err != nil {
+		t.Fatalf("Failed setting the type of an untyped guesser: %v", err)
+	}
+	if err := g.setMAType("ud"); err == nil {
+		t.Error("Expected an error training a guesser on another tag set")
+	}
+	var buf bytes.Buffer
+	if err := g.Write(&buf); err != nil {
+		t.Fatalf("Failed writing guesser: %v", err)
+	}
+	read := new(OOVGuesser)
+	if err := read.Read(&buf); err != nil {
+		t.Fatalf("Failed reading guesser: %v", err)
+	}
+	if read.MAType != "spmrl" {
+		t.Errorf("Got type %q of the read guesser, expected spmrl", read.MAType)
+	}
+	if err := (&BGULex{MAType: "ud"}).SetOOVGuesser(read); err == nil {
+		t.Error("Expected an error setting an spmrl guesser of a ud lexicon")
+	}
+	l := &BGULex{MAType: "spmrl"}
+	if err := l.SetOOVGuesser(read); err != nil || l.OOVGuesser != read {
+		t.Errorf("Failed setting a guesser of the lexicon's type: %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package ma
+
+import (
+	"yap/alg/graph"
+	"yap/nlp/format/lex"
+	. "yap/nlp/types"
+	"yap/util"
+
+	"fmt"
+	"io"
+	"log"
+	"strings"
+)
+
+const (
+	// the analysis is already in the lexicon, and is skipped
+	USER_LEX_DUPLICATE = "duplicate"
+	// the analysis replaces the lexicon's analyses of the form
+	USER_LEX_OVERRIDE = "override"
+	// the analysis is added to the lexicon's analyses of the form
+	USER_LEX_ADDED = "added"
+)
+
+// UserLexConflict reports a user lexicon analysis of a form which is
+// already in the lexicon
+type UserLexConflict struct {
+	File     string
+	Line     int
+	Form     string
+	Kind     string
+	Analysis string
+	Existing []string
+}
+
+func (c UserLexConflict) String() string {
+	return strings.Join([]string{c.File, fmt.Sprintf("%d", c.Line), c.Form, c.Kind, c.Analysis, strings.Join(c.Existing, " ")}, "\t")
+}
+
+// WriteUserLexConflicts writes conflicts as tab separated values, with a
+// header line
+func WriteUserLexConflicts(writer io.Writer, conflicts []UserLexConflict) error {
+	if _, err := writer.Write([]byte("file\tline\tform\tkind\tanalysis\texisting\n")); err != nil {
+		return err
+	}
+	for _, conflict := range conflicts {
+		if _, err := writer.Write([]byte(conflict.String() + "\n")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func analysisString(analysis BasicMorphemes) string {
+	morphs := make([]string, len(analysis))
+	for i, morph := range analysis {
+		morphs[i] = fmt.Sprintf("%s/%s/%s", morph.Lemma, morph.CPOS, morph.FeatureStr)
+	}
+	return strings.Join(morphs, "+")
+}
+
+func analysesStrings(analyses []BasicMorphemes) []string {
+	strs := make([]string, len(analyses))
+	for i, analysis := range analyses {
+		strs[i] = analysisString(analysis)
+	}
+	return strs
+}
+
+// userAnalysis converts a user lexicon entry to a host morpheme, as the
+// lexicon reader of the analyzer's type would
+func (l *BGULex) userAnalysis(entry *lex.UserEntry) BasicMorphemes {
+	var UDFeats string
+	CPOS, POS, featureStr := entry.POS, entry.POS, entry.Features
+	if l.MAType == "ud" {
+		UDSplit := strings.SplitN(util.HEB2UDPOS[entry.POS], "-", 2)
+		CPOS, POS = UDSplit[0], "_"
+		if len(UDSplit) > 1 {
+			UDFeats = UDSplit[1]
+		}
+		featureStr = util.Heb2UDFeaturesString(featureStr)
+		if lex.STRIP_ALL_NNP_OF_FEATS && CPOS == "PROPN" {
+			featureStr, UDFeats = "", ""
+		}
+	}
+	featureStr, features := util.MergeFeatureStrs(featureStr, UDFeats)
+	return BasicMorphemes{&Morpheme{
+		BasicDirectedEdge: graph.BasicDirectedEdge{0, 0, 1},
+		Form:              entry.Form,
+		Lemma:             entry.Lemma,
+		CPOS:              CPOS,
+		POS:               POS,
+		Features:          features,
+		FeatureStr:        featureStr,
+	}}
+}
+
+func sameHost(analysis BasicMorphemes, host *Morpheme) bool {
+	return len(analysis) == 1 && analysis[0].Lemma == host.Lemma &&
+		analysis[0].CPOS == host.CPOS && analysis[0].FeatureStr == host.FeatureStr
+}
+
+// MergeUserLex merges the entries of a user lexicon file into the lexicon,
+// returning the entries of forms already in the lexicon
+//
+// In add mode, entries are added to the lexicon's analyses of their form;
+// with override, the first entry of a form replaces the lexicon's analyses
+// of it. Entries of forms of previously merged user lexicons are always
+// added, so that user lexicons never override each other. Entries already
+// in the lexicon are skipped
+func (l *BGULex) MergeUserLex(file string, entries []*lex.UserEntry, override bool) []UserLexConflict {
+	var conflicts []UserLexConflict
+	if l.Lex == nil {
+		l.Lex = make(map[string][]BasicMorphemes, len(entries))
+	}
+	if l.userForms == nil {
+		l.userForms = make(map[string]bool, len(entries))
+	}
+	for _, entry := range entries {
+		analysis := l.userAnalysis(entry)
+		host := analysis[0]
+		existing, exists := l.Lex[entry.Form]
+		inLexicon, isUser := l.userForms[entry.Form]
+		if !isUser {
+			inLexicon = exists
+		}
+		conflict := UserLexConflict{
+			File:     file,
+			Line:     entry.Line,
+			Form:     entry.Form,
+			Analysis: analysisString(analysis),
+			Existing: analysesStrings(existing),
+		}
+		if exists && !isUser && override {
+			conflict.Kind = USER_LEX_OVERRIDE
+			conflicts = append(conflicts, conflict)
+			existing = nil
+		} else if exists {
+			duplicate := false
+			for _, other := range existing {
+				if sameHost(other, host) {
+					duplicate = true
+					break
+				}
+			}
+			if duplicate {
+				conflict.Kind = USER_LEX_DUPLICATE
+				conflicts = append(conflicts, conflict)
+				continue
+			}
+			if inLexicon && !override {
+				conflict.Kind = USER_LEX_ADDED
+				conflicts = append(conflicts, conflict)
+			}
+		}
+		l.userForms[entry.Form] = inLexicon
+		l.Lex[entry.Form] = append(existing, analysis)
+		if !entry.AnyPrefix() {
+			if l.prefixRestrictions == nil {
+				l.prefixRestrictions = make(map[*Morpheme]map[string]bool)
+			}
+			allowed := make(map[string]bool, len(entry.Prefixes))
+			for _, prefix := range entry.Prefixes {
+				allowed[prefix] = true
+			}
+			l.prefixRestrictions[host] = allowed
+		}
+	}
+	return conflicts
+}
+
+// LoadUserLex reads a user lexicon file (see lex.UserEntry for the format)
+// and merges it into the lexicon
+func (l *BGULex) LoadUserLex(file string, override bool) []UserLexConflict {
+	entries, err := lex.ReadUserFile(file)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to load %v: %v", file, err))
+	}
+	conflicts := l.MergeUserLex(file, entries, override)
+	log.Println("Merged", len(entries), "analyses from user lexicon", file, "with", len(conflicts), "conflicts")
+	return conflicts
+}
+
+// allowedAfterPrefix filters out the analyses of a host which may not
+// follow prefix
+func (l *BGULex) allowedAfterPrefix(analyses []BasicMorphemes, prefix string) []BasicMorphemes {
+	var allowed []BasicMorphemes
+	for i, analysis := range analyses {
+		prefixes, restricted := l.prefixRestrictions[analysis[0]]
+		if restricted && !prefixes[prefix] {
+			if allowed == nil {
+				allowed = make([]BasicMorphemes, i, len(analyses))
+				copy(allowed, analyses[:i])
+			}
+			continue
+		}
+		if allowed != nil {
+			allowed = append(allowed, analysis)
+		}
+	}
+	if allowed == nil {
+		return analyses
+	}
+	return allowed
+}
@@ -0,0 +1,135 @@
+package ma
+
+import (
+	"yap/nlp/format/lex"
+	. "yap/nlp/types"
+
+	"strings"
+	"testing"
+)
+
+const testUserLex = `# form	lemma	POS	features	prefixes
+קורונה	קורונה	NN	gen=F|num=S
+בית	בית	NN	_
+בית	בית	NNP	_	-
+ביתא	_	NNP	_	ה,וה
+`
+
+func testUserLexicon(t *testing.T) []*lex.UserEntry {
+	entries, err := lex.ReadUser(strings.NewReader(testUserLex))
+	if err != nil {
+		t.Fatalf("Failed reading user lexicon: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("Expected 4 entries, got %d", len(entries))
+	}
+	return entries
+}
+
+func testUserLexBase() *BGULex {
+	l := &BGULex{
+		Prefixes: map[string][]BasicMorphemes{
+			"ב":  testPrefix("ב"),
+			"ה":  testPrefix("ה"),
+			"וה": testPrefix("ו", "ה"),
+		},
+		Lex:    map[string][]BasicMorphemes{"בית": makeMorphWithPOS("בית", "בית", "NN")},
+		MAType: "spmrl",
+	}
+	l.MaxPrefixLen = 2
+	return l
+}
+
+func TestReadUserLexErrors(t *testing.T) {
+	for _, line := range []string{
+		"בית\tבית",
+		"בית\tבית\tXX\t_",
+		"בית\tבית\tNN\tgen=X",
+		"בית\tבית\tNN\tgender=M",
+		"בית\tבית\tNN\tgen",
+	} {
+		if _, err := lex.ReadUser(strings.NewReader(line)); err == nil {
+			t.Errorf("Expected error reading user lexicon line %s", line)
+		}
+	}
+}
+
+func TestMergeUserLex(t *testing.T) {
+	l := testUserLexBase()
+	conflicts := l.MergeUserLex("test", testUserLexicon(t), false)
+	if len(conflicts) != 2 {
+		t.Fatalf("Expected 2 conflicts, got %v", conflicts)
+	}
+	if conflicts[0].Kind != USER_LEX_DUPLICATE || conflicts[0].Line != 3 {
+		t.Errorf("Expected duplicate of line 3, got %v", conflicts[0])
+	}
+	if conflicts[1].Kind != USER_LEX_ADDED || conflicts[1].Line != 4 {
+		t.Errorf("Expected addition of line 4, got %v", conflicts[1])
+	}
+	if len(l.Lex["בית"]) != 2 {
+		t.Errorf("Expected 2 analyses of בית, got %d", len(l.Lex["בית"]))
+	}
+	analyses := l.Lex["קורונה"]
+	if len(analyses) != 1 || analyses[0][0].FeatureStr != "gen=F|num=S" || analyses[0][0].Features["gen"] != "F" {
+		t.Errorf("Unexpected analyses of קורונה %v", analyses)
+	}
+	if lemma := l.Lex["ביתא"][0][0].Lemma; lemma != "ביתא" {
+		t.Errorf("Expected empty lemma to default to the form, got %s", lemma)
+	}
+
+	cases := []struct {
+		Token    string
+		Expected []string
+	}{
+		{"בית", []string{"בית/NN", "בית/NNP"}},
+		// the NNP analysis of בית may not be prefixed
+		{"בבית", []string{"ב/PREPOSITION+בית/NN"}},
+		{"בקורונה", []string{"ב/PREPOSITION+קורונה/NN"}},
+		{"הביתא", []string{"ה/PREPOSITION+ביתא/NNP"}},
+		{"והביתא", []string{"ו/PREPOSITION+ה/PREPOSITION+ביתא/NNP"}},
+	}
+	for _, c := range cases {
+		lat, oov := l.AnalyzeToken(c.Token, 0, 0)
+		if oov.(bool) {
+			t.Errorf("Token %s should not be OOV", c.Token)
+			continue
+		}
+		found := make(map[string]bool)
+		for _, analysis := range spellouts(lat) {
+			found[analysis] = true
+		}
+		for _, expected := range c.Expected {
+			if !found[expected] {
+				t.Errorf("Token %s: expected analysis %s in %v", c.Token, expected, spellouts(lat))
+			}
+		}
+		if c.Token == "בבית" && found["ב/PREPOSITION+בית/NNP"] {
+			t.Errorf("Token %s: unexpected prefixed analysis of unprefixable entry", c.Token)
+		}
+	}
+	// ביתא may follow ה or וה only
+	if _, oov := l.AnalyzeToken("בביתא", 0, 0); !oov.(bool) {
+		t.Errorf("Token בביתא should be OOV")
+	}
+}
+
+func TestMergeUserLexOverride(t *testing.T) {
+	l := testUserLexBase()
+	conflicts := l.MergeUserLex("test", testUserLexicon(t), true)
+	if len(conflicts) != 1 || conflicts[0].Kind != USER_LEX_OVERRIDE || conflicts[0].Line != 3 {
+		t.Fatalf("Expected override of line 3, got %v", conflicts)
+	}
+	if len(conflicts[0].Existing) != 1 || conflicts[0].Existing[0] != "בית/NN/" {
+		t.Errorf("Expected overridden analysis בית/NN/, got %v", conflicts[0].Existing)
+	}
+	// the second entry of בית is added to the first rather than replacing it
+	if len(l.Lex["בית"]) != 2 {
+		t.Errorf("Expected 2 analyses of בית, got %d", len(l.Lex["בית"]))
+	}
+	// user lexicons never override each other
+	more := []*lex.UserEntry{{Form: "קורונה", Lemma: "קורונה", POS: "NNP", Line: 1}}
+	conflicts = l.MergeUserLex("more", more, true)
+	if len(conflicts) != 0 || len(l.Lex["קורונה"]) != 2 {
+		t.Errorf("Expected addition without conflicts, got %v and %d analyses", conflicts, len(l.Lex["קורונה"]))
+	}
+}
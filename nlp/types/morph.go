@@ -334,6 +334,11 @@ func (s Spellouts) Find(other Spellout) (int, bool) {
 type Mapping struct {
 	Token    Token
 	Spellout Spellout
+	Range    TokenRange
+	// confidence of the spellout and of each of its morphemes, set by
+	// disambig.SetConfidence (nil MorphConfidence if not computed)
+	Confidence      float64
+	MorphConfidence []float64
 }
 
 func (m *Mapping) Equal(other *Mapping) bool {
@@ -383,6 +388,10 @@ type Lattice struct {
 	Spellouts       Spellouts
 	Next            map[int][]int
 	BottomId, TopId int
+	Range           TokenRange
+	// a spellout fixed in advance (e.g. by partial gold), to which
+	// disambiguation of the lattice is constrained (nil if not fixed)
+	Fixed Spellout
 }
 
 func (l *Lattice) Signature() string {
@@ -822,6 +831,8 @@ func NewRootLattice() Lattice {
 		make(map[int][]int),
 		0,
 		0,
+		TokenRange{},
+		nil,
 	}
 	return *lat
 }
@@ -845,12 +856,45 @@ func (ls LatticeSentence) TaggedSentence() TaggedSentence {
 		for _, m := range sp {
 			res = append(res, EnumTaggedToken{
 				TaggedToken{m.Form, m.Lemma, m.POS},
-				m.EForm, 0, m.EPOS, m.EFCPOS, m.EMHost, m.EMSuffix})
+				m.EForm, 0, m.EPOS, m.EFCPOS, m.EMHost, m.EMSuffix, lat.Range})
 		}
 	}
 	return res
 }
 
+// Copy returns a copy of the lattices of a sentence with their own
+// morphemes, which can be changed (e.g. by AddAnalysis or BumpAll) without
+// changing the original
+func (ls LatticeSentence) Copy() LatticeSentence {
+	retval := make(LatticeSentence, len(ls))
+	for i, lat := range ls {
+		retval[i] = lat
+		retval[i].Morphemes = make(Morphemes, len(lat.Morphemes))
+		for j, morph := range lat.Morphemes {
+			retval[i].Morphemes[j] = morph.Copy()
+		}
+		retval[i].Next = make(map[int][]int, len(lat.Next))
+		for node, next := range lat.Next {
+			retval[i].Next[node] = append([]int(nil), next...)
+		}
+		// the spellouts are of the original morphemes
+		retval[i].Spellouts = nil
+		if len(lat.Spellouts) > 0 {
+			retval[i].GenSpellouts()
+		}
+	}
+	return retval
+}
+
+// SetRanges sets the source text range of each lattice's token
+func (ls LatticeSentence) SetRanges(ranges []TokenRange) {
+	for i := range ls {
+		if i < len(ranges) {
+			ls[i].Range = ranges[i]
+		}
+	}
+}
+
 func (ls LatticeSentence) Tokens() []string {
 	res := make([]string, len(ls))
 	for i, val := range ls {
@@ -1,6 +1,7 @@
 package types
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"sort"
@@ -31,26 +32,49 @@ var (
 )
 
 func InitOpenParamFamily(pType string) {
-	var Main_POS_Types []string
+	Main_POS_Types, err := OpenParamFamilyTypes(pType)
+	if err != nil {
+		panic(err.Error())
+	}
+	log.Println("Using Family", pType, "of Main_POS_Types [", Main_POS_Types, "]")
+	InitOpenParamTypes(Main_POS_Types)
+}
+
+func OpenParamFamilyTypes(pType string) ([]string, error) {
 	switch pType {
 	case "HEBTB":
-		Main_POS_Types = []string{"ADVERB", "BN", "BNT", "CD", "CDT", "JJ", "JJT", "NN", "NNP", "NNT", "RB", "VB"}
-		break
+		return []string{"ADVERB", "BN", "BNT", "CD", "CDT", "JJ", "JJT", "NN", "NNP", "NNT", "RB", "VB"}, nil
 	case "UD":
-		Main_POS_Types = []string{"ADJ", "AUX", "ADV", "PUNCT", "NUM", "INTJ", "NOUN", "PROPN", "VERB"}
-		break
+		return []string{"ADJ", "AUX", "ADV", "PUNCT", "NUM", "INTJ", "NOUN", "PROPN", "VERB"}, nil
 	default:
-		panic(fmt.Sprintf("Unknown open class family %s", pType))
+		return nil, errors.New(fmt.Sprintf("Unknown open class family %s", pType))
 	}
-	log.Println("Using Family", pType, "of Main_POS_Types [", Main_POS_Types, "]")
-	InitOpenParamTypes(Main_POS_Types)
 }
 
 func InitOpenParamTypes(Main_POS_Types []string) {
-	Main_POS = make(map[string]bool, len(Main_POS_Types))
+	Main_POS = NewOpenParamTypes(Main_POS_Types)
+}
+
+func NewOpenParamTypes(Main_POS_Types []string) map[string]bool {
+	mainPOS := make(map[string]bool, len(Main_POS_Types))
 	for _, pos := range Main_POS_Types {
-		Main_POS[pos] = true
+		mainPOS[pos] = true
+	}
+	return mainPOS
+}
+
+// MDParamsFor returns the param funcs with the open class POS bound to mainPOS
+// rather than the global Main_POS, for models of different families in one process
+func MDParamsFor(mainPOS map[string]bool) map[string]MDParam {
+	params := make(map[string]MDParam, len(MDParams))
+	for name, f := range MDParams {
+		params[name] = f
 	}
+	params["Funcs_Main_POS_Both_Prop"] = func(m *EMorpheme) string { return mainPOSBothProp(mainPOS, m) }
+	params["Funcs_Main_POS_Both_Prop_Clitic"] = func(m *EMorpheme) string { return mainPOSBothPropClitic(mainPOS, m) }
+	params["Funcs_Main_POS"] = func(m *EMorpheme) string { return mainPOSOnly(mainPOS, m) }
+	params["Funcs_Main_POS_Prop"] = func(m *EMorpheme) string { return mainPOSProp(mainPOS, m) }
+	return params
 }
 
 func init() {
@@ -122,7 +146,11 @@ func Funcs_Main_POS_No_Prop(m *EMorpheme) string {
 }
 
 func Funcs_Main_POS_Both_Prop(m *EMorpheme) string {
-	if _, exists := Main_POS[m.CPOS]; exists {
+	return mainPOSBothProp(Main_POS, m)
+}
+
+func mainPOSBothProp(mainPOS map[string]bool, m *EMorpheme) string {
+	if _, exists := mainPOS[m.CPOS]; exists {
 		return fmt.Sprintf("%s_%s", m.CPOS, m.FeatureStr)
 	} else {
 		return fmt.Sprintf("%s_%s_%s", m.Form, m.CPOS, m.FeatureStr)
@@ -130,7 +158,11 @@ func Funcs_Main_POS_Both_Prop(m *EMorpheme) string {
 }
 
 func Funcs_Main_POS_Both_Prop_Clitic(m *EMorpheme) string {
-	if _, exists := Main_POS[m.CPOS]; exists {
+	return mainPOSBothPropClitic(Main_POS, m)
+}
+
+func mainPOSBothPropClitic(mainPOS map[string]bool, m *EMorpheme) string {
+	if _, exists := mainPOS[m.CPOS]; exists {
 		if len(m.Form) > 1 && strings.HasSuffix(m.Form, "_") {
 			return fmt.Sprintf("s_%s_%s", m.CPOS, m.FeatureStr)
 		} else {
@@ -163,7 +195,11 @@ func Funcs_Lemma_Main_POS(m *EMorpheme) string {
 }
 
 func Funcs_Main_POS(m *EMorpheme) string {
-	if _, exists := Main_POS[m.CPOS]; exists {
+	return mainPOSOnly(Main_POS, m)
+}
+
+func mainPOSOnly(mainPOS map[string]bool, m *EMorpheme) string {
+	if _, exists := mainPOS[m.CPOS]; exists {
 		return fmt.Sprintf("%s", m.CPOS)
 	} else {
 		return fmt.Sprintf("%s_%s", m.Form, m.CPOS)
@@ -171,7 +207,11 @@ func Funcs_Main_POS(m *EMorpheme) string {
 }
 
 func Funcs_Main_POS_Prop(m *EMorpheme) string {
-	if _, exists := Main_POS[m.CPOS]; exists {
+	return mainPOSProp(Main_POS, m)
+}
+
+func mainPOSProp(mainPOS map[string]bool, m *EMorpheme) string {
+	if _, exists := mainPOS[m.CPOS]; exists {
 		return fmt.Sprintf("%s_%s", m.CPOS, m.FeatureStr)
 	} else {
 		return fmt.Sprintf("%s_%s_%s", m.Form, m.CPOS, m.FeatureStr)
@@ -1,7 +1,10 @@
 package types
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 	"yap/util"
 )
@@ -33,6 +36,63 @@ func (t Token) Suffixes(n int) []interface{} {
 	return suffixes
 }
 
+// TokenRange is the span of a token in the text it was read from, as
+// character (not byte) offsets with an exclusive end
+// The zero value marks an unknown range
+type TokenRange struct {
+	Start, End int
+}
+
+func (r TokenRange) Known() bool {
+	return r.End > r.Start
+}
+
+func (r TokenRange) String() string {
+	return fmt.Sprintf("%d:%d", r.Start, r.End)
+}
+
+// TOKEN_RANGE_ATTR names the range of a token in the files carrying it, as
+// TokenRange=<start>:<end>
+const TOKEN_RANGE_ATTR = "TokenRange"
+
+// Attribute returns the TokenRange=<start>:<end> attribute of a known
+// range, or an empty string
+func (r TokenRange) Attribute() string {
+	if !r.Known() {
+		return ""
+	}
+	return TOKEN_RANGE_ATTR + "=" + r.String()
+}
+
+func ParseTokenRange(value string) (TokenRange, error) {
+	split := strings.Split(value, ":")
+	if len(split) != 2 {
+		return TokenRange{}, errors.New(fmt.Sprintf("Expected start:end token range, got %s", value))
+	}
+	start, err := strconv.Atoi(split[0])
+	if err != nil {
+		return TokenRange{}, err
+	}
+	end, err := strconv.Atoi(split[1])
+	if err != nil {
+		return TokenRange{}, err
+	}
+	if end < start {
+		return TokenRange{}, errors.New(fmt.Sprintf("Token range %s ends before it starts", value))
+	}
+	return TokenRange{start, end}, nil
+}
+
+// ParseTokenRangeAttribute parses a TokenRange=<start>:<end> attribute,
+// returning false if the field is not one
+func ParseTokenRangeAttribute(field string) (TokenRange, bool, error) {
+	if !strings.HasPrefix(field, TOKEN_RANGE_ATTR+"=") {
+		return TokenRange{}, false, nil
+	}
+	r, err := ParseTokenRange(field[len(TOKEN_RANGE_ATTR)+1:])
+	return r, true, err
+}
+
 type EnumToken struct {
 	Token Token
 	Enum  int
@@ -45,6 +105,7 @@ type TaggedToken struct {
 type EnumTaggedToken struct {
 	TaggedToken
 	EToken, ELemma, EPOS, ETPOS, EMHost, EMSuffix int
+	Range                                         TokenRange
 }
 
 type Sentence interface {
@@ -75,6 +136,13 @@ func (b BasicSentence) Equal(other interface{}) bool {
 	return reflect.DeepEqual(b, asBasic)
 }
 
+// RangedSentence is a sentence along with the range of each of its tokens
+// in the source text
+type RangedSentence struct {
+	BasicSentence
+	Ranges []TokenRange
+}
+
 type EnumSentence interface {
 	util.Equaler
 	Tokens() []EnumToken
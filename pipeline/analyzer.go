@@ -0,0 +1,114 @@
+package pipeline
+
+import (
+	"yap/nlp/format/lattice"
+	"yap/nlp/parser/ma"
+	nlp "yap/nlp/types"
+
+	"path/filepath"
+	"sync"
+)
+
+const (
+	DEFAULT_PREFIX_FILE  = "bgupreflex_withdef.utf8.hr"
+	DEFAULT_LEXICON_FILE = "bgulex.utf8.hr"
+	DEFAULT_MA_TYPE      = "spmrl"
+)
+
+// Analyzer is a lexicon based Hebrew morphological analyzer
+// Access to the lexicon is serialized, since analysis updates the OOV statistics
+type Analyzer struct {
+	sync.Mutex
+	Lex   *ma.BGULex
+	Stats *ma.AnalyzeStats
+
+	LatticeOptions lattice.Options
+}
+
+// NewAnalyzer loads the BGU prefix and lexicon files found in lexDir
+func NewAnalyzer(lexDir string) (*Analyzer, error) {
+	return NewAnalyzerFromFiles(filepath.Join(lexDir, DEFAULT_PREFIX_FILE), filepath.Join(lexDir, DEFAULT_LEXICON_FILE), false)
+}
+
+func NewAnalyzerFromFiles(prefixFile, lexiconFile string, nnpnofeats bool) (*Analyzer, error) {
+	return NewTypedAnalyzerFromFiles(prefixFile, lexiconFile, DEFAULT_MA_TYPE, nnpnofeats)
+}
+
+// NewTypedAnalyzerFromFiles loads the prefix and lexicon files with the
+// analyses of maType (spmrl or ud)
+func NewTypedAnalyzerFromFiles(prefixFile, lexiconFile, maType string, nnpnofeats bool) (*Analyzer, error) {
+	if err := verifyExists(prefixFile); err != nil {
+		return nil, err
+	}
+	if err := verifyExists(lexiconFile); err != nil {
+		return nil, err
+	}
+	maData := new(ma.BGULex)
+	maData.MAType = maType
+	maData.LoadPrefixes(prefixFile)
+	maData.LoadLex(lexiconFile, nnpnofeats)
+	stats := new(ma.AnalyzeStats)
+	stats.Init()
+	maData.Stats = stats
+	return &Analyzer{Lex: maData, Stats: stats}, nil
+}
+
+// NewAnalyzerFromCompiled loads the prefixes and lexicon from a file
+// written by lexcompile
+func NewAnalyzerFromCompiled(compiledFile string, nnpnofeats bool) (*Analyzer, error) {
+	return NewTypedAnalyzerFromCompiled(compiledFile, DEFAULT_MA_TYPE, nnpnofeats)
+}
+
+// NewTypedAnalyzerFromCompiled loads a compiled lexicon of maType
+func NewTypedAnalyzerFromCompiled(compiledFile, maType string, nnpnofeats bool) (*Analyzer, error) {
+	if err := verifyExists(compiledFile); err != nil {
+		return nil, err
+	}
+	maData := new(ma.BGULex)
+	maData.MAType = maType
+	maData.LoadCompiled(compiledFile, nnpnofeats)
+	stats := new(ma.AnalyzeStats)
+	stats.Init()
+	maData.Stats = stats
+	return &Analyzer{Lex: maData, Stats: stats}, nil
+}
+
+// LoadUserLex merges a user lexicon file into the lexicon, returning the
+// user analyses of forms which were already in it
+func (a *Analyzer) LoadUserLex(file string, override bool) ([]ma.UserLexConflict, error) {
+	if err := verifyExists(file); err != nil {
+		return nil, err
+	}
+	a.Lock()
+	defer a.Unlock()
+	return a.Lex.LoadUserLex(file, override), nil
+}
+
+// LoadOOVGuesser reads a guesser trained by oovlearn, which analyzes hosts
+// missing from the lexicon instead of the fixed OOV analyses, and must be
+// of the analyzer's tag set
+func (a *Analyzer) LoadOOVGuesser(file string) error {
+	guesser := new(ma.OOVGuesser)
+	if err := guesser.ReadFile(file); err != nil {
+		return err
+	}
+	a.Lock()
+	defer a.Unlock()
+	return a.Lex.SetOOVGuesser(guesser)
+}
+
+// Analyze returns the ambiguous lattice of a tokenized sentence
+func (a *Analyzer) Analyze(tokens []string) lattice.Lattice {
+	a.Lock()
+	defer a.Unlock()
+	sent, _ := a.Lex.Analyze(tokens)
+	return a.LatticeOptions.Sentence2Lattice(sent, nil)
+}
+
+// AnalyzeRanged returns the ambiguous lattice of a tokenized sentence, with
+// each edge carrying the source text range of its token
+func (a *Analyzer) AnalyzeRanged(sent nlp.RangedSentence) lattice.Lattice {
+	lat := a.Analyze(sent.Tokens())
+	lat.SetTokenRanges(sent.Ranges)
+	return lat
+}
@@ -0,0 +1,219 @@
+package pipeline
+
+import (
+	"yap/alg/search"
+	"yap/alg/transition"
+	transitionmodel "yap/alg/transition/model"
+	"yap/nlp/format/lattice"
+	"yap/nlp/format/mapping"
+	"yap/nlp/parser/disambig"
+	nlp "yap/nlp/types"
+	"yap/util"
+
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	DEFAULT_MD_BEAM_SIZE  = 32
+	DEFAULT_MD_PARAM_FUNC = "Funcs_Main_POS_Both_Prop"
+)
+
+// Disambiguator is a beam search morphological disambiguator
+type Disambiguator struct {
+	sync.Mutex
+
+	BeamSize      int
+	ParamFuncName string
+	UsePOP        bool
+	UseWB         bool
+	Concurrent    bool
+	OpenFamily    string
+	// compute the confidence of each token and morpheme of the
+	// disambiguation from the final beam, see disambig.SetConfidence (a
+	// temperature of 0 is taken as disambig.DefaultConfidenceTemperature)
+	Confidence            bool
+	ConfidenceTemperature float64
+
+	LatticeOptions lattice.Options
+
+	EWord, EPOS, EWPOS, EMHost, EMSuffix *util.EnumSet
+	EMorphProp, ETrans, ETokens          *util.EnumSet
+	POP                                  transition.Transition
+	ParamFunc                            nlp.MDParam
+	MDOptions                            *disambig.MDOptions
+
+	TransitionSystem transition.TransitionSystem
+	Beam             *search.Beam
+}
+
+// NewDisambiguator loads a disambiguation model with default settings
+func NewDisambiguator(modelPath, featureConf string) (*Disambiguator, error) {
+	d := &Disambiguator{
+		BeamSize:      DEFAULT_MD_BEAM_SIZE,
+		ParamFuncName: DEFAULT_MD_PARAM_FUNC,
+		UsePOP:        true,
+		Concurrent:    true,
+		OpenFamily:    "HEBTB",
+		// as md, lemmas are ignored by default
+		LatticeOptions: lattice.Options{IgnoreLemma: true},
+	}
+	if err := d.Load(modelPath, featureConf); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Disambiguator) Load(modelPath, featureConf string) error {
+	mainPOS, err := nlp.OpenParamFamilyTypes(d.OpenFamily)
+	if err != nil {
+		return err
+	}
+	paramFunc, exists := nlp.MDParamsFor(nlp.NewOpenParamTypes(mainPOS))[d.ParamFuncName]
+	if !exists {
+		return errors.New(fmt.Sprintf("Param Func %s does not exist", d.ParamFuncName))
+	}
+	featureSetup, err := transition.LoadFeatureConfFile(featureConf)
+	if err != nil {
+		return err
+	}
+	serialization, err := ReadModel(modelPath)
+	if err != nil {
+		return err
+	}
+	model := &transitionmodel.AvgMatrixSparse{}
+	model.Deserialize(serialization.WeightModel)
+	d.EWord, d.EPOS, d.EWPOS, d.EMHost, d.EMSuffix, d.EMorphProp, d.ETrans, d.ETokens = serialization.EWord, serialization.EPOS, serialization.EWPOS, serialization.EMHost, serialization.EMSuffix, serialization.EMorphProp, serialization.ETrans, serialization.ETokens
+	iPOP, exists := d.ETrans.IndexOf("POP")
+	if !exists {
+		return errors.New("Model transitions do not include POP")
+	}
+	d.POP = &transition.TypedTransition{T: 'P', V: iPOP}
+	d.ParamFunc = paramFunc
+	d.MDOptions = &disambig.MDOptions{
+		UsePOP:          d.UsePOP,
+		SwitchFormLemma: !d.LatticeOptions.IgnoreLemma,
+		Lemmas:          !d.LatticeOptions.IgnoreLemma,
+	}
+
+	if d.UseWB {
+		d.TransitionSystem = &disambig.MDWBTrans{
+			ParamFunc:   paramFunc,
+			UsePOP:      d.UsePOP,
+			POP:         d.POP,
+			Transitions: d.ETrans,
+		}
+	} else {
+		d.TransitionSystem = &disambig.MDTrans{
+			ParamFunc:   paramFunc,
+			UsePOP:      d.UsePOP,
+			POP:         d.POP,
+			Transitions: d.ETrans,
+			Options:     d.MDOptions,
+		}
+	}
+	extractor := NewExtractor(featureSetup, []byte("MPL"), d.EWord, d.EPOS, d.EWPOS, nil, d.EMHost, d.EMSuffix, d.EMorphProp, d.ETokens, d.POP)
+	conf := &disambig.MDConfig{
+		ETokens:     d.ETokens,
+		POP:         d.POP,
+		Transitions: d.ETrans,
+		ParamFunc:   paramFunc,
+		Options:     d.MDOptions,
+	}
+	d.Beam = &search.Beam{
+		TransFunc:            d.TransitionSystem,
+		FeatExtractor:        extractor,
+		Base:                 conf,
+		Model:                model,
+		Size:                 d.BeamSize,
+		ConcurrentExec:       d.Concurrent,
+		Transitions:          d.ETrans,
+		EstimatedTransitions: 1000, // chosen by random dice roll
+		ShortTempAgenda:      true,
+	}
+	return nil
+}
+
+// Sentence converts an ambiguous lattice to the model's internal representation
+func (d *Disambiguator) Sentence(lat lattice.Lattice) nlp.LatticeSentence {
+	return lattice.Lattice2Sentence(d.LatticeOptions.Apply(lat), d.EWord, d.EPOS, d.EWPOS, d.EMorphProp, d.EMHost, d.EMSuffix)
+}
+
+// DisambiguateConfig returns the final configuration of the beam for an ambiguous lattice
+func (d *Disambiguator) DisambiguateConfig(lat lattice.Lattice) *disambig.MDConfig {
+	sent := d.Sentence(lat)
+	d.Lock()
+	defer d.Unlock()
+	if d.Confidence {
+		return disambig.SetConfidence(d.Beam.ParseKBest(sent, d.Beam.Size), d.ConfidenceTemperature)
+	}
+	result, _ := d.Beam.Parse(sent)
+	return result.(*disambig.MDConfig)
+}
+
+// Disambiguate returns the disambiguated lattice of an ambiguous lattice,
+// along with its token to morpheme mappings
+func (d *Disambiguator) Disambiguate(lat lattice.Lattice) (lattice.Lattice, nlp.Mappings, error) {
+	mdConfig := d.DisambiguateConfig(lat)
+	disLat, err := MDConfig2Lattice(mdConfig, d.LatticeOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+	return disLat, MDConfigMappings(mdConfig), nil
+}
+
+// MDConfig2Lattice converts a disambiguation into a lattice by round
+// tripping through the mapping format, as dep -inl reads md output
+// Token ranges, which the format does not record, are copied from the
+// mappings
+func MDConfig2Lattice(mdConfig *disambig.MDConfig, options lattice.Options) (lattice.Lattice, error) {
+	var buf bytes.Buffer
+	mapping.Write(&buf, []interface{}{mdConfig})
+	disLats, err := options.Read(&buf, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(disLats) != 1 {
+		return nil, errors.New(fmt.Sprintf("Expected 1 disambiguated lattice, got %d", len(disLats)))
+	}
+	ranges := make([]nlp.TokenRange, len(mdConfig.Mappings))
+	for i, m := range mdConfig.Mappings {
+		ranges[i] = m.Range
+	}
+	disLats[0].SetTokenRanges(ranges)
+	var confidences []float64
+	for _, m := range MDConfigMappings(mdConfig) {
+		confidences = append(confidences, m.MorphConfidence...)
+	}
+	if len(confidences) > 0 {
+		disLats[0].SetConfidences(confidences)
+	}
+	return disLats[0], nil
+}
+
+// MDConfigMappings returns the mappings of a disambiguation as written
+// by the mapping format
+func MDConfigMappings(mdConfig *disambig.MDConfig) nlp.Mappings {
+	mappings := make(nlp.Mappings, 0, len(mdConfig.Mappings))
+	for _, m := range mdConfig.Mappings {
+		if m.Token == nlp.ROOT_TOKEN {
+			continue
+		}
+		hasConfidence := len(m.MorphConfidence) == len(m.Spellout)
+		spellout := make(nlp.Spellout, 0, len(m.Spellout))
+		mapping := &nlp.Mapping{Token: m.Token, Range: m.Range, Confidence: m.Confidence}
+		for i, morph := range m.Spellout {
+			if morph != nil {
+				spellout = append(spellout, morph)
+				if hasConfidence {
+					mapping.MorphConfidence = append(mapping.MorphConfidence, m.MorphConfidence[i])
+				}
+			}
+		}
+		mapping.Spellout = spellout
+		mappings = append(mappings, mapping)
+	}
+	return mappings
+}
@@ -0,0 +1,115 @@
+package pipeline
+
+import (
+	"testing"
+
+	"yap/alg/graph"
+	"yap/nlp/format/lattice"
+	"yap/nlp/parser/disambig"
+	nlp "yap/nlp/types"
+)
+
+func testMorph(id, from, to int, form, CPOS string) *nlp.EMorpheme {
+	return &nlp.EMorpheme{Morpheme: nlp.Morpheme{
+		BasicDirectedEdge: graph.BasicDirectedEdge{id, from, to},
+		Form:              form,
+		Lemma:             form,
+		CPOS:              CPOS,
+		POS:               CPOS,
+		FeatureStr:        "_",
+	}}
+}
+
+// testDisambiguation returns the disambiguation of הבית גדול, with a nil
+// morpheme in the first spellout and the ROOT mapping at its end
+func testDisambiguation() *disambig.MDConfig {
+	return &disambig.MDConfig{Mappings: nlp.Mappings{
+		{
+			Token:           "הבית",
+			Spellout:        nlp.Spellout{testMorph(0, 0, 1, "ה", "DEF"), nil, testMorph(1, 1, 2, "בית", "NN")},
+			Range:           nlp.TokenRange{Start: 0, End: 4},
+			Confidence:      0.5,
+			MorphConfidence: []float64{0.9, 0.1, 0.8},
+		},
+		{
+			Token:           "גדול",
+			Spellout:        nlp.Spellout{testMorph(2, 2, 3, "גדול", "JJ")},
+			Range:           nlp.TokenRange{Start: 5, End: 9},
+			Confidence:      0.7,
+			MorphConfidence: []float64{0.7},
+		},
+		{Token: nlp.ROOT_TOKEN, Spellout: nlp.Spellout{nil}},
+	}}
+}
+
+func TestMDConfigMappings(t *testing.T) {
+	mappings := MDConfigMappings(testDisambiguation())
+	if len(mappings) != 2 {
+		t.Fatalf("Got %d mappings, expected 2 without ROOT", len(mappings))
+	}
+	expected := []struct {
+		Token      nlp.Token
+		Forms      []string
+		Range      nlp.TokenRange
+		Confidence float64
+		Morphs     []float64
+	}{
+		{"הבית", []string{"ה", "בית"}, nlp.TokenRange{Start: 0, End: 4}, 0.5, []float64{0.9, 0.8}},
+		{"גדול", []string{"גדול"}, nlp.TokenRange{Start: 5, End: 9}, 0.7, []float64{0.7}},
+	}
+	for i, m := range mappings {
+		e := expected[i]
+		if m.Token != e.Token || m.Range != e.Range || m.Confidence != e.Confidence {
+			t.Errorf("Mapping %d: got %s %v (%v), expected %s %v (%v)", i, m.Token, m.Range, m.Confidence, e.Token, e.Range, e.Confidence)
+		}
+		if len(m.Spellout) != len(e.Forms) || len(m.MorphConfidence) != len(e.Morphs) {
+			t.Errorf("Mapping %d: got spellout %v with confidences %v, expected %v with %v", i, m.Spellout, m.MorphConfidence, e.Forms, e.Morphs)
+			continue
+		}
+		for j, morph := range m.Spellout {
+			if morph.Form != e.Forms[j] || m.MorphConfidence[j] != e.Morphs[j] {
+				t.Errorf("Mapping %d: got morpheme %s with confidence %v, expected %s with %v", i, morph.Form, m.MorphConfidence[j], e.Forms[j], e.Morphs[j])
+			}
+		}
+	}
+
+	// morpheme confidences not matching the spellout are dropped
+	conf := testDisambiguation()
+	conf.Mappings[1].MorphConfidence = nil
+	if mappings := MDConfigMappings(conf); mappings[1].MorphConfidence != nil {
+		t.Errorf("Got morpheme confidences %v, expected none", mappings[1].MorphConfidence)
+	}
+}
+
+func TestMDConfig2Lattice(t *testing.T) {
+	lat, err := MDConfig2Lattice(testDisambiguation(), lattice.Options{})
+	if err != nil {
+		t.Fatalf("Failed converting disambiguation: %v", err)
+	}
+	expected := []struct {
+		Word       string
+		Range      nlp.TokenRange
+		Confidence float64
+	}{
+		{"ה", nlp.TokenRange{Start: 0, End: 4}, 0.9},
+		{"בית", nlp.TokenRange{Start: 0, End: 4}, 0.8},
+		{"גדול", nlp.TokenRange{Start: 5, End: 9}, 0.7},
+	}
+	if len(lat) != len(expected) {
+		t.Fatalf("Got lattice %v, expected %d edges", lat, len(expected))
+	}
+	for start, e := range expected {
+		edges := lat[start]
+		if len(edges) != 1 {
+			t.Errorf("Got edges %v from node %d, expected 1", edges, start)
+			continue
+		}
+		edge := edges[0]
+		if edge.Word != e.Word || edge.TokenRange != e.Range {
+			t.Errorf("Node %d: got %s %v, expected %s %v", start, edge.Word, edge.TokenRange, e.Word, e.Range)
+		}
+		if edge.Confidence == nil || *edge.Confidence != e.Confidence {
+			t.Errorf("Node %d: got confidence %v, expected %v", start, edge.Confidence, e.Confidence)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Package pipeline provides the morphological analyzer, disambiguator and
+// dependency parser as plain Go types, each owning its own enumerations,
+// transition system and model
+package pipeline
+
+import (
+	"yap/alg/transition"
+	"yap/alg/transition/model"
+	nlp "yap/nlp/types"
+	"yap/util"
+
+	"encoding/gob"
+	"os"
+)
+
+func init() {
+	gob.Register(&Serialization{})
+}
+
+// An approximation of the number of different MD-X:Y:Z transitions
+// Pre-allocating the enumeration saves frequent reallocation during training and parsing
+const (
+	APPROX_MORPH_TRANSITIONS        = 100
+	APPROX_WORDS, APPROX_POS        = 100, 100
+	WORDS_POS_FACTOR                = 5
+	APPROX_MHOSTS, APPROX_MSUFFIXES = 128, 16
+)
+
+type Serialization struct {
+	WeightModel                          *model.AvgMatrixSparseSerialized
+	EWord, EPOS, EWPOS, EMHost, EMSuffix *util.EnumSet
+	EMorphProp                           *util.EnumSet
+	ETrans                               *util.EnumSet
+	ETokens                              *util.EnumSet
+}
+
+func ReadModel(file string) (*Serialization, error) {
+	data := &Serialization{}
+	fObj, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fObj.Close()
+	reader := gob.NewDecoder(fObj)
+	err = reader.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func NewRelationEnum(labels []string) *util.EnumSet {
+	eRel := util.NewEnumSet(len(labels)+1, "ERel")
+	eRel.Add(nlp.DepRel(nlp.ROOT_LABEL))
+	for _, label := range labels {
+		eRel.Add(nlp.DepRel(label))
+	}
+	eRel.Frozen = true
+	return eRel
+}
+
+func NewTransEnum(relations []string) (eTrans *util.EnumSet, SH, RE, PR, LA, RA transition.Transition) {
+	eTrans = util.NewEnumSet((len(relations)+1)*2+2, "ETrans")
+	_, _ = eTrans.Add("IDLE") // dummy no action transition for zpar equivalence
+	iSH, _ := eTrans.Add("SH")
+	iRE, _ := eTrans.Add("RE")
+	_, _ = eTrans.Add("AL") // dummy action transition for zpar equivalence
+	_, _ = eTrans.Add("AR") // dummy action transition for zpar equivalence
+	iPR, _ := eTrans.Add("PR")
+	SH = transition.ConstTransition(iSH)
+	RE = transition.ConstTransition(iRE)
+	PR = transition.ConstTransition(iPR)
+	LA = transition.ConstTransition(iPR + 1)
+	eTrans.Add("LA-" + string(nlp.ROOT_LABEL))
+	for _, transition := range relations {
+		eTrans.Add("LA-" + string(transition))
+	}
+	RA = transition.ConstTransition(eTrans.Len())
+	eTrans.Add("RA-" + string(nlp.ROOT_LABEL))
+	for _, transition := range relations {
+		eTrans.Add("RA-" + string(transition))
+	}
+	return
+}
+
+func NewExtractor(setup *transition.FeatureSetup, transTypes []byte, eWord, ePOS, eWPOS, eRel, eMHost, eMSuffix, eMorphProp, eTokens *util.EnumSet, pop transition.Transition) *transition.GenericExtractor {
+	extractor := &transition.GenericExtractor{
+		EFeatures:  util.NewEnumSet(setup.NumFeatures(), "EFeatures"),
+		Concurrent: false,
+		EWord:      eWord,
+		EPOS:       ePOS,
+		EWPOS:      eWPOS,
+		ERel:       eRel,
+		EMHost:     eMHost,
+		EMSuffix:   eMSuffix,
+		EMorphProp: eMorphProp,
+		EToken:     eTokens,
+		POPTrans:   pop,
+	}
+	if transTypes == nil {
+		extractor.Init()
+	} else {
+		extractor.InitTypes(transTypes)
+	}
+	extractor.LoadFeatureSetup(setup)
+	return extractor
+}
+
+func verifyExists(filename string) error {
+	_, err := os.Stat(filename)
+	return err
+}
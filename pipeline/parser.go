@@ -0,0 +1,193 @@
+package pipeline
+
+import (
+	"yap/alg/search"
+	"yap/alg/transition"
+	transitionmodel "yap/alg/transition/model"
+	"yap/nlp/format/conll"
+	"yap/nlp/format/conllu"
+	"yap/nlp/format/lattice"
+	. "yap/nlp/parser/dependency/transition"
+	nlp "yap/nlp/types"
+	"yap/util"
+	"yap/util/conf"
+
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	DEFAULT_DEP_BEAM_SIZE  = 64
+	DEFAULT_DEP_ARC_SYSTEM = "eager"
+)
+
+// Parser is a beam search transition based dependency parser
+type Parser struct {
+	sync.Mutex
+
+	BeamSize   int
+	ArcSystem  string
+	Concurrent bool
+
+	LatticeOptions lattice.Options
+
+	EWord, EPOS, EWPOS, EMHost, EMSuffix *util.EnumSet
+	EMorphProp, ERel, ETrans             *util.EnumSet
+	SH, RE, PR, LA, RA, SW               transition.Transition
+
+	TransitionSystem transition.TransitionSystem
+	Beam             *search.Beam
+}
+
+// NewParser loads a dependency parsing model with default settings
+func NewParser(modelPath, featureConf, labelsConf string) (*Parser, error) {
+	p := &Parser{
+		BeamSize:   DEFAULT_DEP_BEAM_SIZE,
+		ArcSystem:  DEFAULT_DEP_ARC_SYSTEM,
+		Concurrent: true,
+	}
+	if err := p.Load(modelPath, featureConf, labelsConf); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Parser) Load(modelPath, featureConf, labelsConf string) error {
+	relations, err := conf.ReadFile(labelsConf)
+	if err != nil {
+		return err
+	}
+	return p.LoadRelations(modelPath, featureConf, relations.Values)
+}
+
+// LoadRelations loads a dependency parsing model trained with the given
+// relations, such as the encoded relations of a pseudo-projective model
+func (p *Parser) LoadRelations(modelPath, featureConf string, relations []string) error {
+	p.ERel = NewRelationEnum(relations)
+	p.ETrans, p.SH, p.RE, p.PR, p.LA, p.RA = NewTransEnum(relations)
+
+	var terminalStack int
+	switch p.ArcSystem {
+	case "standard":
+		p.TransitionSystem = &ArcStandard{
+			SHIFT:       p.SH.Value(),
+			LEFT:        p.LA.Value(),
+			RIGHT:       p.RA.Value(),
+			Transitions: p.ETrans,
+			Relations:   p.ERel,
+		}
+		terminalStack = 1
+	case "eager":
+		p.TransitionSystem = &ArcEager{
+			ArcStandard: ArcStandard{
+				SHIFT:       p.SH.Value(),
+				LEFT:        p.LA.Value(),
+				RIGHT:       p.RA.Value(),
+				Relations:   p.ERel,
+				Transitions: p.ETrans,
+			},
+			REDUCE:  p.RE.Value(),
+			POPROOT: p.PR.Value(),
+		}
+		terminalStack = 0
+	case "swap":
+		// the swap transition is enumerated after the others, as when
+		// training
+		iSW, _ := p.ETrans.Add("SW")
+		p.SW = transition.ConstTransition(iSW)
+		p.TransitionSystem = &ArcSwap{
+			ArcStandard: ArcStandard{
+				SHIFT:       p.SH.Value(),
+				LEFT:        p.LA.Value(),
+				RIGHT:       p.RA.Value(),
+				Relations:   p.ERel,
+				Transitions: p.ETrans,
+			},
+			SWAP: p.SW.Value(),
+		}
+		terminalStack = 1
+	case "hybrid":
+		p.TransitionSystem = &ArcHybrid{
+			ArcStandard: ArcStandard{
+				SHIFT:       p.SH.Value(),
+				LEFT:        p.LA.Value(),
+				RIGHT:       p.RA.Value(),
+				Relations:   p.ERel,
+				Transitions: p.ETrans,
+			},
+		}
+		terminalStack = 1
+	default:
+		return errors.New(fmt.Sprintf("Unknown arc system %s", p.ArcSystem))
+	}
+	p.TransitionSystem.AddDefaultOracle()
+
+	featureSetup, err := transition.LoadFeatureConfFile(featureConf)
+	if err != nil {
+		return err
+	}
+	serialization, err := ReadModel(modelPath)
+	if err != nil {
+		return err
+	}
+	model := &transitionmodel.AvgMatrixSparse{}
+	model.Deserialize(serialization.WeightModel)
+	p.EWord, p.EPOS, p.EWPOS, p.EMHost, p.EMSuffix = serialization.EWord, serialization.EPOS, serialization.EWPOS, serialization.EMHost, serialization.EMSuffix
+	p.EMorphProp = serialization.EMorphProp
+	if p.EMorphProp == nil {
+		p.EMorphProp = util.NewEnumSet(130, "EMorphProp") // random guess of number of possible values
+	}
+
+	extractor := NewExtractor(featureSetup, []byte("A"), p.EWord, p.EPOS, p.EWPOS, p.ERel, p.EMHost, p.EMSuffix, p.EMorphProp, nil, nil)
+	conf := &SimpleConfiguration{
+		EWord:         p.EWord,
+		EPOS:          p.EPOS,
+		EWPOS:         p.EWPOS,
+		EMHost:        p.EMHost,
+		EMSuffix:      p.EMSuffix,
+		ERel:          p.ERel,
+		ETrans:        p.ETrans,
+		TerminalStack: terminalStack,
+		TerminalQueue: 0,
+	}
+	p.Beam = &search.Beam{
+		TransFunc:            p.TransitionSystem,
+		FeatExtractor:        extractor,
+		Base:                 conf,
+		Model:                model,
+		Size:                 p.BeamSize,
+		ConcurrentExec:       p.Concurrent,
+		ShortTempAgenda:      true,
+		EstimatedTransitions: p.ERel.Len()*2 + 2,
+		ScoredStoreDense:     true,
+	}
+	return nil
+}
+
+// Sentence converts a disambiguated lattice to the model's internal representation
+func (p *Parser) Sentence(lat lattice.Lattice) nlp.TaggedSentence {
+	sent := lattice.Lattice2Sentence(p.LatticeOptions.Apply(lat), p.EWord, p.EPOS, p.EWPOS, p.EMorphProp, p.EMHost, p.EMSuffix)
+	return sent.TaggedSentence()
+}
+
+// Parse returns the dependency graph of a disambiguated lattice
+func (p *Parser) Parse(lat lattice.Lattice) nlp.LabeledDependencyGraph {
+	sent := p.Sentence(lat)
+	p.Lock()
+	defer p.Unlock()
+	result, _ := p.Beam.Parse(sent)
+	return result.(nlp.LabeledDependencyGraph)
+}
+
+// Conll converts a parsed graph into a CoNLL sentence
+func (p *Parser) Conll(graph nlp.LabeledDependencyGraph) conll.Sentence {
+	return conll.Graph2ConllIgnoreLemma(graph, p.EMHost, p.EMSuffix, p.LatticeOptions.IgnoreLemma)
+}
+
+// ConllU converts a parsed graph into a CoNLL-U sentence, with multi-word
+// tokens taken from the disambiguation's mappings
+func (p *Parser) ConllU(graph nlp.LabeledDependencyGraph, mappings nlp.Mappings) conllu.Sentence {
+	sent := conllu.Graph2ConllUIgnoreLemma(graph, p.EMHost, p.EMSuffix, p.LatticeOptions.IgnoreLemma)
+	return conllu.MergeGraphAndMappings(sent, mappings)
+}
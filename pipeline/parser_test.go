@@ -0,0 +1,116 @@
+package pipeline
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	transitionmodel "yap/alg/transition/model"
+	. "yap/nlp/parser/dependency/transition"
+	"yap/util"
+)
+
+const testFeatureConf = `feature groups:
+ - group: Test
+   transition: Arc
+   features:
+   - S0|w,S0|w
+   - N0|w,N0|w
+`
+
+// writeTestModel writes an untrained model and a feature configuration to
+// dir, returning their file names
+func writeTestModel(t *testing.T, dir string) (string, string) {
+	featureConf := dir + "/features.yaml"
+	if err := ioutil.WriteFile(featureConf, []byte(testFeatureConf), 0644); err != nil {
+		t.Fatalf("Failed writing feature configuration: %v", err)
+	}
+	e := func(name string) *util.EnumSet { return util.NewEnumSet(10, name) }
+	serialization := &Serialization{
+		WeightModel: transitionmodel.NewAvgMatrixSparse(2, nil, true).Serialize(-1),
+		EWord:       e("EWord"),
+		EPOS:        e("EPOS"),
+		EWPOS:       e("EWPOS"),
+		EMHost:      e("EMHost"),
+		EMSuffix:    e("EMSuffix"),
+		EMorphProp:  e("EMorphProp"),
+	}
+	modelFile := dir + "/model"
+	file, err := os.Create(modelFile)
+	if err != nil {
+		t.Fatalf("Failed creating model file: %v", err)
+	}
+	defer file.Close()
+	if err := gob.NewEncoder(file).Encode(serialization); err != nil {
+		t.Fatalf("Failed writing model: %v", err)
+	}
+	return modelFile, featureConf
+}
+
+func TestLoadRelations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pipeline")
+	if err != nil {
+		t.Fatalf("Failed creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	modelFile, featureConf := writeTestModel(t, dir)
+	relations := []string{"subj", "obj"}
+	cases := []struct {
+		ArcSystem     string
+		TerminalStack int
+	}{
+		{"standard", 1},
+		{"eager", 0},
+		{"swap", 1},
+		{"hybrid", 1},
+	}
+	for _, c := range cases {
+		p := &Parser{BeamSize: 4, ArcSystem: c.ArcSystem}
+		if err := p.LoadRelations(modelFile, featureConf, relations); err != nil {
+			t.Errorf("%s: failed loading model: %v", c.ArcSystem, err)
+			continue
+		}
+		var matches bool
+		switch p.TransitionSystem.(type) {
+		case *ArcStandard:
+			matches = c.ArcSystem == "standard"
+		case *ArcEager:
+			matches = c.ArcSystem == "eager"
+		case *ArcSwap:
+			matches = c.ArcSystem == "swap"
+		case *ArcHybrid:
+			matches = c.ArcSystem == "hybrid"
+		}
+		if !matches {
+			t.Errorf("%s: got transition system %T", c.ArcSystem, p.TransitionSystem)
+		}
+		if p.ERel.Len() != len(relations)+1 {
+			t.Errorf("%s: got %d relations, expected %d with ROOT", c.ArcSystem, p.ERel.Len(), len(relations)+1)
+		}
+		if la, ra := p.ETrans.ValueOf(p.LA.Value()), p.ETrans.ValueOf(p.RA.Value()); la != "LA-ROOT" || ra != "RA-ROOT" {
+			t.Errorf("%s: got first arc transitions %v %v, expected LA-ROOT RA-ROOT", c.ArcSystem, la, ra)
+		}
+		numTrans := 6 + 2*(len(relations)+1)
+		if c.ArcSystem == "swap" {
+			// the swap transition is enumerated after the others
+			if p.SW == nil || p.SW.Value() != numTrans || p.ETrans.ValueOf(p.SW.Value()) != "SW" {
+				t.Errorf("%s: got swap transition %v, expected SW enumerated last", c.ArcSystem, p.SW)
+			}
+			numTrans++
+		} else if p.SW != nil {
+			t.Errorf("%s: got swap transition %v", c.ArcSystem, p.SW)
+		}
+		if p.ETrans.Len() != numTrans {
+			t.Errorf("%s: got %d transitions, expected %d", c.ArcSystem, p.ETrans.Len(), numTrans)
+		}
+		if stack := p.Beam.Base.(*SimpleConfiguration).TerminalStack; stack != c.TerminalStack {
+			t.Errorf("%s: got terminal stack %d, expected %d", c.ArcSystem, stack, c.TerminalStack)
+		}
+	}
+
+	p := &Parser{BeamSize: 4, ArcSystem: "unknown"}
+	if err := p.LoadRelations(modelFile, featureConf, relations); err == nil {
+		t.Error("Expected an error loading a model of an unknown arc system")
+	}
+}